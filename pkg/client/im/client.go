@@ -5,22 +5,167 @@
 package im
 
 import (
+	"context"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+
 	"cloudbases.io/im/pkg/global"
 	"cloudbases.io/im/pkg/manager"
 	"cloudbases.io/im/pkg/pb"
 )
 
+// DefaultTimeout is used by the helper methods when ctx has no deadline
+const DefaultTimeout = 10 * time.Second
+
 type Client struct {
 	pb.IdentityManagerClient
+
+	Timeout time.Duration
 }
 
 func NewClient() (*Client, error) {
-	conn, err := manager.NewClient(global.Global().Config.Host, global.Global().Config.Port)
+	g, err := global.Lookup()
 	if err != nil {
 		return nil, err
 	}
+	conn, err := manager.NewClient(g.Config.Host, g.Config.Port,
+		grpc.WithUnaryInterceptor(UnaryClientMetadataInterceptor(DefaultMetadataExtractors)))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewClientWithConn(conn), nil
+}
 
+func NewClientWithConn(conn *grpc.ClientConn) *Client {
 	return &Client{
 		IdentityManagerClient: pb.NewIdentityManagerClient(conn),
-	}, nil
+		Timeout:               DefaultTimeout,
+	}
+}
+
+// The helper methods below build the pb requests and unwrap the responses,
+// they are named differently from the embedded rpc methods to keep them callable.
+
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || c.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.Timeout)
+}
+
+func (c *Client) JoinGroups(ctx context.Context, userIds, groupIds []string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	_, err := c.JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  userIds,
+		GroupId: groupIds,
+	})
+	return err
+}
+
+func (c *Client) LeaveGroups(ctx context.Context, userIds, groupIds []string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	_, err := c.LeaveGroup(ctx, &pb.LeaveGroupRequest{
+		UserId:  userIds,
+		GroupId: groupIds,
+	})
+	return err
+}
+
+func (c *Client) GetUserById(ctx context.Context, userId string) (*pb.User, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	res, err := c.GetUser(ctx, &pb.GetUserRequest{
+		UserId: userId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.User, nil
+}
+
+func (c *Client) GetGroupById(ctx context.Context, groupId string) (*pb.Group, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	res, err := c.GetGroup(ctx, &pb.GetGroupRequest{
+		GroupId: groupId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Group, nil
+}
+
+func (c *Client) DeleteUsersByIds(ctx context.Context, userIds []string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	_, err := c.DeleteUsers(ctx, &pb.DeleteUsersRequest{
+		UserId: userIds,
+	})
+	return err
+}
+
+func (c *Client) DeleteGroupsByIds(ctx context.Context, groupIds []string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	_, err := c.DeleteGroups(ctx, &pb.DeleteGroupsRequest{
+		GroupId: groupIds,
+	})
+	return err
+}
+
+func (c *Client) CheckPassword(ctx context.Context, userId, password string) (bool, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	res, err := c.ComparePassword(ctx, &pb.ComparePasswordRequest{
+		UserId:   userId,
+		Password: password,
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.Ok, nil
+}
+
+func (c *Client) ChangePassword(ctx context.Context, userId, password string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	_, err := c.ModifyPassword(ctx, &pb.ModifyPasswordRequest{
+		UserId:   userId,
+		Password: password,
+	})
+	return err
+}
+
+// ForEachUser calls fn with every user matched by req, in the order of req, reading the pages
+// from req.Offset with req.Limit users each. It stops at the first error of ListUsers or fn and returns it.
+// The pages are read by offset, the users created or deleted meanwhile may be skipped or seen twice.
+func (c *Client) ForEachUser(ctx context.Context, req *pb.ListUsersRequest, fn func(user *pb.User) error) error {
+	req = proto.Clone(req).(*pb.ListUsersRequest)
+	req.CountOnly = false
+	for {
+		res, err := c.listUsersPage(ctx, req)
+		if err != nil {
+			return err
+		}
+		for _, user := range res.UserSet {
+			if err := fn(user); err != nil {
+				return err
+			}
+		}
+		req.Offset = res.Offset + uint32(len(res.UserSet))
+		if len(res.UserSet) == 0 || req.Offset >= res.Total {
+			return nil
+		}
+	}
+}
+
+func (c *Client) listUsersPage(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.ListUsers(ctx, req)
 }
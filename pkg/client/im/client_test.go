@@ -0,0 +1,233 @@
+// Copyright 2019 The OpenPitrix Authors. All rights reserved.
+// Use of this source code is governed by a Apache license
+// that can be found in the LICENSE file.
+
+package im
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/pb"
+)
+
+type spyCall struct {
+	method      string
+	req         proto.Message
+	hasDeadline bool
+}
+
+// spyServer records the calls, rpc methods not overridden panic on the nil embedded server
+type spyServer struct {
+	pb.IdentityManagerServer
+	calls []spyCall
+}
+
+func (s *spyServer) record(ctx context.Context, method string, req proto.Message) {
+	_, ok := ctx.Deadline()
+	s.calls = append(s.calls, spyCall{method, req, ok})
+}
+
+func (s *spyServer) JoinGroup(ctx context.Context, req *pb.JoinGroupRequest) (*pb.JoinGroupResponse, error) {
+	s.record(ctx, "JoinGroup", req)
+	return &pb.JoinGroupResponse{UserId: req.UserId, GroupId: req.GroupId}, nil
+}
+
+func (s *spyServer) LeaveGroup(ctx context.Context, req *pb.LeaveGroupRequest) (*pb.LeaveGroupResponse, error) {
+	s.record(ctx, "LeaveGroup", req)
+	return &pb.LeaveGroupResponse{UserId: req.UserId, GroupId: req.GroupId}, nil
+}
+
+func (s *spyServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {
+	s.record(ctx, "GetUser", req)
+	return &pb.GetUserResponse{User: &pb.User{UserId: req.UserId}}, nil
+}
+
+func (s *spyServer) GetGroup(ctx context.Context, req *pb.GetGroupRequest) (*pb.GetGroupResponse, error) {
+	s.record(ctx, "GetGroup", req)
+	return &pb.GetGroupResponse{Group: &pb.Group{GroupId: req.GroupId}}, nil
+}
+
+func (s *spyServer) DeleteUsers(ctx context.Context, req *pb.DeleteUsersRequest) (*pb.DeleteUsersResponse, error) {
+	s.record(ctx, "DeleteUsers", req)
+	return &pb.DeleteUsersResponse{UserId: req.UserId}, nil
+}
+
+func (s *spyServer) DeleteGroups(ctx context.Context, req *pb.DeleteGroupsRequest) (*pb.DeleteGroupsResponse, error) {
+	s.record(ctx, "DeleteGroups", req)
+	return &pb.DeleteGroupsResponse{GroupId: req.GroupId}, nil
+}
+
+func (s *spyServer) ComparePassword(ctx context.Context, req *pb.ComparePasswordRequest) (*pb.ComparePasswordResponse, error) {
+	s.record(ctx, "ComparePassword", req)
+	return &pb.ComparePasswordResponse{Ok: req.Password == "passw0rd"}, nil
+}
+
+func (s *spyServer) ModifyPassword(ctx context.Context, req *pb.ModifyPasswordRequest) (*pb.ModifyPasswordResponse, error) {
+	s.record(ctx, "ModifyPassword", req)
+	return &pb.ModifyPasswordResponse{UserId: req.UserId}, nil
+}
+
+// newBufconnClient serves server in memory and returns a client connected to it
+func newBufconnClient(t *testing.T, server pb.IdentityManagerServer, opts ...grpc.DialOption) *Client {
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	pb.RegisterIdentityManagerServer(s, server)
+	go s.Serve(lis)
+
+	opts = append(opts,
+		grpc.WithInsecure(),
+		grpc.WithDialer(func(string, time.Duration) (net.Conn, error) {
+			return lis.Dial()
+		}),
+	)
+	conn, err := grpc.Dial("bufnet", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+		s.Stop()
+	})
+	return NewClientWithConn(conn)
+}
+
+func TestClientHelpers(t *testing.T) {
+	server := &spyServer{}
+	client := newBufconnClient(t, server)
+	ctx := context.Background()
+	userIds := []string{"uid-1", "uid-2"}
+	groupIds := []string{"gid-1"}
+
+	require.NoError(t, client.JoinGroups(ctx, userIds, groupIds))
+	require.NoError(t, client.LeaveGroups(ctx, userIds, groupIds))
+
+	user, err := client.GetUserById(ctx, "uid-1")
+	require.NoError(t, err)
+	require.Equal(t, "uid-1", user.UserId)
+
+	group, err := client.GetGroupById(ctx, "gid-1")
+	require.NoError(t, err)
+	require.Equal(t, "gid-1", group.GroupId)
+
+	require.NoError(t, client.DeleteUsersByIds(ctx, userIds))
+	require.NoError(t, client.DeleteGroupsByIds(ctx, groupIds))
+
+	ok, err := client.CheckPassword(ctx, "uid-1", "passw0rd")
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = client.CheckPassword(ctx, "uid-1", "wrong")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, client.ChangePassword(ctx, "uid-1", "newpassw0rd"))
+
+	expected := []spyCall{
+		{"JoinGroup", &pb.JoinGroupRequest{UserId: userIds, GroupId: groupIds}, true},
+		{"LeaveGroup", &pb.LeaveGroupRequest{UserId: userIds, GroupId: groupIds}, true},
+		{"GetUser", &pb.GetUserRequest{UserId: "uid-1"}, true},
+		{"GetGroup", &pb.GetGroupRequest{GroupId: "gid-1"}, true},
+		{"DeleteUsers", &pb.DeleteUsersRequest{UserId: userIds}, true},
+		{"DeleteGroups", &pb.DeleteGroupsRequest{GroupId: groupIds}, true},
+		{"ComparePassword", &pb.ComparePasswordRequest{UserId: "uid-1", Password: "passw0rd"}, true},
+		{"ComparePassword", &pb.ComparePasswordRequest{UserId: "uid-1", Password: "wrong"}, true},
+		{"ModifyPassword", &pb.ModifyPasswordRequest{UserId: "uid-1", Password: "newpassw0rd"}, true},
+	}
+	require.Len(t, server.calls, len(expected))
+	for i, call := range server.calls {
+		require.Equal(t, expected[i].method, call.method)
+		require.True(t, proto.Equal(expected[i].req, call.req), "%s: %v", call.method, call.req)
+		require.True(t, call.hasDeadline, call.method)
+	}
+}
+
+func TestClientHelpersWithoutTimeout(t *testing.T) {
+	server := &spyServer{}
+	client := newBufconnClient(t, server)
+	client.Timeout = 0
+
+	require.NoError(t, client.JoinGroups(context.Background(), []string{"uid-1"}, []string{"gid-1"}))
+	require.Len(t, server.calls, 1)
+	require.False(t, server.calls[0].hasDeadline)
+}
+
+func TestClientForEachUser(t *testing.T) {
+	fake := NewFakeClient()
+	client := &Client{IdentityManagerClient: fake, Timeout: DefaultTimeout}
+	ctx := context.Background()
+	var userIds []string
+	for i := 0; i < 7; i++ {
+		userIds = append(userIds, createFakeUser(t, fake, fmt.Sprintf("user%d", i)))
+	}
+
+	req := &pb.ListUsersRequest{Reverse: true, Limit: 3}
+	var seen []string
+	require.NoError(t, client.ForEachUser(ctx, req, func(user *pb.User) error {
+		seen = append(seen, user.UserId)
+		return nil
+	}))
+	require.Equal(t, userIds, seen)
+	// the request of the caller is left as it is
+	require.Equal(t, uint32(0), req.Offset)
+
+	seen = nil
+	require.NoError(t, client.ForEachUser(ctx, &pb.ListUsersRequest{Reverse: true, Limit: 2, Offset: 4}, func(user *pb.User) error {
+		seen = append(seen, user.UserId)
+		return nil
+	}))
+	require.Equal(t, userIds[4:], seen)
+
+	// the error of fn stops the walk
+	errStop := errors.New("stop")
+	count := 0
+	err := client.ForEachUser(ctx, &pb.ListUsersRequest{Limit: 2}, func(user *pb.User) error {
+		count++
+		if count == 3 {
+			return errStop
+		}
+		return nil
+	})
+	require.Equal(t, errStop, err)
+	require.Equal(t, 3, count)
+
+	// the error of a page stops the walk after the users of the previous pages
+	client.IdentityManagerClient = &failingPagesClient{FakeClient: fake, pages: 1}
+	count = 0
+	err = client.ForEachUser(ctx, &pb.ListUsersRequest{Limit: 3}, func(user *pb.User) error {
+		count++
+		return nil
+	})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.Equal(t, 3, count)
+
+	client.IdentityManagerClient = fake
+	err = client.ForEachUser(ctx, &pb.ListUsersRequest{Status: []string{constants.StatusDeleted}}, func(user *pb.User) error {
+		t.Fatal("called with no users")
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// failingPagesClient fails ListUsers after pages successful calls
+type failingPagesClient struct {
+	*FakeClient
+	pages int
+}
+
+func (c *failingPagesClient) ListUsers(ctx context.Context, in *pb.ListUsersRequest, opts ...grpc.CallOption) (*pb.ListUsersResponse, error) {
+	if c.pages == 0 {
+		return nil, status.Error(codes.Unavailable, "unavailable")
+	}
+	c.pages--
+	return c.FakeClient.ListUsers(ctx, in, opts...)
+}
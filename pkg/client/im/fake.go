@@ -0,0 +1,483 @@
+// Copyright 2019 The OpenPitrix Authors. All rights reserved.
+// Use of this source code is governed by a Apache license
+// that can be found in the LICENSE file.
+
+package im
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/db"
+	"cloudbases.io/im/pkg/models"
+	"cloudbases.io/im/pkg/pb"
+	"cloudbases.io/im/pkg/util/jsonutil"
+	"cloudbases.io/im/pkg/util/stringutil"
+)
+
+// FakeClient is an in-memory pb.IdentityManagerClient for the tests of the services depending on im,
+// it returns the same error codes as the server on the supported operations,
+// the others return codes.Unimplemented.
+type FakeClient struct {
+	mutex    sync.Mutex
+	users    map[string]*models.User
+	groups   map[string]*models.Group
+	bindings map[string]map[string]string // group id -> user id -> binding status
+}
+
+var _ pb.IdentityManagerClient = (*FakeClient)(nil)
+
+func NewFakeClient() *FakeClient {
+	return &FakeClient{
+		users:    make(map[string]*models.User),
+		groups:   make(map[string]*models.Group),
+		bindings: make(map[string]map[string]string),
+	}
+}
+
+// errRecordNotFound is what the server returns for a missing record, the gorm error is not a grpc status
+var errRecordNotFound = status.Error(codes.Unknown, "record not found")
+
+var errUnimplemented = status.Error(codes.Unimplemented, "not implemented by FakeClient")
+
+func (p *FakeClient) getUser(userId string) (*models.User, error) {
+	user, ok := p.users[userId]
+	if !ok {
+		return nil, errRecordNotFound
+	}
+	return user, nil
+}
+
+func (p *FakeClient) getGroup(groupId string) (*models.Group, error) {
+	group, ok := p.groups[groupId]
+	if !ok {
+		return nil, errRecordNotFound
+	}
+	return group, nil
+}
+
+func (p *FakeClient) GetVersion(ctx context.Context, in *pb.GetVersionRequest, opts ...grpc.CallOption) (*pb.GetVersionResponse, error) {
+	return &pb.GetVersionResponse{}, nil
+}
+
+func (p *FakeClient) CreateGroup(ctx context.Context, in *pb.CreateGroupRequest, opts ...grpc.CallOption) (*pb.CreateGroupResponse, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	parentGroupId := stringutil.SimplifyString(in.ParentGroupId)
+	parentGroupPath := ""
+	if parentGroupId != "" {
+		parent, err := p.getGroup(parentGroupId)
+		if err != nil {
+			return nil, err
+		}
+		parentGroupPath = parent.GroupPath
+	}
+	group := models.NewGroup(parentGroupId, parentGroupPath, in.GroupName, in.Description, in.Extra)
+	p.groups[group.GroupId] = group
+	return &pb.CreateGroupResponse{GroupId: group.GroupId}, nil
+}
+
+func (p *FakeClient) DeleteGroups(ctx context.Context, in *pb.DeleteGroupsRequest, opts ...grpc.CallOption) (*pb.DeleteGroupsResponse, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(in.GroupId) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "empty group id")
+	}
+	groupIds := append([]string{}, in.GroupId...)
+	// the active sub groups are collected level by level
+	for found := true; found; {
+		found = false
+		for _, group := range p.groups {
+			if group.Status == constants.StatusActive && stringutil.Contains(groupIds, group.ParentGroupId) &&
+				!stringutil.Contains(groupIds, group.GroupId) {
+				if !in.Cascade || !in.CascadeDescendants {
+					return nil, status.Errorf(codes.PermissionDenied, "there are still sub groups %v in group: %v", []string{group.GroupId}, in.GroupId)
+				}
+				groupIds = append(groupIds, group.GroupId)
+				found = true
+			}
+		}
+	}
+	for _, groupId := range groupIds {
+		if len(p.bindings[groupId]) > 0 && !in.Cascade {
+			return nil, status.Errorf(codes.PermissionDenied, "there are still users in group: %v", in.GroupId)
+		}
+	}
+	now := time.Now()
+	for _, groupId := range groupIds {
+		delete(p.bindings, groupId)
+		if group, ok := p.groups[groupId]; ok {
+			group.Status = constants.StatusDeleted
+			group.StatusTime = now
+			group.UpdateTime = now
+		}
+	}
+	return &pb.DeleteGroupsResponse{GroupId: groupIds}, nil
+}
+
+func (p *FakeClient) ModifyGroup(ctx context.Context, in *pb.ModifyGroupRequest, opts ...grpc.CallOption) (*pb.ModifyGroupResponse, error) {
+	return nil, errUnimplemented
+}
+
+func (p *FakeClient) GetGroup(ctx context.Context, in *pb.GetGroupRequest, opts ...grpc.CallOption) (*pb.GetGroupResponse, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	group, err := p.getGroup(in.GroupId)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetGroupResponse{Group: group.ToPB()}, nil
+}
+
+func (p *FakeClient) GetGroupWithUser(ctx context.Context, in *pb.GetGroupRequest, opts ...grpc.CallOption) (*pb.GetGroupWithUserResponse, error) {
+	return nil, errUnimplemented
+}
+
+func (p *FakeClient) ListGroups(ctx context.Context, in *pb.ListGroupsRequest, opts ...grpc.CallOption) (*pb.ListGroupsResponse, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var groups []*models.Group
+	for _, group := range p.groups {
+		if len(in.GroupId) > 0 && !stringutil.Contains(in.GroupId, group.GroupId) ||
+			len(in.ParentGroupId) > 0 && !stringutil.Contains(in.ParentGroupId, group.ParentGroupId) ||
+			len(in.GroupName) > 0 && !stringutil.Contains(in.GroupName, group.GroupName) ||
+			len(in.Status) > 0 && !stringutil.Contains(in.Status, group.Status) {
+			continue
+		}
+		groups = append(groups, group)
+	}
+	reverse := in.Reverse
+	if in.Pagination != nil {
+		reverse = in.Pagination.Reverse
+	}
+	// ids are sortable by creation, newest first unless reversed
+	sort.Slice(groups, func(i, j int) bool {
+		return (groups[i].GroupId > groups[j].GroupId) != reverse
+	})
+
+	limit := db.GetLimitFromRequest(in)
+	offset := db.GetOffsetFromRequest(in)
+	var pbGroups []*pb.Group
+	start, end := pageRange(len(groups), offset, limit)
+	for _, group := range groups[start:end] {
+		pbGroups = append(pbGroups, group.ToPB())
+	}
+	return &pb.ListGroupsResponse{
+		Total:    uint32(len(groups)),
+		GroupSet: pbGroups,
+		Limit:    limit,
+		Offset:   offset,
+		PageInfo: db.NewPageInfo(uint32(len(groups)), offset, limit),
+	}, nil
+}
+
+func (p *FakeClient) ListGroupsWithUser(ctx context.Context, in *pb.ListGroupsRequest, opts ...grpc.CallOption) (*pb.ListGroupsWithUserResponse, error) {
+	return nil, errUnimplemented
+}
+
+func (p *FakeClient) CountGroups(ctx context.Context, in *pb.ListGroupsRequest, opts ...grpc.CallOption) (*pb.CountGroupsResponse, error) {
+	res, err := p.ListGroups(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CountGroupsResponse{Total: res.Total}, nil
+}
+
+func (p *FakeClient) CreateUser(ctx context.Context, in *pb.CreateUserRequest, opts ...grpc.CallOption) (*pb.CreateUserResponse, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	user := models.NewUser(in.Username, in.Email, in.PhoneNumber, in.Description, in.Password, in.Extra)
+	user.AvatarUrl = in.AvatarUrl
+	user.FirstName = stringutil.SimplifyString(in.FirstName)
+	user.LastName = stringutil.SimplifyString(in.LastName)
+	for _, u := range p.users {
+		if u.Username == user.Username || u.Email == user.Email {
+			return nil, status.Error(codes.Unknown, "UNIQUE constraint failed")
+		}
+		if user.PhoneNumber != "" && u.PhoneNumber == user.PhoneNumber && u.Status == constants.StatusActive {
+			return nil, status.Errorf(codes.AlreadyExists, "phone number [%s] is used by another user", user.PhoneNumber)
+		}
+	}
+	p.users[user.UserId] = user
+	return &pb.CreateUserResponse{UserId: user.UserId}, nil
+}
+
+func (p *FakeClient) DeleteUsers(ctx context.Context, in *pb.DeleteUsersRequest, opts ...grpc.CallOption) (*pb.DeleteUsersResponse, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(in.UserId) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "empty user id")
+	}
+	now := time.Now()
+	for _, userId := range in.UserId {
+		for _, users := range p.bindings {
+			delete(users, userId)
+		}
+		if user, ok := p.users[userId]; ok {
+			user.Status = constants.StatusDeleted
+			user.StatusTime = now
+			user.UpdateTime = now
+		}
+	}
+	return &pb.DeleteUsersResponse{UserId: in.UserId}, nil
+}
+
+func (p *FakeClient) ModifyUser(ctx context.Context, in *pb.ModifyUserRequest, opts ...grpc.CallOption) (*pb.ModifyUserResponse, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	user, err := p.getUser(in.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if in.Version != nil && in.Version.GetValue() != user.Version {
+		return nil, status.Errorf(codes.Aborted, "user [%s] has been modified, version [%d] is stale", in.UserId, in.Version.GetValue())
+	}
+
+	if in.Username != "" {
+		user.Username = in.Username
+	}
+	if in.Description != "" {
+		user.Description = in.Description
+	}
+	if in.Email != "" {
+		user.Email = stringutil.SimplifyString(in.Email)
+	}
+	if in.PhoneNumber != "" {
+		user.PhoneNumber = stringutil.SimplifyString(in.PhoneNumber)
+	}
+	if len(in.Extra) > 0 {
+		user.Extra = stringutil.NewString(jsonutil.ToString(in.Extra))
+	}
+	if in.AvatarUrl != nil {
+		user.AvatarUrl = in.AvatarUrl.GetValue()
+	}
+	if firstName := stringutil.SimplifyString(in.FirstName); firstName != "" {
+		user.FirstName = firstName
+	}
+	if lastName := stringutil.SimplifyString(in.LastName); lastName != "" {
+		user.LastName = lastName
+	}
+	user.UpdateTime = time.Now()
+	user.Version++
+	return &pb.ModifyUserResponse{UserId: user.UserId, Version: user.Version}, nil
+}
+
+func (p *FakeClient) GetUser(ctx context.Context, in *pb.GetUserRequest, opts ...grpc.CallOption) (*pb.GetUserResponse, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	user, err := p.getUser(in.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetUserResponse{User: user.ToPB()}, nil
+}
+
+func (p *FakeClient) GetUserWithGroup(ctx context.Context, in *pb.GetUserRequest, opts ...grpc.CallOption) (*pb.GetUserWithGroupResponse, error) {
+	return nil, errUnimplemented
+}
+
+func (p *FakeClient) ListUsers(ctx context.Context, in *pb.ListUsersRequest, opts ...grpc.CallOption) (*pb.ListUsersResponse, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var users []*models.User
+	for _, user := range p.users {
+		if len(in.UserId) > 0 && !stringutil.Contains(in.UserId, user.UserId) ||
+			len(in.Username) > 0 && !stringutil.Contains(in.Username, user.Username) ||
+			len(in.Email) > 0 && !stringutil.Contains(in.Email, user.Email) ||
+			len(in.PhoneNumber) > 0 && !stringutil.Contains(in.PhoneNumber, user.PhoneNumber) ||
+			len(in.Status) > 0 && !stringutil.Contains(in.Status, user.Status) {
+			continue
+		}
+		if len(in.GroupId) > 0 && !p.inAnyGroup(user.UserId, in.GroupId) ||
+			len(in.InGroupIds) > 0 && !p.inAnyGroup(user.UserId, in.InGroupIds) {
+			continue
+		}
+		users = append(users, user)
+	}
+	// ids are sortable by creation, newest first unless reversed
+	sort.Slice(users, func(i, j int) bool {
+		return (users[i].UserId > users[j].UserId) != in.Reverse
+	})
+
+	limit := db.GetLimitFromRequest(in)
+	offset := db.GetOffsetFromRequest(in)
+	var pbUsers []*pb.User
+	start, end := pageRange(len(users), offset, limit)
+	for _, user := range users[start:end] {
+		pbUsers = append(pbUsers, user.ToPB())
+	}
+	return &pb.ListUsersResponse{
+		Total:   uint32(len(users)),
+		UserSet: pbUsers,
+		Limit:   limit,
+		Offset:  offset,
+	}, nil
+}
+
+// inAnyGroup reports whether user accepted to be a member of any of groupIds
+func (p *FakeClient) inAnyGroup(userId string, groupIds []string) bool {
+	for _, groupId := range groupIds {
+		if p.bindings[groupId][userId] == constants.BindingStatusAccepted {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *FakeClient) ListUsersWithGroup(ctx context.Context, in *pb.ListUsersRequest, opts ...grpc.CallOption) (*pb.ListUsersWithGroupResponse, error) {
+	return nil, errUnimplemented
+}
+
+func (p *FakeClient) CountUsers(ctx context.Context, in *pb.ListUsersRequest, opts ...grpc.CallOption) (*pb.CountUsersResponse, error) {
+	res, err := p.ListUsers(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CountUsersResponse{Total: res.Total}, nil
+}
+
+func (p *FakeClient) JoinGroup(ctx context.Context, in *pb.JoinGroupRequest, opts ...grpc.CallOption) (*pb.JoinGroupResponse, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(in.UserId) == 0 || len(in.GroupId) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "empty user id or group id")
+	}
+	bindingStatus := in.Status
+	if bindingStatus == "" {
+		bindingStatus = constants.BindingStatusAccepted
+	}
+	if !stringutil.Contains(constants.BindingStatuses, bindingStatus) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid binding status [%s]", bindingStatus)
+	}
+	if in.Role != "" && !stringutil.Contains(constants.BindingRoles, in.Role) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid binding role [%s]", in.Role)
+	}
+	for _, groupId := range in.GroupId {
+		for _, userId := range in.UserId {
+			if _, ok := p.bindings[groupId][userId]; ok {
+				return nil, status.Errorf(codes.PermissionDenied, "user already in group")
+			}
+		}
+	}
+
+	for _, groupId := range in.GroupId {
+		if p.bindings[groupId] == nil {
+			p.bindings[groupId] = make(map[string]string)
+		}
+		for _, userId := range in.UserId {
+			p.bindings[groupId][userId] = bindingStatus
+		}
+	}
+	return &pb.JoinGroupResponse{GroupId: in.GroupId, UserId: in.UserId}, nil
+}
+
+func (p *FakeClient) LeaveGroup(ctx context.Context, in *pb.LeaveGroupRequest, opts ...grpc.CallOption) (*pb.LeaveGroupResponse, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(in.UserId) == 0 || len(in.GroupId) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "empty user id or group id")
+	}
+	for _, groupId := range in.GroupId {
+		for _, userId := range in.UserId {
+			if _, ok := p.bindings[groupId][userId]; !ok {
+				return nil, status.Errorf(codes.PermissionDenied, "user not in group")
+			}
+		}
+	}
+
+	for _, groupId := range in.GroupId {
+		for _, userId := range in.UserId {
+			delete(p.bindings[groupId], userId)
+		}
+	}
+	return &pb.LeaveGroupResponse{GroupId: in.GroupId, UserId: in.UserId}, nil
+}
+
+func (p *FakeClient) ComparePassword(ctx context.Context, in *pb.ComparePasswordRequest, opts ...grpc.CallOption) (*pb.ComparePasswordResponse, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var user *models.User
+	if in.UserId == "" && in.PhoneNumber != "" {
+		for _, u := range p.users {
+			if u.PhoneNumber == in.PhoneNumber && u.Status == constants.StatusActive {
+				user = u
+			}
+		}
+	} else {
+		user = p.users[in.UserId]
+	}
+	// the server does not match a missing user
+	if user == nil {
+		return &pb.ComparePasswordResponse{Ok: false}, nil
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(in.Password))
+	if err != nil {
+		return &pb.ComparePasswordResponse{Ok: false}, nil
+	}
+	now := time.Now()
+	user.LastLoginAt = &now
+	res := &pb.ComparePasswordResponse{Ok: true}
+	if in.WithUser {
+		res.User = user.ToPB()
+	}
+	return res, nil
+}
+
+func (p *FakeClient) ModifyPassword(ctx context.Context, in *pb.ModifyPasswordRequest, opts ...grpc.CallOption) (*pb.ModifyPasswordResponse, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if in.Password == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "empty password")
+	}
+	// the server updates nothing for a missing user
+	if user, ok := p.users[in.UserId]; ok {
+		now := time.Now()
+		user.Password = models.GetBcryptPassword(in.Password)
+		user.UpdateTime = now
+		user.PasswordUpdatedAt = &now
+	}
+	return &pb.ModifyPasswordResponse{UserId: in.UserId}, nil
+}
+
+func (p *FakeClient) ChangeOwnPassword(ctx context.Context, in *pb.ChangeOwnPasswordRequest, opts ...grpc.CallOption) (*pb.ModifyPasswordResponse, error) {
+	return nil, errUnimplemented
+}
+
+func (p *FakeClient) ValidatePassword(ctx context.Context, in *pb.ValidatePasswordRequest, opts ...grpc.CallOption) (*pb.ValidatePasswordResponse, error) {
+	return nil, errUnimplemented
+}
+
+// pageRange returns the bounds of [offset, offset+limit) in n items
+func pageRange(n int, offset, limit uint32) (int, int) {
+	start := int(offset)
+	if start > n {
+		start = n
+	}
+	end := start + int(limit)
+	if end > n {
+		end = n
+	}
+	return start, end
+}
@@ -0,0 +1,218 @@
+// Copyright 2019 The OpenPitrix Authors. All rights reserved.
+// Use of this source code is governed by a Apache license
+// that can be found in the LICENSE file.
+
+package im
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/pb"
+)
+
+func createFakeUser(t *testing.T, client *FakeClient, username string) string {
+	res, err := client.CreateUser(context.Background(), &pb.CreateUserRequest{
+		Username: username,
+		Email:    username + "@op.com",
+		Password: "passw0rd",
+	})
+	require.NoError(t, err)
+	return res.UserId
+}
+
+func createFakeGroup(t *testing.T, client *FakeClient, name, parentId string) string {
+	res, err := client.CreateGroup(context.Background(), &pb.CreateGroupRequest{
+		GroupName:     name,
+		ParentGroupId: parentId,
+	})
+	require.NoError(t, err)
+	return res.GroupId
+}
+
+func TestFakeClientJoinLeaveGroup(t *testing.T) {
+	client := NewFakeClient()
+	ctx := context.Background()
+	userId := createFakeUser(t, client, "fake")
+	groupId := createFakeGroup(t, client, "fake", "")
+
+	_, err := client.JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{userId}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{userId}, GroupId: []string{groupId}, Status: "unknown"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{userId}, GroupId: []string{groupId}})
+	require.NoError(t, err)
+	_, err = client.JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{userId}, GroupId: []string{groupId}})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	res, err := client.ListUsers(ctx, &pb.ListUsersRequest{GroupId: []string{groupId}})
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), res.Total)
+	require.Equal(t, userId, res.UserSet[0].UserId)
+	res, err = client.ListUsers(ctx, &pb.ListUsersRequest{InGroupIds: []string{groupId, "gid-missing"}})
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), res.Total)
+	require.Equal(t, userId, res.UserSet[0].UserId)
+
+	_, err = client.DeleteGroups(ctx, &pb.DeleteGroupsRequest{GroupId: []string{groupId}})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.LeaveGroup(ctx, &pb.LeaveGroupRequest{UserId: []string{userId}, GroupId: []string{groupId}})
+	require.NoError(t, err)
+	_, err = client.LeaveGroup(ctx, &pb.LeaveGroupRequest{UserId: []string{userId}, GroupId: []string{groupId}})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.DeleteGroups(ctx, &pb.DeleteGroupsRequest{GroupId: []string{groupId}})
+	require.NoError(t, err)
+}
+
+func TestFakeClientPendingMembers(t *testing.T) {
+	client := NewFakeClient()
+	ctx := context.Background()
+	userId := createFakeUser(t, client, "fake")
+	groupId := createFakeGroup(t, client, "fake", "")
+
+	_, err := client.JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{userId},
+		GroupId: []string{groupId},
+		Status:  constants.BindingStatusPending,
+	})
+	require.NoError(t, err)
+
+	// pending users are not members, but still block deleting the group
+	res, err := client.ListUsers(ctx, &pb.ListUsersRequest{GroupId: []string{groupId}})
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), res.Total)
+	_, err = client.DeleteGroups(ctx, &pb.DeleteGroupsRequest{GroupId: []string{groupId}})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestFakeClientDeleteGroupsCascade(t *testing.T) {
+	client := NewFakeClient()
+	ctx := context.Background()
+	userId := createFakeUser(t, client, "fake")
+	root := createFakeGroup(t, client, "root", "")
+	child := createFakeGroup(t, client, "child", root)
+	_, err := client.JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{userId}, GroupId: []string{child}})
+	require.NoError(t, err)
+
+	_, err = client.DeleteGroups(ctx, &pb.DeleteGroupsRequest{GroupId: []string{child}})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.DeleteGroups(ctx, &pb.DeleteGroupsRequest{GroupId: []string{root}, Cascade: true})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	res, err := client.DeleteGroups(ctx, &pb.DeleteGroupsRequest{GroupId: []string{root}, Cascade: true, CascadeDescendants: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{root, child}, res.GroupId)
+	users, err := client.ListUsers(ctx, &pb.ListUsersRequest{GroupId: []string{child}})
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), users.Total)
+}
+
+func TestFakeClientPassword(t *testing.T) {
+	client := NewFakeClient()
+	ctx := context.Background()
+	userId := createFakeUser(t, client, "fake")
+
+	res, err := client.ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: userId, Password: "passw0rd"})
+	require.NoError(t, err)
+	require.True(t, res.Ok)
+	require.Nil(t, res.User)
+	res, err = client.ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: userId, Password: "passw0rd", WithUser: true})
+	require.NoError(t, err)
+	require.True(t, res.Ok)
+	require.Equal(t, userId, res.User.UserId)
+	require.NotNil(t, res.User.LastLoginAt)
+	res, err = client.ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: userId, Password: "wrong", WithUser: true})
+	require.NoError(t, err)
+	require.False(t, res.Ok)
+	require.Nil(t, res.User)
+	res, err = client.ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: "uid-missing", Password: "passw0rd"})
+	require.NoError(t, err)
+	require.False(t, res.Ok)
+
+	_, err = client.ModifyPassword(ctx, &pb.ModifyPasswordRequest{UserId: userId})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.ModifyPassword(ctx, &pb.ModifyPasswordRequest{UserId: userId, Password: "newpassw0rd"})
+	require.NoError(t, err)
+
+	res, err = client.ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: userId, Password: "newpassw0rd"})
+	require.NoError(t, err)
+	require.True(t, res.Ok)
+}
+
+func TestFakeClientModifyUser(t *testing.T) {
+	client := NewFakeClient()
+	ctx := context.Background()
+	userId := createFakeUser(t, client, "fake")
+
+	res, err := client.ModifyUser(ctx, &pb.ModifyUserRequest{
+		UserId:      userId,
+		Description: "modified",
+		Version:     &wrappers.UInt32Value{Value: 0},
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), res.Version)
+
+	_, err = client.ModifyUser(ctx, &pb.ModifyUserRequest{
+		UserId:      userId,
+		Description: "stale",
+		Version:     &wrappers.UInt32Value{Value: 0},
+	})
+	require.Equal(t, codes.Aborted, status.Code(err))
+
+	getRes, err := client.GetUser(ctx, &pb.GetUserRequest{UserId: userId})
+	require.NoError(t, err)
+	require.Equal(t, "modified", getRes.User.Description)
+	require.Equal(t, uint32(1), getRes.User.Version)
+
+	_, err = client.ModifyUser(ctx, &pb.ModifyUserRequest{UserId: "uid-missing"})
+	require.Equal(t, codes.Unknown, status.Code(err))
+}
+
+func TestFakeClientListUsers(t *testing.T) {
+	client := NewFakeClient()
+	ctx := context.Background()
+	var userIds []string
+	for _, name := range []string{"fake1", "fake2", "fake3"} {
+		userIds = append(userIds, createFakeUser(t, client, name))
+	}
+	_, err := client.DeleteUsers(ctx, &pb.DeleteUsersRequest{UserId: userIds[:1]})
+	require.NoError(t, err)
+	_, err = client.DeleteUsers(ctx, &pb.DeleteUsersRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	res, err := client.ListUsers(ctx, &pb.ListUsersRequest{})
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), res.Total)
+	require.Equal(t, userIds[2], res.UserSet[0].UserId)
+
+	res, err = client.ListUsers(ctx, &pb.ListUsersRequest{
+		Status:  []string{constants.StatusActive},
+		Reverse: true,
+		Limit:   1,
+		Offset:  1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), res.Total)
+	require.Len(t, res.UserSet, 1)
+	require.Equal(t, userIds[2], res.UserSet[0].UserId)
+	require.Equal(t, uint32(1), res.Limit)
+	require.Equal(t, uint32(1), res.Offset)
+
+	count, err := client.CountUsers(ctx, &pb.ListUsersRequest{Status: []string{constants.StatusDeleted}})
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), count.Total)
+}
+
+func TestFakeClientUnimplemented(t *testing.T) {
+	_, err := NewFakeClient().ListUsersWithGroup(context.Background(), &pb.ListUsersRequest{})
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 The OpenPitrix Authors. All rights reserved.
+// Use of this source code is governed by a Apache license
+// that can be found in the LICENSE file.
+
+package im
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"cloudbases.io/im/pkg/db"
+)
+
+const (
+	MetadataRequestId     = "x-request-id"
+	MetadataAuthorization = "authorization"
+)
+
+type contextKey string
+
+const (
+	requestIdKey contextKey = "request_id"
+	authTokenKey contextKey = "auth_token"
+)
+
+func ContextWithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey, requestId)
+}
+
+func RequestIdFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdKey).(string)
+	return requestId
+}
+
+func ContextWithAuthToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, authTokenKey, token)
+}
+
+func AuthTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(authTokenKey).(string)
+	return token
+}
+
+// MetadataExtractor returns the value of a metadata from ctx, empty value is not sent
+type MetadataExtractor func(ctx context.Context) string
+
+// DefaultMetadataExtractors is used by NewClient, keyed by the outgoing metadata key
+var DefaultMetadataExtractors = map[string]MetadataExtractor{
+	MetadataRequestId:     RequestIdFromContext,
+	MetadataAuthorization: AuthTokenFromContext,
+	db.MetadataConsistentRead: func(ctx context.Context) string {
+		if db.IsConsistentRead(ctx) {
+			return "true"
+		}
+		return ""
+	},
+}
+
+// UnaryClientMetadataInterceptor attaches the metadata extracted from ctx to every call
+func UnaryClientMetadataInterceptor(extractors map[string]MetadataExtractor) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		var kv []string
+		for key, extract := range extractors {
+			if value := extract(ctx); value != "" {
+				kv = append(kv, key, value)
+			}
+		}
+		if len(kv) > 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, kv...)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
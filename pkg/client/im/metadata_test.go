@@ -0,0 +1,71 @@
+// Copyright 2019 The OpenPitrix Authors. All rights reserved.
+// Use of this source code is governed by a Apache license
+// that can be found in the LICENSE file.
+
+package im
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"cloudbases.io/im/pkg/pb"
+)
+
+// metadataSpyServer records the incoming metadata of GetUser
+type metadataSpyServer struct {
+	pb.IdentityManagerServer
+	md metadata.MD
+}
+
+func (s *metadataSpyServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {
+	s.md, _ = metadata.FromIncomingContext(ctx)
+	return &pb.GetUserResponse{User: &pb.User{UserId: req.UserId}}, nil
+}
+
+func TestMetadataPropagation(t *testing.T) {
+	server := &metadataSpyServer{}
+	client := newBufconnClient(t, server,
+		grpc.WithUnaryInterceptor(UnaryClientMetadataInterceptor(DefaultMetadataExtractors)))
+
+	ctx := ContextWithRequestId(context.Background(), "req-1")
+	ctx = ContextWithAuthToken(ctx, "Bearer token")
+	_, err := client.GetUserById(ctx, "uid-1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"req-1"}, server.md.Get(MetadataRequestId))
+	require.Equal(t, []string{"Bearer token"}, server.md.Get(MetadataAuthorization))
+
+	// missing values are omitted
+	ctx = ContextWithRequestId(context.Background(), "req-2")
+	_, err = client.GetUserById(ctx, "uid-1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"req-2"}, server.md.Get(MetadataRequestId))
+	require.Empty(t, server.md.Get(MetadataAuthorization))
+
+	_, err = client.GetUserById(context.Background(), "uid-1")
+	require.NoError(t, err)
+	require.Empty(t, server.md.Get(MetadataRequestId))
+	require.Empty(t, server.md.Get(MetadataAuthorization))
+}
+
+func TestMetadataPropagationCustomExtractor(t *testing.T) {
+	server := &metadataSpyServer{}
+	tenant := func(ctx context.Context) string {
+		tenant, _ := ctx.Value(contextKey("tenant")).(string)
+		return tenant
+	}
+	client := newBufconnClient(t, server,
+		grpc.WithUnaryInterceptor(UnaryClientMetadataInterceptor(map[string]MetadataExtractor{
+			"x-tenant": tenant,
+		})))
+
+	ctx := context.WithValue(context.Background(), contextKey("tenant"), "t1")
+	ctx = ContextWithRequestId(ctx, "req-1")
+	_, err := client.GetUserById(ctx, "uid-1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"t1"}, server.md.Get("x-tenant"))
+	require.Empty(t, server.md.Get(MetadataRequestId))
+}
@@ -32,7 +32,9 @@ import (
 const EnvPrefix = "IM"
 
 type Config struct {
-	DB DBConfig
+	DB       DBConfig
+	Password PasswordConfig
+	Audit    AuditConfig
 
 	Host        string `default:"im-service"`
 	Port        int    `default:"9119"`
@@ -40,6 +42,18 @@ type Config struct {
 	TlsCertFile string `default:"server.cert"`
 	TlsKeyFile  string `default:"server.key"`
 	LogLevel    string `default:"DEBUG"`
+
+	// country code used to normalize phone numbers without international prefix
+	DefaultCountryCode string `default:"86"`
+
+	// interval of purging the expired records, 0 disables it
+	CleanupIntervalSeconds int `default:"3600"`
+
+	// max duration of handling a request, 0 means unlimited
+	HandlerTimeoutSeconds int `default:"60"`
+
+	// users and groups whose membership checked by IsUserInGroup are cached, 0 disables the cache
+	MembershipCacheSize int `default:"0"`
 }
 
 type DBConfig struct {
@@ -50,6 +64,67 @@ type DBConfig struct {
 	Password      string `default:"password"`
 	Database      string `default:"im"`
 	LogModeEnable bool   `default:"false"`
+	// level of the SQL statements logged with LogModeEnable, the errors are logged at ERROR
+	LogLevel string `default:"DEBUG"`
+
+	// prepended to the names of the tables, e.g. "im_" for im_user, the tables are created
+	// with the prefix by AutoMigrate, the scripts applied by flyway are not prefixed
+	TablePrefix string `default:""`
+
+	// apply the schema migrations at startup, keep it disabled when migrations are applied by flyway
+	AutoMigrate bool `default:"false"`
+
+	// search words shorter than it are not searched by LIKE, they match nothing,
+	// or are ignored when ShortSearchMatchAll is set
+	SearchMinLength     int  `default:"2"`
+	ShortSearchMatchAll bool `default:"false"`
+	// search ignores accents, e.g. "Jose" matches "José",
+	// mysql columns are compared by their accent-insensitive collation
+	AccentInsensitiveSearch bool `default:"false"`
+	// comma separated columns searched by LIKE in addition to the built-in search columns,
+	// e.g. "description", the columns must be real columns of the tables
+	UserSearchColumns  string `default:""`
+	GroupSearchColumns string `default:""`
+
+	// the deep health check writes in a transaction rolled back, its result is reused
+	// for the interval, so frequent probes do not write on every call
+	DeepHealthCheckIntervalSeconds int `default:"30"`
+
+	// comma separated databases on the same server the users are sharded across by a hash of user id,
+	// the groups and bindings stay in Database, empty means the users are in Database too
+	ShardDatabases string `default:""`
+
+	// host of a read replica of the database, the path of its file for sqlite3, the membership lists
+	// are read from it unless the request asks for a consistent read, empty means no replica
+	ReplicaHost string `default:""`
+}
+
+type PasswordConfig struct {
+	// passwords not changed in max age days must be changed, 0 means never expire
+	MaxAgeDays int `default:"0"`
+
+	MinLength     int  `default:"8"`
+	RequireLetter bool `default:"true"`
+	RequireDigit  bool `default:"true"`
+	RequireSymbol bool `default:"false"`
+	// reject the common passwords, from BlocklistFile or the embedded list if it is empty
+	BlockCommon   bool   `default:"true"`
+	BlocklistFile string `default:""`
+
+	// seconds the password hashes read by BatchComparePassword are cached, 0 disables the cache
+	HashCacheSeconds int `default:"0"`
+
+	// a user is locked for LockoutSeconds after MaxFailedLogins consecutive failed compares
+	// of the password, until it is unlocked by UnlockUser, 0 disables the lockout
+	MaxFailedLogins int `default:"0"`
+	LockoutSeconds  int `default:"900"`
+}
+
+type AuditConfig struct {
+	// the audit records are stored in the database besides the service log, for ExportAuditLog
+	Stored bool `default:"false"`
+	// the stored records older than it are deleted by the cleanup, 0 keeps them forever
+	RetentionDays int `default:"90"`
 }
 
 func (m *Config) Clone() *Config {
@@ -84,6 +159,32 @@ func (m *DBConfig) GetUrl() string {
 	return m.Database
 }
 
+// GetShardDatabases returns the databases of the user shards, nil when the users are not sharded
+func (m *DBConfig) GetShardDatabases() []string {
+	return splitList(m.ShardDatabases)
+}
+
+// splitList splits a comma separated config value, the blank items are dropped
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// GetUserSearchColumns returns the extra search columns of users, nil when there is none
+func (m *DBConfig) GetUserSearchColumns() []string {
+	return splitList(m.UserSearchColumns)
+}
+
+// GetGroupSearchColumns returns the extra search columns of groups, nil when there is none
+func (m *DBConfig) GetGroupSearchColumns() []string {
+	return splitList(m.GroupSearchColumns)
+}
+
 func Default() *Config {
 	conf := new(Config)
 
@@ -17,30 +17,89 @@ limitations under the License.
 package constants
 
 const (
-	ColumnUserId         = "user_id"
-	ColumnGroupId        = "group_id"
-	ColumnCreateTime     = "create_time"
-	ColumnUpdateTime     = "update_time"
-	ColumnStatusTime     = "status_time"
-	ColumnStatus         = "status"
-	ColumnPassword       = "password"
-	ColumnEmail          = "email"
-	ColumnPhoneNumber    = "phone_number"
-	ColumnGroupPath      = "group_path"
-	ColumnUsername       = "username"
-	ColumnGroupName      = "group_name"
-	ColumnParentGroupId  = "parent_group_id"
-	ColumnGroupPathLevel = "group_path_level"
-	ColumnDescription    = "description"
-	ColumnExtra          = "extra"
+	ColumnId                 = "id"
+	ColumnUserId             = "user_id"
+	ColumnGroupId            = "group_id"
+	ColumnCreateTime         = "create_time"
+	ColumnUpdateTime         = "update_time"
+	ColumnStatusTime         = "status_time"
+	ColumnStatus             = "status"
+	ColumnPassword           = "password"
+	ColumnEmail              = "email"
+	ColumnPhoneNumber        = "phone_number"
+	ColumnGroupPath          = "group_path"
+	ColumnUsername           = "username"
+	ColumnGroupName          = "group_name"
+	ColumnParentGroupId      = "parent_group_id"
+	ColumnGroupPathLevel     = "group_path_level"
+	ColumnDescription        = "description"
+	ColumnExtra              = "extra"
+	ColumnVersion            = "version"
+	ColumnPasswordUpdatedAt  = "password_updated_at"
+	ColumnTag                = "tag"
+	ColumnRole               = "role"
+	ColumnAvatarUrl          = "avatar_url"
+	ColumnLastLoginAt        = "last_login_at"
+	ColumnExternalProvider   = "external_provider"
+	ColumnExternalId         = "external_id"
+	ColumnMustChangePassword = "must_change_password"
+	ColumnFailedLoginCount   = "failed_login_count"
+	ColumnLockedUntil        = "locked_until"
+	ColumnDeleteTime         = "delete_time"
+	ColumnType               = "type"
+	ColumnOutcome            = "outcome"
+	ColumnClientIp           = "client_ip"
+	ColumnArchived           = "archived"
+	ColumnCreatedBy          = "created_by"
+	ColumnFirstName          = "first_name"
+	ColumnLastName           = "last_name"
 )
 
 const (
 	TableUserGroupBinding = "user_group_binding"
 	TableUser             = "user"
 	TableGroup            = "group"
+	TableUserTag          = "user_tag"
+	// the tombstones of the deleted bindings
+	TableUserGroupBindingDeletion = "user_group_binding_deletion"
+	// the stored audit records, see config AuditLogStored
+	TableAuditLog = "audit_log"
 )
 
+// real columns of the tables, column names from requests must be one of them
+var TableColumns = map[string][]string{
+	TableUser: {
+		ColumnUserId, ColumnUsername, ColumnEmail, ColumnPhoneNumber, ColumnDescription, ColumnPassword,
+		ColumnStatus, ColumnCreateTime, ColumnUpdateTime, ColumnStatusTime, ColumnExtra, ColumnVersion,
+		ColumnPasswordUpdatedAt, ColumnAvatarUrl, ColumnLastLoginAt, ColumnExternalProvider, ColumnExternalId,
+		ColumnMustChangePassword, ColumnFailedLoginCount, ColumnLockedUntil, ColumnFirstName, ColumnLastName,
+	},
+	TableGroup: {
+		ColumnGroupId, ColumnParentGroupId, ColumnGroupPath, ColumnGroupName, ColumnDescription,
+		ColumnStatus, ColumnCreateTime, ColumnUpdateTime, ColumnStatusTime, ColumnExtra, ColumnGroupPathLevel,
+		ColumnArchived,
+	},
+	TableUserGroupBinding: {
+		ColumnId, ColumnUserId, ColumnGroupId, ColumnCreateTime, ColumnStatus, ColumnRole, ColumnCreatedBy,
+	},
+	TableUserTag: {
+		ColumnUserId, ColumnTag, ColumnCreateTime,
+	},
+	TableUserGroupBindingDeletion: {
+		ColumnId, ColumnUserId, ColumnGroupId, ColumnDeleteTime,
+	},
+	TableAuditLog: {
+		ColumnId, ColumnType, ColumnUserId, ColumnOutcome, ColumnClientIp, ColumnCreateTime,
+	},
+}
+
+// unique columns of the tables, used as the last order column to keep the pages stable
+var PrimaryKeyColumns = map[string]string{
+	TableUser:             ColumnUserId,
+	TableGroup:            ColumnGroupId,
+	TableUserGroupBinding: ColumnId,
+}
+
 // columns that can be search through sql '=' operator
 var IndexedColumns = map[string][]string{
 	TableUser: {
@@ -51,6 +110,32 @@ var IndexedColumns = map[string][]string{
 	},
 }
 
+// columns that can be selected through display columns
+var DisplayColumns = map[string][]string{
+	TableUser: {
+		ColumnUserId, ColumnUsername, ColumnEmail, ColumnPhoneNumber, ColumnDescription,
+		ColumnStatus, ColumnCreateTime, ColumnUpdateTime, ColumnStatusTime, ColumnExtra, ColumnVersion,
+		ColumnAvatarUrl, ColumnLastLoginAt, ColumnExternalProvider, ColumnExternalId, ColumnFirstName, ColumnLastName,
+	},
+	TableGroup: TableColumns[TableGroup],
+}
+
+// columns whose distinct values can be listed, for the filters of the clients
+var DistinctColumns = map[string][]string{
+	TableUser: {
+		ColumnStatus, ColumnExternalProvider,
+	},
+	TableGroup: {
+		ColumnStatus, ColumnParentGroupId, ColumnGroupPathLevel,
+	},
+	TableUserGroupBinding: {
+		ColumnStatus, ColumnRole,
+	},
+	TableUserTag: {
+		ColumnTag,
+	},
+}
+
 var SearchWordColumnTable = []string{
 	TableUser,
 	TableGroup,
@@ -59,9 +144,15 @@ var SearchWordColumnTable = []string{
 // columns that can be search through sql 'like' operator
 var SearchColumns = map[string][]string{
 	TableUser: {
-		ColumnUsername, ColumnEmail, ColumnPhoneNumber,
+		ColumnUsername, ColumnEmail, ColumnPhoneNumber, ColumnFirstName, ColumnLastName,
 	},
 	TableGroup: {
 		ColumnGroupName, ColumnGroupPath,
 	},
 }
+
+// columns joined by a space into the full name, the whole search string is matched against it,
+// e.g. "Jane Doe" matches the first name Jane and the last name Doe
+var FullNameColumns = map[string][]string{
+	TableUser: {ColumnFirstName, ColumnLastName},
+}
@@ -20,6 +20,8 @@ const (
 	PrefixGroupId            = "gid-"
 	PrefixUserId             = "uid-"
 	PrefixUserGroupBindingId = "bid-"
+	PrefixAuditLogId         = "aid-"
+	PrefixCorrelationId      = "cid-"
 )
 
 const (
@@ -30,3 +32,56 @@ const (
 	StatusActive  = "active"
 	StatusDeleted = "deleted"
 )
+
+// status of user group binding, pending bindings are invitations not accepted yet
+const (
+	BindingStatusAccepted = "accepted"
+	BindingStatusPending  = "pending"
+)
+
+var BindingStatuses = []string{
+	BindingStatusAccepted,
+	BindingStatusPending,
+}
+
+// role of user in group, admins manage the group and its descendant groups
+const (
+	BindingRoleMember = "member"
+	BindingRoleAdmin  = "admin"
+)
+
+var BindingRoles = []string{
+	BindingRoleMember,
+	BindingRoleAdmin,
+}
+
+// sort keys of list groups and list users ordering the groups by the number of their accepted members
+// and the users by the number of the groups they are accepted in
+const (
+	SortKeyMemberCount = "member_count"
+	SortKeyGroupCount  = "group_count"
+)
+
+// where search words are matched in the columns, prefix can use the indexes of the columns
+const (
+	SearchModeContains = "contains"
+	SearchModePrefix   = "prefix"
+	SearchModeSuffix   = "suffix"
+)
+
+var SearchModes = []string{
+	SearchModeContains,
+	SearchModePrefix,
+	SearchModeSuffix,
+}
+
+// formats of the audit records exported by ExportAuditLog, json is one object per line
+const (
+	AuditExportFormatJSON = "json"
+	AuditExportFormatCSV  = "csv"
+)
+
+var AuditExportFormats = []string{
+	AuditExportFormatJSON,
+	AuditExportFormatCSV,
+}
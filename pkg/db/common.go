@@ -17,7 +17,9 @@ limitations under the License.
 package db
 
 import (
+	"reflect"
 	"strings"
+	"time"
 
 	"github.com/fatih/structs"
 	"github.com/golang/protobuf/ptypes/wrappers"
@@ -36,6 +38,11 @@ type RequestHadLimit interface {
 	GetLimit() uint32
 }
 
+// RequestWithCountOnly asks for no rows at all, a limit of 0 means the default otherwise
+type RequestWithCountOnly interface {
+	GetCountOnly() bool
+}
+
 const (
 	DefaultOffset = uint32(0)
 	DefaultLimit  = uint32(20)
@@ -45,6 +52,49 @@ const (
 	DefaultSelectLimit = 200
 )
 
+// set by OpenDatabase from config, see getSearchFilter
+var (
+	SearchMinLength         = 2
+	ShortSearchMatchAll     = false
+	AccentInsensitiveSearch = false
+)
+
+// set by OpenDatabase from config, the columns of the tables searched in addition to
+// constants.SearchColumns, see searchColumns
+var ExtraSearchColumns = map[string][]string{}
+
+// searchColumns returns the columns of tableName searched by the search words
+func searchColumns(tableName string) []string {
+	extra := ExtraSearchColumns[tableName]
+	if len(extra) == 0 {
+		return constants.SearchColumns[tableName]
+	}
+	columns := make([]string, 0, len(constants.SearchColumns[tableName])+len(extra))
+	columns = append(columns, constants.SearchColumns[tableName]...)
+	return append(columns, extra...)
+}
+
+// setExtraSearchColumns keeps the columns that are real columns of tableName and not searched yet,
+// the password is never searched
+func setExtraSearchColumns(tableName string, columns []string) {
+	var extra []string
+	for _, column := range columns {
+		if column == constants.ColumnPassword || !stringutil.Contains(constants.TableColumns[tableName], column) {
+			logger.Warnf(nil, "Skip search column [%s], it is not a searchable column of table [%s]", column, tableName)
+			continue
+		}
+		if stringutil.Contains(constants.SearchColumns[tableName], column) || stringutil.Contains(extra, column) {
+			continue
+		}
+		extra = append(extra, column)
+	}
+	if extra == nil {
+		delete(ExtraSearchColumns, tableName)
+		return
+	}
+	ExtraSearchColumns[tableName] = extra
+}
+
 func GetLimit(n uint32) uint32 {
 	if n < 0 {
 		n = 0
@@ -64,6 +114,9 @@ func GetOffset(n uint32) uint32 {
 
 func GetOffsetFromRequest(req RequestHadOffset) uint32 {
 	n := req.GetOffset()
+	if p := getPagination(req); p != nil {
+		n = p.GetOffset()
+	}
 	if n == 0 {
 		return DefaultOffset
 	}
@@ -71,7 +124,13 @@ func GetOffsetFromRequest(req RequestHadOffset) uint32 {
 }
 
 func GetLimitFromRequest(req RequestHadLimit) uint32 {
+	if r, ok := req.(RequestWithCountOnly); ok && r.GetCountOnly() {
+		return 0
+	}
 	n := req.GetLimit()
+	if p := getPagination(req); p != nil {
+		n = p.GetLimit()
+	}
 	if n == 0 {
 		return DefaultLimit
 	}
@@ -92,11 +151,35 @@ type RequestWithReverse interface {
 	RequestWithSortKey
 	GetReverse() bool
 }
+type RequestWithSearchGroupName interface {
+	Request
+	GetSearchGroupName() bool
+}
+type RequestWithRankSearch interface {
+	Request
+	GetSearchWord() []string
+	GetRankSearch() bool
+}
+type RequestWithSearchMode interface {
+	Request
+	GetSearchMode() string
+}
+
+// RequestWithStrict is filtered only by the indexed columns when strict, its search words are ignored
+type RequestWithStrict interface {
+	GetStrict() bool
+}
+
+func isStrict(req interface{}) bool {
+	r, ok := req.(RequestWithStrict)
+	return ok && r.GetStrict()
+}
 
 const (
 	TagName               = "json"
 	SearchWordColumnName  = "search_word"
 	RootGroupIdColumnName = "root_group_id"
+	IsEmptySuffix         = "_is_empty"
 )
 
 func getReqValue(param interface{}) interface{} {
@@ -158,91 +241,360 @@ func getFieldName(field *structs.Field) string {
 
 type Chain struct {
 	*gorm.DB
+	// words of the search filter applied by BuildFilterConditions
+	searchWords []string
+	// the rows are already ordered by relevance to searchWords
+	ranked bool
 }
 
 func GetChain(tx *gorm.DB) *Chain {
 	return &Chain{
-		tx,
+		DB: tx,
 	}
 }
 
+// the Chain methods leave the chain unmodified when there is nothing to build on
+func (c *Chain) isNil() bool {
+	return c == nil || c.DB == nil
+}
+
+// isNilRequest reports whether req is nil or a nil pointer, structs.Fields panics on them
+func isNilRequest(req Request) bool {
+	if req == nil {
+		return true
+	}
+	v := reflect.ValueOf(req)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
 func (c *Chain) BuildFilterConditions(req Request, tableName string, exclude ...string) *Chain {
+	if c.isNil() || isNilRequest(req) {
+		return c
+	}
 	return c.buildFilterConditions(req, tableName, exclude...)
 }
 
+// BuildRootGroupIdConditions filters the groups under any of rootGroupIds, including themselves,
+// by the prefix of their group paths, which are read first
 func (c *Chain) BuildRootGroupIdConditions(rootGroupIds []string) *Chain {
-	if len(rootGroupIds) > 0 {
-		var conditions []string
-		for _, v := range rootGroupIds {
-			likeV := "%" + stringutil.SimplifyString(v) + "%"
-			conditions = append(conditions, constants.ColumnGroupPath+" LIKE '"+likeV+"'")
+	if c.isNil() || len(rootGroupIds) == 0 {
+		return c
+	}
+	var rootGroupPaths []string
+	if err := c.DB.New().Table(TableName(constants.TableGroup)).
+		Where(constants.ColumnGroupId+" in (?)", stringutil.SimplifyStringList(rootGroupIds)).
+		Pluck(constants.ColumnGroupPath, &rootGroupPaths).Error; err != nil {
+		c.DB.AddError(err)
+		return c
+	}
+	return c.BuildGroupPathPrefixConditions(rootGroupPaths)
+}
+
+// BuildGroupPathPrefixConditions filters the groups of groupPaths and their descendants,
+// nothing matches if groupPaths is empty
+func (c *Chain) BuildGroupPathPrefixConditions(groupPaths []string) *Chain {
+	if c.isNil() {
+		return c
+	}
+	if len(groupPaths) == 0 {
+		c.DB = c.DB.Where("1 = 0")
+		return c
+	}
+	var conditions []string
+	var args []interface{}
+	for _, groupPath := range groupPaths {
+		conditions = append(conditions, "("+constants.ColumnGroupPath+" = ? OR "+constants.ColumnGroupPath+" LIKE ?)")
+		args = append(args, groupPath, groupPath+constants.GroupPathSep+"%")
+	}
+	c.DB = c.DB.Where(strings.Join(conditions, " OR "), args...)
+	return c
+}
+
+// "\tfoo  bar " => ["foo", "bar"]
+func tokenizeSearch(s string) []string {
+	return strings.Fields(s)
+}
+
+// likePattern anchors the search word v by mode, contains if mode is empty or unknown
+func likePattern(v, mode string) string {
+	switch mode {
+	case constants.SearchModePrefix:
+		return v + "%"
+	case constants.SearchModeSuffix:
+		return "%" + v
+	}
+	return "%" + v + "%"
+}
+
+// searchColumn strips the accents of column in sqlite, by the function registered by OpenDatabase
+func (c *Chain) searchColumn(column string) string {
+	if AccentInsensitiveSearch && c.DB.Dialect().GetName() == "sqlite3" {
+		return unaccentFunc + "(" + column + ")"
+	}
+	return column
+}
+
+// fullNameColumn returns the expression of the full name of tableName, empty if it has none
+// or any of its columns is excluded from the search
+func (c *Chain) fullNameColumn(tableName string, exclude []string) string {
+	columns := constants.FullNameColumns[tableName]
+	if len(columns) == 0 {
+		return ""
+	}
+	for _, column := range columns {
+		if stringutil.Contains(exclude, column) {
+			return ""
 		}
-		condition := strings.Join(conditions, " OR ")
-		c.DB = c.DB.Where(condition)
 	}
+	if c.DB.Dialect().GetName() == "sqlite3" {
+		return c.searchColumn(strings.Join(columns, " || ' ' || "))
+	}
+	return c.searchColumn("CONCAT(" + strings.Join(columns, ", ' ', ") + ")")
+}
+
+// users are matched by the names of the active groups they belong to,
+// the condition takes the like pattern as its only argument
+func getGroupNameSearchCondition(groupNameColumn string) string {
+	return constants.ColumnUserId + " IN (SELECT DISTINCT `" + constants.TableUserGroupBinding + "`." + constants.ColumnUserId +
+		" FROM " + AliasTable(constants.TableUserGroupBinding) + " JOIN " + AliasTable(constants.TableGroup) +
+		" ON `" + constants.TableGroup + "`." + constants.ColumnGroupId + "=`" + constants.TableUserGroupBinding + "`." + constants.ColumnGroupId +
+		" AND `" + constants.TableUserGroupBinding + "`." + constants.ColumnStatus + " = '" + constants.BindingStatusAccepted + "'" +
+		" WHERE `" + constants.TableGroup + "`." + constants.ColumnStatus + " = '" + constants.StatusActive + "'" +
+		" AND " + groupNameColumn + " LIKE ?)"
+}
+
+// AddSearchRankOrder orders the rows by relevance when req asks for it, the rows with a column
+// equal to the search words come first, then the rows with a column starting with them
+func (c *Chain) AddSearchRankOrder(req Request, tableName string) *Chain {
+	if c.isNil() || isNilRequest(req) {
+		return c
+	}
+	r, ok := req.(RequestWithRankSearch)
+	if !ok || !r.GetRankSearch() || isStrict(req) {
+		return c
+	}
+	vs := r.GetSearchWord()
+	if len(vs) == 1 {
+		vs = tokenizeSearch(vs[0])
+	}
+	c.addSearchRank(vs, tableName)
 	return c
 }
 
-func (c *Chain) getSearchFilter(tableName string, value interface{}, exclude ...string) {
+func (c *Chain) addSearchRank(vs []string, tableName string) {
+	var ranks []string
+	var args []interface{}
+	for _, v := range vs {
+		v = stringutil.SimplifyString(v)
+		if len([]rune(v)) < SearchMinLength {
+			continue
+		}
+		if AccentInsensitiveSearch {
+			v = stringutil.RemoveAccents(v)
+		}
+		var exactConditions, prefixConditions []string
+		for _, column := range searchColumns(tableName) {
+			if strings.HasSuffix(column, "_id") {
+				continue
+			}
+			column = c.searchColumn(column)
+			exactConditions = append(exactConditions, "LOWER("+column+") = LOWER(?)")
+			prefixConditions = append(prefixConditions, column+" LIKE ?")
+		}
+		if len(exactConditions) == 0 {
+			continue
+		}
+		ranks = append(ranks, "(CASE WHEN "+strings.Join(exactConditions, " OR ")+" THEN 0"+
+			" WHEN "+strings.Join(prefixConditions, " OR ")+" THEN 1 ELSE 2 END)")
+		for range exactConditions {
+			args = append(args, v)
+		}
+		for range prefixConditions {
+			args = append(args, v+"%")
+		}
+	}
+	if len(ranks) == 0 {
+		return
+	}
+	c.DB = c.Order(gorm.Expr(strings.Join(ranks, " + "), args...))
+	c.ranked = true
+}
+
+// BuildTimeRangeConditions filters column in [start, end), zero time means no bound
+func (c *Chain) BuildTimeRangeConditions(column string, start, end time.Time) *Chain {
+	if c.isNil() {
+		return c
+	}
+	if !start.IsZero() {
+		c.DB = c.DB.Where(column+" >= ?", start.UTC())
+	}
+	if !end.IsZero() {
+		c.DB = c.DB.Where(column+" < ?", end.UTC())
+	}
+	return c
+}
+
+func (c *Chain) getSearchFilter(req Request, tableName string, value interface{}, exclude ...string) {
+	searchGroupName := false
+	if r, ok := req.(RequestWithSearchGroupName); ok && tableName == constants.TableUser {
+		searchGroupName = r.GetSearchGroupName()
+	}
+	searchMode := constants.SearchModeContains
+	if r, ok := req.(RequestWithSearchMode); ok && r.GetSearchMode() != "" {
+		searchMode = r.GetSearchMode()
+	}
+
 	var andConditions []string
+	// the arguments of the placeholders of andConditions in order, the search words are never put in the sql
+	var args []interface{}
+	var searchWords []string
+	// the words together matched against the full name, e.g. "Jane D" against "Jane Doe",
+	// the condition takes fullNameLike as its argument
+	var fullNameCondition, fullNameLike string
 	if vs, ok := value.([]string); ok {
-		var orConditions []string
+		// a single free-text search string is split into words
+		if len(vs) == 1 {
+			vs = tokenizeSearch(vs[0])
+		}
+		searchWords = vs
+		if fullName := c.fullNameColumn(tableName, exclude); fullName != "" && len(vs) > 1 {
+			fullNameLike = likePattern(strings.Join(strings.Fields(strings.Join(vs, " ")), " "), searchMode)
+			if AccentInsensitiveSearch {
+				fullNameLike = stringutil.RemoveAccents(fullNameLike)
+			}
+			fullNameCondition = fullName + " LIKE ?"
+		}
+		// every word must be matched by one of the columns
 		for _, v := range vs {
-			for _, column := range constants.SearchColumns[tableName] {
+			// short words match too many rows with a costly full scan
+			if len([]rune(v)) < SearchMinLength {
+				if ShortSearchMatchAll {
+					continue
+				}
+				// the other words are still matched as a whole against the full name
+				if fullNameCondition != "" {
+					c.DB = c.DB.Where(fullNameCondition, fullNameLike)
+					c.searchWords = searchWords
+					return
+				}
+				c.DB = c.DB.Where("1 = 0")
+				return
+			}
+			var orConditions []string
+			likeV := likePattern(stringutil.SimplifyString(v), searchMode)
+			if AccentInsensitiveSearch {
+				likeV = stringutil.RemoveAccents(likeV)
+			}
+			for _, column := range searchColumns(tableName) {
 				if stringutil.Contains(exclude, column) {
 					continue
 				}
 				// if column suffix is _id, must exact match
 				if strings.HasSuffix(column, "_id") {
-					orConditions = append(orConditions, column+" = '"+v+"'")
+					orConditions = append(orConditions, column+" = ?")
+					args = append(args, v)
 				} else {
-					likeV := "%" + stringutil.SimplifyString(v) + "%"
-					orConditions = append(orConditions, column+" LIKE '"+likeV+"'")
+					orConditions = append(orConditions, c.searchColumn(column)+" LIKE ?")
+					args = append(args, likeV)
 				}
 			}
+			if searchGroupName {
+				orConditions = append(orConditions, getGroupNameSearchCondition(c.searchColumn("`"+constants.TableGroup+"`."+constants.ColumnGroupName)))
+				args = append(args, likeV)
+			}
+			if len(orConditions) > 0 {
+				andConditions = append(andConditions, "("+strings.Join(orConditions, " OR ")+")")
+			}
 		}
-		andConditions = append(andConditions, strings.Join(orConditions, " OR "))
 
 	} else if value != nil {
 		logger.Warnf(nil, "search_word [%+v] is not []string", value)
 	}
+	// nothing to search, e.g. no search columns of the table
+	if len(andConditions) == 0 {
+		return
+	}
 	condition := strings.Join(andConditions, " AND ")
-	c.DB = c.DB.Where(condition)
+	if fullNameCondition != "" {
+		condition = "((" + condition + ") OR " + fullNameCondition + ")"
+		args = append(args, fullNameLike)
+	}
+	c.DB = c.DB.Where(condition, args...)
+	c.searchWords = searchWords
+}
+
+// column names come from the json tags of requests, so they are only used in sql
+// when they are indexed columns and real columns of the table
+func isFilterColumn(tableName, column string) bool {
+	if !stringutil.Contains(constants.IndexedColumns[tableName], column) {
+		return false
+	}
+	if !stringutil.Contains(constants.TableColumns[tableName], column) {
+		logger.Warnf(nil, "Skip filter [%s], it is not a column of table [%s]", column, tableName)
+		return false
+	}
+	return true
 }
 
 func (c *Chain) buildFilterConditions(req Request, tableName string, exclude ...string) *Chain {
 	for _, field := range structs.Fields(req) {
 		column := getFieldName(field)
 		param := field.Value()
-		indexedColumns, ok := constants.IndexedColumns[tableName]
-		if ok && stringutil.Contains(indexedColumns, column) {
+		if isFilterColumn(tableName, column) {
 			value := getReqValue(param)
 			if value != nil {
 				key := column
 				c.DB = c.Where(key+" in (?)", value)
 			}
 		}
-		if column == SearchWordColumnName && stringutil.Contains(constants.SearchWordColumnTable, tableName) {
+		// <column>_is_empty = true matches the rows without value of the indexed column
+		if strings.HasSuffix(column, IsEmptySuffix) {
+			emptyColumn := strings.TrimSuffix(column, IsEmptySuffix)
+			if isEmpty, _ := param.(bool); isEmpty && isFilterColumn(tableName, emptyColumn) {
+				c.DB = c.Where("(" + emptyColumn + " IS NULL OR " + emptyColumn + " = '')")
+			}
+		}
+		if column == SearchWordColumnName && stringutil.Contains(constants.SearchWordColumnTable, tableName) && !isStrict(req) {
 			value := getReqValue(param)
-			c.getSearchFilter(tableName, value, exclude...)
+			c.getSearchFilter(req, tableName, value, exclude...)
 		}
 	}
 	return c
 }
 
-func (c *Chain) AddQueryOrderDir(req Request, defaultColumn string) *Chain {
-	order := "DESC"
+// getSortOrder returns the sort key and the reverse flag of req, the ones of its pagination when set
+func getSortOrder(req Request) (sortKey string, reverse bool) {
+	if p := getPagination(req); p != nil {
+		return p.GetSortKey(), p.GetReverse()
+	}
 	if r, ok := req.(RequestWithReverse); ok {
-		if r.GetReverse() {
-			order = "ASC"
-		}
+		reverse = r.GetReverse()
 	}
 	if r, ok := req.(RequestWithSortKey); ok {
-		s := r.GetSortKey()
-		if s != "" {
-			defaultColumn = s
-		}
+		sortKey = r.GetSortKey()
+	}
+	return sortKey, reverse
+}
+
+func (c *Chain) AddQueryOrderDir(req Request, tableName, defaultColumn string) *Chain {
+	if c.isNil() {
+		return c
+	}
+	order := "DESC"
+	sortKey, reverse := getSortOrder(req)
+	if reverse {
+		order = "ASC"
+	}
+	if sortKey != "" {
+		defaultColumn = sortKey
+	} else if len(c.searchWords) > 0 && !c.ranked {
+		// the searched rows are ordered by relevance first, unless they are sorted by an explicit sort key
+		c.addSearchRank(c.searchWords, tableName)
 	}
 	c.DB = c.Order(defaultColumn + " " + order)
+	// rows with the same sort column are ordered by the primary key, so offset pagination is deterministic
+	if primaryKey, ok := constants.PrimaryKeyColumns[tableName]; ok && primaryKey != defaultColumn {
+		c.DB = c.Order(primaryKey + " " + order)
+	}
 	return c
 }
@@ -0,0 +1,469 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/require"
+
+	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/pb"
+)
+
+const testTable = "test_search"
+
+type testRequest struct {
+	SearchWord []string `json:"search_word,omitempty"`
+	Status     []string `json:"status,omitempty"`
+	Unknown    []string `json:"unknown,omitempty"`
+	RankSearch bool     `json:"rank_search,omitempty"`
+	SearchMode string   `json:"search_mode,omitempty"`
+	SortKey    string   `json:"sort_key,omitempty"`
+	Strict     bool     `json:"strict,omitempty"`
+}
+
+func (r *testRequest) GetSearchWord() []string { return r.SearchWord }
+func (r *testRequest) GetRankSearch() bool     { return r.RankSearch }
+func (r *testRequest) GetSearchMode() string   { return r.SearchMode }
+func (r *testRequest) GetStrict() bool         { return r.Strict }
+
+// nil safe like the generated getters, AddQueryOrderDir reads it from nil requests
+func (r *testRequest) GetSortKey() string {
+	if r == nil {
+		return ""
+	}
+	return r.SortKey
+}
+
+func (*testRequest) Reset()                      {}
+func (*testRequest) String() string              { return "" }
+func (*testRequest) ProtoMessage()               {}
+func (*testRequest) Descriptor() ([]byte, []int) { return nil, nil }
+
+type testRow struct {
+	Name   string
+	Status string
+}
+
+// registers testTable to the column constants during the test
+func prepareTestTable(t *testing.T, searchColumns []string) *Database {
+	database := openTestDatabase(t)
+	require.NoError(t, database.Exec("CREATE TABLE "+testTable+" (name varchar(50), status varchar(50))").Error)
+	for _, row := range []testRow{{"a", constants.StatusActive}, {"b", constants.StatusActive}, {"c", constants.StatusDeleted}} {
+		require.NoError(t, database.Table(testTable).Create(&row).Error)
+	}
+
+	searchWordColumnTable := constants.SearchWordColumnTable
+	constants.SearchWordColumnTable = append(constants.SearchWordColumnTable, testTable)
+	constants.SearchColumns[testTable] = searchColumns
+	constants.IndexedColumns[testTable] = []string{constants.ColumnStatus}
+	constants.TableColumns[testTable] = []string{"name", constants.ColumnStatus}
+	constants.PrimaryKeyColumns[testTable] = "name"
+	// the names of the rows are single letters
+	SearchMinLength = 1
+	t.Cleanup(func() {
+		SearchMinLength = 2
+		ShortSearchMatchAll = false
+		AccentInsensitiveSearch = false
+		constants.SearchWordColumnTable = searchWordColumnTable
+		delete(constants.SearchColumns, testTable)
+		delete(constants.IndexedColumns, testTable)
+		delete(constants.TableColumns, testTable)
+		delete(constants.PrimaryKeyColumns, testTable)
+	})
+	return database
+}
+
+func findTestRows(t *testing.T, database *Database, req Request, exclude ...string) []string {
+	var rows []testRow
+	require.NoError(t, GetChain(database.Table(testTable)).
+		BuildFilterConditions(req, testTable, exclude...).
+		Order("name").
+		Find(&rows).Error)
+	var names []string
+	for _, row := range rows {
+		names = append(names, row.Name)
+	}
+	return names
+}
+
+func TestSearchWithoutSearchColumns(t *testing.T) {
+	database := prepareTestTable(t, nil)
+
+	names := findTestRows(t, database, &testRequest{SearchWord: []string{"a"}})
+	require.Equal(t, []string{"a", "b", "c"}, names)
+
+	// the other conditions still work
+	names = findTestRows(t, database, &testRequest{
+		SearchWord: []string{"a"},
+		Status:     []string{constants.StatusActive},
+	})
+	require.Equal(t, []string{"a", "b"}, names)
+}
+
+func TestSearchWithExcludedSearchColumns(t *testing.T) {
+	database := prepareTestTable(t, []string{"name"})
+
+	names := findTestRows(t, database, &testRequest{SearchWord: []string{"a"}})
+	require.Equal(t, []string{"a"}, names)
+
+	names = findTestRows(t, database, &testRequest{SearchWord: []string{"a"}}, "name")
+	require.Equal(t, []string{"a", "b", "c"}, names)
+}
+
+func TestSearchQuotes(t *testing.T) {
+	database := prepareTestTable(t, []string{"name"})
+	require.NoError(t, database.Table(testTable).Create(&testRow{"o'brien", constants.StatusActive}).Error)
+
+	names := findTestRows(t, database, &testRequest{SearchWord: []string{"o'b"}})
+	require.Equal(t, []string{"o'brien"}, names)
+	names = findTestRows(t, database, &testRequest{SearchWord: []string{"' OR '1'='1"}})
+	require.Empty(t, names)
+	// a placeholder in the search words is a literal
+	names = findTestRows(t, database, &testRequest{SearchWord: []string{"?", "a"}})
+	require.Empty(t, names)
+}
+
+func TestSearchStrict(t *testing.T) {
+	database := prepareTestTable(t, []string{"name"})
+	var queries []string
+	database.Callback().Query().After("gorm:query").Register("test:capture_sql", func(scope *gorm.Scope) {
+		queries = append(queries, scope.SQL)
+	})
+
+	names := findTestRows(t, database, &testRequest{SearchWord: []string{"a"}})
+	require.Equal(t, []string{"a"}, names)
+	require.Contains(t, queries[0], "LIKE")
+
+	// only the indexed columns filter the rows
+	queries = nil
+	names = findTestRows(t, database, &testRequest{
+		SearchWord: []string{"a"},
+		Status:     []string{constants.StatusActive},
+		Strict:     true,
+	})
+	require.Equal(t, []string{"a", "b"}, names)
+	require.Len(t, queries, 1)
+	require.NotContains(t, queries[0], "LIKE")
+
+	// nor are the rows ranked by the search words
+	queries = nil
+	req := &testRequest{SearchWord: []string{"a"}, RankSearch: true, Strict: true}
+	var rows []testRow
+	require.NoError(t, GetChain(database.Table(testTable)).
+		BuildFilterConditions(req, testTable).
+		AddSearchRankOrder(req, testTable).
+		Find(&rows).Error)
+	require.Len(t, rows, 3)
+	require.NotContains(t, queries[0], "LIKE")
+}
+
+func TestFilterNonColumn(t *testing.T) {
+	database := prepareTestTable(t, nil)
+	// misconfigured indexed column
+	constants.IndexedColumns[testTable] = append(constants.IndexedColumns[testTable], "unknown")
+
+	names := findTestRows(t, database, &testRequest{
+		Status:  []string{constants.StatusActive},
+		Unknown: []string{"1) OR (1=1"},
+	})
+	require.Equal(t, []string{"a", "b"}, names)
+
+	require.True(t, isFilterColumn(testTable, constants.ColumnStatus))
+	require.False(t, isFilterColumn(testTable, "unknown"))
+	require.False(t, isFilterColumn(testTable, "name"))
+}
+
+func TestIndexedColumnsAreTableColumns(t *testing.T) {
+	for tableName, columns := range constants.IndexedColumns {
+		for _, column := range columns {
+			require.Contains(t, constants.TableColumns[tableName], column, tableName)
+		}
+	}
+	for tableName, columns := range constants.SearchColumns {
+		for _, column := range columns {
+			require.Contains(t, constants.TableColumns[tableName], column, tableName)
+		}
+	}
+	for tableName, columns := range constants.FullNameColumns {
+		for _, column := range columns {
+			require.Contains(t, constants.TableColumns[tableName], column, tableName)
+		}
+	}
+}
+
+func TestTokenizeSearch(t *testing.T) {
+	var tests = []struct {
+		s      string
+		expect []string
+	}{
+		{s: "foo", expect: []string{"foo"}},
+		{s: "\tfoo  bar ", expect: []string{"foo", "bar"}},
+		{s: "  ", expect: []string{}},
+	}
+	for _, v := range tests {
+		require.Equal(t, v.expect, tokenizeSearch(v.s), v.s)
+	}
+}
+
+func TestSearchMultiWords(t *testing.T) {
+	database := prepareTestTable(t, []string{"name", constants.ColumnStatus})
+	require.NoError(t, database.Table(testTable).Create(&testRow{"ab", constants.StatusDeleted}).Error)
+
+	var tests = []struct {
+		searchWord []string
+		expect     []string
+	}{
+		{searchWord: []string{"a"}, expect: []string{"a", "ab", "b"}},
+		// words are matched by different columns
+		{searchWord: []string{"a deleted"}, expect: []string{"ab"}},
+		{searchWord: []string{"a", "deleted"}, expect: []string{"ab"}},
+		{searchWord: []string{"  b   active "}, expect: []string{"b"}},
+		{searchWord: []string{"ab active"}, expect: nil},
+	}
+	for _, v := range tests {
+		names := findTestRows(t, database, &testRequest{SearchWord: v.searchWord})
+		require.Equal(t, v.expect, names, "%q", v.searchWord)
+	}
+
+	// the search condition is grouped with the other conditions
+	names := findTestRows(t, database, &testRequest{
+		SearchWord: []string{"a"},
+		Status:     []string{constants.StatusActive},
+	})
+	require.Equal(t, []string{"a", "b"}, names)
+}
+
+func TestSearchMinLength(t *testing.T) {
+	database := prepareTestTable(t, []string{"name", constants.ColumnStatus})
+	require.NoError(t, database.Table(testTable).Create(&testRow{"ab", constants.StatusDeleted}).Error)
+	SearchMinLength = 2
+
+	var tests = []struct {
+		searchWord []string
+		matchAll   bool
+		expect     []string
+	}{
+		{searchWord: []string{"a"}, expect: nil},
+		{searchWord: []string{"ab"}, expect: []string{"ab"}},
+		{searchWord: []string{"a deleted"}, expect: nil},
+		{searchWord: []string{"a"}, matchAll: true, expect: []string{"a", "ab", "b", "c"}},
+		{searchWord: []string{"a deleted"}, matchAll: true, expect: []string{"ab", "c"}},
+		{searchWord: []string{"ab", "c"}, matchAll: true, expect: []string{"ab"}},
+	}
+	for _, v := range tests {
+		ShortSearchMatchAll = v.matchAll
+		names := findTestRows(t, database, &testRequest{SearchWord: v.searchWord})
+		require.Equal(t, v.expect, names, "%q match all: %v", v.searchWord, v.matchAll)
+	}
+
+	// other conditions still apply when short words are ignored
+	ShortSearchMatchAll = true
+	names := findTestRows(t, database, &testRequest{
+		SearchWord: []string{"a"},
+		Status:     []string{constants.StatusActive},
+	})
+	require.Equal(t, []string{"a", "b"}, names)
+}
+
+func TestSearchAccentInsensitive(t *testing.T) {
+	database := prepareTestTable(t, []string{"name"})
+	require.NoError(t, database.Table(testTable).Create(&testRow{"José", constants.StatusActive}).Error)
+	require.NoError(t, database.Table(testTable).Create(&testRow{"Jose", constants.StatusActive}).Error)
+
+	names := findTestRows(t, database, &testRequest{SearchWord: []string{"jose"}})
+	require.Equal(t, []string{"Jose"}, names)
+
+	AccentInsensitiveSearch = true
+	for _, searchWord := range []string{"jose", "josé", "JOSE"} {
+		names := findTestRows(t, database, &testRequest{SearchWord: []string{searchWord}})
+		require.Equal(t, []string{"Jose", "José"}, names, searchWord)
+	}
+}
+
+func TestSearchRankOrder(t *testing.T) {
+	database := prepareTestTable(t, []string{"name", constants.ColumnStatus})
+	for _, name := range []string{"jimbob", "bobby", "Bob"} {
+		require.NoError(t, database.Table(testTable).Create(&testRow{name, constants.StatusActive}).Error)
+	}
+
+	find := func(req *testRequest) []string {
+		var rows []testRow
+		require.NoError(t, GetChain(database.Table(testTable)).
+			BuildFilterConditions(req, testTable).
+			AddSearchRankOrder(req, testTable).
+			Order("name DESC").
+			Find(&rows).Error)
+		var names []string
+		for _, row := range rows {
+			names = append(names, row.Name)
+		}
+		return names
+	}
+
+	require.Equal(t, []string{"jimbob", "bobby", "Bob"}, find(&testRequest{SearchWord: []string{"bob"}}))
+	// exact, prefix, then substring matches
+	require.Equal(t, []string{"Bob", "bobby", "jimbob"}, find(&testRequest{SearchWord: []string{"bob"}, RankSearch: true}))
+	// the ranks of the words are added up
+	require.Equal(t, []string{"Bob", "bobby", "jimbob"}, find(&testRequest{SearchWord: []string{"bob active"}, RankSearch: true}))
+	// ranking without search words keeps the order
+	require.Equal(t, []string{"jimbob", "c", "bobby", "b", "a", "Bob"}, find(&testRequest{RankSearch: true}))
+}
+
+func TestSearchRankDefaultOrder(t *testing.T) {
+	database := prepareTestTable(t, []string{"name"})
+	for _, name := range []string{"Bob", "bobby", "jimbob"} {
+		require.NoError(t, database.Table(testTable).Create(&testRow{name, constants.StatusActive}).Error)
+	}
+
+	find := func(req *testRequest) []string {
+		var rows []testRow
+		require.NoError(t, GetChain(database.Table(testTable)).
+			BuildFilterConditions(req, testTable).
+			AddQueryOrderDir(req, testTable, "name").
+			Find(&rows).Error)
+		var names []string
+		for _, row := range rows {
+			names = append(names, row.Name)
+		}
+		return names
+	}
+
+	// searching orders by relevance, then by the default column
+	require.Equal(t, []string{"Bob", "bobby", "jimbob"}, find(&testRequest{SearchWord: []string{"bob"}}))
+	// an explicit sort key overrides relevance
+	require.Equal(t, []string{"jimbob", "bobby", "Bob"}, find(&testRequest{SearchWord: []string{"bob"}, SortKey: "name"}))
+	// no search, no relevance
+	require.Equal(t, []string{"jimbob", "c", "bobby", "b", "a", "Bob"}, find(&testRequest{}))
+}
+
+func TestChainNilRequest(t *testing.T) {
+	database := prepareTestTable(t, []string{"name"})
+
+	var nilRequest *testRequest
+	for _, req := range []Request{nil, nilRequest} {
+		var names []string
+		require.NotPanics(t, func() {
+			names = findTestRows(t, database, req)
+		})
+		require.Equal(t, []string{"a", "b", "c"}, names)
+
+		var rows []testRow
+		require.NotPanics(t, func() {
+			require.NoError(t, GetChain(database.Table(testTable)).
+				AddSearchRankOrder(req, testTable).
+				AddQueryOrderDir(req, testTable, "name").
+				Find(&rows).Error)
+		})
+		require.Len(t, rows, 3)
+		require.Equal(t, "c", rows[0].Name)
+	}
+}
+
+func TestChainNilDB(t *testing.T) {
+	req := &testRequest{SearchWord: []string{"a"}, Status: []string{constants.StatusActive}, RankSearch: true}
+	for _, chain := range []*Chain{nil, GetChain(nil)} {
+		require.NotPanics(t, func() {
+			c := chain.BuildFilterConditions(req, testTable).
+				BuildRootGroupIdConditions([]string{"gid-1"}).
+				BuildTimeRangeConditions(constants.ColumnCreateTime, time.Now(), time.Time{}).
+				AddSearchRankOrder(req, testTable).
+				AddQueryOrderDir(req, testTable, "name")
+			require.Equal(t, chain, c)
+		})
+	}
+}
+
+func TestAddQueryOrderDirTiebreaker(t *testing.T) {
+	database := prepareTestTable(t, []string{"name"})
+	for _, name := range []string{"e", "d"} {
+		require.NoError(t, database.Table(testTable).Create(&testRow{Name: name, Status: constants.StatusActive}).Error)
+	}
+
+	// rows a, b, d, e have the same status, pages are ordered by name
+	var names []string
+	for offset := 0; offset < 5; offset += 2 {
+		var rows []testRow
+		require.NoError(t, GetChain(database.Table(testTable)).
+			AddQueryOrderDir(&testRequest{}, testTable, constants.ColumnStatus).
+			Offset(offset).
+			Limit(2).
+			Find(&rows).Error)
+		for _, row := range rows {
+			names = append(names, row.Name)
+		}
+	}
+	require.Equal(t, []string{"c", "e", "d", "b", "a"}, names)
+}
+
+func TestGetLimitFromRequest(t *testing.T) {
+	require.Equal(t, DefaultLimit, GetLimitFromRequest(&pb.ListUsersRequest{}))
+	require.EqualValues(t, 5, GetLimitFromRequest(&pb.ListUsersRequest{Limit: 5}))
+	require.EqualValues(t, DefaultSelectLimit, GetLimitFromRequest(&pb.ListUsersRequest{Limit: 1000}))
+	require.EqualValues(t, 0, GetLimitFromRequest(&pb.ListUsersRequest{CountOnly: true}))
+	require.EqualValues(t, 0, GetLimitFromRequest(&pb.ListGroupsRequest{Limit: 5, CountOnly: true}))
+}
+
+func TestPagination(t *testing.T) {
+	// the pagination message overrides the fields of the request
+	req := &pb.ListGroupsRequest{Offset: 1, Limit: 2, Pagination: &pb.Pagination{Offset: 10, Limit: 1000}}
+	require.EqualValues(t, 10, GetOffsetFromRequest(req))
+	require.EqualValues(t, DefaultSelectLimit, GetLimitFromRequest(req))
+	req.Pagination = &pb.Pagination{}
+	require.EqualValues(t, DefaultOffset, GetOffsetFromRequest(req))
+	require.EqualValues(t, DefaultLimit, GetLimitFromRequest(req))
+	req.Pagination = nil
+	require.EqualValues(t, 1, GetOffsetFromRequest(req))
+	require.EqualValues(t, 2, GetLimitFromRequest(req))
+	req = &pb.ListGroupsRequest{CountOnly: true, Pagination: &pb.Pagination{Limit: 5}}
+	require.EqualValues(t, 0, GetLimitFromRequest(req))
+
+	require.Equal(t, &pb.PageInfo{Total: 5, Offset: 2, Limit: 2, HasMore: true}, NewPageInfo(5, 2, 2))
+	require.Equal(t, &pb.PageInfo{Total: 5, Offset: 4, Limit: 2, HasMore: false}, NewPageInfo(5, 4, 2))
+	require.Equal(t, &pb.PageInfo{Total: 0, Offset: 0, Limit: 20, HasMore: false}, NewPageInfo(0, 0, 20))
+	require.Equal(t, &pb.PageInfo{Total: 5, Offset: 0, Limit: 0, HasMore: false}, NewPageInfo(5, 0, 0))
+}
+
+func TestSearchMode(t *testing.T) {
+	database := prepareTestTable(t, []string{"name"})
+	for _, name := range []string{"ab", "ba", "bab"} {
+		require.NoError(t, database.Table(testTable).Create(&testRow{Name: name, Status: constants.StatusActive}).Error)
+	}
+
+	var tests = []struct {
+		mode   string
+		expect []string
+	}{
+		{mode: "", expect: []string{"a", "ab", "ba", "bab"}},
+		{mode: constants.SearchModeContains, expect: []string{"a", "ab", "ba", "bab"}},
+		{mode: constants.SearchModePrefix, expect: []string{"a", "ab"}},
+		{mode: constants.SearchModeSuffix, expect: []string{"a", "ba"}},
+	}
+	for _, v := range tests {
+		names := findTestRows(t, database, &testRequest{SearchWord: []string{"a"}, SearchMode: v.mode})
+		require.Equal(t, v.expect, names, "search mode [%s]", v.mode)
+	}
+
+	// prefix has no leading wildcard, so the index of the column can be used
+	require.Equal(t, "ab%", likePattern("ab", constants.SearchModePrefix))
+	require.Equal(t, "%ab", likePattern("ab", constants.SearchModeSuffix))
+	require.Equal(t, "%ab%", likePattern("ab", constants.SearchModeContains))
+	require.Equal(t, "%ab%", likePattern("ab", ""))
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"context"
+)
+
+// MetadataConsistentRead is the grpc metadata asking the reads of a request to be served by the primary,
+// e.g. to read the bindings just written by the client
+const MetadataConsistentRead = "x-consistent-read"
+
+type consistentReadKey struct{}
+
+// WithConsistentRead marks ctx so the reads under it are not sent to the read replica
+func WithConsistentRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, consistentReadKey{}, true)
+}
+
+// IsConsistentRead reports whether the reads under ctx must see the latest writes
+func IsConsistentRead(ctx context.Context) bool {
+	consistent, _ := ctx.Value(consistentReadKey{}).(bool)
+	return consistent
+}
@@ -17,19 +17,47 @@ limitations under the License.
 package db
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/mysql"
 	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/mattn/go-sqlite3"
 	"openpitrix.io/logger"
 
 	"cloudbases.io/im/pkg/config"
+	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/util/stringutil"
 )
 
+const (
+	sqliteDriver = "sqlite3_im"
+	unaccentFunc = "im_unaccent"
+)
+
+// sqlite has no function to strip accents, it is registered on every connection
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc(unaccentFunc, func(v interface{}) string {
+				switch s := v.(type) {
+				case string:
+					return stringutil.RemoveAccents(s)
+				case []byte:
+					return stringutil.RemoveAccents(string(s))
+				}
+				return ""
+			}, true)
+		},
+	})
+}
+
 type Database struct {
 	cfg *config.Config
 	*gorm.DB
+
+	health deepHealth
 }
 
 func OpenDatabase(cfg *config.Config) (*Database, error) {
@@ -43,18 +71,37 @@ func OpenDatabase(cfg *config.Config) (*Database, error) {
 	logger.Infof(nil, "\tDatabase: %s", cfg.DB.Database)
 	logger.Infof(nil, "DB config: end")
 
+	SearchMinLength = cfg.DB.SearchMinLength
+	ShortSearchMatchAll = cfg.DB.ShortSearchMatchAll
+	AccentInsensitiveSearch = cfg.DB.AccentInsensitiveSearch
+	TablePrefix = cfg.DB.TablePrefix
+	setExtraSearchColumns(constants.TableUser, cfg.DB.GetUserSearchColumns())
+	setExtraSearchColumns(constants.TableGroup, cfg.DB.GetGroupSearchColumns())
+
 	var p = &Database{cfg: cfg}
 	var err error
 
-	p.DB, err = gorm.Open(cfg.DB.Type, cfg.DB.GetUrl())
-	if err != nil {
-		return nil, err
+	if cfg.DB.Type == "sqlite3" {
+		sqlDB, err := sql.Open(sqliteDriver, cfg.DB.GetUrl())
+		if err != nil {
+			return nil, err
+		}
+		p.DB, err = gorm.Open(cfg.DB.Type, sqlDB)
+		if err != nil {
+			sqlDB.Close()
+			return nil, err
+		}
+	} else {
+		p.DB, err = gorm.Open(cfg.DB.Type, cfg.DB.GetUrl())
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	p.DB.SingularTable(true)
 
 	// Enable Logger, show detailed log
-	p.DB.LogMode(cfg.DB.LogModeEnable)
+	p.setLogger(p.DB)
 
 	// SetMaxIdleConns sets the maximum number of connections in the idle connection pool.
 	p.DB.DB().SetMaxIdleConns(10)
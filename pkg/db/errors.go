@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"regexp"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MySQLErrorCodes maps the numbers of the mysql errors to the codes returned by MapError,
+// the errors not in it are returned unchanged
+var MySQLErrorCodes = map[uint16]codes.Code{
+	1062: codes.AlreadyExists,      // ER_DUP_ENTRY
+	1451: codes.FailedPrecondition, // ER_ROW_IS_REFERENCED_2
+	1452: codes.FailedPrecondition, // ER_NO_REFERENCED_ROW_2
+	1048: codes.InvalidArgument,    // ER_BAD_NULL_ERROR
+	1406: codes.InvalidArgument,    // ER_DATA_TOO_LONG
+	1205: codes.Aborted,            // ER_LOCK_WAIT_TIMEOUT
+	1213: codes.Aborted,            // ER_LOCK_DEADLOCK
+}
+
+// SQLiteErrorCodes is MySQLErrorCodes of sqlite, by the extended error codes
+var SQLiteErrorCodes = map[sqlite3.ErrNoExtended]codes.Code{
+	sqlite3.ErrConstraintUnique:     codes.AlreadyExists,
+	sqlite3.ErrConstraintPrimaryKey: codes.AlreadyExists,
+	sqlite3.ErrConstraintForeignKey: codes.FailedPrecondition,
+	sqlite3.ErrConstraintNotNull:    codes.InvalidArgument,
+}
+
+var (
+	// Duplicate entry 'x' for key 'user_external_id_idx', or the foreign key errors naming CONSTRAINT `fk`
+	reMySQLConstraint = regexp.MustCompile("for key '([^']+)'|CONSTRAINT `([^`]+)`")
+	// UNIQUE constraint failed: user.external_provider, user.external_id
+	reSQLiteConstraint = regexp.MustCompile(`constraint failed: (.+)$`)
+)
+
+// MapError translates the known errors of the database drivers to status errors, e.g. a duplicate key
+// to AlreadyExists, the violated constraint is attached as a PreconditionFailure detail.
+// The other errors are returned unchanged.
+func MapError(err error) error {
+	var code codes.Code
+	var known bool
+	var matches []string
+	switch e := err.(type) {
+	case *mysql.MySQLError:
+		code, known = MySQLErrorCodes[e.Number]
+		matches = reMySQLConstraint.FindStringSubmatch(e.Message)
+	case sqlite3.Error:
+		code, known = SQLiteErrorCodes[e.ExtendedCode]
+		matches = reSQLiteConstraint.FindStringSubmatch(e.Error())
+	}
+	if !known {
+		return err
+	}
+
+	s := status.New(code, err.Error())
+	// the first group matched is the constraint
+	var constraint string
+	for i := 1; i < len(matches) && constraint == ""; i++ {
+		constraint = matches[i]
+	}
+	if constraint == "" {
+		return s.Err()
+	}
+	detailed, detailErr := s.WithDetails(&errdetails.PreconditionFailure{
+		Violations: []*errdetails.PreconditionFailure_Violation{{
+			Type:        "constraint",
+			Subject:     constraint,
+			Description: err.Error(),
+		}},
+	})
+	if detailErr != nil {
+		return s.Err()
+	}
+	return detailed.Err()
+}
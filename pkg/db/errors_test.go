@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// constraintOf returns the constraint attached to err by MapError
+func constraintOf(t *testing.T, err error) string {
+	for _, detail := range status.Convert(err).Details() {
+		if failure, ok := detail.(*errdetails.PreconditionFailure); ok {
+			require.Len(t, failure.Violations, 1)
+			return failure.Violations[0].Subject
+		}
+	}
+	return ""
+}
+
+func TestMapMySQLError(t *testing.T) {
+	var tests = []struct {
+		err        error
+		code       codes.Code
+		constraint string
+	}{
+		{
+			err:        &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'github-1001' for key 'user_external_id_idx'"},
+			code:       codes.AlreadyExists,
+			constraint: "user_external_id_idx",
+		},
+		{
+			err: &mysql.MySQLError{Number: 1451, Message: "Cannot delete or update a parent row: a foreign key constraint fails " +
+				"(`im`.`user_group_binding`, CONSTRAINT `binding_group_fk` FOREIGN KEY (`group_id`) REFERENCES `group` (`group_id`))"},
+			code:       codes.FailedPrecondition,
+			constraint: "binding_group_fk",
+		},
+		{
+			err:  &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"},
+			code: codes.Aborted,
+		},
+		{
+			err:  &mysql.MySQLError{Number: 1146, Message: "Table 'im.unknown' doesn't exist"},
+			code: codes.Unknown,
+		},
+		{
+			err:  errors.New("bad connection"),
+			code: codes.Unknown,
+		},
+	}
+	for _, v := range tests {
+		err := MapError(v.err)
+		require.Equal(t, v.code, status.Code(err), v.err.Error())
+		require.Equal(t, v.constraint, constraintOf(t, err), v.err.Error())
+		if v.code == codes.Unknown {
+			require.Equal(t, v.err, err)
+		}
+	}
+}
+
+func TestMapSQLiteError(t *testing.T) {
+	database := openTestDatabase(t)
+	require.NoError(t, database.Exec("CREATE TABLE test_unique (name varchar(50) NOT NULL, UNIQUE (name))").Error)
+	require.NoError(t, database.Exec("INSERT INTO test_unique (name) VALUES ('a')").Error)
+
+	err := MapError(database.Exec("INSERT INTO test_unique (name) VALUES ('a')").Error)
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+	require.Equal(t, "test_unique.name", constraintOf(t, err))
+
+	err = MapError(database.Exec("INSERT INTO test_unique (name) VALUES (NULL)").Error)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Equal(t, "test_unique.name", constraintOf(t, err))
+}
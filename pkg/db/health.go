@@ -0,0 +1,81 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"openpitrix.io/logger"
+
+	"cloudbases.io/im/pkg/constants"
+)
+
+// errHealthRollback rolls back the write of the deep health check
+var errHealthRollback = errors.New("rollback health check")
+
+// deepHealth keeps the result of the last deep health check
+type deepHealth struct {
+	mutex     sync.Mutex
+	checkedAt time.Time
+	err       error
+}
+
+// HealthCheck reports whether the database answers a query, the deep check also confirms
+// that it accepts writes, e.g. it is not a read-only replica or on a full disk.
+// The result of the deep check is reused for DeepHealthCheckIntervalSeconds.
+func (p *Database) HealthCheck(ctx context.Context, deep bool) error {
+	var one int
+	if err := p.DB.DB().QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		logger.Errorf(ctx, "Health check failed: %+v", err)
+		return err
+	}
+	if !deep {
+		return nil
+	}
+
+	p.health.mutex.Lock()
+	defer p.health.mutex.Unlock()
+	interval := time.Duration(p.cfg.DB.DeepHealthCheckIntervalSeconds) * time.Second
+	if !p.health.checkedAt.IsZero() && time.Since(p.health.checkedAt) < interval {
+		return p.health.err
+	}
+	p.health.err = p.checkWritable(ctx)
+	p.health.checkedAt = time.Now()
+	return p.health.err
+}
+
+// checkWritable updates no row in a transaction which is rolled back,
+// the database rejects the statement when it does not accept writes
+func (p *Database) checkWritable(ctx context.Context) error {
+	err := p.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Table(TableName(constants.TableUser)).
+			Where("1 = 0").
+			UpdateColumn(constants.ColumnVersion, gorm.Expr(constants.ColumnVersion)).Error; err != nil {
+			return err
+		}
+		return errHealthRollback
+	})
+	if err == errHealthRollback {
+		return nil
+	}
+	logger.Errorf(ctx, "Deep health check failed: %+v", err)
+	return err
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"cloudbases.io/im/pkg/config"
+)
+
+func TestHealthCheck(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDatabase(t)
+	require.NoError(t, database.Migrate())
+
+	require.NoError(t, database.HealthCheck(ctx, false))
+	require.NoError(t, database.HealthCheck(ctx, true))
+
+	// a single connection made read-only
+	database.DB.DB().SetMaxOpenConns(1)
+	require.NoError(t, database.Exec("PRAGMA query_only = ON").Error)
+	require.NoError(t, database.HealthCheck(ctx, false))
+	// the result of the last deep check is reused in the interval
+	require.NoError(t, database.HealthCheck(ctx, true))
+
+	database.health.checkedAt = database.health.checkedAt.Add(-time.Duration(database.cfg.DB.DeepHealthCheckIntervalSeconds) * time.Second)
+	require.Error(t, database.HealthCheck(ctx, true))
+	require.NoError(t, database.HealthCheck(ctx, false))
+}
+
+func TestHealthCheckReadOnlyDatabase(t *testing.T) {
+	ctx := context.Background()
+	database := openTestDatabase(t)
+	require.NoError(t, database.Migrate())
+	path := database.cfg.DB.Database
+
+	cfg := config.Default()
+	cfg.DB.Type = "sqlite3"
+	cfg.DB.Database = "file:" + filepath.ToSlash(path) + "?mode=ro"
+	readOnly, err := OpenDatabase(cfg)
+	require.NoError(t, err)
+	defer readOnly.Close()
+
+	require.NoError(t, readOnly.HealthCheck(ctx, false))
+	require.Error(t, readOnly.HealthCheck(ctx, true))
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"strings"
+
+	"github.com/fatih/structs"
+	"github.com/jinzhu/gorm"
+
+	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/util/stringutil"
+)
+
+type RequestWithHighlight interface {
+	RequestWithSearchMode
+	GetSearchWord() []string
+	GetHighlight() bool
+}
+
+// MatchedColumns returns the search columns of tableName in which row matches any search word of req,
+// it is checked again in Go like the conditions of getSearchFilter, the group names are not checked.
+// Nothing matches for a strict req as its search words are not searched.
+func MatchedColumns(req RequestWithHighlight, tableName string, row interface{}) []string {
+	if isStrict(req) {
+		return nil
+	}
+	vs := req.GetSearchWord()
+	if len(vs) == 1 {
+		vs = tokenizeSearch(vs[0])
+	}
+	var words []string
+	for _, v := range vs {
+		v = stringutil.SimplifyString(v)
+		if len([]rune(v)) < SearchMinLength {
+			continue
+		}
+		words = append(words, v)
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	searchMode := req.GetSearchMode()
+
+	values := make(map[string]string)
+	for _, field := range structs.Fields(row) {
+		if !field.IsExported() {
+			continue
+		}
+		if value, ok := field.Value().(string); ok {
+			values[gorm.ToDBName(field.Name())] = value
+		}
+	}
+
+	var columns []string
+	for _, column := range searchColumns(tableName) {
+		value, ok := values[column]
+		if !ok {
+			continue
+		}
+		for _, word := range words {
+			if matchSearchWord(column, value, word, searchMode) {
+				columns = append(columns, column)
+				break
+			}
+		}
+	}
+	return columns
+}
+
+func matchSearchWord(column, value, word, searchMode string) bool {
+	// if column suffix is _id, must exact match
+	if strings.HasSuffix(column, "_id") {
+		return value == word
+	}
+	// LIKE is case insensitive in mysql and sqlite for ascii
+	value, word = strings.ToLower(value), strings.ToLower(word)
+	if AccentInsensitiveSearch {
+		value, word = stringutil.RemoveAccents(value), stringutil.RemoveAccents(word)
+	}
+	switch searchMode {
+	case constants.SearchModePrefix:
+		return strings.HasPrefix(value, word)
+	case constants.SearchModeSuffix:
+		return strings.HasSuffix(value, word)
+	}
+	return strings.Contains(value, word)
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"openpitrix.io/logger"
+)
+
+// gormLogger sends the SQL statements and errors of gorm to the project logger,
+// gorm has no context so they are logged without one
+type gormLogger struct {
+	// logs the SQL statements, at the level of DBConfig.LogLevel
+	logf func(ctx context.Context, format string, a ...interface{})
+}
+
+func newGormLogger(level string) gormLogger {
+	logf := logger.Debugf
+	switch strings.ToUpper(level) {
+	case "INFO":
+		logf = logger.Infof
+	case "WARN":
+		logf = logger.Warnf
+	case "ERROR":
+		logf = logger.Errorf
+	}
+	return gormLogger{logf: logf}
+}
+
+// Print receives ("sql", source, duration, sql, vars, rowsAffected) for the statements when log mode is enabled,
+// ("log", source, err) for the errors in log mode, or (source, err) otherwise
+func (l gormLogger) Print(values ...interface{}) {
+	if len(values) == 0 {
+		return
+	}
+	switch values[0] {
+	case "sql":
+		if len(values) < 6 {
+			return
+		}
+		duration, _ := values[2].(time.Duration)
+		l.logf(nil, "SQL [%s] %v %.2fms, %v rows affected (%v)",
+			values[3], values[4], float64(duration.Nanoseconds())/1e6, values[5], values[1])
+	case "log":
+		if len(values) < 3 {
+			return
+		}
+		logger.Errorf(nil, "DB error: %v (%v)", values[2:], values[1])
+	default:
+		if len(values) < 2 {
+			return
+		}
+		logger.Errorf(nil, "DB error: %v (%v)", values[1:], values[0])
+	}
+}
+
+// setLogger routes the logs of db to the project logger, the SQL statements are logged only
+// with LogModeEnable, the errors always
+func (p *Database) setLogger(db *gorm.DB) {
+	db.SetLogger(newGormLogger(p.cfg.DB.LogLevel))
+	if p.cfg.DB.LogModeEnable {
+		db.LogMode(true)
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"openpitrix.io/logger"
+
+	"cloudbases.io/im/pkg/config"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func captureLogger(t *testing.T) *syncBuffer {
+	output := new(syncBuffer)
+	logger.SetOutput(output)
+	t.Cleanup(func() {
+		logger.SetOutput(os.Stdout)
+	})
+	return output
+}
+
+func openLoggingTestDatabase(t *testing.T, logModeEnable bool) *Database {
+	cfg := config.Default()
+	cfg.DB.Type = "sqlite3"
+	cfg.DB.Database = filepath.Join(t.TempDir(), "im.db")
+	cfg.DB.LogModeEnable = logModeEnable
+	cfg.DB.LogLevel = "ERROR"
+	database, err := OpenDatabase(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		database.Close()
+	})
+	return database
+}
+
+func TestGormLoggerLogMode(t *testing.T) {
+	database := openLoggingTestDatabase(t, true)
+	output := captureLogger(t)
+
+	var rows []testRow
+	require.Error(t, database.Table("missing_table").Find(&rows).Error)
+
+	log := output.String()
+	require.Contains(t, log, "SQL [SELECT * FROM \"missing_table\"")
+	require.Contains(t, log, "DB error: [no such table: missing_table]")
+}
+
+func TestGormLoggerErrorsOnly(t *testing.T) {
+	database := openLoggingTestDatabase(t, false)
+	output := captureLogger(t)
+
+	var rows []testRow
+	require.Error(t, database.Table("missing_table").Find(&rows).Error)
+
+	// gorm prints the errors in background without log mode
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(output.String(), "no such table: missing_table") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	log := output.String()
+	require.Contains(t, log, "DB error: [no such table: missing_table]")
+	require.NotContains(t, log, "SQL [")
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"openpitrix.io/logger"
+
+	"cloudbases.io/im/pkg/constants"
+)
+
+// the same scripts are applied by flyway in docker-compose,
+// so they must follow its naming: V<version>__<description>.sql
+//
+//go:embed schema/im/*.sql
+var schemaFS embed.FS
+
+const (
+	schemaDir            = "schema/im"
+	TableSchemaMigration = "schema_migrations"
+	// the history of the migrations applied by flyway, versions like "0.1"
+	TableFlywaySchemaHistory = "flyway_schema_history"
+)
+
+// the tables created by the init migration
+var initTables = []string{constants.TableUser, constants.TableGroup, constants.TableUserGroupBinding}
+
+var reMigrationName = regexp.MustCompile(`^V([0-9]+(?:_[0-9]+)*)__(\w+)\.sql$`)
+
+type Migration struct {
+	Version     string
+	Description string
+	Script      string
+}
+
+type SchemaMigration struct {
+	Version     string    `gorm:"type:varchar(50);primary_key"`
+	Description string    `gorm:"type:varchar(255);not null"`
+	AppliedTime time.Time `gorm:"default CURRENT_TIMESTAMP"`
+}
+
+func (SchemaMigration) TableName() string {
+	return TableSchemaMigration
+}
+
+// "0_10" > "0_9"
+func compareVersion(a, b string) int {
+	as, bs := strings.Split(a, "_"), strings.Split(b, "_")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// GetMigrations returns the embedded migrations ordered by version
+func GetMigrations() ([]Migration, error) {
+	entries, err := schemaFS.ReadDir(schemaDir)
+	if err != nil {
+		return nil, err
+	}
+	var migrations []Migration
+	for _, entry := range entries {
+		matches := reMigrationName.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("invalid migration file name [%s]", entry.Name())
+		}
+		script, err := schemaFS.ReadFile(path.Join(schemaDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{
+			Version:     matches[1],
+			Description: matches[2],
+			Script:      string(script),
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return compareVersion(migrations[i].Version, migrations[j].Version) < 0
+	})
+	return migrations, nil
+}
+
+// statements of a script are separated by ';'
+func splitStatements(script string) []string {
+	var statements []string
+	for _, statement := range strings.Split(script, ";") {
+		if statement = strings.TrimSpace(statement); statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+// Migrate applies the migrations not recorded in schema_migrations yet,
+// it is safe to be called every time the service starts
+func (p *Database) Migrate() error {
+	// gorm prefixes the table of SchemaMigration only when it is not queried by a single value, name it explicitly
+	table := TableName(TableSchemaMigration)
+	if err := p.Table(table).AutoMigrate(&SchemaMigration{}).Error; err != nil {
+		logger.Errorf(nil, "Create table [%s] failed: %+v", TableSchemaMigration, err)
+		return err
+	}
+
+	migrations, err := GetMigrations()
+	if err != nil {
+		logger.Errorf(nil, "Load migrations failed: %+v", err)
+		return err
+	}
+
+	var applied []SchemaMigration
+	if err := p.Table(table).Find(&applied).Error; err != nil {
+		logger.Errorf(nil, "Get applied migrations failed: %+v", err)
+		return err
+	}
+	appliedVersions := make(map[string]bool)
+	for _, m := range applied {
+		appliedVersions[m.Version] = true
+	}
+	if len(applied) == 0 && len(migrations) > 0 {
+		if appliedVersions, err = p.baseline(table, migrations); err != nil {
+			return err
+		}
+	}
+
+	for _, migration := range migrations {
+		if appliedVersions[migration.Version] {
+			continue
+		}
+		logger.Infof(nil, "Apply migration [V%s__%s]", migration.Version, migration.Description)
+
+		// mysql commits ddl implicitly, the transaction only helps the databases support transactional ddl
+		tx := p.Begin()
+		for _, statement := range splitStatements(prefixTables(migration.Script)) {
+			if err := tx.Exec(statement).Error; err != nil {
+				tx.Rollback()
+				logger.Errorf(nil, "Apply migration [V%s__%s] failed: %+v", migration.Version, migration.Description, err)
+				return err
+			}
+		}
+		if err := tx.Table(table).Create(&SchemaMigration{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedTime: time.Now(),
+		}).Error; err != nil {
+			tx.Rollback()
+			logger.Errorf(nil, "Record migration [V%s__%s] failed: %+v", migration.Version, migration.Description, err)
+			return err
+		}
+		if err := tx.Commit().Error; err != nil {
+			logger.Errorf(nil, "Commit migration [V%s__%s] failed: %+v", migration.Version, migration.Description, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// baseline records the migrations applied to a database created without schema_migrations, by flyway
+// as docker-compose does, so that they are not applied again. Without the flyway history the init
+// migration is recorded when its tables exist. The recorded versions are returned.
+func (p *Database) baseline(table string, migrations []Migration) (map[string]bool, error) {
+	baselineVersions := make(map[string]bool)
+	if p.HasTable(TableFlywaySchemaHistory) {
+		var flywayVersions []string
+		if err := p.Table(TableFlywaySchemaHistory).
+			Where("success = ? AND version IS NOT NULL", true).
+			Pluck("version", &flywayVersions).Error; err != nil {
+			logger.Errorf(nil, "Get flyway schema history failed: %+v", err)
+			return nil, err
+		}
+		for _, version := range flywayVersions {
+			baselineVersions[strings.Replace(version, ".", "_", -1)] = true
+		}
+	} else {
+		for _, initTable := range initTables {
+			if !p.HasTable(TableName(initTable)) {
+				return baselineVersions, nil
+			}
+		}
+		baselineVersions[migrations[0].Version] = true
+	}
+
+	for _, migration := range migrations {
+		if !baselineVersions[migration.Version] {
+			continue
+		}
+		logger.Infof(nil, "Baseline migration [V%s__%s]", migration.Version, migration.Description)
+		if err := p.Table(table).Create(&SchemaMigration{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedTime: time.Now(),
+		}).Error; err != nil {
+			logger.Errorf(nil, "Record migration [V%s__%s] failed: %+v", migration.Version, migration.Description, err)
+			return nil, err
+		}
+	}
+	return baselineVersions, nil
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cloudbases.io/im/pkg/config"
+)
+
+func openTestDatabase(t *testing.T) *Database {
+	cfg := config.Default()
+	cfg.DB.Type = "sqlite3"
+	cfg.DB.Database = filepath.Join(t.TempDir(), "im.db")
+	database, err := OpenDatabase(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		database.Close()
+	})
+	return database
+}
+
+func TestCompareVersion(t *testing.T) {
+	var tests = []struct {
+		a, b   string
+		expect int
+	}{
+		{a: "0_1", b: "0_1", expect: 0},
+		{a: "0_1", b: "0_2", expect: -1},
+		{a: "0_10", b: "0_9", expect: 1},
+		{a: "1", b: "0_9", expect: 1},
+		{a: "0_1", b: "0_1_1", expect: -1},
+	}
+	for _, v := range tests {
+		require.Equal(t, v.expect, compareVersion(v.a, v.b), "%s <=> %s", v.a, v.b)
+	}
+}
+
+func TestGetMigrations(t *testing.T) {
+	migrations, err := GetMigrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	require.Equal(t, "0_1", migrations[0].Version)
+	require.Equal(t, "init", migrations[0].Description)
+	for i := 1; i < len(migrations); i++ {
+		require.True(t, compareVersion(migrations[i-1].Version, migrations[i].Version) < 0)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	database := openTestDatabase(t)
+
+	require.NoError(t, database.Migrate())
+	for _, table := range []string{"user", "group", "user_group_binding"} {
+		require.True(t, database.HasTable(table), table)
+	}
+
+	migrations, err := GetMigrations()
+	require.NoError(t, err)
+	var applied []SchemaMigration
+	require.NoError(t, database.Order("applied_time").Find(&applied).Error)
+	require.Len(t, applied, len(migrations))
+
+	// re-run is a no-op
+	require.NoError(t, database.Migrate())
+	var count int
+	require.NoError(t, database.Model(&SchemaMigration{}).Count(&count).Error)
+	require.Equal(t, len(migrations), count)
+}
+
+func TestMigrateFailed(t *testing.T) {
+	database := openTestDatabase(t)
+
+	// the init migration fails to create index on user.email
+	require.NoError(t, database.Exec("CREATE TABLE user (user_id varchar(50))").Error)
+	require.Error(t, database.Migrate())
+
+	var count int
+	require.NoError(t, database.Model(&SchemaMigration{}).Count(&count).Error)
+	require.Equal(t, 0, count)
+}
+
+// applyScripts applies the first n migrations without recording them, like flyway does
+func applyScripts(t *testing.T, database *Database, n int) []Migration {
+	migrations, err := GetMigrations()
+	require.NoError(t, err)
+	for _, migration := range migrations[:n] {
+		for _, statement := range splitStatements(prefixTables(migration.Script)) {
+			require.NoError(t, database.Exec(statement).Error)
+		}
+	}
+	return migrations
+}
+
+func TestMigrateExistingSchema(t *testing.T) {
+	database := openTestDatabase(t)
+	migrations := applyScripts(t, database, 1)
+
+	// the init migration is not applied again
+	require.NoError(t, database.Migrate())
+	var count int
+	require.NoError(t, database.Model(&SchemaMigration{}).Count(&count).Error)
+	require.Equal(t, len(migrations), count)
+	require.True(t, database.HasTable("user_tag"))
+}
+
+func TestMigrateFlywaySchema(t *testing.T) {
+	database := openTestDatabase(t)
+	migrations := applyScripts(t, database, 3)
+	require.NoError(t, database.Exec("CREATE TABLE "+TableFlywaySchemaHistory+
+		" (installed_rank int, version varchar(50), description varchar(200), success boolean)").Error)
+	for i, migration := range migrations[:3] {
+		require.NoError(t, database.Exec("INSERT INTO "+TableFlywaySchemaHistory+" VALUES (?, ?, ?, ?)",
+			i+1, strings.Replace(migration.Version, "_", ".", -1), migration.Description, true).Error)
+	}
+
+	// the migrations in the flyway history are recorded, the later ones are applied
+	require.NoError(t, database.Migrate())
+	var applied []SchemaMigration
+	require.NoError(t, database.Find(&applied).Error)
+	require.Len(t, applied, len(migrations))
+	require.NoError(t, database.Migrate())
+}
+
+func TestMigrateTablePrefix(t *testing.T) {
+	database := openTestDatabase(t)
+	TablePrefix = "im_"
+	t.Cleanup(func() {
+		TablePrefix = ""
+	})
+
+	require.Equal(t, "ALTER TABLE `im_user`\n  ADD COLUMN version int;\nCREATE INDEX im_group_status_idx ON `im_group` (status);",
+		prefixTables("ALTER TABLE user\n  ADD COLUMN version int;\nCREATE INDEX group_status_idx ON `group` (status);"))
+
+	require.NoError(t, database.Migrate())
+	for _, table := range []string{"user", "group", "user_group_binding", "user_tag", TableSchemaMigration} {
+		require.True(t, database.HasTable("im_"+table), table)
+		require.False(t, database.HasTable(table), table)
+	}
+	require.NoError(t, database.Migrate())
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"cloudbases.io/im/pkg/pb"
+)
+
+// RequestWithPagination is a list request with the shared pagination message,
+// which overrides its own offset, limit, sort_key and reverse fields when set
+type RequestWithPagination interface {
+	GetPagination() *pb.Pagination
+}
+
+// getPagination returns the pagination message of req, nil when it is not set
+func getPagination(req interface{}) *pb.Pagination {
+	if r, ok := req.(RequestWithPagination); ok {
+		return r.GetPagination()
+	}
+	return nil
+}
+
+// GetSortKeyFromRequest returns the sort key of req, the one of its pagination when set
+func GetSortKeyFromRequest(req interface{}) string {
+	if p := getPagination(req); p != nil {
+		return p.GetSortKey()
+	}
+	if r, ok := req.(RequestWithSortKey); ok {
+		return r.GetSortKey()
+	}
+	return ""
+}
+
+// NewPageInfo describes the page of a list response, the offset and limit are the applied ones,
+// a count only response (limit 0) has no more pages
+func NewPageInfo(total, offset, limit uint32) *pb.PageInfo {
+	return &pb.PageInfo{
+		Total:   total,
+		Offset:  offset,
+		Limit:   limit,
+		HasMore: limit > 0 && offset+limit < total,
+	}
+}
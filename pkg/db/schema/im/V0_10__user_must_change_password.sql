@@ -0,0 +1,2 @@
+ALTER TABLE user
+  ADD COLUMN must_change_password tinyint(1) NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE user
+  ADD COLUMN failed_login_count int NOT NULL DEFAULT 0;
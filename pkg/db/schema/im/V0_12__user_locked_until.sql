@@ -0,0 +1,2 @@
+ALTER TABLE user
+  ADD COLUMN locked_until timestamp NULL DEFAULT NULL;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS user_group_binding_deletion (
+  id          varchar(50) NOT NULL,
+  user_id     varchar(50) NOT NULL,
+  group_id    varchar(50) NOT NULL,
+  delete_time timestamp   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id)
+);
+CREATE INDEX user_group_binding_deletion_delete_time_idx
+  ON user_group_binding_deletion (delete_time);
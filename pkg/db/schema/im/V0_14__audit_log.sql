@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+  id          varchar(50) NOT NULL,
+  type        varchar(50) NOT NULL,
+  user_id     varchar(50) NOT NULL,
+  outcome     varchar(50) NOT NULL,
+  client_ip   varchar(50) NOT NULL,
+  create_time timestamp   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id)
+);
+CREATE INDEX audit_log_create_time_idx
+  ON audit_log (create_time);
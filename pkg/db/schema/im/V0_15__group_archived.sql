@@ -0,0 +1,2 @@
+ALTER TABLE `group`
+  ADD COLUMN archived tinyint(1) NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE user_group_binding
+  ADD COLUMN created_by varchar(50) NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE user
+  ADD COLUMN first_name varchar(50) NOT NULL DEFAULT '';
+ALTER TABLE user
+  ADD COLUMN last_name varchar(50) NOT NULL DEFAULT '';
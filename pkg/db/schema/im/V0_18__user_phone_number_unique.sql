@@ -0,0 +1,4 @@
+-- the phone number is unique among the users not deleted, the empty ones are indexed as NULL
+-- the expression index requires mysql 8.0.13 or later
+CREATE UNIQUE INDEX user_active_phone_number_idx
+  ON user ((CASE WHEN status = 'deleted' OR phone_number = '' THEN NULL ELSE phone_number END));
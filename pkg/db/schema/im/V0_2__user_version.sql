@@ -0,0 +1,2 @@
+ALTER TABLE user
+  ADD COLUMN version int NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+ALTER TABLE user
+  ADD COLUMN password_updated_at timestamp NULL DEFAULT NULL;
+
+UPDATE user
+SET password_updated_at = update_time;
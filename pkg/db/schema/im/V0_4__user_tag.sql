@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS user_tag (
+  user_id     varchar(50) NOT NULL,
+  tag         varchar(50) NOT NULL,
+  create_time timestamp   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (user_id, tag)
+);
+CREATE INDEX user_tag_tag_idx
+  ON user_tag (tag);
@@ -0,0 +1,5 @@
+ALTER TABLE user_group_binding
+  ADD COLUMN status varchar(50) NOT NULL DEFAULT 'accepted';
+
+CREATE INDEX user_group_binding_status_idx
+  ON user_group_binding (status);
@@ -0,0 +1,2 @@
+ALTER TABLE user
+  ADD COLUMN avatar_url varchar(1000) NOT NULL DEFAULT '';
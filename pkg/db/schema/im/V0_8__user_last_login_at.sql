@@ -0,0 +1,2 @@
+ALTER TABLE user
+  ADD COLUMN last_login_at timestamp NULL DEFAULT NULL;
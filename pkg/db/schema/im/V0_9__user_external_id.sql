@@ -0,0 +1,7 @@
+ALTER TABLE user
+  ADD COLUMN external_provider varchar(50) NULL DEFAULT NULL;
+ALTER TABLE user
+  ADD COLUMN external_id varchar(255) NULL DEFAULT NULL;
+
+CREATE UNIQUE INDEX user_external_id_idx
+  ON user (external_provider, external_id);
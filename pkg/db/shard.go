@@ -0,0 +1,229 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/structs"
+	"github.com/jinzhu/gorm"
+
+	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/util/stringutil"
+)
+
+// ShardIndex returns the shard of key among count shards by the fnv hash of key
+func ShardIndex(key string, count int) int {
+	if count <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(count))
+}
+
+// SortRows sorts rows, a slice of pointers to models of tableName, in the order AddSearchRankOrder
+// and AddQueryOrderDir give them in the database, the rows read from several shards are merged by it.
+// The searched rows are ranked by searchRank like addSearchRank does, then sorted by their columns.
+func SortRows(rows interface{}, req Request, tableName, defaultColumn string) {
+	sortKey, reverse := getSortOrder(req)
+	words := rankWords(req, sortKey)
+	if sortKey != "" {
+		defaultColumn = sortKey
+	}
+	columns := []string{defaultColumn}
+	if primaryKey, ok := constants.PrimaryKeyColumns[tableName]; ok && primaryKey != defaultColumn {
+		columns = append(columns, primaryKey)
+	}
+
+	v := reflect.ValueOf(rows)
+	values := make([]map[string]interface{}, v.Len())
+	ranks := make([]int, v.Len())
+	order := make([]int, v.Len())
+	for i := range values {
+		values[i] = columnValues(v.Index(i).Interface())
+		if len(words) > 0 {
+			ranks[i] = searchRank(words, tableName, values[i])
+		}
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if ranks[order[i]] != ranks[order[j]] {
+			return ranks[order[i]] < ranks[order[j]]
+		}
+		for _, column := range columns {
+			if c := compareValues(values[order[i]][column], values[order[j]][column]); c != 0 {
+				// DESC unless reversed, like AddQueryOrderDir
+				return (c > 0) != reverse
+			}
+		}
+		return false
+	})
+
+	sorted := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+	for i, k := range order {
+		sorted.Index(i).Set(v.Index(k))
+	}
+	reflect.Copy(v, sorted)
+}
+
+// rankWords returns the search words the rows of req are ranked by before their columns, by
+// AddSearchRankOrder when req asks for it or by AddQueryOrderDir without a sort key, nil if they are not
+func rankWords(req Request, sortKey string) []string {
+	r, ok := req.(interface{ GetSearchWord() []string })
+	if !ok || isStrict(req) {
+		return nil
+	}
+	if rs, ok := req.(RequestWithRankSearch); !(ok && rs.GetRankSearch()) && sortKey != "" {
+		return nil
+	}
+	vs := r.GetSearchWord()
+	if len(vs) == 1 {
+		vs = tokenizeSearch(vs[0])
+	}
+	return vs
+}
+
+// searchRank is the rank addSearchRank computes in the database for the row of values,
+// 0 for each word equal to a column, 1 for each word a column starts with and 2 for the others
+func searchRank(words []string, tableName string, values map[string]interface{}) int {
+	var rank int
+	for _, word := range words {
+		word = stringutil.SimplifyString(word)
+		if len([]rune(word)) < SearchMinLength {
+			continue
+		}
+		// LOWER and LIKE are case insensitive in mysql and sqlite for ascii
+		word = strings.ToLower(word)
+		if AccentInsensitiveSearch {
+			word = stringutil.RemoveAccents(word)
+		}
+		wordRank := 2
+		for _, column := range searchColumns(tableName) {
+			if strings.HasSuffix(column, "_id") {
+				continue
+			}
+			value, ok := indirect(values[column]).(string)
+			if !ok {
+				continue
+			}
+			value = strings.ToLower(value)
+			if AccentInsensitiveSearch {
+				value = stringutil.RemoveAccents(value)
+			}
+			if value == word {
+				wordRank = 0
+				break
+			}
+			if strings.HasPrefix(value, word) {
+				wordRank = 1
+			}
+		}
+		rank += wordRank
+	}
+	return rank
+}
+
+// columnValues returns the values of the exported fields of row keyed by their column names
+func columnValues(row interface{}) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, field := range structs.Fields(row) {
+		if field.IsExported() {
+			values[gorm.ToDBName(field.Name())] = field.Value()
+		}
+	}
+	return values
+}
+
+// compareValues compares two values of a column, nil is less than any value like NULL in mysql and sqlite
+func compareValues(a, b interface{}) int {
+	a, b = indirect(a), indirect(b)
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	switch x := a.(type) {
+	case string:
+		return strings.Compare(x, b.(string))
+	case time.Time:
+		y := b.(time.Time)
+		if x.Before(y) {
+			return -1
+		}
+		if x.After(y) {
+			return 1
+		}
+		return 0
+	case bool:
+		y := b.(bool)
+		if x == y {
+			return 0
+		}
+		if !x {
+			return -1
+		}
+		return 1
+	}
+	x, y := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch x.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareInt64(x.Int(), y.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compareUint64(x.Uint(), y.Uint())
+	}
+	return 0
+}
+
+// indirect dereferences the pointer fields, a nil pointer is nil
+func indirect(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr {
+		return v
+	}
+	if rv.IsNil() {
+		return nil
+	}
+	return rv.Elem().Interface()
+}
+
+func compareInt64(x, y int64) int {
+	if x < y {
+		return -1
+	}
+	if x > y {
+		return 1
+	}
+	return 0
+}
+
+func compareUint64(x, y uint64) int {
+	if x < y {
+		return -1
+	}
+	if x > y {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"cloudbases.io/im/pkg/constants"
+)
+
+func TestShardIndex(t *testing.T) {
+	require.Equal(t, 0, ShardIndex("uid-a", 0))
+	require.Equal(t, 0, ShardIndex("uid-a", 1))
+
+	shards := make(map[int]int)
+	for _, key := range []string{"uid-a", "uid-b", "uid-c", "uid-d", "uid-e", "uid-f", "uid-g", "uid-h"} {
+		index := ShardIndex(key, 2)
+		require.Equal(t, index, ShardIndex(key, 2))
+		require.True(t, index == 0 || index == 1)
+		shards[index]++
+	}
+	require.Len(t, shards, 2)
+}
+
+func TestSortRows(t *testing.T) {
+	type row struct {
+		Name    string
+		Age     uint32
+		LoginAt *time.Time
+	}
+	now := time.Now()
+	before := now.Add(-time.Hour)
+	names := func(rows []*row) []string {
+		var names []string
+		for _, r := range rows {
+			names = append(names, r.Name)
+		}
+		return names
+	}
+	rows := []*row{
+		{Name: "a", Age: 20, LoginAt: &before},
+		{Name: "b", Age: 30},
+		{Name: "c", Age: 10, LoginAt: &now},
+	}
+
+	SortRows(rows, &testRequest{SortKey: "age"}, testTable, "name")
+	require.Equal(t, []string{"b", "a", "c"}, names(rows))
+	SortRows(rows, &testRequest{}, testTable, "name")
+	require.Equal(t, []string{"c", "b", "a"}, names(rows))
+	// null is the least value
+	SortRows(rows, &testRequest{SortKey: "login_at"}, testTable, "name")
+	require.Equal(t, []string{"c", "a", "b"}, names(rows))
+}
+
+func TestSortRowsSearchRank(t *testing.T) {
+	database := prepareTestTable(t, []string{"name"})
+	for _, name := range []string{"ann", "anna", "joanna", "annie", "hanna"} {
+		require.NoError(t, database.Table(testTable).Create(&testRow{name, constants.StatusActive}).Error)
+	}
+	find := func(req *testRequest) []*testRow {
+		var rows []*testRow
+		require.NoError(t, GetChain(database.Table(testTable)).
+			BuildFilterConditions(req, testTable).
+			AddSearchRankOrder(req, testTable).
+			AddQueryOrderDir(req, testTable, "name").
+			Find(&rows).Error)
+		return rows
+	}
+
+	// the rows of the shards merged by SortRows are in the order of the database
+	for _, req := range []*testRequest{
+		{SearchWord: []string{"ann"}},
+		{SearchWord: []string{"ann"}, SortKey: "name"},
+		{SearchWord: []string{"ann"}, SortKey: "name", RankSearch: true},
+		{SearchWord: []string{"ANN"}, RankSearch: true},
+	} {
+		expected := find(req)
+		require.Len(t, expected, 5)
+		var merged []*testRow
+		for i := len(expected) - 1; i >= 0; i-- {
+			merged = append(merged, expected[i])
+		}
+		SortRows(merged, req, testTable, "name")
+		require.Equal(t, expected, merged, "%+v", req)
+	}
+}
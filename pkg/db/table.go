@@ -0,0 +1,58 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"regexp"
+
+	"github.com/jinzhu/gorm"
+)
+
+// set by OpenDatabase from config, prepended to the names of all the tables
+var TablePrefix = ""
+
+// the tables of the models are prefixed too
+func init() {
+	gorm.DefaultTableNameHandler = func(db *gorm.DB, defaultTableName string) string {
+		return TableName(defaultTableName)
+	}
+}
+
+// TableName returns the name of table in the database
+func TableName(table string) string {
+	return TablePrefix + table
+}
+
+// AliasTable returns the prefixed table aliased to its name, for the queries referring to its columns by `table`.column
+func AliasTable(table string) string {
+	return "`" + TableName(table) + "` AS `" + table + "`"
+}
+
+var (
+	reMigrationTable = regexp.MustCompile("(?i)(\\b(?:TABLE(?:\\s+IF\\s+NOT\\s+EXISTS)?|ON|UPDATE)\\s+)`?(user_group_binding_deletion|user_group_binding|user_tag|user|group|audit_log)\\b`?")
+	reMigrationIndex = regexp.MustCompile(`(?i)(\bINDEX\s+)(\w+)`)
+)
+
+// prefixTables prefixes the tables and indexes created and altered by a migration script,
+// the index names are global in sqlite, so they are prefixed as well
+func prefixTables(script string) string {
+	if TablePrefix == "" {
+		return script
+	}
+	script = reMigrationTable.ReplaceAllString(script, "${1}`"+TablePrefix+"${2}`")
+	return reMigrationIndex.ReplaceAllString(script, "${1}"+TablePrefix+"${2}")
+}
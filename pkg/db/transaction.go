@@ -0,0 +1,85 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jinzhu/gorm"
+	"openpitrix.io/logger"
+)
+
+type TxOption func(opts *sql.TxOptions)
+
+// WithIsolation sets the isolation level of the transaction, e.g. sql.LevelSerializable
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(opts *sql.TxOptions) {
+		opts.Isolation = level
+	}
+}
+
+// gorm.DB.Begin does not accept options, so the transaction is started on sql.DB,
+// replaced in tests to observe the options
+var beginTx = func(ctx context.Context, db *sql.DB, opts *sql.TxOptions) (*sql.Tx, error) {
+	return db.BeginTx(ctx, opts)
+}
+
+// WithTransaction runs fn in a transaction, it is committed when fn returns nil, otherwise rolled back.
+// Without options the default isolation level of the driver is used.
+func (p *Database) WithTransaction(ctx context.Context, fn func(tx *gorm.DB) error, options ...TxOption) (err error) {
+	var opts *sql.TxOptions
+	if len(options) > 0 {
+		opts = new(sql.TxOptions)
+		for _, option := range options {
+			option(opts)
+		}
+	}
+
+	sqlTx, err := beginTx(ctx, p.DB.DB(), opts)
+	if err != nil {
+		logger.Errorf(ctx, "Begin transaction failed: %+v", err)
+		return err
+	}
+	tx, err := gorm.Open(p.cfg.DB.Type, sqlTx)
+	if err != nil {
+		sqlTx.Rollback()
+		logger.Errorf(ctx, "Begin transaction failed: %+v", err)
+		return err
+	}
+	tx.SingularTable(true)
+	p.setLogger(tx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			sqlTx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rollbackErr := sqlTx.Rollback(); rollbackErr != nil {
+			logger.Errorf(ctx, "Rollback transaction failed: %+v", rollbackErr)
+		}
+		return err
+	}
+	if err := sqlTx.Commit(); err != nil {
+		logger.Errorf(ctx, "Commit transaction failed: %+v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/require"
+)
+
+// spyBeginTx records the options of the transactions begun during the test
+func spyBeginTx(t *testing.T) *[]*sql.TxOptions {
+	var optsList []*sql.TxOptions
+	origin := beginTx
+	beginTx = func(ctx context.Context, db *sql.DB, opts *sql.TxOptions) (*sql.Tx, error) {
+		optsList = append(optsList, opts)
+		return origin(ctx, db, opts)
+	}
+	t.Cleanup(func() {
+		beginTx = origin
+	})
+	return &optsList
+}
+
+func TestWithTransactionIsolation(t *testing.T) {
+	database := prepareTestTable(t, nil)
+	optsList := spyBeginTx(t)
+	ctx := context.Background()
+	noop := func(tx *gorm.DB) error { return nil }
+
+	require.NoError(t, database.WithTransaction(ctx, noop))
+	require.NoError(t, database.WithTransaction(ctx, noop, WithIsolation(sql.LevelSerializable)))
+
+	require.Len(t, *optsList, 2)
+	require.Nil(t, (*optsList)[0])
+	require.Equal(t, sql.LevelSerializable, (*optsList)[1].Isolation)
+}
+
+func TestWithTransaction(t *testing.T) {
+	database := prepareTestTable(t, nil)
+	ctx := context.Background()
+
+	count := func() int {
+		var n int
+		require.NoError(t, database.Table(testTable).Count(&n).Error)
+		return n
+	}
+	before := count()
+
+	err := database.WithTransaction(ctx, func(tx *gorm.DB) error {
+		return tx.Table(testTable).Create(&testRow{"committed", ""}).Error
+	})
+	require.NoError(t, err)
+	require.Equal(t, before+1, count())
+
+	rollback := errors.New("rollback")
+	err = database.WithTransaction(ctx, func(tx *gorm.DB) error {
+		require.NoError(t, tx.Table(testTable).Create(&testRow{"rolled back", ""}).Error)
+		return rollback
+	})
+	require.Equal(t, rollback, err)
+	require.Equal(t, before+1, count())
+
+	require.Panics(t, func() {
+		database.WithTransaction(ctx, func(tx *gorm.DB) error {
+			require.NoError(t, tx.Table(testTable).Create(&testRow{"panic", ""}).Error)
+			panic("panic")
+		})
+	})
+	require.Equal(t, before+1, count())
+}
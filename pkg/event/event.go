@@ -0,0 +1,106 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/peer"
+	"openpitrix.io/logger"
+
+	"cloudbases.io/im/pkg/util/jsonutil"
+)
+
+const (
+	TypeComparePassword = "compare_password"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// AuditRecord is a structured security event, it must never carry secret material such as passwords
+type AuditRecord struct {
+	Type     string    `json:"type"`
+	UserId   string    `json:"user_id"`
+	Outcome  string    `json:"outcome"`
+	ClientIp string    `json:"client_ip"`
+	Time     time.Time `json:"time"`
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, record *AuditRecord)
+}
+
+// logPublisher writes the records to the service log as json
+type logPublisher struct{}
+
+func (logPublisher) Publish(ctx context.Context, record *AuditRecord) {
+	logger.Infof(ctx, "Audit: %s", jsonutil.ToString(record))
+}
+
+// LogPublisher returns the default publisher writing the records to the service log
+func LogPublisher() Publisher {
+	return logPublisher{}
+}
+
+var publisher Publisher = logPublisher{}
+var publisherMutex sync.RWMutex
+
+// SetPublisher replaces the publisher, nil restores the default log publisher
+func SetPublisher(p Publisher) {
+	publisherMutex.Lock()
+	defer publisherMutex.Unlock()
+	if p == nil {
+		p = logPublisher{}
+	}
+	publisher = p
+}
+
+func Publish(ctx context.Context, record *AuditRecord) {
+	publisherMutex.RLock()
+	p := publisher
+	publisherMutex.RUnlock()
+	p.Publish(ctx, record)
+}
+
+func NewAuditRecord(ctx context.Context, recordType, userId, outcome string) *AuditRecord {
+	return &AuditRecord{
+		Type:     recordType,
+		UserId:   userId,
+		Outcome:  outcome,
+		ClientIp: GetClientIp(ctx),
+		Time:     time.Now(),
+	}
+}
+
+// GetClientIp returns the ip of the grpc peer, empty if ctx is not from a grpc call
+func GetClientIp(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
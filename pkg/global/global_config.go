@@ -17,6 +17,8 @@ limitations under the License.
 package global
 
 import (
+	"context"
+	"errors"
 	"sync"
 
 	"openpitrix.io/logger"
@@ -25,24 +27,69 @@ import (
 	"cloudbases.io/im/pkg/db"
 )
 
+// ErrNotInitialized is returned when the global config is read before it is set
+var ErrNotInitialized = errors.New("global config is not initialized, call global.Init first")
+
 var global *Config
 var globalMutex sync.RWMutex
+var initOnce sync.Once
+
+// Init sets the global config from config on the first call only, the concurrent calls wait
+// for it and all of them return the same config
+func Init(config *config.Config) *Config {
+	initOnce.Do(func() {
+		SetGlobal(config)
+	})
+	return Global()
+}
 
+// SetGlobal replaces the global config unconditionally, the tests reset it with it
 func SetGlobal(config *config.Config) {
+	c := NewConfig(config)
 	globalMutex.Lock()
-	global = NewConfig(config)
+	global = c
 	globalMutex.Unlock()
 }
 
-func Global() *Config {
+// SetDatabase points the global config to database without opening any, the tests inject their
+// databases with it. The users are not sharded and there is no replica, the config is kept or
+// is the default one when there was no global config yet
+func SetDatabase(database *db.Database) {
+	globalMutex.Lock()
+	defer globalMutex.Unlock()
+	c := &Config{Config: config.Default(), Database: database}
+	if global != nil {
+		c.Config = global.Config
+	}
+	global = c
+}
+
+// Lookup returns the global config, ErrNotInitialized when it is not set yet
+func Lookup() (*Config, error) {
 	globalMutex.RLock()
 	defer globalMutex.RUnlock()
-	return global
+	if global == nil {
+		return nil, ErrNotInitialized
+	}
+	return global, nil
+}
+
+// Global returns the global config, it panics with ErrNotInitialized when it is not set yet
+func Global() *Config {
+	c, err := Lookup()
+	if err != nil {
+		panic(err)
+	}
+	return c
 }
 
 type Config struct {
 	Config   *config.Config
 	Database *db.Database
+	// the databases the users are sharded across, empty when the users are in Database
+	Shards []*db.Database
+	// the read replica of Database, nil when there is none
+	Replica *db.Database
 }
 
 func NewConfig(config *config.Config) *Config {
@@ -59,4 +106,91 @@ func (c *Config) openDatabase() {
 		panic(err)
 	}
 	c.Database = database
+
+	if c.Config.DB.AutoMigrate {
+		if err := database.Migrate(); err != nil {
+			logger.Criticalf(nil, "failed to migrate database")
+			panic(err)
+		}
+	}
+
+	for _, shardDatabase := range c.Config.DB.GetShardDatabases() {
+		cfg := c.Config.Clone()
+		cfg.DB.Database = shardDatabase
+		shard, err := db.OpenDatabase(cfg)
+		if err != nil {
+			logger.Criticalf(nil, "failed to connect shard database [%s]", shardDatabase)
+			panic(err)
+		}
+		if cfg.DB.AutoMigrate {
+			if err := shard.Migrate(); err != nil {
+				logger.Criticalf(nil, "failed to migrate shard database [%s]", shardDatabase)
+				panic(err)
+			}
+		}
+		c.Shards = append(c.Shards, shard)
+	}
+
+	// the schema of the replica is replicated from Database, it is not migrated
+	if replicaHost := c.Config.DB.ReplicaHost; replicaHost != "" {
+		cfg := c.Config.Clone()
+		if cfg.DB.Type == "sqlite3" {
+			cfg.DB.Database = replicaHost
+		} else {
+			cfg.DB.Host = replicaHost
+		}
+		replica, err := db.OpenDatabase(cfg)
+		if err != nil {
+			logger.Criticalf(nil, "failed to connect replica database [%s]", replicaHost)
+			panic(err)
+		}
+		c.Replica = replica
+	}
+}
+
+// ReadDatabase returns the database the reads under ctx are sent to, the replica when there is one
+// and ctx does not ask for a consistent read, the replica may lag behind the latest writes
+func (c *Config) ReadDatabase(ctx context.Context) *db.Database {
+	if c.Replica == nil || db.IsConsistentRead(ctx) {
+		return c.Database
+	}
+	return c.Replica
+}
+
+// UserDatabase returns the database holding the user, its shard when the users are sharded
+func (c *Config) UserDatabase(userId string) *db.Database {
+	if len(c.Shards) == 0 {
+		return c.Database
+	}
+	return c.Shards[db.ShardIndex(userId, len(c.Shards))]
+}
+
+// UserDatabases returns the databases holding the users, the list operations fan out to all of them
+func (c *Config) UserDatabases() []*db.Database {
+	if len(c.Shards) == 0 {
+		return []*db.Database{c.Database}
+	}
+	return c.Shards
+}
+
+// GroupUserIds groups userIds by the databases holding them, the batch operations send
+// a single query to each database
+func (c *Config) GroupUserIds(userIds []string) map[*db.Database][]string {
+	groups := make(map[*db.Database][]string)
+	for _, userId := range userIds {
+		database := c.UserDatabase(userId)
+		groups[database] = append(groups[database], userId)
+	}
+	return groups
+}
+
+// Close closes the database and the shards
+func (c *Config) Close() error {
+	for _, shard := range c.Shards {
+		shard.Close()
+	}
+	if c.Replica != nil {
+		c.Replica.Close()
+	}
+	return c.Database.Close()
 }
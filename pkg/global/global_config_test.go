@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package global
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cloudbases.io/im/pkg/config"
+	"cloudbases.io/im/pkg/db"
+)
+
+func testConfig(t *testing.T) *config.Config {
+	cfg := config.Default()
+	cfg.DB.Type = "sqlite3"
+	cfg.DB.Database = filepath.Join(t.TempDir(), "im.db")
+	return cfg
+}
+
+// resetGlobal clears the global config and restores it after the test
+func resetGlobal(t *testing.T) {
+	globalMutex.Lock()
+	saved := global
+	global = nil
+	initOnce = sync.Once{}
+	globalMutex.Unlock()
+	t.Cleanup(func() {
+		globalMutex.Lock()
+		global = saved
+		globalMutex.Unlock()
+	})
+}
+
+func TestGlobalNotInitialized(t *testing.T) {
+	resetGlobal(t)
+
+	_, err := Lookup()
+	require.Equal(t, ErrNotInitialized, err)
+	require.PanicsWithValue(t, ErrNotInitialized, func() {
+		Global()
+	})
+}
+
+func TestSetDatabase(t *testing.T) {
+	resetGlobal(t)
+	database, err := db.OpenDatabase(testConfig(t))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		database.Close()
+	})
+
+	SetDatabase(database)
+	c := Global()
+	require.Equal(t, database, c.Database)
+	require.Equal(t, config.Default(), c.Config)
+	require.Equal(t, database, c.ReadDatabase(context.Background()))
+	require.Equal(t, database, c.UserDatabase("usr-1"))
+	require.NoError(t, database.Exec("SELECT 1").Error)
+}
+
+func TestGroupUserIds(t *testing.T) {
+	database := &db.Database{}
+	c := &Config{Database: database}
+	require.Equal(t, map[*db.Database][]string{database: {"usr-1", "usr-2"}}, c.GroupUserIds([]string{"usr-1", "usr-2"}))
+
+	c.Shards = []*db.Database{{}, {}, {}}
+	userIds := []string{"usr-1", "usr-2", "usr-3", "usr-4", "usr-5", "usr-6"}
+	var grouped []string
+	for database, ids := range c.GroupUserIds(userIds) {
+		for _, userId := range ids {
+			require.True(t, database == c.UserDatabase(userId))
+		}
+		grouped = append(grouped, ids...)
+	}
+	require.ElementsMatch(t, userIds, grouped)
+}
+
+func TestInitConcurrent(t *testing.T) {
+	resetGlobal(t)
+	cfg := testConfig(t)
+
+	const n = 10
+	configs := make([]*Config, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			configs[i] = Init(cfg)
+		}(i)
+	}
+	wg.Wait()
+	t.Cleanup(func() {
+		configs[0].Close()
+	})
+
+	for _, c := range configs {
+		require.True(t, c == configs[0])
+	}
+	require.True(t, Global() == configs[0])
+
+	// the later calls keep the first config
+	require.True(t, Init(testConfig(t)) == configs[0])
+}
@@ -27,13 +27,15 @@ var ClientOptions = []grpc.DialOption{
 
 var clientCache sync.Map
 
-func NewClient(host string, port int) (*grpc.ClientConn, error) {
+// NewClient dials endpoint with ClientOptions and opts, the connection is cached by endpoint
+func NewClient(host string, port int, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
 	endpoint := fmt.Sprintf("%s:%d", host, port)
 	if conn, ok := clientCache.Load(endpoint); ok {
 		return conn.(*grpc.ClientConn), nil
 	}
 	ctx := context.Background()
-	conn, err := grpc.DialContext(ctx, endpoint, ClientOptions...)
+	dialOptions := append(append([]grpc.DialOption{}, ClientOptions...), opts...)
+	conn, err := grpc.DialContext(ctx, endpoint, dialOptions...)
 	if err != nil {
 		return nil, err
 	}
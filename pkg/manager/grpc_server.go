@@ -33,16 +33,22 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 	"openpitrix.io/logger"
 
+	"cloudbases.io/im/pkg/db"
 	"cloudbases.io/im/pkg/version"
 )
 
 type GrpcServer struct {
 	ServiceName string
 	Port        int
+	// max duration of a unary handler, the context is cancelled after it, 0 means unlimited
+	HandlerTimeout time.Duration
+	// the server is stopped gracefully when it is done, nil means serving until the process exits
+	Context context.Context
 }
 
 type RegisterCallback func(*grpc.Server)
@@ -54,6 +60,18 @@ func NewGrpcServer(serviceName string, port int) *GrpcServer {
 	}
 }
 
+// WithHandlerTimeout sets HandlerTimeout
+func (g *GrpcServer) WithHandlerTimeout(timeout time.Duration) *GrpcServer {
+	g.HandlerTimeout = timeout
+	return g
+}
+
+// WithContext sets Context
+func (g *GrpcServer) WithContext(ctx context.Context) *GrpcServer {
+	g.Context = ctx
+	return g
+}
+
 func (g *GrpcServer) Serve(callback RegisterCallback, opt ...grpc.ServerOption) {
 	version.PrintVersionInfo(func(s string, i ...interface{}) {
 		logger.Infof(nil, s, i)
@@ -71,6 +89,8 @@ func (g *GrpcServer) Serve(callback RegisterCallback, opt ...grpc.ServerOption)
 			PermitWithoutStream: true,
 		}),
 		grpc_middleware.WithUnaryServerChain(
+			UnaryServerTimeoutInterceptor(g.HandlerTimeout),
+			UnaryServerConsistentReadInterceptor(),
 			grpc_validator.UnaryServerInterceptor(),
 			g.unaryServerLogInterceptor(),
 			grpc_recovery.UnaryServerInterceptor(
@@ -102,6 +122,19 @@ func (g *GrpcServer) Serve(callback RegisterCallback, opt ...grpc.ServerOption)
 	reflection.Register(grpcServer)
 	callback(grpcServer)
 
+	if g.Context != nil {
+		served := make(chan struct{})
+		defer close(served)
+		go func() {
+			select {
+			case <-g.Context.Done():
+				logger.Infof(nil, "Service [%s] is stopping", g.ServiceName)
+				grpcServer.GracefulStop()
+			case <-served:
+			}
+		}()
+	}
+
 	if err = grpcServer.Serve(lis); err != nil {
 		err = errors.WithStack(err)
 		logger.Criticalf(nil, "%+v", err)
@@ -141,3 +174,38 @@ func (g *GrpcServer) unaryServerLogInterceptor() grpc.UnaryServerInterceptor {
 		return resp, err
 	}
 }
+
+// UnaryServerTimeoutInterceptor cancels the context of the handler after timeout,
+// the earlier deadline of the client is kept
+func UnaryServerTimeoutInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if timeout <= 0 {
+			return handler(ctx, req)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		resp, err := handler(ctx, req)
+		if err != nil && ctx.Err() == context.DeadlineExceeded && status.Code(err) == codes.Unknown {
+			logger.Errorf(ctx, "Handle request [%s] exceeded the timeout [%s]: %+v", info.FullMethod, timeout, err)
+			return nil, status.Errorf(codes.DeadlineExceeded, "request exceeded the timeout [%s]", timeout)
+		}
+		return resp, err
+	}
+}
+
+// UnaryServerConsistentReadInterceptor serves the reads of the handler from the primary database
+// when the client sends the consistent read metadata, e.g. right after its own writes
+func UnaryServerConsistentReadInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			for _, value := range md.Get(db.MetadataConsistentRead) {
+				if value == "true" {
+					ctx = db.WithConsistentRead(ctx)
+					break
+				}
+			}
+		}
+		return handler(ctx, req)
+	}
+}
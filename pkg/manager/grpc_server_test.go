@@ -0,0 +1,109 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"cloudbases.io/im/pkg/db"
+)
+
+func TestUnaryServerTimeoutInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/im.IdentityManager/ListUsers"}
+	slowHandler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+			return "done", nil
+		}
+	}
+
+	start := time.Now()
+	resp, err := UnaryServerTimeoutInterceptor(50*time.Millisecond)(context.Background(), nil, info, slowHandler)
+	elapsed := time.Since(start)
+	require.Nil(t, resp)
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	require.True(t, elapsed >= 50*time.Millisecond && elapsed < time.Second, "cancelled after %s", elapsed)
+
+	// the earlier deadline of the client is kept
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, err = UnaryServerTimeoutInterceptor(time.Minute)(ctx, nil, info, slowHandler)
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	require.True(t, time.Since(start) < time.Second)
+
+	// errors of the handler are returned as is
+	_, err = UnaryServerTimeoutInterceptor(time.Minute)(context.Background(), nil, info,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			_, hasDeadline := ctx.Deadline()
+			require.True(t, hasDeadline)
+			return nil, status.Errorf(codes.NotFound, "not found")
+		})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	// 0 means unlimited
+	resp, err = UnaryServerTimeoutInterceptor(0)(context.Background(), nil, info,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			_, hasDeadline := ctx.Deadline()
+			require.False(t, hasDeadline)
+			return "done", nil
+		})
+	require.NoError(t, err)
+	require.Equal(t, "done", resp)
+}
+
+func TestUnaryServerConsistentReadInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/im.IdentityManager/GetGroupsByUserIds"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return db.IsConsistentRead(ctx), nil
+	}
+
+	resp, err := UnaryServerConsistentReadInterceptor()(context.Background(), nil, info, handler)
+	require.NoError(t, err)
+	require.Equal(t, false, resp)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(db.MetadataConsistentRead, "true"))
+	resp, err = UnaryServerConsistentReadInterceptor()(ctx, nil, info, handler)
+	require.NoError(t, err)
+	require.Equal(t, true, resp)
+}
+
+func TestGrpcServerStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		NewGrpcServer("test", 0).WithContext(ctx).Serve(func(*grpc.Server) {})
+	}()
+
+	cancel()
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"time"
+
+	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/event"
+	"cloudbases.io/im/pkg/util/idutil"
+)
+
+// AuditLog is a stored audit record, the time of the record is its create time
+type AuditLog struct {
+	Id         string `gorm:"type:varchar(50);primary_key"`
+	Type       string `gorm:"type:varchar(50);not null"`
+	UserId     string `gorm:"type:varchar(50);not null"`
+	Outcome    string `gorm:"type:varchar(50);not null"`
+	ClientIp   string `gorm:"type:varchar(50);not null"`
+	CreateTime time.Time
+}
+
+func NewAuditLog(record *event.AuditRecord) *AuditLog {
+	return &AuditLog{
+		Id:         idutil.GetSortableId(constants.PrefixAuditLogId),
+		Type:       record.Type,
+		UserId:     record.UserId,
+		Outcome:    record.Outcome,
+		ClientIp:   record.ClientIp,
+		CreateTime: record.Time.UTC(),
+	}
+}
+
+func (p *AuditLog) ToRecord() *event.AuditRecord {
+	return &event.AuditRecord{
+		Type:     p.Type,
+		UserId:   p.UserId,
+		Outcome:  p.Outcome,
+		ClientIp: p.ClientIp,
+		Time:     p.CreateTime.UTC(),
+	}
+}
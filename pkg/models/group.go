@@ -40,6 +40,8 @@ type Group struct {
 	UpdateTime    time.Time
 	StatusTime    time.Time
 	Extra         *string `gorm:"type:JSON"`
+	// an archived group is kept with its members but hidden from the groups of its members
+	Archived bool `gorm:"not null"`
 
 	// internal
 	GroupPathLevel int
@@ -72,10 +74,10 @@ func GetGroupPath(parentGroupPath, groupId string) string {
 }
 
 func NewGroup(parentGroupId, parentGroupPath, groupName, description string, extra map[string]string) *Group {
-	groupId := idutil.GetUuid(constants.PrefixGroupId)
+	groupId := idutil.GetSortableId(constants.PrefixGroupId)
 	groupPath := GetGroupPath(parentGroupPath, groupId)
 	data := jsonutil.ToString(extra)
-	now := time.Now()
+	now := NowUTC()
 	group := &Group{
 		ParentGroupId:  stringutil.SimplifyString(parentGroupId),
 		GroupId:        stringutil.SimplifyString(groupId),
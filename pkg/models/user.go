@@ -29,18 +29,35 @@ import (
 	"cloudbases.io/im/pkg/util/stringutil"
 )
 
+// User is a row of the user table, the password hash is never serialized to JSON or pb
 type User struct {
 	UserId      string `gorm:"primary_key"`
 	Username    string `gorm:"type:varchar(50);not null;unique;"`
 	Email       string `gorm:"type:varchar(50);not null;unique"`
 	PhoneNumber string `gorm:"type:varchar(50);not null"`
 	Description string `gorm:"type:varchar(1000);not null"`
-	Password    string `gorm:"type:varchar(128);not null"`
+	Password    string `gorm:"type:varchar(128);not null" json:"-"`
 	Status      string `gorm:"type:varchar(50);not null"`
 	CreateTime  time.Time
 	UpdateTime  time.Time
 	StatusTime  time.Time
 	Extra       *string `gorm:"type:JSON"`
+	Version     uint32  `gorm:"not null"`
+	AvatarUrl   string  `gorm:"type:varchar(1000);not null"`
+	FirstName   string  `gorm:"type:varchar(50);not null"`
+	LastName    string  `gorm:"type:varchar(50);not null"`
+
+	PasswordUpdatedAt *time.Time
+	LastLoginAt       *time.Time
+	// the subject of the user at an external identity provider, unique per provider
+	ExternalProvider *string `gorm:"type:varchar(50)"`
+	ExternalId       *string `gorm:"type:varchar(255)"`
+	// set by ForcePasswordResetForUsers, cleared when the password is modified
+	MustChangePassword bool `gorm:"not null"`
+	// the consecutive failed compares of the password, reset by a successful one
+	FailedLoginCount uint32 `gorm:"not null"`
+	// the compares of the password fail until then, set after too many failed ones
+	LockedUntil *time.Time
 }
 
 type UserWithGroup struct {
@@ -71,9 +88,9 @@ func GetBcryptPassword(password string) string {
 
 func NewUser(username, email, phoneNumber, description, password string, extra map[string]string) *User {
 	data := jsonutil.ToString(extra)
-	now := time.Now()
+	now := NowUTC()
 	user := &User{
-		UserId:      idutil.GetUuid(constants.PrefixUserId),
+		UserId:      idutil.GetSortableId(constants.PrefixUserId),
 		Username:    stringutil.SimplifyString(username),
 		Email:       stringutil.SimplifyString(email),
 		PhoneNumber: stringutil.SimplifyString(phoneNumber),
@@ -84,6 +101,8 @@ func NewUser(username, email, phoneNumber, description, password string, extra m
 		UpdateTime:  now,
 		StatusTime:  now,
 		Extra:       stringutil.NewString(data),
+
+		PasswordUpdatedAt: &now,
 	}
 	return user
 }
@@ -104,11 +123,22 @@ func (p *User) ToProtoMessage() (*pb.User, error) {
 		PhoneNumber: p.PhoneNumber,
 		Description: p.Description,
 		Status:      p.Status,
+		Version:     p.Version,
+		AvatarUrl:   p.AvatarUrl,
+		FirstName:   p.FirstName,
+		LastName:    p.LastName,
 	}
 
 	q.CreateTime, _ = ptypes.TimestampProto(p.CreateTime)
 	q.UpdateTime, _ = ptypes.TimestampProto(p.UpdateTime)
 	q.StatusTime, _ = ptypes.TimestampProto(p.StatusTime)
+	if p.LastLoginAt != nil {
+		q.LastLoginAt, _ = ptypes.TimestampProto(*p.LastLoginAt)
+	}
+	if p.ExternalProvider != nil && p.ExternalId != nil {
+		q.ExternalProvider = *p.ExternalProvider
+		q.ExternalId = *p.ExternalId
+	}
 
 	if p.Extra != nil && *p.Extra != "" {
 		if q.Extra == nil {
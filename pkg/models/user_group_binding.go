@@ -27,14 +27,28 @@ type UserGroupBinding struct {
 	Id         string    `gorm:"type:varchar(50);primary_key"`
 	GroupId    string    `gorm:"type:varchar(50);not null"`
 	UserId     string    `gorm:"type:varchar(50);not null"`
+	Status     string    `gorm:"type:varchar(50);not null"`
+	Role       string    `gorm:"type:varchar(50);not null"`
 	CreateTime time.Time `gorm:"default CURRENT_TIMESTAMP"`
+	// the user who created the binding, e.g. the admin inviting the user, empty if unknown
+	CreatedBy string `gorm:"type:varchar(50);not null"`
 }
 
 func NewUserGroupBinding(userId, groupId string) *UserGroupBinding {
 	return &UserGroupBinding{
-		Id:         idutil.GetUuid(constants.PrefixUserGroupBindingId),
+		Id:         idutil.GetSortableId(constants.PrefixUserGroupBindingId),
 		GroupId:    groupId,
 		UserId:     userId,
-		CreateTime: time.Now(),
+		Status:     constants.BindingStatusAccepted,
+		Role:       constants.BindingRoleMember,
+		CreateTime: NowUTC(),
 	}
 }
+
+// UserGroupBindingDeletion is the tombstone of a deleted binding
+type UserGroupBindingDeletion struct {
+	Id         string `gorm:"type:varchar(50);primary_key"`
+	GroupId    string `gorm:"type:varchar(50);not null"`
+	UserId     string `gorm:"type:varchar(50);not null"`
+	DeleteTime time.Time
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"time"
+
+	"cloudbases.io/im/pkg/util/stringutil"
+)
+
+type UserTag struct {
+	UserId     string `gorm:"type:varchar(50);primary_key"`
+	Tag        string `gorm:"type:varchar(50);primary_key"`
+	CreateTime time.Time
+}
+
+func NewUserTag(userId, tag string) *UserTag {
+	return &UserTag{
+		UserId:     userId,
+		Tag:        stringutil.SimplifyString(tag),
+		CreateTime: NowUTC(),
+	}
+}
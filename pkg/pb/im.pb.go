@@ -9,6 +9,7 @@ import (
 
 	proto "github.com/golang/protobuf/proto"
 	timestamp "github.com/golang/protobuf/ptypes/timestamp"
+	wrappers "github.com/golang/protobuf/ptypes/wrappers"
 	context "golang.org/x/net/context"
 	_ "google.golang.org/genproto/googleapis/api/annotations"
 	grpc "google.golang.org/grpc"
@@ -198,7 +199,11 @@ func (m *CreateGroupResponse) GetGroupId() string {
 }
 
 type DeleteGroupsRequest struct {
-	GroupId              []string `protobuf:"bytes,1,rep,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	GroupId []string `protobuf:"bytes,1,rep,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	// remove the bindings of the groups instead of refusing to delete groups with users
+	Cascade bool `protobuf:"varint,2,opt,name=cascade,proto3" json:"cascade,omitempty"`
+	// with cascade, delete the sub groups and remove their bindings too
+	CascadeDescendants   bool     `protobuf:"varint,3,opt,name=cascade_descendants,json=cascadeDescendants,proto3" json:"cascade_descendants,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -236,6 +241,20 @@ func (m *DeleteGroupsRequest) GetGroupId() []string {
 	return nil
 }
 
+func (m *DeleteGroupsRequest) GetCascade() bool {
+	if m != nil {
+		return m.Cascade
+	}
+	return false
+}
+
+func (m *DeleteGroupsRequest) GetCascadeDescendants() bool {
+	if m != nil {
+		return m.CascadeDescendants
+	}
+	return false
+}
+
 type DeleteGroupsResponse struct {
 	GroupId              []string `protobuf:"bytes,1,rep,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -661,17 +680,30 @@ func (m *GetGroupWithUserResponse) GetGroup() *GroupWithUser {
 }
 
 type ListGroupsRequest struct {
-	SearchWord           []string `protobuf:"bytes,1,rep,name=search_word,json=searchWord,proto3" json:"search_word,omitempty"`
-	SortKey              string   `protobuf:"bytes,2,opt,name=sort_key,json=sortKey,proto3" json:"sort_key,omitempty"`
-	Reverse              bool     `protobuf:"varint,3,opt,name=reverse,proto3" json:"reverse,omitempty"`
-	Offset               uint32   `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
-	Limit                uint32   `protobuf:"varint,5,opt,name=limit,proto3" json:"limit,omitempty"`
-	RootGroupId          []string `protobuf:"bytes,6,rep,name=root_group_id,json=rootGroupId,proto3" json:"root_group_id,omitempty"`
-	ParentGroupId        []string `protobuf:"bytes,7,rep,name=parent_group_id,json=parentGroupId,proto3" json:"parent_group_id,omitempty"`
-	GroupId              []string `protobuf:"bytes,8,rep,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
-	GroupPath            []string `protobuf:"bytes,9,rep,name=group_path,json=groupPath,proto3" json:"group_path,omitempty"`
-	GroupName            []string `protobuf:"bytes,10,rep,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
-	Status               []string `protobuf:"bytes,11,rep,name=status,proto3" json:"status,omitempty"`
+	SearchWord []string `protobuf:"bytes,1,rep,name=search_word,json=searchWord,proto3" json:"search_word,omitempty"`
+	// a column of group, or member_count for the number of accepted members
+	SortKey       string   `protobuf:"bytes,2,opt,name=sort_key,json=sortKey,proto3" json:"sort_key,omitempty"`
+	Reverse       bool     `protobuf:"varint,3,opt,name=reverse,proto3" json:"reverse,omitempty"`
+	Offset        uint32   `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	Limit         uint32   `protobuf:"varint,5,opt,name=limit,proto3" json:"limit,omitempty"`
+	RootGroupId   []string `protobuf:"bytes,6,rep,name=root_group_id,json=rootGroupId,proto3" json:"root_group_id,omitempty"`
+	ParentGroupId []string `protobuf:"bytes,7,rep,name=parent_group_id,json=parentGroupId,proto3" json:"parent_group_id,omitempty"`
+	GroupId       []string `protobuf:"bytes,8,rep,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	GroupPath     []string `protobuf:"bytes,9,rep,name=group_path,json=groupPath,proto3" json:"group_path,omitempty"`
+	GroupName     []string `protobuf:"bytes,10,rep,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
+	Status        []string `protobuf:"bytes,11,rep,name=status,proto3" json:"status,omitempty"`
+	// exact and prefix matches of search_word come first
+	RankSearch bool `protobuf:"varint,12,opt,name=rank_search,json=rankSearch,proto3" json:"rank_search,omitempty"`
+	// only the total is returned, limit is 0 and group_set is empty
+	CountOnly bool `protobuf:"varint,13,opt,name=count_only,json=countOnly,proto3" json:"count_only,omitempty"`
+	// contains (default), prefix or suffix
+	SearchMode string `protobuf:"bytes,14,opt,name=search_mode,json=searchMode,proto3" json:"search_mode,omitempty"`
+	// return the columns matched by search_word in highlight_set
+	Highlight bool `protobuf:"varint,15,opt,name=highlight,proto3" json:"highlight,omitempty"`
+	// overrides offset, limit, sort_key and reverse when set
+	Pagination *Pagination `protobuf:"bytes,16,opt,name=pagination,proto3" json:"pagination,omitempty"`
+	// only the indexed columns filter the rows, search_word is not searched by LIKE
+	Strict               bool     `protobuf:"varint,17,opt,name=strict,proto3" json:"strict,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -779,12 +811,60 @@ func (m *ListGroupsRequest) GetStatus() []string {
 	return nil
 }
 
+func (m *ListGroupsRequest) GetRankSearch() bool {
+	if m != nil {
+		return m.RankSearch
+	}
+	return false
+}
+
+func (m *ListGroupsRequest) GetCountOnly() bool {
+	if m != nil {
+		return m.CountOnly
+	}
+	return false
+}
+
+func (m *ListGroupsRequest) GetSearchMode() string {
+	if m != nil {
+		return m.SearchMode
+	}
+	return ""
+}
+
+func (m *ListGroupsRequest) GetHighlight() bool {
+	if m != nil {
+		return m.Highlight
+	}
+	return false
+}
+
+func (m *ListGroupsRequest) GetPagination() *Pagination {
+	if m != nil {
+		return m.Pagination
+	}
+	return nil
+}
+
+func (m *ListGroupsRequest) GetStrict() bool {
+	if m != nil {
+		return m.Strict
+	}
+	return false
+}
+
 type ListGroupsResponse struct {
-	Total                uint32   `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
-	GroupSet             []*Group `protobuf:"bytes,2,rep,name=group_set,json=groupSet,proto3" json:"group_set,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Total    uint32   `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	GroupSet []*Group `protobuf:"bytes,2,rep,name=group_set,json=groupSet,proto3" json:"group_set,omitempty"`
+	// the limit and offset applied, after clamping
+	Limit  uint32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset uint32 `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	// one per group in group_set when highlight is requested
+	HighlightSet         []*SearchHighlight `protobuf:"bytes,5,rep,name=highlight_set,json=highlightSet,proto3" json:"highlight_set,omitempty"`
+	PageInfo             *PageInfo          `protobuf:"bytes,6,opt,name=page_info,json=pageInfo,proto3" json:"page_info,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
 }
 
 func (m *ListGroupsResponse) Reset()         { *m = ListGroupsResponse{} }
@@ -826,19 +906,266 @@ func (m *ListGroupsResponse) GetGroupSet() []*Group {
 	return nil
 }
 
+func (m *ListGroupsResponse) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *ListGroupsResponse) GetOffset() uint32 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *ListGroupsResponse) GetHighlightSet() []*SearchHighlight {
+	if m != nil {
+		return m.HighlightSet
+	}
+	return nil
+}
+
+func (m *ListGroupsResponse) GetPageInfo() *PageInfo {
+	if m != nil {
+		return m.PageInfo
+	}
+	return nil
+}
+
+// the page and order of a list request
+type Pagination struct {
+	Offset               uint32   `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	Limit                uint32   `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	SortKey              string   `protobuf:"bytes,3,opt,name=sort_key,json=sortKey,proto3" json:"sort_key,omitempty"`
+	Reverse              bool     `protobuf:"varint,4,opt,name=reverse,proto3" json:"reverse,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Pagination) Reset()         { *m = Pagination{} }
+func (m *Pagination) String() string { return proto.CompactTextString(m) }
+func (*Pagination) ProtoMessage()    {}
+func (*Pagination) Descriptor() ([]byte, []int) {
+	return fileDescriptor_36f2114a3e4ddb9e, []int{15}
+}
+
+func (m *Pagination) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Pagination.Unmarshal(m, b)
+}
+func (m *Pagination) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Pagination.Marshal(b, m, deterministic)
+}
+func (m *Pagination) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Pagination.Merge(m, src)
+}
+func (m *Pagination) XXX_Size() int {
+	return xxx_messageInfo_Pagination.Size(m)
+}
+func (m *Pagination) XXX_DiscardUnknown() {
+	xxx_messageInfo_Pagination.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Pagination proto.InternalMessageInfo
+
+func (m *Pagination) GetOffset() uint32 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *Pagination) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *Pagination) GetSortKey() string {
+	if m != nil {
+		return m.SortKey
+	}
+	return ""
+}
+
+func (m *Pagination) GetReverse() bool {
+	if m != nil {
+		return m.Reverse
+	}
+	return false
+}
+
+// the page of a list response, offset and limit are the ones applied
+type PageInfo struct {
+	Total  uint32 `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	Offset uint32 `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Limit  uint32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	// there are more rows after the page
+	HasMore              bool     `protobuf:"varint,4,opt,name=has_more,json=hasMore,proto3" json:"has_more,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PageInfo) Reset()         { *m = PageInfo{} }
+func (m *PageInfo) String() string { return proto.CompactTextString(m) }
+func (*PageInfo) ProtoMessage()    {}
+func (*PageInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_36f2114a3e4ddb9e, []int{16}
+}
+
+func (m *PageInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PageInfo.Unmarshal(m, b)
+}
+func (m *PageInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PageInfo.Marshal(b, m, deterministic)
+}
+func (m *PageInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PageInfo.Merge(m, src)
+}
+func (m *PageInfo) XXX_Size() int {
+	return xxx_messageInfo_PageInfo.Size(m)
+}
+func (m *PageInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_PageInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PageInfo proto.InternalMessageInfo
+
+func (m *PageInfo) GetTotal() uint32 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
+func (m *PageInfo) GetOffset() uint32 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *PageInfo) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *PageInfo) GetHasMore() bool {
+	if m != nil {
+		return m.HasMore
+	}
+	return false
+}
+
+// the columns of a row matched by the search words
+type SearchHighlight struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Column               []string `protobuf:"bytes,2,rep,name=column,proto3" json:"column,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SearchHighlight) Reset()         { *m = SearchHighlight{} }
+func (m *SearchHighlight) String() string { return proto.CompactTextString(m) }
+func (*SearchHighlight) ProtoMessage()    {}
+func (*SearchHighlight) Descriptor() ([]byte, []int) {
+	return fileDescriptor_36f2114a3e4ddb9e, []int{17}
+}
+
+func (m *SearchHighlight) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SearchHighlight.Unmarshal(m, b)
+}
+func (m *SearchHighlight) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SearchHighlight.Marshal(b, m, deterministic)
+}
+func (m *SearchHighlight) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SearchHighlight.Merge(m, src)
+}
+func (m *SearchHighlight) XXX_Size() int {
+	return xxx_messageInfo_SearchHighlight.Size(m)
+}
+func (m *SearchHighlight) XXX_DiscardUnknown() {
+	xxx_messageInfo_SearchHighlight.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SearchHighlight proto.InternalMessageInfo
+
+func (m *SearchHighlight) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *SearchHighlight) GetColumn() []string {
+	if m != nil {
+		return m.Column
+	}
+	return nil
+}
+
+type CountGroupsResponse struct {
+	Total                uint32   `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CountGroupsResponse) Reset()         { *m = CountGroupsResponse{} }
+func (m *CountGroupsResponse) String() string { return proto.CompactTextString(m) }
+func (*CountGroupsResponse) ProtoMessage()    {}
+func (*CountGroupsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_36f2114a3e4ddb9e, []int{18}
+}
+
+func (m *CountGroupsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CountGroupsResponse.Unmarshal(m, b)
+}
+func (m *CountGroupsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CountGroupsResponse.Marshal(b, m, deterministic)
+}
+func (m *CountGroupsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CountGroupsResponse.Merge(m, src)
+}
+func (m *CountGroupsResponse) XXX_Size() int {
+	return xxx_messageInfo_CountGroupsResponse.Size(m)
+}
+func (m *CountGroupsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CountGroupsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CountGroupsResponse proto.InternalMessageInfo
+
+func (m *CountGroupsResponse) GetTotal() uint32 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
 type ListGroupsWithUserResponse struct {
-	Total                uint32           `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
-	GroupSet             []*GroupWithUser `protobuf:"bytes,2,rep,name=group_set,json=groupSet,proto3" json:"group_set,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
-	XXX_unrecognized     []byte           `json:"-"`
-	XXX_sizecache        int32            `json:"-"`
+	Total    uint32           `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	GroupSet []*GroupWithUser `protobuf:"bytes,2,rep,name=group_set,json=groupSet,proto3" json:"group_set,omitempty"`
+	// the limit and offset applied, after clamping
+	Limit                uint32   `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset               uint32   `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *ListGroupsWithUserResponse) Reset()         { *m = ListGroupsWithUserResponse{} }
 func (m *ListGroupsWithUserResponse) String() string { return proto.CompactTextString(m) }
 func (*ListGroupsWithUserResponse) ProtoMessage()    {}
 func (*ListGroupsWithUserResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{15}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{19}
 }
 
 func (m *ListGroupsWithUserResponse) XXX_Unmarshal(b []byte) error {
@@ -873,23 +1200,41 @@ func (m *ListGroupsWithUserResponse) GetGroupSet() []*GroupWithUser {
 	return nil
 }
 
+func (m *ListGroupsWithUserResponse) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *ListGroupsWithUserResponse) GetOffset() uint32 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
 type CreateUserRequest struct {
-	Username             string            `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
-	Email                string            `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
-	PhoneNumber          string            `protobuf:"bytes,3,opt,name=phone_number,json=phoneNumber,proto3" json:"phone_number,omitempty"`
-	Description          string            `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
-	Password             string            `protobuf:"bytes,5,opt,name=password,proto3" json:"password,omitempty"`
-	Extra                map[string]string `protobuf:"bytes,6,rep,name=extra,proto3" json:"extra,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
-	XXX_unrecognized     []byte            `json:"-"`
-	XXX_sizecache        int32             `json:"-"`
+	Username    string            `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	Email       string            `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	PhoneNumber string            `protobuf:"bytes,3,opt,name=phone_number,json=phoneNumber,proto3" json:"phone_number,omitempty"`
+	Description string            `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	Password    string            `protobuf:"bytes,5,opt,name=password,proto3" json:"password,omitempty"`
+	Extra       map[string]string `protobuf:"bytes,6,rep,name=extra,proto3" json:"extra,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// url or object store key of the avatar
+	AvatarUrl            string   `protobuf:"bytes,7,opt,name=avatar_url,json=avatarUrl,proto3" json:"avatar_url,omitempty"`
+	FirstName            string   `protobuf:"bytes,8,opt,name=first_name,json=firstName,proto3" json:"first_name,omitempty"`
+	LastName             string   `protobuf:"bytes,9,opt,name=last_name,json=lastName,proto3" json:"last_name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *CreateUserRequest) Reset()         { *m = CreateUserRequest{} }
 func (m *CreateUserRequest) String() string { return proto.CompactTextString(m) }
 func (*CreateUserRequest) ProtoMessage()    {}
 func (*CreateUserRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{16}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{20}
 }
 
 func (m *CreateUserRequest) XXX_Unmarshal(b []byte) error {
@@ -952,6 +1297,27 @@ func (m *CreateUserRequest) GetExtra() map[string]string {
 	return nil
 }
 
+func (m *CreateUserRequest) GetAvatarUrl() string {
+	if m != nil {
+		return m.AvatarUrl
+	}
+	return ""
+}
+
+func (m *CreateUserRequest) GetFirstName() string {
+	if m != nil {
+		return m.FirstName
+	}
+	return ""
+}
+
+func (m *CreateUserRequest) GetLastName() string {
+	if m != nil {
+		return m.LastName
+	}
+	return ""
+}
+
 type CreateUserResponse struct {
 	UserId               string   `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -963,7 +1329,7 @@ func (m *CreateUserResponse) Reset()         { *m = CreateUserResponse{} }
 func (m *CreateUserResponse) String() string { return proto.CompactTextString(m) }
 func (*CreateUserResponse) ProtoMessage()    {}
 func (*CreateUserResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{17}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{21}
 }
 
 func (m *CreateUserResponse) XXX_Unmarshal(b []byte) error {
@@ -1002,7 +1368,7 @@ func (m *DeleteUsersRequest) Reset()         { *m = DeleteUsersRequest{} }
 func (m *DeleteUsersRequest) String() string { return proto.CompactTextString(m) }
 func (*DeleteUsersRequest) ProtoMessage()    {}
 func (*DeleteUsersRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{18}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{22}
 }
 
 func (m *DeleteUsersRequest) XXX_Unmarshal(b []byte) error {
@@ -1041,7 +1407,7 @@ func (m *DeleteUsersResponse) Reset()         { *m = DeleteUsersResponse{} }
 func (m *DeleteUsersResponse) String() string { return proto.CompactTextString(m) }
 func (*DeleteUsersResponse) ProtoMessage()    {}
 func (*DeleteUsersResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{19}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{23}
 }
 
 func (m *DeleteUsersResponse) XXX_Unmarshal(b []byte) error {
@@ -1070,22 +1436,28 @@ func (m *DeleteUsersResponse) GetUserId() []string {
 }
 
 type ModifyUserRequest struct {
-	UserId               string            `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	Username             string            `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
-	Email                string            `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
-	PhoneNumber          string            `protobuf:"bytes,4,opt,name=phone_number,json=phoneNumber,proto3" json:"phone_number,omitempty"`
-	Description          string            `protobuf:"bytes,5,opt,name=description,proto3" json:"description,omitempty"`
-	Extra                map[string]string `protobuf:"bytes,7,rep,name=extra,proto3" json:"extra,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
-	XXX_unrecognized     []byte            `json:"-"`
-	XXX_sizecache        int32             `json:"-"`
+	UserId      string            `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Username    string            `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
+	Email       string            `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
+	PhoneNumber string            `protobuf:"bytes,4,opt,name=phone_number,json=phoneNumber,proto3" json:"phone_number,omitempty"`
+	Description string            `protobuf:"bytes,5,opt,name=description,proto3" json:"description,omitempty"`
+	Extra       map[string]string `protobuf:"bytes,7,rep,name=extra,proto3" json:"extra,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// version of the user read before, modify is aborted when the user has been changed since then
+	Version *wrappers.UInt32Value `protobuf:"bytes,8,opt,name=version,proto3" json:"version,omitempty"`
+	// unchanged if null, cleared if empty
+	AvatarUrl            *wrappers.StringValue `protobuf:"bytes,9,opt,name=avatar_url,json=avatarUrl,proto3" json:"avatar_url,omitempty"`
+	FirstName            string                `protobuf:"bytes,10,opt,name=first_name,json=firstName,proto3" json:"first_name,omitempty"`
+	LastName             string                `protobuf:"bytes,11,opt,name=last_name,json=lastName,proto3" json:"last_name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
 }
 
 func (m *ModifyUserRequest) Reset()         { *m = ModifyUserRequest{} }
 func (m *ModifyUserRequest) String() string { return proto.CompactTextString(m) }
 func (*ModifyUserRequest) ProtoMessage()    {}
 func (*ModifyUserRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{20}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{24}
 }
 
 func (m *ModifyUserRequest) XXX_Unmarshal(b []byte) error {
@@ -1148,8 +1520,37 @@ func (m *ModifyUserRequest) GetExtra() map[string]string {
 	return nil
 }
 
+func (m *ModifyUserRequest) GetVersion() *wrappers.UInt32Value {
+	if m != nil {
+		return m.Version
+	}
+	return nil
+}
+
+func (m *ModifyUserRequest) GetAvatarUrl() *wrappers.StringValue {
+	if m != nil {
+		return m.AvatarUrl
+	}
+	return nil
+}
+
+func (m *ModifyUserRequest) GetFirstName() string {
+	if m != nil {
+		return m.FirstName
+	}
+	return ""
+}
+
+func (m *ModifyUserRequest) GetLastName() string {
+	if m != nil {
+		return m.LastName
+	}
+	return ""
+}
+
 type ModifyUserResponse struct {
 	UserId               string   `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Version              uint32   `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -1159,7 +1560,7 @@ func (m *ModifyUserResponse) Reset()         { *m = ModifyUserResponse{} }
 func (m *ModifyUserResponse) String() string { return proto.CompactTextString(m) }
 func (*ModifyUserResponse) ProtoMessage()    {}
 func (*ModifyUserResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{21}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{25}
 }
 
 func (m *ModifyUserResponse) XXX_Unmarshal(b []byte) error {
@@ -1187,6 +1588,13 @@ func (m *ModifyUserResponse) GetUserId() string {
 	return ""
 }
 
+func (m *ModifyUserResponse) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
 type User struct {
 	UserId               string               `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	Username             string               `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
@@ -1198,6 +1606,13 @@ type User struct {
 	CreateTime           *timestamp.Timestamp `protobuf:"bytes,8,opt,name=create_time,json=createTime,proto3" json:"create_time,omitempty"`
 	UpdateTime           *timestamp.Timestamp `protobuf:"bytes,9,opt,name=update_time,json=updateTime,proto3" json:"update_time,omitempty"`
 	StatusTime           *timestamp.Timestamp `protobuf:"bytes,10,opt,name=status_time,json=statusTime,proto3" json:"status_time,omitempty"`
+	Version              uint32               `protobuf:"varint,11,opt,name=version,proto3" json:"version,omitempty"`
+	AvatarUrl            string               `protobuf:"bytes,12,opt,name=avatar_url,json=avatarUrl,proto3" json:"avatar_url,omitempty"`
+	LastLoginAt          *timestamp.Timestamp `protobuf:"bytes,13,opt,name=last_login_at,json=lastLoginAt,proto3" json:"last_login_at,omitempty"`
+	ExternalProvider     string               `protobuf:"bytes,14,opt,name=external_provider,json=externalProvider,proto3" json:"external_provider,omitempty"`
+	ExternalId           string               `protobuf:"bytes,15,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
+	FirstName            string               `protobuf:"bytes,16,opt,name=first_name,json=firstName,proto3" json:"first_name,omitempty"`
+	LastName             string               `protobuf:"bytes,17,opt,name=last_name,json=lastName,proto3" json:"last_name,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
 	XXX_unrecognized     []byte               `json:"-"`
 	XXX_sizecache        int32                `json:"-"`
@@ -1207,7 +1622,7 @@ func (m *User) Reset()         { *m = User{} }
 func (m *User) String() string { return proto.CompactTextString(m) }
 func (*User) ProtoMessage()    {}
 func (*User) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{22}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{26}
 }
 
 func (m *User) XXX_Unmarshal(b []byte) error {
@@ -1298,6 +1713,55 @@ func (m *User) GetStatusTime() *timestamp.Timestamp {
 	return nil
 }
 
+func (m *User) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *User) GetAvatarUrl() string {
+	if m != nil {
+		return m.AvatarUrl
+	}
+	return ""
+}
+
+func (m *User) GetLastLoginAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.LastLoginAt
+	}
+	return nil
+}
+
+func (m *User) GetExternalProvider() string {
+	if m != nil {
+		return m.ExternalProvider
+	}
+	return ""
+}
+
+func (m *User) GetExternalId() string {
+	if m != nil {
+		return m.ExternalId
+	}
+	return ""
+}
+
+func (m *User) GetFirstName() string {
+	if m != nil {
+		return m.FirstName
+	}
+	return ""
+}
+
+func (m *User) GetLastName() string {
+	if m != nil {
+		return m.LastName
+	}
+	return ""
+}
+
 type UserWithGroup struct {
 	User                 *User    `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
 	GroupSet             []*Group `protobuf:"bytes,2,rep,name=group_set,json=groupSet,proto3" json:"group_set,omitempty"`
@@ -1310,7 +1774,7 @@ func (m *UserWithGroup) Reset()         { *m = UserWithGroup{} }
 func (m *UserWithGroup) String() string { return proto.CompactTextString(m) }
 func (*UserWithGroup) ProtoMessage()    {}
 func (*UserWithGroup) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{23}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{27}
 }
 
 func (m *UserWithGroup) XXX_Unmarshal(b []byte) error {
@@ -1356,7 +1820,7 @@ func (m *GetUserRequest) Reset()         { *m = GetUserRequest{} }
 func (m *GetUserRequest) String() string { return proto.CompactTextString(m) }
 func (*GetUserRequest) ProtoMessage()    {}
 func (*GetUserRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{24}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{28}
 }
 
 func (m *GetUserRequest) XXX_Unmarshal(b []byte) error {
@@ -1395,7 +1859,7 @@ func (m *GetUserResponse) Reset()         { *m = GetUserResponse{} }
 func (m *GetUserResponse) String() string { return proto.CompactTextString(m) }
 func (*GetUserResponse) ProtoMessage()    {}
 func (*GetUserResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{25}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{29}
 }
 
 func (m *GetUserResponse) XXX_Unmarshal(b []byte) error {
@@ -1434,7 +1898,7 @@ func (m *GetUserWithGroupResponse) Reset()         { *m = GetUserWithGroupRespon
 func (m *GetUserWithGroupResponse) String() string { return proto.CompactTextString(m) }
 func (*GetUserWithGroupResponse) ProtoMessage()    {}
 func (*GetUserWithGroupResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{26}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{30}
 }
 
 func (m *GetUserWithGroupResponse) XXX_Unmarshal(b []byte) error {
@@ -1463,28 +1927,56 @@ func (m *GetUserWithGroupResponse) GetUser() *UserWithGroup {
 }
 
 type ListUsersRequest struct {
-	SearchWord           []string `protobuf:"bytes,1,rep,name=search_word,json=searchWord,proto3" json:"search_word,omitempty"`
-	SortKey              string   `protobuf:"bytes,2,opt,name=sort_key,json=sortKey,proto3" json:"sort_key,omitempty"`
-	Reverse              bool     `protobuf:"varint,3,opt,name=reverse,proto3" json:"reverse,omitempty"`
-	Offset               uint32   `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
-	Limit                uint32   `protobuf:"varint,5,opt,name=limit,proto3" json:"limit,omitempty"`
-	RootGroupId          []string `protobuf:"bytes,6,rep,name=root_group_id,json=rootGroupId,proto3" json:"root_group_id,omitempty"`
-	GroupId              []string `protobuf:"bytes,7,rep,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
-	UserId               []string `protobuf:"bytes,8,rep,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	Username             []string `protobuf:"bytes,9,rep,name=username,proto3" json:"username,omitempty"`
-	Email                []string `protobuf:"bytes,10,rep,name=email,proto3" json:"email,omitempty"`
-	PhoneNumber          []string `protobuf:"bytes,11,rep,name=phone_number,json=phoneNumber,proto3" json:"phone_number,omitempty"`
-	Status               []string `protobuf:"bytes,12,rep,name=status,proto3" json:"status,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	SearchWord []string `protobuf:"bytes,1,rep,name=search_word,json=searchWord,proto3" json:"search_word,omitempty"`
+	// a column of user, or group_count for the number of groups the user is accepted in
+	SortKey     string   `protobuf:"bytes,2,opt,name=sort_key,json=sortKey,proto3" json:"sort_key,omitempty"`
+	Reverse     bool     `protobuf:"varint,3,opt,name=reverse,proto3" json:"reverse,omitempty"`
+	Offset      uint32   `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	Limit       uint32   `protobuf:"varint,5,opt,name=limit,proto3" json:"limit,omitempty"`
+	RootGroupId []string `protobuf:"bytes,6,rep,name=root_group_id,json=rootGroupId,proto3" json:"root_group_id,omitempty"`
+	GroupId     []string `protobuf:"bytes,7,rep,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	UserId      []string `protobuf:"bytes,8,rep,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Username    []string `protobuf:"bytes,9,rep,name=username,proto3" json:"username,omitempty"`
+	Email       []string `protobuf:"bytes,10,rep,name=email,proto3" json:"email,omitempty"`
+	PhoneNumber []string `protobuf:"bytes,11,rep,name=phone_number,json=phoneNumber,proto3" json:"phone_number,omitempty"`
+	// the users in any of the statuses, e.g. active and deleted
+	Status []string `protobuf:"bytes,12,rep,name=status,proto3" json:"status,omitempty"`
+	// search_word also matches the names of the groups the user belongs to
+	SearchGroupName bool `protobuf:"varint,13,opt,name=search_group_name,json=searchGroupName,proto3" json:"search_group_name,omitempty"`
+	// only the users created in the last n days
+	CreatedInDays uint32 `protobuf:"varint,14,opt,name=created_in_days,json=createdInDays,proto3" json:"created_in_days,omitempty"`
+	// only the users without email/phone number
+	EmailIsEmpty       bool `protobuf:"varint,15,opt,name=email_is_empty,json=emailIsEmpty,proto3" json:"email_is_empty,omitempty"`
+	PhoneNumberIsEmpty bool `protobuf:"varint,16,opt,name=phone_number_is_empty,json=phoneNumberIsEmpty,proto3" json:"phone_number_is_empty,omitempty"`
+	// only the users having any of the tags
+	Tag []string `protobuf:"bytes,17,rep,name=tag,proto3" json:"tag,omitempty"`
+	// exact and prefix matches of search_word come first
+	RankSearch bool `protobuf:"varint,18,opt,name=rank_search,json=rankSearch,proto3" json:"rank_search,omitempty"`
+	// only the total is returned, limit is 0 and user_set is empty
+	CountOnly bool `protobuf:"varint,19,opt,name=count_only,json=countOnly,proto3" json:"count_only,omitempty"`
+	// contains (default), prefix or suffix
+	SearchMode string `protobuf:"bytes,20,opt,name=search_mode,json=searchMode,proto3" json:"search_mode,omitempty"`
+	// return the columns matched by search_word in highlight_set
+	Highlight bool `protobuf:"varint,21,opt,name=highlight,proto3" json:"highlight,omitempty"`
+	// only the accepted members of any of the groups, checked in the query unlike group_id
+	// which reads the members first, so it suits the groups with many members
+	InGroupIds []string `protobuf:"bytes,22,rep,name=in_group_ids,json=inGroupIds,proto3" json:"in_group_ids,omitempty"`
+	// only the indexed columns filter the rows, search_word is not searched by LIKE
+	Strict bool `protobuf:"varint,23,opt,name=strict,proto3" json:"strict,omitempty"`
+	// only the users accepted in any group in [joined_group_since, joined_group_before),
+	// by the create time of the binding, either bound may be null
+	JoinedGroupSince     *timestamp.Timestamp `protobuf:"bytes,24,opt,name=joined_group_since,json=joinedGroupSince,proto3" json:"joined_group_since,omitempty"`
+	JoinedGroupBefore    *timestamp.Timestamp `protobuf:"bytes,25,opt,name=joined_group_before,json=joinedGroupBefore,proto3" json:"joined_group_before,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
 }
 
 func (m *ListUsersRequest) Reset()         { *m = ListUsersRequest{} }
 func (m *ListUsersRequest) String() string { return proto.CompactTextString(m) }
 func (*ListUsersRequest) ProtoMessage()    {}
 func (*ListUsersRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{27}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{31}
 }
 
 func (m *ListUsersRequest) XXX_Unmarshal(b []byte) error {
@@ -1589,19 +2081,115 @@ func (m *ListUsersRequest) GetStatus() []string {
 	return nil
 }
 
+func (m *ListUsersRequest) GetSearchGroupName() bool {
+	if m != nil {
+		return m.SearchGroupName
+	}
+	return false
+}
+
+func (m *ListUsersRequest) GetCreatedInDays() uint32 {
+	if m != nil {
+		return m.CreatedInDays
+	}
+	return 0
+}
+
+func (m *ListUsersRequest) GetEmailIsEmpty() bool {
+	if m != nil {
+		return m.EmailIsEmpty
+	}
+	return false
+}
+
+func (m *ListUsersRequest) GetPhoneNumberIsEmpty() bool {
+	if m != nil {
+		return m.PhoneNumberIsEmpty
+	}
+	return false
+}
+
+func (m *ListUsersRequest) GetTag() []string {
+	if m != nil {
+		return m.Tag
+	}
+	return nil
+}
+
+func (m *ListUsersRequest) GetRankSearch() bool {
+	if m != nil {
+		return m.RankSearch
+	}
+	return false
+}
+
+func (m *ListUsersRequest) GetCountOnly() bool {
+	if m != nil {
+		return m.CountOnly
+	}
+	return false
+}
+
+func (m *ListUsersRequest) GetSearchMode() string {
+	if m != nil {
+		return m.SearchMode
+	}
+	return ""
+}
+
+func (m *ListUsersRequest) GetHighlight() bool {
+	if m != nil {
+		return m.Highlight
+	}
+	return false
+}
+
+func (m *ListUsersRequest) GetInGroupIds() []string {
+	if m != nil {
+		return m.InGroupIds
+	}
+	return nil
+}
+
+func (m *ListUsersRequest) GetStrict() bool {
+	if m != nil {
+		return m.Strict
+	}
+	return false
+}
+
+func (m *ListUsersRequest) GetJoinedGroupSince() *timestamp.Timestamp {
+	if m != nil {
+		return m.JoinedGroupSince
+	}
+	return nil
+}
+
+func (m *ListUsersRequest) GetJoinedGroupBefore() *timestamp.Timestamp {
+	if m != nil {
+		return m.JoinedGroupBefore
+	}
+	return nil
+}
+
 type ListUsersResponse struct {
-	Total                uint32   `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
-	UserSet              []*User  `protobuf:"bytes,2,rep,name=user_set,json=userSet,proto3" json:"user_set,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Total   uint32  `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	UserSet []*User `protobuf:"bytes,2,rep,name=user_set,json=userSet,proto3" json:"user_set,omitempty"`
+	// the limit and offset applied, after clamping
+	Limit  uint32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset uint32 `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	// one per user in user_set when highlight is requested
+	HighlightSet         []*SearchHighlight `protobuf:"bytes,5,rep,name=highlight_set,json=highlightSet,proto3" json:"highlight_set,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
 }
 
 func (m *ListUsersResponse) Reset()         { *m = ListUsersResponse{} }
 func (m *ListUsersResponse) String() string { return proto.CompactTextString(m) }
 func (*ListUsersResponse) ProtoMessage()    {}
 func (*ListUsersResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{28}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{32}
 }
 
 func (m *ListUsersResponse) XXX_Unmarshal(b []byte) error {
@@ -1636,19 +2224,82 @@ func (m *ListUsersResponse) GetUserSet() []*User {
 	return nil
 }
 
+func (m *ListUsersResponse) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *ListUsersResponse) GetOffset() uint32 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *ListUsersResponse) GetHighlightSet() []*SearchHighlight {
+	if m != nil {
+		return m.HighlightSet
+	}
+	return nil
+}
+
+type CountUsersResponse struct {
+	Total                uint32   `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CountUsersResponse) Reset()         { *m = CountUsersResponse{} }
+func (m *CountUsersResponse) String() string { return proto.CompactTextString(m) }
+func (*CountUsersResponse) ProtoMessage()    {}
+func (*CountUsersResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_36f2114a3e4ddb9e, []int{33}
+}
+
+func (m *CountUsersResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CountUsersResponse.Unmarshal(m, b)
+}
+func (m *CountUsersResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CountUsersResponse.Marshal(b, m, deterministic)
+}
+func (m *CountUsersResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CountUsersResponse.Merge(m, src)
+}
+func (m *CountUsersResponse) XXX_Size() int {
+	return xxx_messageInfo_CountUsersResponse.Size(m)
+}
+func (m *CountUsersResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CountUsersResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CountUsersResponse proto.InternalMessageInfo
+
+func (m *CountUsersResponse) GetTotal() uint32 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
 type ListUsersWithGroupResponse struct {
-	Total                uint32           `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
-	UserSet              []*UserWithGroup `protobuf:"bytes,2,rep,name=user_set,json=userSet,proto3" json:"user_set,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
-	XXX_unrecognized     []byte           `json:"-"`
-	XXX_sizecache        int32            `json:"-"`
+	Total   uint32           `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	UserSet []*UserWithGroup `protobuf:"bytes,2,rep,name=user_set,json=userSet,proto3" json:"user_set,omitempty"`
+	// the limit and offset applied, after clamping
+	Limit                uint32   `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset               uint32   `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *ListUsersWithGroupResponse) Reset()         { *m = ListUsersWithGroupResponse{} }
 func (m *ListUsersWithGroupResponse) String() string { return proto.CompactTextString(m) }
 func (*ListUsersWithGroupResponse) ProtoMessage()    {}
 func (*ListUsersWithGroupResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{29}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{34}
 }
 
 func (m *ListUsersWithGroupResponse) XXX_Unmarshal(b []byte) error {
@@ -1683,9 +2334,29 @@ func (m *ListUsersWithGroupResponse) GetUserSet() []*UserWithGroup {
 	return nil
 }
 
+func (m *ListUsersWithGroupResponse) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *ListUsersWithGroupResponse) GetOffset() uint32 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
 type JoinGroupRequest struct {
-	GroupId              []string `protobuf:"bytes,1,rep,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
-	UserId               []string `protobuf:"bytes,2,rep,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	GroupId []string `protobuf:"bytes,1,rep,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	UserId  []string `protobuf:"bytes,2,rep,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// accepted by default, pending means the users are invited to the groups
+	Status string `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	// member by default, admin means the users manage the groups and their descendant groups
+	Role string `protobuf:"bytes,4,opt,name=role,proto3" json:"role,omitempty"`
+	// the user creating the bindings, e.g. the admin inviting the users, recorded for the audits
+	CreatedBy            string   `protobuf:"bytes,5,opt,name=created_by,json=createdBy,proto3" json:"created_by,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -1695,7 +2366,7 @@ func (m *JoinGroupRequest) Reset()         { *m = JoinGroupRequest{} }
 func (m *JoinGroupRequest) String() string { return proto.CompactTextString(m) }
 func (*JoinGroupRequest) ProtoMessage()    {}
 func (*JoinGroupRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{30}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{35}
 }
 
 func (m *JoinGroupRequest) XXX_Unmarshal(b []byte) error {
@@ -1730,6 +2401,27 @@ func (m *JoinGroupRequest) GetUserId() []string {
 	return nil
 }
 
+func (m *JoinGroupRequest) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *JoinGroupRequest) GetRole() string {
+	if m != nil {
+		return m.Role
+	}
+	return ""
+}
+
+func (m *JoinGroupRequest) GetCreatedBy() string {
+	if m != nil {
+		return m.CreatedBy
+	}
+	return ""
+}
+
 type JoinGroupResponse struct {
 	GroupId              []string `protobuf:"bytes,1,rep,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
 	UserId               []string `protobuf:"bytes,2,rep,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
@@ -1742,7 +2434,7 @@ func (m *JoinGroupResponse) Reset()         { *m = JoinGroupResponse{} }
 func (m *JoinGroupResponse) String() string { return proto.CompactTextString(m) }
 func (*JoinGroupResponse) ProtoMessage()    {}
 func (*JoinGroupResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{31}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{36}
 }
 
 func (m *JoinGroupResponse) XXX_Unmarshal(b []byte) error {
@@ -1789,7 +2481,7 @@ func (m *LeaveGroupRequest) Reset()         { *m = LeaveGroupRequest{} }
 func (m *LeaveGroupRequest) String() string { return proto.CompactTextString(m) }
 func (*LeaveGroupRequest) ProtoMessage()    {}
 func (*LeaveGroupRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{32}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{37}
 }
 
 func (m *LeaveGroupRequest) XXX_Unmarshal(b []byte) error {
@@ -1836,7 +2528,7 @@ func (m *LeaveGroupResponse) Reset()         { *m = LeaveGroupResponse{} }
 func (m *LeaveGroupResponse) String() string { return proto.CompactTextString(m) }
 func (*LeaveGroupResponse) ProtoMessage()    {}
 func (*LeaveGroupResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{33}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{38}
 }
 
 func (m *LeaveGroupResponse) XXX_Unmarshal(b []byte) error {
@@ -1883,7 +2575,7 @@ func (m *ModifyPasswordRequest) Reset()         { *m = ModifyPasswordRequest{} }
 func (m *ModifyPasswordRequest) String() string { return proto.CompactTextString(m) }
 func (*ModifyPasswordRequest) ProtoMessage()    {}
 func (*ModifyPasswordRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{34}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{39}
 }
 
 func (m *ModifyPasswordRequest) XXX_Unmarshal(b []byte) error {
@@ -1919,7 +2611,9 @@ func (m *ModifyPasswordRequest) GetPassword() string {
 }
 
 type ModifyPasswordResponse struct {
-	UserId               string   `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	UserId string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// strength of the new password, from 0 (very weak) to 4 (very strong)
+	Strength             uint32   `protobuf:"varint,2,opt,name=strength,proto3" json:"strength,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -1929,7 +2623,7 @@ func (m *ModifyPasswordResponse) Reset()         { *m = ModifyPasswordResponse{}
 func (m *ModifyPasswordResponse) String() string { return proto.CompactTextString(m) }
 func (*ModifyPasswordResponse) ProtoMessage()    {}
 func (*ModifyPasswordResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{35}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{40}
 }
 
 func (m *ModifyPasswordResponse) XXX_Unmarshal(b []byte) error {
@@ -1957,9 +2651,73 @@ func (m *ModifyPasswordResponse) GetUserId() string {
 	return ""
 }
 
+func (m *ModifyPasswordResponse) GetStrength() uint32 {
+	if m != nil {
+		return m.Strength
+	}
+	return 0
+}
+
+type ChangeOwnPasswordRequest struct {
+	UserId               string   `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	OldPassword          string   `protobuf:"bytes,2,opt,name=old_password,json=oldPassword,proto3" json:"old_password,omitempty"`
+	NewPassword          string   `protobuf:"bytes,3,opt,name=new_password,json=newPassword,proto3" json:"new_password,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ChangeOwnPasswordRequest) Reset()         { *m = ChangeOwnPasswordRequest{} }
+func (m *ChangeOwnPasswordRequest) String() string { return proto.CompactTextString(m) }
+func (*ChangeOwnPasswordRequest) ProtoMessage()    {}
+func (*ChangeOwnPasswordRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_36f2114a3e4ddb9e, []int{41}
+}
+
+func (m *ChangeOwnPasswordRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ChangeOwnPasswordRequest.Unmarshal(m, b)
+}
+func (m *ChangeOwnPasswordRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ChangeOwnPasswordRequest.Marshal(b, m, deterministic)
+}
+func (m *ChangeOwnPasswordRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ChangeOwnPasswordRequest.Merge(m, src)
+}
+func (m *ChangeOwnPasswordRequest) XXX_Size() int {
+	return xxx_messageInfo_ChangeOwnPasswordRequest.Size(m)
+}
+func (m *ChangeOwnPasswordRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ChangeOwnPasswordRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ChangeOwnPasswordRequest proto.InternalMessageInfo
+
+func (m *ChangeOwnPasswordRequest) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+func (m *ChangeOwnPasswordRequest) GetOldPassword() string {
+	if m != nil {
+		return m.OldPassword
+	}
+	return ""
+}
+
+func (m *ChangeOwnPasswordRequest) GetNewPassword() string {
+	if m != nil {
+		return m.NewPassword
+	}
+	return ""
+}
+
 type ComparePasswordRequest struct {
 	UserId               string   `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	Password             string   `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	PhoneNumber          string   `protobuf:"bytes,3,opt,name=phone_number,json=phoneNumber,proto3" json:"phone_number,omitempty"`
+	WithUser             bool     `protobuf:"varint,4,opt,name=with_user,json=withUser,proto3" json:"with_user,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -1969,7 +2727,7 @@ func (m *ComparePasswordRequest) Reset()         { *m = ComparePasswordRequest{}
 func (m *ComparePasswordRequest) String() string { return proto.CompactTextString(m) }
 func (*ComparePasswordRequest) ProtoMessage()    {}
 func (*ComparePasswordRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{36}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{42}
 }
 
 func (m *ComparePasswordRequest) XXX_Unmarshal(b []byte) error {
@@ -2004,18 +2762,40 @@ func (m *ComparePasswordRequest) GetPassword() string {
 	return ""
 }
 
+func (m *ComparePasswordRequest) GetPhoneNumber() string {
+	if m != nil {
+		return m.PhoneNumber
+	}
+	return ""
+}
+
+func (m *ComparePasswordRequest) GetWithUser() bool {
+	if m != nil {
+		return m.WithUser
+	}
+	return false
+}
+
 type ComparePasswordResponse struct {
-	Ok                   bool     `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Ok bool `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	// the password is older than the max age, user should change it
+	MustChangePassword bool `protobuf:"varint,2,opt,name=must_change_password,json=mustChangePassword,proto3" json:"must_change_password,omitempty"`
+	// the matched user when with_user is set, the password hash is never returned
+	User *User `protobuf:"bytes,3,opt,name=user,proto3" json:"user,omitempty"`
+	// the user is locked after too many failed compares, the password is not compared
+	Locked bool `protobuf:"varint,4,opt,name=locked,proto3" json:"locked,omitempty"`
+	// the end of the lock when locked is set
+	LockedUntil          *timestamp.Timestamp `protobuf:"bytes,5,opt,name=locked_until,json=lockedUntil,proto3" json:"locked_until,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
 }
 
 func (m *ComparePasswordResponse) Reset()         { *m = ComparePasswordResponse{} }
 func (m *ComparePasswordResponse) String() string { return proto.CompactTextString(m) }
 func (*ComparePasswordResponse) ProtoMessage()    {}
 func (*ComparePasswordResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_36f2114a3e4ddb9e, []int{37}
+	return fileDescriptor_36f2114a3e4ddb9e, []int{43}
 }
 
 func (m *ComparePasswordResponse) XXX_Unmarshal(b []byte) error {
@@ -2043,6 +2823,129 @@ func (m *ComparePasswordResponse) GetOk() bool {
 	return false
 }
 
+func (m *ComparePasswordResponse) GetMustChangePassword() bool {
+	if m != nil {
+		return m.MustChangePassword
+	}
+	return false
+}
+
+func (m *ComparePasswordResponse) GetUser() *User {
+	if m != nil {
+		return m.User
+	}
+	return nil
+}
+
+func (m *ComparePasswordResponse) GetLocked() bool {
+	if m != nil {
+		return m.Locked
+	}
+	return false
+}
+
+func (m *ComparePasswordResponse) GetLockedUntil() *timestamp.Timestamp {
+	if m != nil {
+		return m.LockedUntil
+	}
+	return nil
+}
+
+type ValidatePasswordRequest struct {
+	Password             string   `protobuf:"bytes,1,opt,name=password,proto3" json:"password,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ValidatePasswordRequest) Reset()         { *m = ValidatePasswordRequest{} }
+func (m *ValidatePasswordRequest) String() string { return proto.CompactTextString(m) }
+func (*ValidatePasswordRequest) ProtoMessage()    {}
+func (*ValidatePasswordRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_36f2114a3e4ddb9e, []int{44}
+}
+
+func (m *ValidatePasswordRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ValidatePasswordRequest.Unmarshal(m, b)
+}
+func (m *ValidatePasswordRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ValidatePasswordRequest.Marshal(b, m, deterministic)
+}
+func (m *ValidatePasswordRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ValidatePasswordRequest.Merge(m, src)
+}
+func (m *ValidatePasswordRequest) XXX_Size() int {
+	return xxx_messageInfo_ValidatePasswordRequest.Size(m)
+}
+func (m *ValidatePasswordRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ValidatePasswordRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ValidatePasswordRequest proto.InternalMessageInfo
+
+func (m *ValidatePasswordRequest) GetPassword() string {
+	if m != nil {
+		return m.Password
+	}
+	return ""
+}
+
+type ValidatePasswordResponse struct {
+	Ok        bool     `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	Violation []string `protobuf:"bytes,2,rep,name=violation,proto3" json:"violation,omitempty"`
+	// from 0 (very weak) to 4 (very strong)
+	Strength             uint32   `protobuf:"varint,3,opt,name=strength,proto3" json:"strength,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ValidatePasswordResponse) Reset()         { *m = ValidatePasswordResponse{} }
+func (m *ValidatePasswordResponse) String() string { return proto.CompactTextString(m) }
+func (*ValidatePasswordResponse) ProtoMessage()    {}
+func (*ValidatePasswordResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_36f2114a3e4ddb9e, []int{45}
+}
+
+func (m *ValidatePasswordResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ValidatePasswordResponse.Unmarshal(m, b)
+}
+func (m *ValidatePasswordResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ValidatePasswordResponse.Marshal(b, m, deterministic)
+}
+func (m *ValidatePasswordResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ValidatePasswordResponse.Merge(m, src)
+}
+func (m *ValidatePasswordResponse) XXX_Size() int {
+	return xxx_messageInfo_ValidatePasswordResponse.Size(m)
+}
+func (m *ValidatePasswordResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ValidatePasswordResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ValidatePasswordResponse proto.InternalMessageInfo
+
+func (m *ValidatePasswordResponse) GetOk() bool {
+	if m != nil {
+		return m.Ok
+	}
+	return false
+}
+
+func (m *ValidatePasswordResponse) GetViolation() []string {
+	if m != nil {
+		return m.Violation
+	}
+	return nil
+}
+
+func (m *ValidatePasswordResponse) GetStrength() uint32 {
+	if m != nil {
+		return m.Strength
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*GetVersionRequest)(nil), "kubesphere.GetVersionRequest")
 	proto.RegisterType((*GetVersionResponse)(nil), "kubesphere.GetVersionResponse")
@@ -2062,6 +2965,10 @@ func init() {
 	proto.RegisterType((*GetGroupWithUserResponse)(nil), "kubesphere.GetGroupWithUserResponse")
 	proto.RegisterType((*ListGroupsRequest)(nil), "kubesphere.ListGroupsRequest")
 	proto.RegisterType((*ListGroupsResponse)(nil), "kubesphere.ListGroupsResponse")
+	proto.RegisterType((*Pagination)(nil), "kubesphere.Pagination")
+	proto.RegisterType((*PageInfo)(nil), "kubesphere.PageInfo")
+	proto.RegisterType((*SearchHighlight)(nil), "kubesphere.SearchHighlight")
+	proto.RegisterType((*CountGroupsResponse)(nil), "kubesphere.CountGroupsResponse")
 	proto.RegisterType((*ListGroupsWithUserResponse)(nil), "kubesphere.ListGroupsWithUserResponse")
 	proto.RegisterType((*CreateUserRequest)(nil), "kubesphere.CreateUserRequest")
 	proto.RegisterMapType((map[string]string)(nil), "kubesphere.CreateUserRequest.ExtraEntry")
@@ -2079,6 +2986,7 @@ func init() {
 	proto.RegisterType((*GetUserWithGroupResponse)(nil), "kubesphere.GetUserWithGroupResponse")
 	proto.RegisterType((*ListUsersRequest)(nil), "kubesphere.ListUsersRequest")
 	proto.RegisterType((*ListUsersResponse)(nil), "kubesphere.ListUsersResponse")
+	proto.RegisterType((*CountUsersResponse)(nil), "kubesphere.CountUsersResponse")
 	proto.RegisterType((*ListUsersWithGroupResponse)(nil), "kubesphere.ListUsersWithGroupResponse")
 	proto.RegisterType((*JoinGroupRequest)(nil), "kubesphere.JoinGroupRequest")
 	proto.RegisterType((*JoinGroupResponse)(nil), "kubesphere.JoinGroupResponse")
@@ -2086,106 +2994,167 @@ func init() {
 	proto.RegisterType((*LeaveGroupResponse)(nil), "kubesphere.LeaveGroupResponse")
 	proto.RegisterType((*ModifyPasswordRequest)(nil), "kubesphere.ModifyPasswordRequest")
 	proto.RegisterType((*ModifyPasswordResponse)(nil), "kubesphere.ModifyPasswordResponse")
+	proto.RegisterType((*ChangeOwnPasswordRequest)(nil), "kubesphere.ChangeOwnPasswordRequest")
 	proto.RegisterType((*ComparePasswordRequest)(nil), "kubesphere.ComparePasswordRequest")
 	proto.RegisterType((*ComparePasswordResponse)(nil), "kubesphere.ComparePasswordResponse")
+	proto.RegisterType((*ValidatePasswordRequest)(nil), "kubesphere.ValidatePasswordRequest")
+	proto.RegisterType((*ValidatePasswordResponse)(nil), "kubesphere.ValidatePasswordResponse")
 }
 
 func init() { proto.RegisterFile("im.proto", fileDescriptor_36f2114a3e4ddb9e) }
 
 var fileDescriptor_36f2114a3e4ddb9e = []byte{
-	// 1460 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xec, 0x58, 0x5b, 0x6f, 0xdc, 0x44,
-	0x14, 0x56, 0xbc, 0xd7, 0x9c, 0xcd, 0x36, 0xd9, 0x69, 0x69, 0xb7, 0x6e, 0x9a, 0xa4, 0xa6, 0x2a,
-	0xa9, 0xa0, 0x1b, 0x1a, 0x10, 0x54, 0x54, 0x2a, 0x52, 0x4b, 0xd9, 0x86, 0xb4, 0x55, 0x59, 0x7a,
-	0x91, 0xc2, 0xc3, 0xca, 0xc9, 0x4e, 0xb2, 0x26, 0xbb, 0xb6, 0xb1, 0x67, 0x03, 0xf9, 0x15, 0x3c,
-	0xf0, 0x8c, 0xc4, 0x6f, 0x42, 0xbc, 0xf1, 0x03, 0xf8, 0x0b, 0x3c, 0xa2, 0xb9, 0xd8, 0x9e, 0xf1,
-	0xf8, 0xb2, 0x55, 0x82, 0x04, 0x12, 0x6f, 0x9e, 0x39, 0xe7, 0x7c, 0x73, 0x7c, 0xee, 0x33, 0xd0,
-	0x74, 0xa6, 0x3d, 0x3f, 0xf0, 0x88, 0x87, 0xe0, 0x78, 0xb6, 0x8f, 0x43, 0x7f, 0x8c, 0x03, 0x6c,
-	0xae, 0x1e, 0x79, 0xde, 0xd1, 0x04, 0x6f, 0xd9, 0xbe, 0xb3, 0x65, 0xbb, 0xae, 0x47, 0x6c, 0xe2,
-	0x78, 0x6e, 0xc8, 0x39, 0xcd, 0x75, 0x41, 0x65, 0xab, 0xfd, 0xd9, 0xe1, 0x16, 0x71, 0xa6, 0x38,
-	0x24, 0xf6, 0xd4, 0xe7, 0x0c, 0xd6, 0x45, 0xe8, 0xf4, 0x31, 0x79, 0x8d, 0x83, 0xd0, 0xf1, 0xdc,
-	0x01, 0xfe, 0x7e, 0x86, 0x43, 0x62, 0xf5, 0x00, 0xc9, 0x9b, 0xa1, 0xef, 0xb9, 0x21, 0x46, 0x5d,
-	0x68, 0x9c, 0xf0, 0xad, 0xee, 0xc2, 0xc6, 0xc2, 0xe6, 0xe2, 0x20, 0x5a, 0x5a, 0x7f, 0x2d, 0x00,
-	0x7a, 0x14, 0x60, 0x9b, 0xe0, 0x7e, 0xe0, 0xcd, 0x7c, 0x01, 0x83, 0x6e, 0xc1, 0xb2, 0x6f, 0x07,
-	0xd8, 0x25, 0xc3, 0x23, 0xba, 0x3d, 0x74, 0x46, 0x42, 0xb0, 0xcd, 0xb7, 0x19, 0xf3, 0xce, 0x08,
-	0x5d, 0x07, 0xe0, 0x0c, 0xae, 0x3d, 0xc5, 0x5d, 0x83, 0xb1, 0x2c, 0xb2, 0x9d, 0xe7, 0xf6, 0x14,
-	0xa3, 0x0d, 0x68, 0x8d, 0x70, 0x78, 0x10, 0x38, 0x3e, 0xfd, 0xb3, 0x6e, 0x85, 0xd1, 0xe5, 0x2d,
-	0xf4, 0x39, 0xd4, 0xf0, 0x8f, 0x24, 0xb0, 0xbb, 0xd5, 0x8d, 0xca, 0x66, 0x6b, 0xfb, 0x76, 0x2f,
-	0xb1, 0x4f, 0x4f, 0xd7, 0xab, 0xf7, 0x98, 0xf2, 0x3e, 0x76, 0x49, 0x70, 0x3a, 0xe0, 0x72, 0xe6,
-	0x3d, 0x80, 0x64, 0x13, 0xad, 0x40, 0xe5, 0x18, 0x9f, 0x0a, 0x5d, 0xe9, 0x27, 0xba, 0x04, 0xb5,
-	0x13, 0x7b, 0x32, 0x8b, 0x94, 0xe3, 0x8b, 0xcf, 0x8c, 0x7b, 0x0b, 0xd6, 0x87, 0x70, 0x51, 0x39,
-	0x41, 0xd8, 0xea, 0x2a, 0x34, 0x53, 0xff, 0xdc, 0x38, 0xe2, 0x7f, 0x4b, 0x25, 0xbe, 0xc0, 0x13,
-	0x2c, 0x24, 0xc2, 0xc8, 0x58, 0xaa, 0x44, 0x45, 0x96, 0xb8, 0x0b, 0x97, 0x54, 0x89, 0xcc, 0x43,
-	0x14, 0x91, 0x9f, 0x0d, 0x40, 0xcf, 0xbc, 0x91, 0x73, 0x78, 0xaa, 0x78, 0x24, 0x5f, 0xad, 0x2c,
-	0x67, 0x19, 0xe5, 0xce, 0xaa, 0x94, 0x38, 0xab, 0x5a, 0xe0, 0xac, 0x9a, 0xee, 0x2c, 0x5d, 0xe5,
-	0xf3, 0x76, 0x96, 0x72, 0x42, 0xb9, 0xb3, 0xfe, 0xac, 0x40, 0x8d, 0x31, 0xcf, 0x1d, 0xcc, 0x32,
-	0x98, 0xa1, 0x9a, 0x38, 0x36, 0x9d, 0x6f, 0x93, 0xb1, 0x62, 0xba, 0x17, 0x36, 0x19, 0xa7, 0x2c,
-	0x5b, 0x2d, 0xb1, 0x6c, 0x4d, 0xb7, 0xec, 0x65, 0xa8, 0x87, 0xc4, 0x26, 0xb3, 0xb0, 0x5b, 0x67,
-	0x44, 0xb1, 0x42, 0xdb, 0x91, 0xc5, 0x1b, 0xcc, 0xe2, 0xab, 0xb2, 0xc5, 0x99, 0xda, 0xba, 0x91,
-	0xd1, 0x7d, 0x68, 0x1d, 0xb0, 0xb8, 0x1e, 0xd2, 0x8a, 0xd1, 0x6d, 0x6e, 0x2c, 0x6c, 0xb6, 0xb6,
-	0xcd, 0x1e, 0x2f, 0x27, 0xbd, 0xa8, 0x9c, 0xf4, 0x5e, 0x46, 0xe5, 0x64, 0x00, 0x9c, 0x9d, 0x6e,
-	0x50, 0xe1, 0x99, 0x3f, 0x8a, 0x85, 0x17, 0xcb, 0x85, 0x39, 0x7b, 0x24, 0xcc, 0xf5, 0xe6, 0xc2,
-	0x50, 0x2e, 0xcc, 0xd9, 0xe9, 0xc6, 0x19, 0x62, 0x03, 0x43, 0x9b, 0xd9, 0xe2, 0x8d, 0x43, 0xc6,
-	0xaf, 0x42, 0x1c, 0xa0, 0xf7, 0xa0, 0xc6, 0x8c, 0xcf, 0xc4, 0x5b, 0xdb, 0x1d, 0xcd, 0x6a, 0x03,
-	0x4e, 0x47, 0xef, 0x43, 0x73, 0x16, 0xe2, 0x60, 0x18, 0x62, 0xd2, 0x35, 0x98, 0x85, 0x57, 0x64,
-	0x5e, 0x0a, 0x36, 0x68, 0x50, 0x8e, 0x6f, 0x30, 0xb1, 0x3e, 0x80, 0xe5, 0x3e, 0x26, 0x73, 0x26,
-	0xa5, 0x75, 0x1f, 0x56, 0x12, 0x6e, 0x11, 0xad, 0xf3, 0xea, 0x65, 0xed, 0x42, 0x37, 0x12, 0x8e,
-	0x7e, 0x2a, 0x06, 0xd9, 0x52, 0x41, 0xae, 0x6a, 0x20, 0xb1, 0x84, 0x00, 0xfb, 0xdd, 0x80, 0xce,
-	0x53, 0x27, 0x24, 0x6a, 0xd1, 0x5a, 0x87, 0x56, 0x88, 0xed, 0xe0, 0x60, 0x3c, 0xfc, 0xc1, 0x0b,
-	0xa2, 0x22, 0x04, 0x7c, 0xeb, 0x8d, 0x17, 0xb0, 0x6c, 0x08, 0xbd, 0x80, 0x0c, 0xa9, 0x1b, 0x44,
-	0x36, 0xd0, 0xf5, 0x2e, 0x3e, 0xa5, 0xed, 0x24, 0xc0, 0xb4, 0x83, 0xf0, 0x2a, 0xd2, 0x1c, 0x44,
-	0x4b, 0x1a, 0xc7, 0xde, 0xe1, 0x21, 0x35, 0x27, 0x4d, 0x82, 0xf6, 0x40, 0xac, 0xa8, 0xf3, 0x26,
-	0xce, 0xd4, 0x21, 0x2c, 0xf6, 0xdb, 0x03, 0xbe, 0x40, 0x16, 0xb4, 0x03, 0xcf, 0x93, 0xd2, 0xb2,
-	0xce, 0xb4, 0x68, 0xd1, 0xcd, 0x7e, 0x7e, 0x71, 0x6b, 0x30, 0xae, 0x82, 0xe4, 0x6d, 0x2a, 0x15,
-	0x35, 0x95, 0xbc, 0x8b, 0x8c, 0x98, 0x9b, 0xbc, 0x20, 0x91, 0x59, 0xf2, 0x26, 0xa9, 0xd9, 0x62,
-	0x24, 0xb1, 0xb2, 0xf6, 0x00, 0xc9, 0x56, 0x15, 0xde, 0xb9, 0x04, 0x35, 0xe2, 0x11, 0x7b, 0xc2,
-	0xbc, 0xd3, 0x1e, 0xf0, 0x05, 0xea, 0x01, 0x07, 0x94, 0x02, 0x2d, 0xc3, 0xf9, 0xfc, 0x07, 0x68,
-	0xa8, 0x7d, 0x07, 0x66, 0x82, 0xad, 0x45, 0x40, 0xf6, 0x19, 0x9f, 0xe8, 0x67, 0x14, 0xc4, 0x46,
-	0x72, 0xd6, 0xaf, 0x06, 0x74, 0x78, 0x1f, 0xe4, 0x87, 0xf0, 0xf0, 0x30, 0x79, 0x66, 0x30, 0x93,
-	0xf0, 0xc8, 0x8e, 0xd7, 0xf4, 0x7c, 0x3c, 0xb5, 0x9d, 0x49, 0x94, 0x89, 0x6c, 0x81, 0x6e, 0xc0,
-	0x92, 0x3f, 0xf6, 0x5c, 0x3c, 0x74, 0x67, 0xd3, 0x7d, 0x1c, 0x44, 0xcd, 0x9e, 0xed, 0x3d, 0x67,
-	0x5b, 0x73, 0x74, 0x18, 0x13, 0x9a, 0xbe, 0x1d, 0x86, 0x2c, 0x24, 0x79, 0x99, 0x8c, 0xd7, 0xe8,
-	0x41, 0x54, 0x0b, 0xeb, 0xec, 0xe7, 0x36, 0xf5, 0x51, 0x41, 0xfa, 0x81, 0x73, 0x6d, 0x3e, 0x77,
-	0xa2, 0x19, 0x49, 0x71, 0xc3, 0x15, 0x60, 0xa5, 0x21, 0xc9, 0xfd, 0x3a, 0x5d, 0xee, 0x8c, 0x28,
-	0x3b, 0x6f, 0xfa, 0x94, 0x3d, 0x4e, 0x38, 0x85, 0xbd, 0x22, 0xb1, 0xf7, 0xa2, 0xa9, 0x42, 0xb0,
-	0x67, 0xc1, 0xcb, 0xfc, 0xbf, 0x18, 0xd0, 0xe1, 0xbd, 0x50, 0x76, 0x58, 0x9e, 0x36, 0x8a, 0x27,
-	0x8d, 0x3c, 0x4f, 0x56, 0x8a, 0x3c, 0x59, 0x2d, 0xf5, 0x64, 0x46, 0x47, 0x7b, 0xa0, 0x76, 0xae,
-	0x4d, 0x7d, 0x56, 0xf8, 0x07, 0xbd, 0x25, 0x1f, 0x50, 0xe6, 0xad, 0xdf, 0x2a, 0x50, 0x65, 0x5d,
-	0xe3, 0xdf, 0x66, 0xc1, 0xbc, 0x99, 0xe0, 0xae, 0x6a, 0xd9, 0x6b, 0xe9, 0x8e, 0xf5, 0xff, 0x48,
-	0xc0, 0x46, 0x02, 0x6a, 0x0a, 0x5a, 0xee, 0xf8, 0x0c, 0x78, 0x13, 0xaa, 0xd4, 0x67, 0xa2, 0x69,
-	0xea, 0x5d, 0x9e, 0x51, 0xdf, 0xba, 0x4e, 0xdf, 0x86, 0x0b, 0x7d, 0x4c, 0xe6, 0x49, 0x43, 0xeb,
-	0x53, 0x36, 0x3d, 0x28, 0x21, 0x39, 0x97, 0x4e, 0xd6, 0x0e, 0x9b, 0x05, 0x94, 0xbf, 0x89, 0x11,
-	0xee, 0x28, 0x08, 0x57, 0xd3, 0x08, 0x89, 0x00, 0x87, 0xfa, 0xc3, 0x80, 0x15, 0xda, 0x57, 0x94,
-	0xba, 0xf4, 0x5f, 0x19, 0x04, 0xe4, 0x06, 0xdf, 0x50, 0x1b, 0xbc, 0x64, 0xf4, 0xa6, 0x5c, 0x2a,
-	0x95, 0xcc, 0xe5, 0x7d, 0x3f, 0x23, 0x73, 0x79, 0xc7, 0xcf, 0xc9, 0x5c, 0xde, 0xf3, 0x95, 0xcc,
-	0x4d, 0xf2, 0x72, 0x49, 0x19, 0x08, 0x5e, 0xf3, 0x31, 0x4b, 0xad, 0xe2, 0xd9, 0xbd, 0xfa, 0xad,
-	0xe6, 0xce, 0x31, 0x1f, 0x06, 0x18, 0xae, 0x1e, 0x02, 0xd9, 0x07, 0x7c, 0xac, 0x1d, 0x50, 0x10,
-	0x1c, 0xf1, 0x49, 0x5f, 0xc2, 0xca, 0x57, 0x9e, 0xe3, 0x16, 0x8c, 0xb8, 0x79, 0x66, 0x37, 0x94,
-	0x0e, 0xd5, 0x87, 0x8e, 0x84, 0x53, 0x7a, 0xe5, 0x2d, 0x04, 0x7a, 0x8a, 0xed, 0x13, 0x7c, 0x66,
-	0x8d, 0x9e, 0x00, 0x92, 0x81, 0xce, 0xa0, 0xd2, 0x53, 0x78, 0x87, 0x77, 0x97, 0x17, 0x62, 0x2e,
-	0x99, 0xa7, 0x01, 0xc7, 0x33, 0x8d, 0xa1, 0xce, 0x34, 0xd6, 0x5d, 0xb8, 0x9c, 0x46, 0x2b, 0xeb,
-	0x57, 0xcf, 0xe0, 0xf2, 0x23, 0x6f, 0x4a, 0x87, 0xdf, 0x73, 0xd1, 0xe0, 0x36, 0x5c, 0xd1, 0xe0,
-	0x84, 0x0a, 0x17, 0xc0, 0xf0, 0x8e, 0x19, 0x54, 0x73, 0x60, 0x78, 0xc7, 0xdb, 0x3f, 0x2d, 0xc1,
-	0xf2, 0xce, 0x08, 0xbb, 0xc4, 0x21, 0xa7, 0xcf, 0x6c, 0xd7, 0x3e, 0xc2, 0x01, 0xda, 0x05, 0x48,
-	0xde, 0x9b, 0xd0, 0x75, 0xa5, 0x58, 0xa6, 0x1f, 0xa7, 0xcc, 0xb5, 0x3c, 0xb2, 0x38, 0xf0, 0x39,
-	0xb4, 0xa4, 0x17, 0x19, 0xb4, 0x56, 0xfc, 0x18, 0x64, 0xae, 0xe7, 0xd2, 0x05, 0xde, 0xd7, 0xb0,
-	0x24, 0xbf, 0xbe, 0x20, 0x45, 0x20, 0xe3, 0x25, 0xc7, 0xdc, 0xc8, 0x67, 0x48, 0x54, 0x94, 0xde,
-	0x21, 0x54, 0x15, 0xf5, 0x27, 0x10, 0x55, 0xc5, 0xac, 0x07, 0x8c, 0xc7, 0xd0, 0x8c, 0x6e, 0x7a,
-	0xe8, 0x5a, 0xca, 0x3c, 0x0a, 0xd2, 0x6a, 0x36, 0x51, 0xc0, 0xbc, 0x4a, 0x6e, 0x9b, 0xf1, 0x2d,
-	0xb8, 0x10, 0xee, 0x66, 0x16, 0x51, 0xbb, 0x69, 0xec, 0x02, 0x24, 0xf7, 0x10, 0xd5, 0xbb, 0xda,
-	0x8d, 0x52, 0xf5, 0x6e, 0xc6, 0xd5, 0xe8, 0x5b, 0xf9, 0xc2, 0x14, 0x6b, 0x59, 0x02, 0x7a, 0x2b,
-	0x9b, 0x9c, 0xa5, 0x69, 0x32, 0xa2, 0xab, 0xa0, 0xda, 0xdd, 0xc0, 0x5c, 0xcb, 0x23, 0x27, 0x4e,
-	0x96, 0x26, 0x72, 0xd5, 0xc9, 0xfa, 0x64, 0x6f, 0xae, 0xe7, 0xd2, 0x13, 0xe5, 0x92, 0x89, 0x54,
-	0x55, 0x4e, 0x1b, 0x85, 0xcd, 0xb5, 0x3c, 0xb2, 0x00, 0x7b, 0x08, 0x0d, 0x31, 0x0f, 0x20, 0x33,
-	0xe5, 0x44, 0x19, 0xe6, 0x5a, 0x26, 0x4d, 0x60, 0xbc, 0x64, 0xe1, 0xa2, 0x4e, 0x48, 0x45, 0x60,
-	0x37, 0x33, 0x68, 0x7a, 0x2b, 0x7a, 0x02, 0x8b, 0x71, 0xa3, 0x42, 0xab, 0x69, 0xc7, 0x29, 0x26,
-	0xbb, 0x9e, 0x43, 0x15, 0x48, 0xe2, 0x6e, 0xad, 0xb6, 0xbc, 0x12, 0xc8, 0x5b, 0x99, 0xd4, 0x4c,
-	0x2d, 0xe3, 0xe6, 0xa4, 0x42, 0xa6, 0x7b, 0x9f, 0xaa, 0xa5, 0xde, 0xd1, 0x68, 0x76, 0xc4, 0x4d,
-	0x25, 0x15, 0xc8, 0xe9, 0xae, 0x95, 0xca, 0x0e, 0xbd, 0x17, 0xed, 0xc1, 0x72, 0xaa, 0x0e, 0x23,
-	0x4b, 0x09, 0xd3, 0xcc, 0x9a, 0x6f, 0xbe, 0x5b, 0xc8, 0x23, 0xb0, 0xdf, 0xc0, 0x05, 0xb5, 0xcb,
-	0xa0, 0x1b, 0x7a, 0x90, 0xa5, 0x91, 0xad, 0x22, 0x16, 0x0e, 0xfc, 0xb0, 0xba, 0x67, 0xf8, 0xfb,
-	0xfb, 0x75, 0x36, 0xc6, 0x7f, 0xf4, 0x77, 0x00, 0x00, 0x00, 0xff, 0xff, 0x95, 0xb0, 0xc0, 0xf5,
-	0xe6, 0x18, 0x00, 0x00,
+	// 2398 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xdd, 0x19, 0x6b, 0x6f, 0x1b, 0x59,
+	0x55, 0x7e, 0x24, 0xb1, 0x8f, 0xe3, 0xc4, 0xbe, 0x49, 0x53, 0xd7, 0x49, 0xd3, 0xec, 0x50, 0x95,
+	0xee, 0x2e, 0xeb, 0x6c, 0xb3, 0x50, 0x16, 0x56, 0x14, 0xe8, 0x43, 0x69, 0xda, 0xa6, 0x5b, 0x9c,
+	0xcd, 0x56, 0x2a, 0x42, 0xd6, 0xc4, 0xbe, 0xb1, 0x87, 0xd8, 0x33, 0x66, 0x66, 0x9c, 0x60, 0xf1,
+	0x1b, 0xf8, 0x80, 0x90, 0x10, 0xdf, 0xf9, 0x2f, 0x7c, 0xe4, 0x1b, 0x12, 0xff, 0x00, 0xf8, 0x07,
+	0x48, 0x08, 0x89, 0x73, 0x1f, 0x33, 0x73, 0xef, 0x3c, 0x6c, 0xef, 0xb6, 0x42, 0xc0, 0x07, 0x4b,
+	0xbe, 0xe7, 0x75, 0xcf, 0x3d, 0xaf, 0x7b, 0xce, 0x1d, 0x28, 0x59, 0xa3, 0xd6, 0xd8, 0x75, 0x7c,
+	0x87, 0xc0, 0xc5, 0xe4, 0x8c, 0x7a, 0xe3, 0x01, 0x75, 0x69, 0x73, 0xa7, 0xef, 0x38, 0xfd, 0x21,
+	0xdd, 0x37, 0xc7, 0xd6, 0xbe, 0x69, 0xdb, 0x8e, 0x6f, 0xfa, 0x96, 0x63, 0x7b, 0x82, 0xb2, 0x79,
+	0x4b, 0x62, 0xf9, 0xea, 0x6c, 0x72, 0xbe, 0xef, 0x5b, 0x23, 0xea, 0xf9, 0xe6, 0x68, 0x2c, 0x09,
+	0x76, 0xe3, 0x04, 0x57, 0xae, 0x39, 0x1e, 0x53, 0x57, 0x0a, 0x30, 0x36, 0xa0, 0x7e, 0x48, 0xfd,
+	0x2f, 0x11, 0x80, 0x52, 0xdb, 0xf4, 0x17, 0x13, 0xe4, 0x36, 0x5a, 0x40, 0x54, 0xa0, 0x37, 0xc6,
+	0x0d, 0x29, 0x69, 0xc0, 0xca, 0xa5, 0x00, 0x35, 0x72, 0x7b, 0xb9, 0xbb, 0xe5, 0x76, 0xb0, 0x34,
+	0xfe, 0x91, 0x03, 0xf2, 0xc8, 0xa5, 0xa6, 0x4f, 0x0f, 0x5d, 0x67, 0x32, 0x96, 0x62, 0xc8, 0x1d,
+	0x58, 0x1f, 0x9b, 0x2e, 0xb5, 0xfd, 0x4e, 0x9f, 0x81, 0x3b, 0x56, 0x4f, 0x32, 0x56, 0x05, 0x98,
+	0x13, 0x1f, 0xf5, 0xc8, 0x4d, 0x00, 0x41, 0x60, 0x9b, 0x23, 0xda, 0xc8, 0x73, 0x92, 0x32, 0x87,
+	0xbc, 0x44, 0x00, 0xd9, 0x83, 0x4a, 0x8f, 0x7a, 0x5d, 0xd7, 0x1a, 0xb3, 0x93, 0x37, 0x0a, 0x1c,
+	0xaf, 0x82, 0xc8, 0x0f, 0x61, 0x89, 0xfe, 0xd2, 0x77, 0xcd, 0x46, 0x71, 0xaf, 0x70, 0xb7, 0x72,
+	0xf0, 0x7e, 0x2b, 0xb2, 0x5f, 0x2b, 0xa9, 0x57, 0xeb, 0x09, 0xa3, 0x7d, 0x62, 0xfb, 0xee, 0xb4,
+	0x2d, 0xf8, 0x9a, 0x9f, 0x02, 0x44, 0x40, 0x52, 0x83, 0xc2, 0x05, 0x9d, 0x4a, 0x5d, 0xd9, 0x5f,
+	0xb2, 0x09, 0x4b, 0x97, 0xe6, 0x70, 0x12, 0x28, 0x27, 0x16, 0xdf, 0xcf, 0x7f, 0x9a, 0x33, 0x3e,
+	0x86, 0x0d, 0x6d, 0x07, 0x69, 0xab, 0x1b, 0x50, 0x8a, 0x9d, 0x79, 0xa5, 0x2f, 0x4e, 0x6b, 0xfc,
+	0x0a, 0x36, 0x1e, 0xd3, 0x21, 0x95, 0x1c, 0x5e, 0x60, 0x2c, 0x9d, 0xa3, 0xa0, 0x70, 0x30, 0xc3,
+	0x77, 0x4d, 0xaf, 0x6b, 0xf6, 0xc4, 0xfe, 0xa5, 0x76, 0xb0, 0x24, 0xfb, 0xb0, 0x21, 0xff, 0x76,
+	0x98, 0x3d, 0xa8, 0xdd, 0x33, 0x6d, 0xdf, 0xe3, 0x26, 0x2a, 0xb5, 0x89, 0x44, 0x3d, 0x8e, 0x30,
+	0xc6, 0x3d, 0xd8, 0xd4, 0x37, 0x4f, 0xd5, 0x57, 0xdd, 0xdd, 0xf8, 0x6d, 0x1e, 0xc8, 0xb1, 0xd3,
+	0xb3, 0xce, 0xa7, 0x9a, 0x73, 0xb3, 0x4f, 0x98, 0xe6, 0xf7, 0xfc, 0x7c, 0xbf, 0x17, 0xe6, 0xf8,
+	0xbd, 0x38, 0xc3, 0xef, 0x4b, 0x49, 0xbf, 0x27, 0x55, 0x7e, 0xd7, 0x7e, 0xd7, 0x76, 0x98, 0xef,
+	0xf7, 0xbf, 0x16, 0x60, 0x89, 0x13, 0x2f, 0x9c, 0x17, 0xaa, 0xb0, 0xbc, 0x6e, 0xe2, 0xd0, 0x74,
+	0x63, 0xd3, 0x1f, 0x68, 0xa6, 0x7b, 0x85, 0x80, 0x98, 0x65, 0x8b, 0x73, 0x2c, 0xbb, 0x94, 0xb4,
+	0xec, 0x16, 0x2c, 0x63, 0x15, 0xf1, 0x27, 0x5e, 0x63, 0x99, 0x23, 0xe5, 0x8a, 0x1c, 0x04, 0x16,
+	0x5f, 0xe1, 0x16, 0xdf, 0x51, 0x2d, 0xce, 0xd5, 0x4e, 0x1a, 0x99, 0x7c, 0x06, 0x95, 0x2e, 0x4f,
+	0x91, 0x0e, 0x2b, 0x4e, 0x8d, 0x12, 0x0a, 0xac, 0x1c, 0x34, 0x5b, 0xa2, 0x30, 0xb5, 0x82, 0xc2,
+	0xd4, 0xfa, 0x22, 0xa8, 0x5c, 0x6d, 0x10, 0xe4, 0x0c, 0xc0, 0x98, 0x27, 0xe3, 0x5e, 0xc8, 0x5c,
+	0x9e, 0xcf, 0x2c, 0xc8, 0x03, 0x66, 0xa1, 0xb7, 0x60, 0x86, 0xf9, 0xcc, 0x82, 0x9c, 0x01, 0xde,
+	0x22, 0x36, 0x28, 0x54, 0xb9, 0x2d, 0x5e, 0x5b, 0xfe, 0xe0, 0xd4, 0xa3, 0x2e, 0xf9, 0x26, 0x2c,
+	0x71, 0xe3, 0x73, 0xf6, 0xca, 0x41, 0x3d, 0x61, 0xb5, 0xb6, 0xc0, 0x93, 0x0f, 0xa1, 0x34, 0x41,
+	0x86, 0x8e, 0x47, 0x7d, 0x14, 0xcb, 0x2c, 0x5c, 0x53, 0x69, 0x99, 0xb0, 0xf6, 0x0a, 0xa3, 0x38,
+	0xa1, 0xbe, 0xf1, 0x2d, 0x58, 0xc7, 0x2a, 0xbd, 0x60, 0x52, 0x1a, 0x9f, 0x41, 0x2d, 0xa2, 0x96,
+	0xd1, 0xba, 0xa8, 0x5e, 0xc6, 0x73, 0x68, 0x04, 0xcc, 0xc1, 0xa1, 0x42, 0x21, 0xfb, 0xba, 0x90,
+	0x1b, 0x09, 0x21, 0x21, 0x87, 0x14, 0xf6, 0x87, 0x22, 0xd4, 0x5f, 0x58, 0x9e, 0xaf, 0xd7, 0xbf,
+	0x5b, 0xe8, 0x2b, 0x6a, 0xba, 0xdd, 0x41, 0xe7, 0xca, 0x71, 0x83, 0x22, 0x04, 0x02, 0xf4, 0x1a,
+	0x21, 0xec, 0x6c, 0x9e, 0xe3, 0xfa, 0x1d, 0xe6, 0x06, 0x99, 0x0d, 0x6c, 0xfd, 0x1c, 0x5d, 0x81,
+	0x05, 0xd2, 0xa5, 0xec, 0x32, 0xa2, 0xb2, 0xf4, 0x05, 0x4b, 0x16, 0xc7, 0xce, 0xf9, 0x39, 0x33,
+	0x27, 0x4b, 0x82, 0x6a, 0x5b, 0xae, 0x98, 0xf3, 0x86, 0xd6, 0xc8, 0xf2, 0x79, 0xec, 0x57, 0xdb,
+	0x62, 0x41, 0x0c, 0xa8, 0xba, 0x8e, 0xa3, 0xa4, 0xe5, 0x32, 0xd7, 0xa2, 0xc2, 0x80, 0x87, 0xd9,
+	0xc5, 0x6d, 0x85, 0x53, 0xcd, 0x48, 0xde, 0x92, 0x5e, 0xcf, 0xf5, 0xe4, 0x2d, 0x73, 0x64, 0x66,
+	0xf2, 0x82, 0x82, 0xe6, 0xc9, 0x1b, 0xa5, 0x66, 0x85, 0xa3, 0x82, 0xd4, 0x44, 0x03, 0xba, 0xa6,
+	0x7d, 0xd1, 0x11, 0x26, 0x6b, 0xac, 0x72, 0x43, 0x00, 0x03, 0x9d, 0x70, 0x08, 0x93, 0xdb, 0x75,
+	0x26, 0xa8, 0xb8, 0x63, 0x0f, 0xa7, 0x8d, 0x2a, 0xc7, 0x97, 0x39, 0xe4, 0x73, 0x04, 0x28, 0x0e,
+	0x18, 0x39, 0x78, 0xd3, 0xac, 0x71, 0x13, 0x4b, 0x07, 0x60, 0xa9, 0xa3, 0x64, 0x07, 0xca, 0x03,
+	0xab, 0x3f, 0x18, 0xe2, 0xcf, 0x6f, 0xac, 0x0b, 0xf6, 0x10, 0x40, 0xee, 0x03, 0x8c, 0xcd, 0xbe,
+	0x65, 0xf3, 0xf6, 0xa4, 0x51, 0xe3, 0xb1, 0xb0, 0xa5, 0xc6, 0xc2, 0xab, 0x10, 0xdb, 0x56, 0x28,
+	0xc5, 0x71, 0x5c, 0xab, 0xeb, 0x37, 0xea, 0x5c, 0xa4, 0x5c, 0x19, 0xff, 0xc2, 0x9e, 0x42, 0x8d,
+	0x12, 0x19, 0x6d, 0xe8, 0x38, 0x1f, 0x7b, 0xa0, 0x21, 0x8f, 0x36, 0x74, 0x1c, 0x5f, 0x90, 0x16,
+	0x08, 0x03, 0x29, 0x89, 0x93, 0x12, 0xcc, 0xc2, 0x21, 0x27, 0xaa, 0xfb, 0x0b, 0xaa, 0xfb, 0xb3,
+	0x82, 0xe5, 0x47, 0x50, 0x0d, 0xcf, 0xc9, 0x77, 0x10, 0xd7, 0xcd, 0xb6, 0xba, 0x83, 0xb0, 0xf1,
+	0xd3, 0x80, 0xac, 0xbd, 0x1a, 0x72, 0xb0, 0xfd, 0xee, 0x41, 0x19, 0x8f, 0x4c, 0x3b, 0x96, 0x7d,
+	0xee, 0xf0, 0x8a, 0x5a, 0x39, 0xd8, 0x8c, 0xd9, 0x86, 0x1e, 0x21, 0xae, 0x5d, 0x1a, 0xcb, 0x7f,
+	0x86, 0x03, 0xf0, 0x4a, 0xb3, 0x92, 0x54, 0x2d, 0x97, 0x1e, 0xc7, 0x79, 0xf5, 0x20, 0x6a, 0xaa,
+	0x14, 0x32, 0x53, 0xa5, 0xa8, 0xa5, 0x8a, 0x61, 0x41, 0x29, 0x50, 0x23, 0xc3, 0xca, 0x91, 0x12,
+	0xf9, 0x74, 0x25, 0x0a, 0x31, 0x25, 0x06, 0xa6, 0x87, 0xc1, 0xe4, 0x86, 0x5b, 0xe1, 0xfa, 0x18,
+	0x97, 0xc6, 0xf7, 0x60, 0x3d, 0x66, 0x2f, 0xb2, 0x06, 0xf9, 0xb0, 0x66, 0xe1, 0x3f, 0xb6, 0x57,
+	0xd7, 0x19, 0x4e, 0x46, 0x36, 0x77, 0x27, 0x46, 0xb9, 0x58, 0x19, 0x1f, 0x62, 0xbf, 0xc5, 0x42,
+	0x76, 0x91, 0xb0, 0x30, 0x7e, 0x9f, 0x83, 0x66, 0x14, 0x43, 0x89, 0xca, 0x95, 0x7e, 0xca, 0xfb,
+	0xc9, 0x58, 0x9a, 0x51, 0xd3, 0xbe, 0x66, 0x4c, 0x19, 0xff, 0xcc, 0x43, 0x5d, 0x34, 0x8e, 0x42,
+	0x25, 0x51, 0x04, 0x9b, 0xa2, 0xfe, 0xf3, 0xc4, 0x17, 0xb6, 0x08, 0xd7, 0x4c, 0x3e, 0x1d, 0x99,
+	0xd6, 0x30, 0xb8, 0x6f, 0xf8, 0x82, 0xbc, 0x07, 0xab, 0xe3, 0x81, 0x63, 0xd3, 0x8e, 0x3d, 0x19,
+	0x9d, 0x51, 0x37, 0xe8, 0x8e, 0x39, 0xec, 0x25, 0x07, 0x2d, 0xd0, 0x47, 0xe1, 0xb6, 0x63, 0xd3,
+	0xf3, 0x78, 0xe1, 0x15, 0xcd, 0x40, 0xb8, 0x26, 0x0f, 0x82, 0x1b, 0x7f, 0x99, 0x9b, 0xe2, 0x6e,
+	0xb2, 0xb7, 0x56, 0x0e, 0x90, 0x72, 0xfb, 0x63, 0xd5, 0x31, 0x2f, 0x4d, 0xdf, 0x74, 0x3b, 0x13,
+	0x77, 0x88, 0xa5, 0x92, 0xb7, 0x22, 0x02, 0x72, 0xea, 0x0e, 0x19, 0xfa, 0xdc, 0x72, 0x3d, 0x5f,
+	0x14, 0xbb, 0x92, 0x40, 0x73, 0x08, 0x2f, 0x76, 0xdb, 0x50, 0x1e, 0x9a, 0x01, 0xb6, 0x2c, 0x54,
+	0x63, 0x00, 0x86, 0x7c, 0x8b, 0x1b, 0xfa, 0xa3, 0x60, 0x5e, 0xd1, 0xe2, 0xe1, 0x3a, 0xf0, 0xbb,
+	0x35, 0xba, 0x3c, 0x97, 0xd9, 0x12, 0xef, 0x4e, 0x24, 0x17, 0x5d, 0x33, 0x23, 0x0f, 0x6f, 0x2c,
+	0x8d, 0xbc, 0xa0, 0x90, 0xb7, 0x82, 0x0e, 0x5f, 0x92, 0xa7, 0x89, 0x57, 0xe9, 0xff, 0x54, 0x80,
+	0xba, 0x68, 0x26, 0xd5, 0x58, 0xc8, 0xd2, 0x46, 0x0b, 0x92, 0x7c, 0x56, 0x90, 0x14, 0x66, 0x05,
+	0x49, 0x71, 0x6e, 0x90, 0xa4, 0xb4, 0x84, 0x0f, 0xf4, 0xd6, 0xef, 0x6e, 0xb2, 0xd9, 0x9e, 0x1d,
+	0x08, 0xf7, 0xa3, 0xf1, 0x51, 0xb4, 0x80, 0x3b, 0x89, 0x46, 0xec, 0xf4, 0xc8, 0xf6, 0x3f, 0x39,
+	0xf8, 0x92, 0xb9, 0x29, 0x1c, 0x2e, 0xb1, 0x89, 0x53, 0x03, 0xa8, 0x9c, 0xc1, 0x7a, 0x82, 0xb7,
+	0x86, 0xdd, 0x17, 0xac, 0x99, 0xe1, 0x05, 0x33, 0xc3, 0xab, 0xf2, 0xce, 0xc2, 0xeb, 0x30, 0x98,
+	0x98, 0x16, 0x0a, 0x2f, 0x75, 0xb0, 0x16, 0x85, 0x35, 0x1c, 0xac, 0xff, 0xbc, 0x04, 0x45, 0xde,
+	0x41, 0xfe, 0xb7, 0x05, 0x43, 0xd6, 0x7c, 0x70, 0x4f, 0x0f, 0x92, 0xed, 0x78, 0xf7, 0xfa, 0x7f,
+	0x33, 0x1e, 0xa8, 0x4e, 0xab, 0x68, 0x4e, 0x8b, 0x55, 0xbc, 0xd5, 0x78, 0xc5, 0x7b, 0x00, 0x55,
+	0x1e, 0x73, 0x43, 0x07, 0x6f, 0xf7, 0x8e, 0xe9, 0xf3, 0x4e, 0x6c, 0xf6, 0xbe, 0x15, 0xc6, 0xf0,
+	0x82, 0xd1, 0xff, 0xd8, 0xc7, 0x19, 0xa1, 0x8e, 0x86, 0x63, 0x2e, 0x1e, 0x76, 0x90, 0xf6, 0xd2,
+	0xea, 0xa1, 0x13, 0x45, 0xb7, 0x56, 0x0b, 0x10, 0xaf, 0x24, 0x9c, 0x35, 0x75, 0x21, 0x31, 0xc6,
+	0xce, 0xba, 0x68, 0xea, 0x02, 0x90, 0xe8, 0x45, 0x95, 0x04, 0xa9, 0xcd, 0x4c, 0x90, 0xfa, 0x3b,
+	0x4b, 0x10, 0x9c, 0x90, 0x58, 0x34, 0xb0, 0x5b, 0x54, 0x8c, 0xc4, 0xb7, 0xa1, 0xc8, 0xc2, 0x56,
+	0xce, 0x10, 0xc9, 0xa1, 0x87, 0x63, 0xbf, 0x6a, 0x9b, 0x67, 0xbc, 0x0f, 0x6b, 0x38, 0xb6, 0x2c,
+	0x52, 0x54, 0x8d, 0xef, 0xf2, 0x61, 0x4a, 0xcb, 0xd7, 0x85, 0x74, 0x32, 0x8e, 0xf8, 0x68, 0xa4,
+	0x9d, 0x26, 0x94, 0xf0, 0x91, 0x26, 0xe1, 0x46, 0x5c, 0x42, 0xc4, 0x20, 0x44, 0xfd, 0x7d, 0x19,
+	0x6a, 0xac, 0x5d, 0xd1, 0x6e, 0x99, 0xff, 0x95, 0xb9, 0x48, 0x9d, 0x77, 0x56, 0xf4, 0x79, 0x47,
+	0x31, 0x7a, 0x49, 0xbd, 0xf8, 0xb4, 0xe2, 0x25, 0xc6, 0xa0, 0x94, 0xe2, 0x25, 0x06, 0xa0, 0x8c,
+	0xe2, 0x25, 0x46, 0x20, 0xad, 0x78, 0x45, 0xa5, 0x69, 0x55, 0x9b, 0x8f, 0x3e, 0x80, 0xba, 0x34,
+	0xa4, 0x32, 0x5d, 0x89, 0x29, 0x68, 0x5d, 0x20, 0x0e, 0xc3, 0x19, 0x0b, 0x87, 0x3c, 0x51, 0x64,
+	0x7a, 0xd8, 0xb2, 0x77, 0x7a, 0xe6, 0xd4, 0xe3, 0x19, 0x56, 0x6d, 0x57, 0x25, 0xf8, 0xc8, 0x7e,
+	0x8c, 0x40, 0x0c, 0x91, 0x35, 0xae, 0x57, 0xc7, 0xf2, 0x3a, 0x74, 0x34, 0xf6, 0xa7, 0x72, 0x2e,
+	0x5a, 0xe5, 0xd0, 0x23, 0xef, 0x09, 0x83, 0x61, 0x51, 0xbc, 0xa6, 0x2a, 0x1d, 0x11, 0xd7, 0xc4,
+	0x3b, 0x9d, 0xa2, 0x7d, 0xc0, 0x82, 0xc9, 0xe4, 0x9b, 0x7d, 0xcc, 0x38, 0x76, 0x02, 0xf6, 0x37,
+	0x3e, 0xde, 0x91, 0x39, 0xe3, 0xdd, 0xc6, 0x9c, 0xf1, 0x6e, 0x73, 0xf6, 0x78, 0x77, 0x2d, 0x3e,
+	0xde, 0xed, 0xc1, 0x2a, 0x5a, 0x22, 0xf0, 0xb0, 0xd7, 0xd8, 0x12, 0x71, 0x68, 0xd9, 0xd2, 0xff,
+	0x9e, 0x32, 0xc8, 0x5d, 0x57, 0x07, 0x39, 0xf2, 0x14, 0xc8, 0xcf, 0x1d, 0xcb, 0x46, 0x53, 0xca,
+	0xdc, 0xb5, 0xec, 0x2e, 0x6d, 0x34, 0xe6, 0x16, 0xbd, 0x9a, 0xe0, 0xe2, 0xf2, 0x4f, 0x18, 0x0f,
+	0x79, 0x06, 0x1b, 0x9a, 0xa4, 0x33, 0x7a, 0xce, 0x86, 0x8b, 0x1b, 0x73, 0x45, 0xd5, 0x15, 0x51,
+	0x0f, 0x39, 0x93, 0xf1, 0xc7, 0x9c, 0x78, 0x84, 0xd0, 0x5b, 0xb4, 0xf4, 0x89, 0xe0, 0xab, 0xbc,
+	0xca, 0xfc, 0xa7, 0x47, 0x4b, 0xe3, 0x03, 0x6c, 0x65, 0x99, 0x93, 0x17, 0x38, 0x88, 0xf1, 0x3b,
+	0x39, 0x0f, 0x71, 0xda, 0x64, 0xb9, 0x4a, 0x3f, 0xfd, 0xb7, 0x13, 0xa7, 0x9f, 0x51, 0xc8, 0xbe,
+	0x9e, 0x19, 0x8c, 0xdf, 0xe4, 0xa0, 0xf6, 0xcc, 0x91, 0xc1, 0xb4, 0xc0, 0x8b, 0xb8, 0x52, 0x51,
+	0xf2, 0x5a, 0x45, 0x89, 0x92, 0xbf, 0xa0, 0xf5, 0x25, 0x04, 0x8a, 0xae, 0x33, 0x0c, 0x9e, 0x42,
+	0xf9, 0x7f, 0x9e, 0x30, 0x32, 0xc9, 0xcf, 0xa6, 0xb2, 0xc9, 0x29, 0x4b, 0xc8, 0xc3, 0x29, 0x36,
+	0x71, 0x75, 0x45, 0xa5, 0xb9, 0xef, 0xe4, 0x99, 0x3a, 0x31, 0x41, 0x2f, 0xa8, 0x79, 0x49, 0xdf,
+	0xf6, 0x70, 0x06, 0x66, 0x92, 0x2a, 0xe8, 0x2d, 0x54, 0x7a, 0x01, 0xd7, 0x44, 0x83, 0xfa, 0x4a,
+	0x8e, 0x79, 0x8b, 0x0c, 0x1d, 0xe1, 0x88, 0x98, 0xd7, 0x47, 0x44, 0xe3, 0x18, 0xb6, 0xe2, 0xd2,
+	0xe6, 0xb5, 0xbc, 0x28, 0x0e, 0xcb, 0x03, 0xb5, 0xfb, 0xfe, 0x40, 0xf6, 0xbc, 0xe1, 0xda, 0x98,
+	0x42, 0xe3, 0xd1, 0xc0, 0xb4, 0xfb, 0xf4, 0xf3, 0x2b, 0x7b, 0x61, 0xfd, 0xf0, 0x62, 0x70, 0x86,
+	0xbd, 0x4e, 0x4c, 0xc7, 0x0a, 0xc2, 0x02, 0x11, 0x8c, 0xc4, 0xa6, 0x57, 0x11, 0x89, 0x1c, 0x95,
+	0x11, 0x16, 0x90, 0x18, 0xbf, 0xce, 0xc1, 0xd6, 0x23, 0x67, 0xc4, 0x5e, 0xf2, 0xde, 0x85, 0x65,
+	0x16, 0x99, 0xce, 0xb1, 0xc3, 0xba, 0xc2, 0xf4, 0xe9, 0xf0, 0x46, 0x41, 0xbc, 0x93, 0x94, 0xae,
+	0xe4, 0xeb, 0x82, 0xf1, 0x97, 0x1c, 0x5c, 0x4f, 0xe8, 0x23, 0x6d, 0xbb, 0x06, 0x79, 0xe7, 0x82,
+	0xeb, 0x52, 0x6a, 0xe3, 0x3f, 0xf2, 0x31, 0x6c, 0x8e, 0x26, 0xd8, 0xaa, 0x75, 0xb9, 0xed, 0x74,
+	0x4b, 0xe0, 0x25, 0xc3, 0x70, 0xc2, 0xac, 0xa1, 0x41, 0x82, 0x06, 0xa7, 0x30, 0xb3, 0xe9, 0xc2,
+	0x94, 0x1a, 0x3a, 0xdd, 0x0b, 0xda, 0x93, 0xda, 0xc9, 0x15, 0xf9, 0x01, 0xac, 0x8a, 0x7f, 0x1d,
+	0xac, 0x3e, 0x78, 0x4f, 0x2f, 0x2d, 0xd0, 0xc6, 0x72, 0xfa, 0x53, 0x46, 0x6e, 0x7c, 0x07, 0xae,
+	0xe3, 0xb4, 0x66, 0xb1, 0x66, 0x3c, 0x6e, 0x6a, 0xd5, 0xa2, 0xb9, 0x58, 0xac, 0xf5, 0xa0, 0x91,
+	0x64, 0xcb, 0xb0, 0x08, 0xde, 0x68, 0x97, 0x96, 0x33, 0x14, 0x2f, 0x92, 0x22, 0x01, 0x22, 0x80,
+	0x16, 0x82, 0x05, 0x3d, 0x04, 0x0f, 0xfe, 0xb6, 0x06, 0xeb, 0x47, 0x3d, 0x8a, 0x8a, 0xfa, 0xd3,
+	0x63, 0xd3, 0x36, 0xfb, 0x68, 0x87, 0xe7, 0x00, 0xd1, 0x47, 0x51, 0x72, 0x53, 0xeb, 0x3b, 0xe3,
+	0x5f, 0x50, 0x9b, 0xbb, 0x59, 0x68, 0xa9, 0xea, 0x4b, 0xa8, 0x28, 0x9f, 0x0d, 0xc9, 0xee, 0xec,
+	0x2f, 0x96, 0xcd, 0x5b, 0x99, 0x78, 0x29, 0xef, 0x27, 0xb0, 0xaa, 0x7e, 0xd7, 0x23, 0x1a, 0x43,
+	0xca, 0xe7, 0xc6, 0xe6, 0x5e, 0x36, 0x41, 0xa4, 0xa2, 0xf2, 0x85, 0x4b, 0x57, 0x31, 0xf9, 0x71,
+	0x4d, 0x57, 0x31, 0xed, 0xd3, 0xd8, 0x13, 0x28, 0x05, 0xdf, 0x10, 0xc8, 0x76, 0xcc, 0x3c, 0x9a,
+	0xa4, 0x9d, 0x74, 0xa4, 0x14, 0x73, 0x1a, 0x7d, 0xc7, 0x08, 0xbf, 0xaf, 0xcc, 0x14, 0x77, 0x3b,
+	0x0d, 0x99, 0x78, 0x0b, 0x44, 0xef, 0x46, 0x2f, 0x85, 0xba, 0x77, 0x13, 0xdf, 0x2a, 0x74, 0xef,
+	0xa6, 0x3c, 0x52, 0xff, 0x54, 0x7d, 0xba, 0x0e, 0xb5, 0x9c, 0x23, 0xf4, 0x4e, 0x3a, 0x3a, 0xa1,
+	0xe9, 0x31, 0x86, 0x4e, 0xf4, 0x02, 0x3a, 0x4f, 0xaa, 0x1e, 0x39, 0x29, 0x2f, 0xa7, 0x78, 0xf0,
+	0xe8, 0x29, 0x4c, 0x97, 0x96, 0x78, 0xde, 0x6b, 0xee, 0x66, 0xa1, 0xa3, 0x98, 0x51, 0x5e, 0xbe,
+	0xf4, 0x98, 0x49, 0xbe, 0xa0, 0x35, 0x6f, 0x65, 0xe2, 0x23, 0xe5, 0xa2, 0x87, 0x14, 0x5d, 0xb9,
+	0xc4, 0x93, 0x53, 0x73, 0x37, 0x0b, 0x2d, 0x85, 0x3d, 0x84, 0x15, 0x39, 0xa9, 0x91, 0x66, 0x2c,
+	0x26, 0x54, 0x31, 0xdb, 0xa9, 0x38, 0x29, 0xe3, 0x0b, 0x1e, 0x7d, 0xfa, 0xec, 0x3a, 0x4b, 0xd8,
+	0xed, 0x14, 0x5c, 0xb2, 0xf1, 0x7a, 0x0a, 0xe5, 0xb0, 0x2d, 0x23, 0x3b, 0x71, 0x87, 0x6a, 0x26,
+	0xbb, 0x99, 0x81, 0x95, 0x92, 0xde, 0x88, 0xc8, 0xd3, 0x1b, 0xbc, 0x39, 0x22, 0xef, 0xa4, 0x62,
+	0x93, 0x5a, 0x3e, 0xc3, 0x48, 0x09, 0x3b, 0xcd, 0x39, 0x32, 0x77, 0x13, 0x61, 0xa7, 0xeb, 0x89,
+	0x27, 0x0e, 0x9b, 0x2b, 0x5d, 0x54, 0xbc, 0x0d, 0xd4, 0x4f, 0x9c, 0xec, 0xc8, 0x58, 0xe2, 0x86,
+	0x4d, 0x51, 0x2c, 0x1b, 0xe2, 0x5d, 0x57, 0x2c, 0x71, 0x93, 0xbd, 0xd4, 0x1b, 0x58, 0x8f, 0x5d,
+	0xb7, 0xc4, 0xd0, 0x4f, 0x92, 0xd6, 0x1b, 0x34, 0xbf, 0x31, 0x93, 0x46, 0xca, 0x7e, 0x0d, 0x6b,
+	0x7a, 0x97, 0x44, 0xde, 0x4b, 0x06, 0x6c, 0x5c, 0xb2, 0x31, 0x8b, 0x44, 0x0a, 0xfe, 0x19, 0xd4,
+	0x13, 0xfd, 0x12, 0xd1, 0x02, 0x2f, 0xab, 0x9d, 0x5a, 0x50, 0x7c, 0x2d, 0x7e, 0xe3, 0x12, 0xed,
+	0xc0, 0x19, 0xd7, 0xb8, 0x1e, 0xfb, 0x59, 0x97, 0xf6, 0xc3, 0xe2, 0x9b, 0xfc, 0xf8, 0xec, 0x6c,
+	0x99, 0xb7, 0x0b, 0x9f, 0xfc, 0x1b, 0x30, 0xba, 0x06, 0xaa, 0x04, 0x25, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -2208,6 +3177,7 @@ type IdentityManagerClient interface {
 	GetGroupWithUser(ctx context.Context, in *GetGroupRequest, opts ...grpc.CallOption) (*GetGroupWithUserResponse, error)
 	ListGroups(ctx context.Context, in *ListGroupsRequest, opts ...grpc.CallOption) (*ListGroupsResponse, error)
 	ListGroupsWithUser(ctx context.Context, in *ListGroupsRequest, opts ...grpc.CallOption) (*ListGroupsWithUserResponse, error)
+	CountGroups(ctx context.Context, in *ListGroupsRequest, opts ...grpc.CallOption) (*CountGroupsResponse, error)
 	CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*CreateUserResponse, error)
 	DeleteUsers(ctx context.Context, in *DeleteUsersRequest, opts ...grpc.CallOption) (*DeleteUsersResponse, error)
 	ModifyUser(ctx context.Context, in *ModifyUserRequest, opts ...grpc.CallOption) (*ModifyUserResponse, error)
@@ -2215,10 +3185,13 @@ type IdentityManagerClient interface {
 	GetUserWithGroup(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*GetUserWithGroupResponse, error)
 	ListUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*ListUsersResponse, error)
 	ListUsersWithGroup(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*ListUsersWithGroupResponse, error)
+	CountUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*CountUsersResponse, error)
 	JoinGroup(ctx context.Context, in *JoinGroupRequest, opts ...grpc.CallOption) (*JoinGroupResponse, error)
 	LeaveGroup(ctx context.Context, in *LeaveGroupRequest, opts ...grpc.CallOption) (*LeaveGroupResponse, error)
 	ComparePassword(ctx context.Context, in *ComparePasswordRequest, opts ...grpc.CallOption) (*ComparePasswordResponse, error)
 	ModifyPassword(ctx context.Context, in *ModifyPasswordRequest, opts ...grpc.CallOption) (*ModifyPasswordResponse, error)
+	ChangeOwnPassword(ctx context.Context, in *ChangeOwnPasswordRequest, opts ...grpc.CallOption) (*ModifyPasswordResponse, error)
+	ValidatePassword(ctx context.Context, in *ValidatePasswordRequest, opts ...grpc.CallOption) (*ValidatePasswordResponse, error)
 }
 
 type identityManagerClient struct {
@@ -2301,6 +3274,15 @@ func (c *identityManagerClient) ListGroupsWithUser(ctx context.Context, in *List
 	return out, nil
 }
 
+func (c *identityManagerClient) CountGroups(ctx context.Context, in *ListGroupsRequest, opts ...grpc.CallOption) (*CountGroupsResponse, error) {
+	out := new(CountGroupsResponse)
+	err := c.cc.Invoke(ctx, "/kubesphere.IdentityManager/CountGroups", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *identityManagerClient) CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*CreateUserResponse, error) {
 	out := new(CreateUserResponse)
 	err := c.cc.Invoke(ctx, "/kubesphere.IdentityManager/CreateUser", in, out, opts...)
@@ -2364,6 +3346,15 @@ func (c *identityManagerClient) ListUsersWithGroup(ctx context.Context, in *List
 	return out, nil
 }
 
+func (c *identityManagerClient) CountUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*CountUsersResponse, error) {
+	out := new(CountUsersResponse)
+	err := c.cc.Invoke(ctx, "/kubesphere.IdentityManager/CountUsers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *identityManagerClient) JoinGroup(ctx context.Context, in *JoinGroupRequest, opts ...grpc.CallOption) (*JoinGroupResponse, error) {
 	out := new(JoinGroupResponse)
 	err := c.cc.Invoke(ctx, "/kubesphere.IdentityManager/JoinGroup", in, out, opts...)
@@ -2400,6 +3391,24 @@ func (c *identityManagerClient) ModifyPassword(ctx context.Context, in *ModifyPa
 	return out, nil
 }
 
+func (c *identityManagerClient) ChangeOwnPassword(ctx context.Context, in *ChangeOwnPasswordRequest, opts ...grpc.CallOption) (*ModifyPasswordResponse, error) {
+	out := new(ModifyPasswordResponse)
+	err := c.cc.Invoke(ctx, "/kubesphere.IdentityManager/ChangeOwnPassword", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityManagerClient) ValidatePassword(ctx context.Context, in *ValidatePasswordRequest, opts ...grpc.CallOption) (*ValidatePasswordResponse, error) {
+	out := new(ValidatePasswordResponse)
+	err := c.cc.Invoke(ctx, "/kubesphere.IdentityManager/ValidatePassword", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // IdentityManagerServer is the server API for IdentityManager service.
 type IdentityManagerServer interface {
 	GetVersion(context.Context, *GetVersionRequest) (*GetVersionResponse, error)
@@ -2410,6 +3419,7 @@ type IdentityManagerServer interface {
 	GetGroupWithUser(context.Context, *GetGroupRequest) (*GetGroupWithUserResponse, error)
 	ListGroups(context.Context, *ListGroupsRequest) (*ListGroupsResponse, error)
 	ListGroupsWithUser(context.Context, *ListGroupsRequest) (*ListGroupsWithUserResponse, error)
+	CountGroups(context.Context, *ListGroupsRequest) (*CountGroupsResponse, error)
 	CreateUser(context.Context, *CreateUserRequest) (*CreateUserResponse, error)
 	DeleteUsers(context.Context, *DeleteUsersRequest) (*DeleteUsersResponse, error)
 	ModifyUser(context.Context, *ModifyUserRequest) (*ModifyUserResponse, error)
@@ -2417,10 +3427,13 @@ type IdentityManagerServer interface {
 	GetUserWithGroup(context.Context, *GetUserRequest) (*GetUserWithGroupResponse, error)
 	ListUsers(context.Context, *ListUsersRequest) (*ListUsersResponse, error)
 	ListUsersWithGroup(context.Context, *ListUsersRequest) (*ListUsersWithGroupResponse, error)
+	CountUsers(context.Context, *ListUsersRequest) (*CountUsersResponse, error)
 	JoinGroup(context.Context, *JoinGroupRequest) (*JoinGroupResponse, error)
 	LeaveGroup(context.Context, *LeaveGroupRequest) (*LeaveGroupResponse, error)
 	ComparePassword(context.Context, *ComparePasswordRequest) (*ComparePasswordResponse, error)
 	ModifyPassword(context.Context, *ModifyPasswordRequest) (*ModifyPasswordResponse, error)
+	ChangeOwnPassword(context.Context, *ChangeOwnPasswordRequest) (*ModifyPasswordResponse, error)
+	ValidatePassword(context.Context, *ValidatePasswordRequest) (*ValidatePasswordResponse, error)
 }
 
 func RegisterIdentityManagerServer(s *grpc.Server, srv IdentityManagerServer) {
@@ -2571,6 +3584,24 @@ func _IdentityManager_ListGroupsWithUser_Handler(srv interface{}, ctx context.Co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _IdentityManager_CountGroups_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListGroupsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityManagerServer).CountGroups(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kubesphere.IdentityManager/CountGroups",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityManagerServer).CountGroups(ctx, req.(*ListGroupsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _IdentityManager_CreateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(CreateUserRequest)
 	if err := dec(in); err != nil {
@@ -2697,6 +3728,24 @@ func _IdentityManager_ListUsersWithGroup_Handler(srv interface{}, ctx context.Co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _IdentityManager_CountUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityManagerServer).CountUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kubesphere.IdentityManager/CountUsers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityManagerServer).CountUsers(ctx, req.(*ListUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _IdentityManager_JoinGroup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(JoinGroupRequest)
 	if err := dec(in); err != nil {
@@ -2769,6 +3818,42 @@ func _IdentityManager_ModifyPassword_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _IdentityManager_ChangeOwnPassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeOwnPasswordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityManagerServer).ChangeOwnPassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kubesphere.IdentityManager/ChangeOwnPassword",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityManagerServer).ChangeOwnPassword(ctx, req.(*ChangeOwnPasswordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IdentityManager_ValidatePassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidatePasswordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityManagerServer).ValidatePassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kubesphere.IdentityManager/ValidatePassword",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityManagerServer).ValidatePassword(ctx, req.(*ValidatePasswordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _IdentityManager_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "kubesphere.IdentityManager",
 	HandlerType: (*IdentityManagerServer)(nil),
@@ -2805,6 +3890,10 @@ var _IdentityManager_serviceDesc = grpc.ServiceDesc{
 			MethodName: "ListGroupsWithUser",
 			Handler:    _IdentityManager_ListGroupsWithUser_Handler,
 		},
+		{
+			MethodName: "CountGroups",
+			Handler:    _IdentityManager_CountGroups_Handler,
+		},
 		{
 			MethodName: "CreateUser",
 			Handler:    _IdentityManager_CreateUser_Handler,
@@ -2833,6 +3922,10 @@ var _IdentityManager_serviceDesc = grpc.ServiceDesc{
 			MethodName: "ListUsersWithGroup",
 			Handler:    _IdentityManager_ListUsersWithGroup_Handler,
 		},
+		{
+			MethodName: "CountUsers",
+			Handler:    _IdentityManager_CountUsers_Handler,
+		},
 		{
 			MethodName: "JoinGroup",
 			Handler:    _IdentityManager_JoinGroup_Handler,
@@ -2849,6 +3942,14 @@ var _IdentityManager_serviceDesc = grpc.ServiceDesc{
 			MethodName: "ModifyPassword",
 			Handler:    _IdentityManager_ModifyPassword_Handler,
 		},
+		{
+			MethodName: "ChangeOwnPassword",
+			Handler:    _IdentityManager_ChangeOwnPassword_Handler,
+		},
+		{
+			MethodName: "ValidatePassword",
+			Handler:    _IdentityManager_ValidatePassword_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "im.proto",
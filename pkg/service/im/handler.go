@@ -67,6 +67,10 @@ func (p *Server) ListGroupsWithUser(ctx context.Context, req *pb.ListGroupsReque
 	return resource.ListGroupsWithUser(ctx, req)
 }
 
+func (p *Server) CountGroups(ctx context.Context, req *pb.ListGroupsRequest) (*pb.CountGroupsResponse, error) {
+	return resource.CountGroups(ctx, req)
+}
+
 func (p *Server) ModifyGroup(ctx context.Context, req *pb.ModifyGroupRequest) (*pb.ModifyGroupResponse, error) {
 	return resource.ModifyGroup(ctx, req)
 }
@@ -91,12 +95,12 @@ func (p *Server) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUs
 }
 
 func (p *Server) GetUserWithGroup(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserWithGroupResponse, error) {
-	userWithGroup, err := resource.GetUserWithGroup(ctx, req.UserId)
+	profile, err := resource.GetUserProfile(ctx, req.UserId)
 	if err != nil {
 		return nil, err
 	} else {
 		return &pb.GetUserWithGroupResponse{
-			User: userWithGroup.ToPB(),
+			User: profile,
 		}, nil
 	}
 }
@@ -109,6 +113,10 @@ func (p *Server) ListUsersWithGroup(ctx context.Context, req *pb.ListUsersReques
 	return resource.ListUsersWithGroup(ctx, req)
 }
 
+func (p *Server) CountUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.CountUsersResponse, error) {
+	return resource.CountUsers(ctx, req)
+}
+
 func (p *Server) ModifyUser(ctx context.Context, req *pb.ModifyUserRequest) (*pb.ModifyUserResponse, error) {
 	return resource.ModifyUser(ctx, req)
 }
@@ -128,3 +136,16 @@ func (p *Server) ComparePassword(ctx context.Context, req *pb.ComparePasswordReq
 func (p *Server) ModifyPassword(ctx context.Context, req *pb.ModifyPasswordRequest) (*pb.ModifyPasswordResponse, error) {
 	return resource.ModifyPassword(ctx, req)
 }
+
+func (p *Server) ChangeOwnPassword(ctx context.Context, req *pb.ChangeOwnPasswordRequest) (*pb.ModifyPasswordResponse, error) {
+	return resource.ChangeOwnPassword(ctx, req.UserId, req.OldPassword, req.NewPassword)
+}
+
+func (p *Server) ValidatePassword(ctx context.Context, req *pb.ValidatePasswordRequest) (*pb.ValidatePasswordResponse, error) {
+	ok, violations := resource.ValidatePassword(ctx, req.Password)
+	return &pb.ValidatePasswordResponse{
+		Ok:        ok,
+		Violation: violations,
+		Strength:  resource.PasswordStrength(req.Password),
+	}, nil
+}
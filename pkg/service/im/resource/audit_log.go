@@ -0,0 +1,125 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"time"
+
+	"openpitrix.io/logger"
+
+	"cloudbases.io/im/pkg/config"
+	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/db"
+	"cloudbases.io/im/pkg/event"
+	"cloudbases.io/im/pkg/global"
+	"cloudbases.io/im/pkg/models"
+	"cloudbases.io/im/pkg/util/stringutil"
+)
+
+// auditLogPublisher stores the audit records besides writing them to the service log,
+// a failure to store a record is logged and does not fail the audited operation
+type auditLogPublisher struct{}
+
+func (auditLogPublisher) Publish(ctx context.Context, record *event.AuditRecord) {
+	event.LogPublisher().Publish(ctx, record)
+	if err := global.Global().Database.Create(models.NewAuditLog(record)).Error; err != nil {
+		logger.Errorf(ctx, "Store audit record [%s] of user [%s] failed: %+v", record.Type, record.UserId, err)
+	}
+}
+
+// SetupAuditLog stores the audit records when Audit.Stored is set, and registers the cleanup
+// of the stored records older than Audit.RetentionDays
+func SetupAuditLog(cfg config.AuditConfig) {
+	if cfg.Stored {
+		event.SetPublisher(auditLogPublisher{})
+	}
+	if cfg.RetentionDays > 0 {
+		RegisterCleanupTask(ExpiredRowsCleanupTask(constants.TableAuditLog, constants.ColumnCreateTime,
+			time.Duration(cfg.RetentionDays)*24*time.Hour))
+	}
+}
+
+var auditCSVHeader = []string{
+	constants.ColumnType, constants.ColumnUserId, constants.ColumnOutcome, constants.ColumnClientIp, "time",
+}
+
+// ExportAuditLog writes the stored audit records in [start, end) to w in format, ordered by time,
+// zero time means no bound. The records are streamed from the database without loading them all.
+func ExportAuditLog(ctx context.Context, start, end time.Time, format string, w io.Writer) error {
+	var violations fieldViolations
+	if !stringutil.Contains(constants.AuditExportFormats, format) {
+		violations.Add("format", "invalid audit export format ["+format+"]")
+	}
+	if err := violations.Err(ctx); err != nil {
+		return err
+	}
+
+	rows, err := db.GetChain(global.Global().Database.Table(db.TableName(constants.TableAuditLog))).
+		BuildTimeRangeConditions(constants.ColumnCreateTime, start, end).
+		Order(constants.ColumnCreateTime).
+		Order(constants.ColumnId).
+		Rows()
+	if err != nil {
+		logger.Errorf(ctx, "Export audit log failed: %+v", err)
+		return err
+	}
+	defer rows.Close()
+
+	var encoder *json.Encoder
+	var csvWriter *csv.Writer
+	if format == constants.AuditExportFormatCSV {
+		csvWriter = csv.NewWriter(w)
+		if err := csvWriter.Write(auditCSVHeader); err != nil {
+			return err
+		}
+	} else {
+		encoder = json.NewEncoder(w)
+	}
+
+	for rows.Next() {
+		var auditLog models.AuditLog
+		if err := global.Global().Database.ScanRows(rows, &auditLog); err != nil {
+			logger.Errorf(ctx, "Scan audit log failed: %+v", err)
+			return err
+		}
+		record := auditLog.ToRecord()
+		if csvWriter != nil {
+			err = csvWriter.Write([]string{
+				record.Type, record.UserId, record.Outcome, record.ClientIp, record.Time.Format(time.RFC3339),
+			})
+		} else {
+			err = encoder.Encode(record)
+		}
+		if err != nil {
+			logger.Errorf(ctx, "Write audit log failed: %+v", err)
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logger.Errorf(ctx, "Export audit log failed: %+v", err)
+		return err
+	}
+	if csvWriter != nil {
+		csvWriter.Flush()
+		return csvWriter.Error()
+	}
+	return nil
+}
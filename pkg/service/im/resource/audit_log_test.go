@@ -0,0 +1,94 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"cloudbases.io/im/pkg/config"
+	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/event"
+)
+
+func TestExportAuditLog(t *testing.T) {
+	prepare(t)
+	resetCleanupTasks(t)
+	SetupAuditLog(config.AuditConfig{Stored: true, RetentionDays: 30})
+	t.Cleanup(func() {
+		event.SetPublisher(nil)
+	})
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	start, end := now.Add(-2*time.Hour), now.Add(-time.Hour)
+	for _, record := range []*event.AuditRecord{
+		{Type: event.TypeComparePassword, UserId: "uid-expired", Outcome: event.OutcomeSuccess, Time: now.AddDate(0, 0, -31)},
+		{Type: event.TypeComparePassword, UserId: "uid-1", Outcome: event.OutcomeSuccess, ClientIp: "10.0.0.1", Time: start},
+		{Type: event.TypeComparePassword, UserId: "uid-2", Outcome: event.OutcomeFailure, ClientIp: "10.0.0.2", Time: start.Add(time.Minute)},
+		{Type: event.TypeComparePassword, UserId: "uid-after", Outcome: event.OutcomeSuccess, Time: end},
+	} {
+		event.Publish(ctx, record)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, ExportAuditLog(ctx, start, end, constants.AuditExportFormatJSON, &buf))
+	var records []*event.AuditRecord
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var record event.AuditRecord
+		require.NoError(t, decoder.Decode(&record))
+		records = append(records, &record)
+	}
+	require.Len(t, records, 2)
+	require.Equal(t, "uid-1", records[0].UserId)
+	require.Equal(t, event.OutcomeSuccess, records[0].Outcome)
+	require.Equal(t, "10.0.0.1", records[0].ClientIp)
+	require.True(t, start.Equal(records[0].Time))
+	require.Equal(t, "uid-2", records[1].UserId)
+	require.Equal(t, event.OutcomeFailure, records[1].Outcome)
+
+	buf.Reset()
+	require.NoError(t, ExportAuditLog(ctx, start, end, constants.AuditExportFormatCSV, &buf))
+	lines, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"type", "user_id", "outcome", "client_ip", "time"},
+		{event.TypeComparePassword, "uid-1", event.OutcomeSuccess, "10.0.0.1", start.Format(time.RFC3339)},
+		{event.TypeComparePassword, "uid-2", event.OutcomeFailure, "10.0.0.2", start.Add(time.Minute).Format(time.RFC3339)},
+	}, lines)
+
+	// the cleanup honors the retention
+	RunCleanup(ctx)
+	buf.Reset()
+	require.NoError(t, ExportAuditLog(ctx, time.Time{}, time.Time{}, constants.AuditExportFormatCSV, &buf))
+	lines, err = csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, lines, 4)
+	require.Equal(t, "uid-1", lines[1][1])
+
+	err = ExportAuditLog(ctx, start, end, "xml", &buf)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BatchItemError is the reason an item of a bulk operation failed
+type BatchItemError struct {
+	Item   string
+	Code   codes.Code
+	Reason string
+}
+
+// BatchResult reports every item of a bulk operation as succeeded or failed, the items are
+// applied one by one so the failure of an item does not stop the others
+type BatchResult struct {
+	Succeeded []string
+	Failed    []*BatchItemError
+}
+
+func (r *BatchResult) succeed(item string) {
+	r.Succeeded = append(r.Succeeded, item)
+}
+
+// fail records item as failed with the code and message of err
+func (r *BatchResult) fail(item string, err error) {
+	st := status.Convert(err)
+	r.Failed = append(r.Failed, &BatchItemError{
+		Item:   item,
+		Code:   st.Code(),
+		Reason: st.Message(),
+	})
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"openpitrix.io/logger"
+
+	"cloudbases.io/im/pkg/db"
+	"cloudbases.io/im/pkg/global"
+	"cloudbases.io/im/pkg/models"
+)
+
+// CleanupTask deletes one kind of expired records, it returns the number of records deleted
+type CleanupTask struct {
+	Name string
+	Run  func(ctx context.Context, now time.Time) (int64, error)
+}
+
+var cleanupTasks []CleanupTask
+var cleanupTasksMutex sync.Mutex
+
+// RegisterCleanupTask adds task to the tasks run by every cleanup pass
+func RegisterCleanupTask(task CleanupTask) {
+	cleanupTasksMutex.Lock()
+	defer cleanupTasksMutex.Unlock()
+	cleanupTasks = append(cleanupTasks, task)
+}
+
+// ExpiredRowsCleanupTask deletes the rows of table whose timeColumn is older than retention
+func ExpiredRowsCleanupTask(table, timeColumn string, retention time.Duration) CleanupTask {
+	return CleanupTask{
+		Name: table,
+		Run: func(ctx context.Context, now time.Time) (int64, error) {
+			result := global.Global().Database.Table(db.TableName(table)).
+				Where(timeColumn+" < ?", now.Add(-retention)).
+				Delete(nil)
+			return result.RowsAffected, result.Error
+		},
+	}
+}
+
+// RunCleanup runs every registered task once, a failed task does not stop the others
+func RunCleanup(ctx context.Context) {
+	cleanupTasksMutex.Lock()
+	tasks := append([]CleanupTask{}, cleanupTasks...)
+	cleanupTasksMutex.Unlock()
+
+	now := models.NowUTC()
+	for _, task := range tasks {
+		deleted, err := task.Run(ctx, now)
+		if err != nil {
+			logger.Errorf(ctx, "Cleanup [%s] failed: %+v", task.Name, err)
+			continue
+		}
+		if deleted > 0 {
+			logger.Infof(ctx, "Cleanup [%s] deleted %d records", task.Name, deleted)
+		}
+	}
+}
+
+// StartCleanup runs the cleanup every interval in background until ctx is done,
+// the returned channel is closed when the loop has exited
+func StartCleanup(ctx context.Context, interval time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				RunCleanup(ctx)
+			}
+		}
+	}()
+	return done
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"cloudbases.io/im/pkg/global"
+)
+
+// registers tasks only during the test
+func resetCleanupTasks(t *testing.T) {
+	origin := cleanupTasks
+	cleanupTasks = nil
+	t.Cleanup(func() {
+		cleanupTasks = origin
+	})
+}
+
+func TestRunCleanup(t *testing.T) {
+	prepare(t)
+	resetCleanupTasks(t)
+	database := global.Global().Database
+
+	require.NoError(t, database.Exec("CREATE TABLE test_token (token varchar(50), expire_time timestamp)").Error)
+	now := time.Now()
+	for token, expireTime := range map[string]time.Time{
+		"expired1": now.Add(-48 * time.Hour),
+		"expired2": now.Add(-25 * time.Hour),
+		"valid":    now.Add(-time.Hour),
+	} {
+		require.NoError(t, database.Exec("INSERT INTO test_token VALUES (?, ?)", token, expireTime).Error)
+	}
+
+	RegisterCleanupTask(CleanupTask{
+		Name: "failed",
+		Run: func(ctx context.Context, now time.Time) (int64, error) {
+			return 0, errors.New("failed")
+		},
+	})
+	RegisterCleanupTask(ExpiredRowsCleanupTask("test_token", "expire_time", 24*time.Hour))
+	RunCleanup(context.Background())
+
+	var tokens []string
+	require.NoError(t, database.Table("test_token").Pluck("token", &tokens).Error)
+	require.Equal(t, []string{"valid"}, tokens)
+}
+
+func TestStartCleanup(t *testing.T) {
+	resetCleanupTasks(t)
+
+	runs := make(chan struct{}, 10)
+	RegisterCleanupTask(CleanupTask{
+		Name: "count",
+		Run: func(ctx context.Context, now time.Time) (int64, error) {
+			runs <- struct{}{}
+			return 0, nil
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := StartCleanup(ctx, 10*time.Millisecond)
+	<-runs
+	<-runs
+	cancel()
+
+	// the loop exits on cancel
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("cleanup loop did not exit")
+	}
+
+	// drain the pass that may be running while cancelled, then no more passes
+	time.Sleep(50 * time.Millisecond)
+	for len(runs) > 0 {
+		<-runs
+	}
+	time.Sleep(50 * time.Millisecond)
+	require.Len(t, runs, 0)
+}
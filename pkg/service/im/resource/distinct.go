@@ -0,0 +1,63 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"context"
+	"sort"
+
+	"openpitrix.io/logger"
+
+	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/db"
+	"cloudbases.io/im/pkg/global"
+	"cloudbases.io/im/pkg/util/stringutil"
+)
+
+// DistinctValues returns the distinct non-null values of column among the rows of tableName
+// matching the filters of req, the column must be one of constants.DistinctColumns
+func DistinctValues(ctx context.Context, tableName, column string, req db.Request) ([]string, error) {
+	var violations fieldViolations
+	violations.checkDistinctColumn("column", tableName, column)
+	if err := violations.Err(ctx); err != nil {
+		return nil, err
+	}
+
+	// the users are read from all the databases holding them, their values are merged
+	databases := []*db.Database{global.Global().Database}
+	if tableName == constants.TableUser {
+		databases = global.Global().UserDatabases()
+	}
+	var values []string
+	for _, database := range databases {
+		var shardValues []string
+		if err := db.GetChain(database.Table(db.TableName(tableName))).
+			BuildFilterConditions(req, tableName).
+			Where(column+" IS NOT NULL").
+			Order(column).
+			Pluck("DISTINCT "+column, &shardValues).Error; err != nil {
+			logger.Errorf(ctx, "Distinct values of [%s.%s] failed: %+v", tableName, column, err)
+			return nil, err
+		}
+		values = append(values, shardValues...)
+	}
+	if len(databases) > 1 {
+		values = stringutil.Unique(values)
+		sort.Strings(values)
+	}
+	return values, nil
+}
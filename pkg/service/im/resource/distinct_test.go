@@ -0,0 +1,85 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/pb"
+)
+
+func TestDistinctValues(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	user1 := createTestUser(t, "u1", "13900000001")
+	user2 := createTestUser(t, "u2", "13900000002")
+	user3 := createTestUser(t, "u3", "13900000003")
+	_, err := DeleteUsers(ctx, &pb.DeleteUsersRequest{UserId: []string{user3}})
+	require.NoError(t, err)
+
+	values, err := DistinctValues(ctx, constants.TableUser, constants.ColumnStatus, &pb.ListUsersRequest{})
+	require.NoError(t, err)
+	require.Equal(t, []string{constants.StatusActive, constants.StatusDeleted}, values)
+
+	values, err = DistinctValues(ctx, constants.TableUser, constants.ColumnStatus, &pb.ListUsersRequest{
+		UserId: []string{user1, user2},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{constants.StatusActive}, values)
+
+	// null values are left out
+	values, err = DistinctValues(ctx, constants.TableUser, constants.ColumnExternalProvider, &pb.ListUsersRequest{})
+	require.NoError(t, err)
+	require.Empty(t, values)
+
+	require.NoError(t, LinkExternalId(ctx, user1, "github", "1"))
+	require.NoError(t, LinkExternalId(ctx, user2, "github", "2"))
+	values, err = DistinctValues(ctx, constants.TableUser, constants.ColumnExternalProvider, &pb.ListUsersRequest{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"github"}, values)
+
+	_, err = DistinctValues(ctx, constants.TableUser, constants.ColumnPassword, &pb.ListUsersRequest{})
+	require.Equal(t, []string{"column"}, violatedFields(t, err))
+	_, err = DistinctValues(ctx, "unknown", constants.ColumnStatus, &pb.ListUsersRequest{})
+	require.Equal(t, []string{"column"}, violatedFields(t, err))
+}
+
+func TestDistinctValuesSharded(t *testing.T) {
+	prepareShards(t)
+	ctx := context.Background()
+
+	userIds := createShardedTestUsers(t)
+	_, err := DeleteUsers(ctx, &pb.DeleteUsersRequest{UserId: userIds[:1]})
+	require.NoError(t, err)
+	for i, userId := range userIds {
+		require.NoError(t, LinkExternalId(ctx, userId, "github", fmt.Sprint(i)))
+	}
+
+	// the values of the shards are merged without duplicates
+	values, err := DistinctValues(ctx, constants.TableUser, constants.ColumnStatus, &pb.ListUsersRequest{})
+	require.NoError(t, err)
+	require.Equal(t, []string{constants.StatusActive, constants.StatusDeleted}, values)
+	values, err = DistinctValues(ctx, constants.TableUser, constants.ColumnExternalProvider, &pb.ListUsersRequest{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"github"}, values)
+}
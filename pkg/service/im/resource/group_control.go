@@ -18,9 +18,10 @@ package resource
 
 import (
 	"context"
+	"fmt"
 	"strings"
-	"time"
 
+	"github.com/jinzhu/gorm"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"openpitrix.io/logger"
@@ -36,12 +37,18 @@ import (
 
 func CreateGroup(ctx context.Context, req *pb.CreateGroupRequest) (*pb.CreateGroupResponse, error) {
 	parentGroupId := stringutil.SimplifyString(req.ParentGroupId)
+	groupName := stringutil.SimplifyString(req.GroupName)
+	var violations fieldViolations
+	violations.checkGroupName("group_name", groupName)
+	if err := violations.Err(ctx); err != nil {
+		return nil, err
+	}
 	parentGroupPath, err := GetParentGroupPath(ctx, parentGroupId)
 	if err != nil {
 		return nil, err
 	}
 
-	group := models.NewGroup(parentGroupId, parentGroupPath, req.GroupName, req.Description, req.Extra)
+	group := models.NewGroup(parentGroupId, parentGroupPath, groupName, req.Description, req.Extra)
 
 	var allParentGroupIds []string
 	// skip groupId
@@ -55,7 +62,7 @@ func CreateGroup(ctx context.Context, req *pb.CreateGroupRequest) (*pb.CreateGro
 	if len(allParentGroupIds) > 0 {
 
 		var total int
-		if err := global.Global().Database.Table(constants.TableGroup).
+		if err := global.Global().Database.Table(db.TableName(constants.TableGroup)).
 			Where(constants.ColumnGroupId+" in (?)", allParentGroupIds).
 			Count(&total).Error; err != nil {
 			logger.Errorf(ctx, "Get parent group ids failed: %+v", err)
@@ -74,7 +81,7 @@ func CreateGroup(ctx context.Context, req *pb.CreateGroupRequest) (*pb.CreateGro
 	// create new record
 	if err := global.Global().Database.Create(group).Error; err != nil {
 		logger.Errorf(ctx, "Insert group failed: %+v", err)
-		return nil, err
+		return nil, db.MapError(err)
 	}
 
 	return &pb.CreateGroupResponse{
@@ -82,49 +89,128 @@ func CreateGroup(ctx context.Context, req *pb.CreateGroupRequest) (*pb.CreateGro
 	}, nil
 }
 
-func DeleteGroups(ctx context.Context, req *pb.DeleteGroupsRequest) (*pb.DeleteGroupsResponse, error) {
-	groupIds := req.GroupId
-	if len(groupIds) == 0 {
-		err := status.Errorf(codes.InvalidArgument, "empty group id")
+// BatchCreateGroups creates groups of names under parentGroupId, or root groups if it is empty, in one transaction.
+// Nothing is created if a name is duplicated or used by an active sibling group.
+func BatchCreateGroups(ctx context.Context, parentGroupId string, names []string) ([]*models.Group, error) {
+	parentGroupId = stringutil.SimplifyString(parentGroupId)
+	var violations fieldViolations
+	violations.checkNotEmpty("names", names)
+	names = append([]string(nil), names...)
+	for i := range names {
+		names[i] = stringutil.SimplifyString(names[i])
+		violations.checkGroupName(fmt.Sprintf("names[%d]", i), names[i])
+	}
+	if err := violations.Err(ctx); err != nil {
+		return nil, err
+	}
+	if len(stringutil.Unique(names)) != len(names) {
+		err := status.Errorf(codes.AlreadyExists, "duplicated group names in %v", names)
 		logger.Errorf(ctx, "%+v", err)
 		return nil, err
 	}
 
-	// 1. check sub groups
-	subGroupIds, err := getAllSubGroupIds(ctx, groupIds, constants.StatusActive)
+	parentGroupPath, err := GetParentGroupPath(ctx, parentGroupId)
 	if err != nil {
 		return nil, err
 	}
-	if len(subGroupIds) > 0 {
-		err := status.Errorf(codes.PermissionDenied, "there are still sub groups %v in group: %v", subGroupIds, groupIds)
-		logger.Errorf(ctx, "%+v", err)
+
+	var groups []*models.Group
+	err = global.Global().Database.WithTransaction(ctx, func(tx *gorm.DB) error {
+		var existingNames []string
+		if err := tx.Table(db.TableName(constants.TableGroup)).
+			Where(constants.ColumnParentGroupId+" = ?", parentGroupId).
+			Where(constants.ColumnGroupName+" in (?)", names).
+			Where(constants.ColumnStatus+" = ?", constants.StatusActive).
+			Pluck(constants.ColumnGroupName, &existingNames).Error; err != nil {
+			logger.Errorf(ctx, "Get sibling groups failed: %+v", err)
+			return err
+		}
+		if len(existingNames) > 0 {
+			err := status.Errorf(codes.AlreadyExists, "groups %v already exist under group [%s]", existingNames, parentGroupId)
+			logger.Errorf(ctx, "%+v", err)
+			return err
+		}
+
+		for _, name := range names {
+			group := models.NewGroup(parentGroupId, parentGroupPath, name, "", nil)
+			if err := tx.Create(group).Error; err != nil {
+				logger.Errorf(ctx, "Insert group failed: %+v", err)
+				return db.MapError(err)
+			}
+			groups = append(groups, group)
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// 2. check users
-	users, err := GetUserIdsByGroupIds(ctx, groupIds)
-	if err != nil {
+	return groups, nil
+}
+
+func DeleteGroups(ctx context.Context, req *pb.DeleteGroupsRequest) (*pb.DeleteGroupsResponse, error) {
+	groupIds := req.GroupId
+	var violations fieldViolations
+	violations.checkNotEmpty("group_id", groupIds)
+	if err := violations.Err(ctx); err != nil {
 		return nil, err
 	}
-	if len(users) > 0 {
-		err := status.Errorf(codes.PermissionDenied, "there are still users in group: %v", subGroupIds)
-		logger.Errorf(ctx, "%+v", err)
+
+	// 1. check sub groups, they are deleted too with cascade descendants
+	subGroupIds, err := getAllSubGroupIds(ctx, groupIds, constants.StatusActive)
+	if err != nil {
 		return nil, err
 	}
+	if len(subGroupIds) > 0 {
+		if !req.Cascade || !req.CascadeDescendants {
+			err := status.Errorf(codes.PermissionDenied, "there are still sub groups %v in group: %v", subGroupIds, groupIds)
+			logger.Errorf(ctx, "%+v", err)
+			return nil, err
+		}
+		groupIds = append(stringutil.Unique(groupIds), subGroupIds...)
+	}
+
+	// 2. check users, including the invited ones, their bindings are removed with cascade
+	if !req.Cascade {
+		users, err := GetUserIdsByGroupIdsWithOptions(ctx, groupIds, MembershipOptions{IncludePending: true})
+		if err != nil {
+			return nil, err
+		}
+		if len(users) > 0 {
+			err := status.Errorf(codes.PermissionDenied, "there are still users in group: %v", groupIds)
+			logger.Errorf(ctx, "%+v", err)
+			return nil, err
+		}
+	}
 
-	// 3. update group status to deleted
-	now := time.Now()
+	// 3. remove the bindings and update group status to deleted
+	now := models.NowUTC()
 	attributes := map[string]interface{}{
 		constants.ColumnStatusTime: now,
 		constants.ColumnUpdateTime: now,
 		constants.ColumnStatus:     constants.StatusDeleted,
 	}
-	if err := global.Global().Database.Table(constants.TableGroup).
-		Where(constants.ColumnGroupId+" in (?)", groupIds).
-		Updates(attributes).Error; err != nil {
-		logger.Errorf(ctx, "Update group status failed: %+v", err)
+	err = global.Global().Database.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if req.Cascade {
+			if _, err := deleteBindings(tx, constants.ColumnGroupId+" in (?)", groupIds); err != nil {
+				logger.Errorf(ctx, "Delete user group binding failed: %+v", err)
+				return err
+			}
+		}
+		if err := tx.Table(db.TableName(constants.TableGroup)).
+			Where(constants.ColumnGroupId+" in (?)", groupIds).
+			Updates(attributes).Error; err != nil {
+			logger.Errorf(ctx, "Update group status failed: %+v", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
+	if req.Cascade {
+		memberships.invalidate(nil, groupIds)
+	}
 
 	return &pb.DeleteGroupsResponse{
 		GroupId: groupIds,
@@ -133,6 +219,14 @@ func DeleteGroups(ctx context.Context, req *pb.DeleteGroupsRequest) (*pb.DeleteG
 
 func ModifyGroup(ctx context.Context, req *pb.ModifyGroupRequest) (*pb.ModifyGroupResponse, error) {
 	groupId := req.GroupId
+	groupName := stringutil.SimplifyString(req.GroupName)
+	if req.GroupName != "" {
+		var violations fieldViolations
+		violations.checkGroupName("group_name", groupName)
+		if err := violations.Err(ctx); err != nil {
+			return nil, err
+		}
+	}
 	group, err := GetGroup(ctx, groupId)
 	if err != nil {
 		return nil, err
@@ -150,8 +244,8 @@ func ModifyGroup(ctx context.Context, req *pb.ModifyGroupRequest) (*pb.ModifyGro
 		attributes[constants.ColumnGroupPath] = groupPath
 		attributes[constants.ColumnGroupPathLevel] = strings.Count(stringutil.SimplifyString(groupPath), constants.GroupPathSep) + 1
 	}
-	if req.GroupName != "" {
-		attributes[constants.ColumnGroupName] = req.GroupName
+	if groupName != "" {
+		attributes[constants.ColumnGroupName] = groupName
 	}
 	if req.Description != "" {
 		attributes[constants.ColumnDescription] = req.Description
@@ -159,9 +253,9 @@ func ModifyGroup(ctx context.Context, req *pb.ModifyGroupRequest) (*pb.ModifyGro
 	if len(req.Extra) > 0 {
 		attributes[constants.ColumnExtra] = stringutil.NewString(jsonutil.ToString(req.Extra))
 	}
-	attributes[constants.ColumnUpdateTime] = time.Now()
+	attributes[constants.ColumnUpdateTime] = models.NowUTC()
 
-	if err := global.Global().Database.Table(constants.TableGroup).
+	if err := global.Global().Database.Table(db.TableName(constants.TableGroup)).
 		Where(constants.ColumnGroupId+" = ?", groupId).
 		Updates(attributes).Error; err != nil {
 		logger.Errorf(ctx, "Update group [%s] failed: %+v", groupId, err)
@@ -173,6 +267,27 @@ func ModifyGroup(ctx context.Context, req *pb.ModifyGroupRequest) (*pb.ModifyGro
 	}, nil
 }
 
+// ArchiveGroups sets the archived flag of groups, the archived groups keep their members
+// but are left out of the groups of users unless asked with IncludeArchivedGroups
+func ArchiveGroups(ctx context.Context, groupIds []string, archived bool) error {
+	var violations fieldViolations
+	violations.checkNotEmpty("group_id", groupIds)
+	if err := violations.Err(ctx); err != nil {
+		return err
+	}
+
+	if err := global.Global().Database.Table(db.TableName(constants.TableGroup)).
+		Where(constants.ColumnGroupId+" in (?)", stringutil.Unique(groupIds)).
+		Updates(map[string]interface{}{
+			constants.ColumnArchived:   archived,
+			constants.ColumnUpdateTime: models.NowUTC(),
+		}).Error; err != nil {
+		logger.Errorf(ctx, "Update archived of groups %v failed: %+v", groupIds, err)
+		return err
+	}
+	return nil
+}
+
 func GetParentGroupPath(ctx context.Context, parentGroupId string) (string, error) {
 	parentGroupPath := ""
 	if parentGroupId != "" {
@@ -189,7 +304,7 @@ func GetParentGroupPath(ctx context.Context, parentGroupId string) (string, erro
 
 func GetGroup(ctx context.Context, groupId string) (*models.Group, error) {
 	var group = &models.Group{GroupId: groupId}
-	if err := global.Global().Database.Table(constants.TableGroup).Take(group).Error; err != nil {
+	if err := global.Global().Database.Table(db.TableName(constants.TableGroup)).Take(group).Error; err != nil {
 		logger.Errorf(ctx, "Get group [%s] failed: %+v", groupId, err)
 		return nil, err
 	}
@@ -212,13 +327,72 @@ func GetGroupWithUser(ctx context.Context, groupId string) (*models.GroupWithUse
 	}, nil
 }
 
-func ListGroups(ctx context.Context, req *pb.ListGroupsRequest) (*pb.ListGroupsResponse, error) {
+// escapes the wildcards of LIKE, '!' is used as the escape character of both mysql and sqlite
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// SearchGroupsByNamePrefix returns the active groups whose names start with prefix, ordered by name,
+// the condition is a prefix LIKE that is served by the index of group_name.
+// A limit of 0 means the default limit, it is capped like the limit of ListGroups.
+func SearchGroupsByNamePrefix(ctx context.Context, prefix string, limit uint32) ([]*models.Group, error) {
+	prefix = stringutil.SimplifyString(prefix)
+	if prefix == "" {
+		return nil, nil
+	}
+	if limit == 0 {
+		limit = db.DefaultLimit
+	}
+
+	var groups []*models.Group
+	if err := global.Global().Database.Table(db.TableName(constants.TableGroup)).
+		Where(constants.ColumnGroupName+" LIKE ? ESCAPE '!'", likeEscaper.Replace(prefix)+"%").
+		Where(constants.ColumnStatus+" = ?", constants.StatusActive).
+		Order(constants.ColumnGroupName).
+		Order(constants.ColumnGroupId).
+		Limit(db.GetLimit(limit)).
+		Find(&groups).Error; err != nil {
+		logger.Errorf(ctx, "Search groups by name prefix [%s] failed: %+v", prefix, err)
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+func simplifyListGroupsRequest(req *pb.ListGroupsRequest) {
 	req.RootGroupId = stringutil.SimplifyStringList(req.RootGroupId)
 	req.ParentGroupId = stringutil.SimplifyStringList(req.ParentGroupId)
 	req.GroupId = stringutil.SimplifyStringList(req.GroupId)
 	req.GroupPath = stringutil.SimplifyStringList(req.GroupPath)
 	req.GroupName = stringutil.SimplifyStringList(req.GroupName)
 	req.Status = stringutil.SimplifyStringList(req.Status)
+}
+
+func getListGroupsChain(req *pb.ListGroupsRequest) *db.Chain {
+	return db.GetChain(global.Global().Database.Table(db.TableName(constants.TableGroup))).
+		BuildFilterConditions(req, constants.TableGroup).
+		BuildRootGroupIdConditions(req.GetRootGroupId())
+}
+
+// addMemberCountColumn joins the number of accepted members of the groups as member_count,
+// when the groups are sorted by it, the count is computed in the same query as the page
+func addMemberCountColumn(chain *db.Chain, req *pb.ListGroupsRequest) *db.Chain {
+	if db.GetSortKeyFromRequest(req) != constants.SortKeyMemberCount {
+		return chain
+	}
+	groupTable := db.TableName(constants.TableGroup)
+	chain.DB = chain.Select("`"+groupTable+"`.*").
+		Joins("LEFT JOIN (SELECT group_id AS counted_group_id, COUNT(*) AS member_count FROM `"+
+			db.TableName(constants.TableUserGroupBinding)+"` WHERE status = ? GROUP BY group_id) AS member_counts "+
+			"ON member_counts.counted_group_id = `"+groupTable+"`.group_id", constants.BindingStatusAccepted)
+	return chain
+}
+
+func ListGroups(ctx context.Context, req *pb.ListGroupsRequest) (*pb.ListGroupsResponse, error) {
+	simplifyListGroupsRequest(req)
+	var violations fieldViolations
+	violations.checkSearchMode("search_mode", req.SearchMode)
+	if err := violations.Err(ctx); err != nil {
+		return nil, err
+	}
 
 	limit := db.GetLimitFromRequest(req)
 	offset := db.GetOffsetFromRequest(req)
@@ -226,10 +400,11 @@ func ListGroups(ctx context.Context, req *pb.ListGroupsRequest) (*pb.ListGroupsR
 	var groups []*models.Group
 	var count int
 
-	if err := db.GetChain(global.Global().Database.Table(constants.TableGroup)).
-		AddQueryOrderDir(req, constants.ColumnCreateTime).
-		BuildFilterConditions(req, constants.TableGroup).
-		BuildRootGroupIdConditions(req.GetRootGroupId()).
+	if limit == 0 {
+		// count only
+	} else if err := addMemberCountColumn(getListGroupsChain(req), req).
+		AddSearchRankOrder(req, constants.TableGroup).
+		AddQueryOrderDir(req, constants.TableGroup, constants.ColumnCreateTime).
 		Offset(offset).
 		Limit(limit).
 		Find(&groups).Error; err != nil {
@@ -237,25 +412,53 @@ func ListGroups(ctx context.Context, req *pb.ListGroupsRequest) (*pb.ListGroupsR
 		return nil, err
 	}
 
-	if err := db.GetChain(global.Global().Database.Table(constants.TableGroup)).
-		BuildFilterConditions(req, constants.TableGroup).
-		BuildRootGroupIdConditions(req.GetRootGroupId()).
+	if err := getListGroupsChain(req).
 		Count(&count).Error; err != nil {
 		logger.Errorf(ctx, "List group count failed: %+v", err)
 		return nil, err
 	}
 
 	var pbGroups []*pb.Group
+	var highlights []*pb.SearchHighlight
 	for _, group := range groups {
 		pbGroups = append(pbGroups, group.ToPB())
+		if req.Highlight {
+			highlights = append(highlights, &pb.SearchHighlight{
+				Id:     group.GroupId,
+				Column: db.MatchedColumns(req, constants.TableGroup, group),
+			})
+		}
 	}
 
 	return &pb.ListGroupsResponse{
-		GroupSet: pbGroups,
-		Total:    uint32(count),
+		GroupSet:     pbGroups,
+		Total:        uint32(count),
+		Limit:        limit,
+		Offset:       offset,
+		HighlightSet: highlights,
+		PageInfo:     db.NewPageInfo(uint32(count), offset, limit),
 	}, nil
 }
 
+// CountGroups returns the number of groups matching req, offset and limit are ignored
+func CountGroups(ctx context.Context, req *pb.ListGroupsRequest) (*pb.CountGroupsResponse, error) {
+	simplifyListGroupsRequest(req)
+	var violations fieldViolations
+	violations.checkSearchMode("search_mode", req.SearchMode)
+	if err := violations.Err(ctx); err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := getListGroupsChain(req).
+		Count(&count).Error; err != nil {
+		logger.Errorf(ctx, "Count groups failed: %+v", err)
+		return nil, err
+	}
+
+	return &pb.CountGroupsResponse{Total: uint32(count)}, nil
+}
+
 func ListGroupsWithUser(ctx context.Context, req *pb.ListGroupsRequest) (*pb.ListGroupsWithUserResponse, error) {
 	response, err := ListGroups(ctx, req)
 	if err != nil {
@@ -282,13 +485,15 @@ func ListGroupsWithUser(ctx context.Context, req *pb.ListGroupsRequest) (*pb.Lis
 	return &pb.ListGroupsWithUserResponse{
 		GroupSet: groupWithUsers,
 		Total:    response.Total,
+		Limit:    response.Limit,
+		Offset:   response.Offset,
 	}, nil
 }
 
 func getAllSubGroupIds(ctx context.Context, groupIds []string, status ...string) ([]string, error) {
 	var groups []*models.Group
 
-	tx := global.Global().Database.Table(constants.TableGroup)
+	tx := global.Global().Database.Table(db.TableName(constants.TableGroup))
 	for _, groupId := range groupIds {
 		likeGroupId := "%" + groupId + "%"
 		tx = tx.Or(constants.ColumnGroupPath+" LIKE ?", likeGroupId)
@@ -309,3 +514,100 @@ func getAllSubGroupIds(ctx context.Context, groupIds []string, status ...string)
 
 	return allGroupId, nil
 }
+
+// GroupPathProblem is a group whose group path does not match the chain of its parent groups,
+// ExpectedGroupPath is empty when the chain is broken by a missing parent or a cycle
+type GroupPathProblem struct {
+	GroupId           string
+	GroupPath         string
+	ExpectedGroupPath string
+}
+
+// VerifyGroupPaths returns the groups whose group path is not built from their parent group ids
+func VerifyGroupPaths(ctx context.Context) ([]*GroupPathProblem, error) {
+	return verifyGroupPaths(ctx, global.Global().Database.DB)
+}
+
+func verifyGroupPaths(ctx context.Context, tx *gorm.DB) ([]*GroupPathProblem, error) {
+	var groups []*models.Group
+	if err := tx.Table(db.TableName(constants.TableGroup)).
+		Select([]string{constants.ColumnGroupId, constants.ColumnParentGroupId, constants.ColumnGroupPath}).
+		Order(constants.ColumnGroupId).
+		Find(&groups).Error; err != nil {
+		logger.Errorf(ctx, "Get group paths failed: %+v", err)
+		return nil, err
+	}
+
+	parents := make(map[string]string, len(groups))
+	for _, group := range groups {
+		parents[group.GroupId] = group.ParentGroupId
+	}
+	expectedPaths := make(map[string]string, len(groups))
+	var expectedPath func(groupId string, depth int) string
+	expectedPath = func(groupId string, depth int) string {
+		if path, ok := expectedPaths[groupId]; ok {
+			return path
+		}
+		parentGroupId, ok := parents[groupId]
+		// missing group, or a cycle longer than all the groups
+		if !ok || depth > len(groups) {
+			return ""
+		}
+		path := groupId
+		if parentGroupId != "" {
+			parentPath := expectedPath(parentGroupId, depth+1)
+			if parentPath == "" {
+				return ""
+			}
+			path = models.GetGroupPath(parentPath, groupId)
+		}
+		expectedPaths[groupId] = path
+		return path
+	}
+
+	var problems []*GroupPathProblem
+	for _, group := range groups {
+		if path := expectedPath(group.GroupId, 0); path != group.GroupPath {
+			problems = append(problems, &GroupPathProblem{
+				GroupId:           group.GroupId,
+				GroupPath:         group.GroupPath,
+				ExpectedGroupPath: path,
+			})
+		}
+	}
+	return problems, nil
+}
+
+// RepairGroupPaths rebuilds the group paths found by VerifyGroupPaths from the parent group ids in one transaction,
+// the groups with a broken parent chain can not be repaired and are returned as problems left
+func RepairGroupPaths(ctx context.Context) (repaired, left []*GroupPathProblem, err error) {
+	err = global.Global().Database.WithTransaction(ctx, func(tx *gorm.DB) error {
+		problems, err := verifyGroupPaths(ctx, tx)
+		if err != nil {
+			return err
+		}
+		for _, problem := range problems {
+			if problem.ExpectedGroupPath == "" {
+				logger.Errorf(ctx, "Group [%s] has a broken parent chain, path [%s] is not repaired", problem.GroupId, problem.GroupPath)
+				left = append(left, problem)
+				continue
+			}
+			if err := tx.Table(db.TableName(constants.TableGroup)).
+				Where(constants.ColumnGroupId+" = ?", problem.GroupId).
+				Updates(map[string]interface{}{
+					constants.ColumnGroupPath:      problem.ExpectedGroupPath,
+					constants.ColumnGroupPathLevel: strings.Count(problem.ExpectedGroupPath, constants.GroupPathSep) + 1,
+					constants.ColumnUpdateTime:     models.NowUTC(),
+				}).Error; err != nil {
+				logger.Errorf(ctx, "Repair path of group [%s] failed: %+v", problem.GroupId, err)
+				return err
+			}
+			repaired = append(repaired, problem)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return repaired, left, nil
+}
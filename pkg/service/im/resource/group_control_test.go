@@ -0,0 +1,331 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/db"
+	"cloudbases.io/im/pkg/global"
+	"cloudbases.io/im/pkg/pb"
+)
+
+func TestCountGroups(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	rootGroupId := createTestGroup(t, "root", "")
+	subGroupId := createTestGroup(t, "sub", rootGroupId)
+	createTestGroup(t, "sub-sub", subGroupId)
+	otherGroupId := createTestGroup(t, "other", "")
+	_, err := DeleteGroups(ctx, &pb.DeleteGroupsRequest{GroupId: []string{otherGroupId}})
+	require.NoError(t, err)
+
+	var tests = []struct {
+		req    *pb.ListGroupsRequest
+		expect uint32
+	}{
+		{req: &pb.ListGroupsRequest{}, expect: 4},
+		{req: &pb.ListGroupsRequest{Status: []string{constants.StatusActive}}, expect: 3},
+		{req: &pb.ListGroupsRequest{SearchWord: []string{"sub"}}, expect: 2},
+		{req: &pb.ListGroupsRequest{RootGroupId: []string{subGroupId}}, expect: 2},
+		{req: &pb.ListGroupsRequest{ParentGroupId: []string{rootGroupId}}, expect: 1},
+	}
+	for _, v := range tests {
+		listResponse, err := ListGroups(ctx, v.req)
+		require.NoError(t, err)
+		require.Len(t, listResponse.GroupSet, int(v.expect), "%+v", v.req)
+
+		countResponse, err := CountGroups(ctx, v.req)
+		require.NoError(t, err)
+		require.Equal(t, v.expect, countResponse.Total, "%+v", v.req)
+	}
+}
+
+func TestListGroupsLimitOffset(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	response, err := ListGroups(ctx, &pb.ListGroupsRequest{Limit: 1000, Offset: 5})
+	require.NoError(t, err)
+	require.EqualValues(t, db.DefaultSelectLimit, response.Limit)
+	require.EqualValues(t, 5, response.Offset)
+
+	withUserResponse, err := ListGroupsWithUser(ctx, &pb.ListGroupsRequest{Limit: 10})
+	require.NoError(t, err)
+	require.EqualValues(t, 10, withUserResponse.Limit)
+	require.EqualValues(t, 0, withUserResponse.Offset)
+}
+
+func TestListGroupsCountOnly(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	createTestGroup(t, "count", "")
+
+	response, err := ListGroups(ctx, &pb.ListGroupsRequest{CountOnly: true})
+	require.NoError(t, err)
+	require.EqualValues(t, 0, response.Limit)
+	require.EqualValues(t, 1, response.Total)
+	require.Empty(t, response.GroupSet)
+	require.Equal(t, &pb.PageInfo{Total: 1, Offset: 0, Limit: 0, HasMore: false}, response.PageInfo)
+
+	withUserResponse, err := ListGroupsWithUser(ctx, &pb.ListGroupsRequest{CountOnly: true})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, withUserResponse.Total)
+	require.Empty(t, withUserResponse.GroupSet)
+}
+
+func TestListGroupsPagination(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"a", "b", "c"} {
+		createTestGroup(t, name, "")
+	}
+	groupNames := func(response *pb.ListGroupsResponse) []string {
+		var names []string
+		for _, group := range response.GroupSet {
+			names = append(names, group.GroupName)
+		}
+		return names
+	}
+
+	response, err := ListGroups(ctx, &pb.ListGroupsRequest{
+		Pagination: &pb.Pagination{Limit: 2, SortKey: constants.ColumnGroupName, Reverse: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, groupNames(response))
+	require.Equal(t, &pb.PageInfo{Total: 3, Offset: 0, Limit: 2, HasMore: true}, response.PageInfo)
+
+	// the pagination overrides the fields of the request
+	response, err = ListGroups(ctx, &pb.ListGroupsRequest{
+		Offset:     0,
+		Limit:      1,
+		SortKey:    constants.ColumnCreateTime,
+		Pagination: &pb.Pagination{Offset: 2, Limit: 2, SortKey: constants.ColumnGroupName, Reverse: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"c"}, groupNames(response))
+	require.Equal(t, &pb.PageInfo{Total: 3, Offset: 2, Limit: 2, HasMore: false}, response.PageInfo)
+}
+
+func TestListGroupsSortByMemberCount(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	groupA := createTestGroup(t, "a", "")
+	groupB := createTestGroup(t, "b", "")
+	groupC := createTestGroup(t, "c", "")
+	user1 := createTestUser(t, "u1", "13900000001")
+	user2 := createTestUser(t, "u2", "13900000002")
+	user3 := createTestUser(t, "u3", "13900000003")
+
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{user1, user2},
+		GroupId: []string{groupB},
+	})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{user3},
+		GroupId: []string{groupC},
+	})
+	require.NoError(t, err)
+	// pending invitations are not counted
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{user1, user2, user3},
+		GroupId: []string{groupA},
+		Status:  constants.BindingStatusPending,
+	})
+	require.NoError(t, err)
+
+	groupNames := func(response *pb.ListGroupsResponse) []string {
+		var names []string
+		for _, group := range response.GroupSet {
+			names = append(names, group.GroupName)
+		}
+		return names
+	}
+
+	response, err := ListGroups(ctx, &pb.ListGroupsRequest{
+		Pagination: &pb.Pagination{Limit: 2, SortKey: constants.SortKeyMemberCount},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"b", "c"}, groupNames(response))
+	require.Equal(t, &pb.PageInfo{Total: 3, Offset: 0, Limit: 2, HasMore: true}, response.PageInfo)
+
+	response, err = ListGroups(ctx, &pb.ListGroupsRequest{
+		Pagination: &pb.Pagination{Offset: 2, Limit: 2, SortKey: constants.SortKeyMemberCount},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, groupNames(response))
+
+	response, err = ListGroups(ctx, &pb.ListGroupsRequest{
+		SortKey: constants.SortKeyMemberCount,
+		Reverse: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "c", "b"}, groupNames(response))
+
+	// the filters of the request apply to the groups, not to the joined counts
+	response, err = ListGroups(ctx, &pb.ListGroupsRequest{
+		SortKey: constants.SortKeyMemberCount,
+		GroupId: []string{groupA, groupC},
+		Status:  []string{constants.StatusActive},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"c", "a"}, groupNames(response))
+	require.Equal(t, uint32(2), response.Total)
+}
+
+func TestSearchGroupsByNamePrefix(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"dev-ops", "dev", "develop", "web-dev", "dev_x", "devil"} {
+		createTestGroup(t, name, "")
+	}
+	deletedGroupId := createTestGroup(t, "dev-deleted", "")
+	_, err := DeleteGroups(ctx, &pb.DeleteGroupsRequest{GroupId: []string{deletedGroupId}})
+	require.NoError(t, err)
+
+	groupNames := func(prefix string, limit uint32) []string {
+		groups, err := SearchGroupsByNamePrefix(ctx, prefix, limit)
+		require.NoError(t, err)
+		var names []string
+		for _, group := range groups {
+			names = append(names, group.GroupName)
+		}
+		return names
+	}
+
+	// web-dev contains dev but does not start with it
+	require.Equal(t, []string{"dev", "dev-ops", "dev_x", "develop", "devil"}, groupNames("dev", 0))
+	require.Equal(t, []string{"dev", "dev-ops"}, groupNames("dev", 2))
+	require.Equal(t, []string{"develop"}, groupNames("deve", 0))
+	// the wildcards of LIKE match themselves
+	require.Equal(t, []string{"dev_x"}, groupNames("dev_", 0))
+	require.Empty(t, groupNames("%dev", 0))
+	require.Empty(t, groupNames("", 0))
+}
+
+func TestBatchCreateGroups(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	parentId := createTestGroup(t, "tenant", "")
+	parent, err := GetGroup(ctx, parentId)
+	require.NoError(t, err)
+
+	groups, err := BatchCreateGroups(ctx, parentId, []string{"dev", "ops", " qa "})
+	require.NoError(t, err)
+	require.Len(t, groups, 3)
+	for i, name := range []string{"dev", "ops", "qa"} {
+		group, err := GetGroup(ctx, groups[i].GroupId)
+		require.NoError(t, err)
+		require.Equal(t, name, group.GroupName)
+		require.Equal(t, parentId, group.ParentGroupId)
+		require.Equal(t, parent.GroupPath+"."+group.GroupId, group.GroupPath)
+	}
+
+	countChildren := func() uint32 {
+		response, err := CountGroups(ctx, &pb.ListGroupsRequest{ParentGroupId: []string{parentId}})
+		require.NoError(t, err)
+		return response.Total
+	}
+	require.EqualValues(t, 3, countChildren())
+
+	// collision with an existing sibling rolls back all
+	_, err = BatchCreateGroups(ctx, parentId, []string{"sales", "ops"})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+	require.EqualValues(t, 3, countChildren())
+
+	// collision inside the batch
+	_, err = BatchCreateGroups(ctx, parentId, []string{"sales", "sales"})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+	require.EqualValues(t, 3, countChildren())
+
+	// the same names under another parent are fine
+	groups, err = BatchCreateGroups(ctx, groups[0].GroupId, []string{"ops", "qa"})
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+
+	_, err = BatchCreateGroups(ctx, "gid-unknown", []string{"sales"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = BatchCreateGroups(ctx, parentId, []string{"sales", ""})
+	require.Equal(t, []string{"names[1]"}, violatedFields(t, err))
+}
+
+func TestVerifyAndRepairGroupPaths(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	root := createTestGroup(t, "root", "")
+	child := createTestGroup(t, "child", root)
+	grandchild := createTestGroup(t, "grandchild", child)
+	orphan := createTestGroup(t, "orphan", "")
+
+	problems, err := VerifyGroupPaths(ctx)
+	require.NoError(t, err)
+	require.Empty(t, problems)
+
+	setGroupColumn := func(groupId, column string, value interface{}) {
+		require.NoError(t, global.Global().Database.Table(constants.TableGroup).
+			Where(constants.ColumnGroupId+" = ?", groupId).
+			Update(column, value).Error)
+	}
+	// child lost its parent in the path, orphan points to a missing parent
+	setGroupColumn(child, constants.ColumnGroupPath, child)
+	setGroupColumn(orphan, constants.ColumnParentGroupId, "gid-missing")
+
+	problems, err = VerifyGroupPaths(ctx)
+	require.NoError(t, err)
+	require.Len(t, problems, 2)
+	require.Equal(t, &GroupPathProblem{
+		GroupId:           child,
+		GroupPath:         child,
+		ExpectedGroupPath: root + "." + child,
+	}, problems[0])
+	require.Equal(t, &GroupPathProblem{
+		GroupId:           orphan,
+		GroupPath:         orphan,
+		ExpectedGroupPath: "",
+	}, problems[1])
+
+	repaired, left, err := RepairGroupPaths(ctx)
+	require.NoError(t, err)
+	require.Equal(t, problems[:1], repaired)
+	require.Equal(t, problems[1:], left)
+
+	group, err := GetGroup(ctx, child)
+	require.NoError(t, err)
+	require.Equal(t, root+"."+child, group.GroupPath)
+	require.Equal(t, 2, group.GroupPathLevel)
+	group, err = GetGroup(ctx, grandchild)
+	require.NoError(t, err)
+	require.Equal(t, root+"."+child+"."+grandchild, group.GroupPath)
+
+	problems, err = VerifyGroupPaths(ctx)
+	require.NoError(t, err)
+	require.Equal(t, left, problems)
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"path/filepath"
+	"testing"
+
+	"cloudbases.io/im/pkg/config"
+	"cloudbases.io/im/pkg/global"
+)
+
+// prepare points the global database to a fresh sqlite database with the im schema
+func prepare(t *testing.T) {
+	cfg := config.Default()
+	cfg.DB.Type = "sqlite3"
+	cfg.DB.Database = filepath.Join(t.TempDir(), "im.db") + "?_busy_timeout=5000"
+	cfg.DB.AutoMigrate = true
+	global.SetGlobal(cfg)
+	t.Cleanup(func() {
+		global.Global().Close()
+	})
+}
+
+// prepareShards is prepare with the users sharded across two databases
+func prepareShards(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.DB.Type = "sqlite3"
+	cfg.DB.Database = filepath.Join(dir, "im.db") + "?_busy_timeout=5000"
+	cfg.DB.ShardDatabases = filepath.Join(dir, "shard0.db") + "," + filepath.Join(dir, "shard1.db")
+	cfg.DB.AutoMigrate = true
+	global.SetGlobal(cfg)
+	t.Cleanup(func() {
+		global.Global().Close()
+	})
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"container/list"
+	"sync"
+
+	"cloudbases.io/im/pkg/util/stringutil"
+)
+
+// memberships caches the results of IsUserInGroup, the entries of the changed bindings are invalidated
+var memberships = newMembershipCache()
+
+type membershipKey struct {
+	userId  string
+	groupId string
+}
+
+type membershipEntry struct {
+	key membershipKey
+	in  bool
+}
+
+// membershipCache keeps the recently used entries, the least recently used one is evicted when it is full
+type membershipCache struct {
+	mu      sync.Mutex
+	order   *list.List
+	entries map[membershipKey]*list.Element
+	// increased by every invalidation, a result read from the database before it is not cached
+	generation uint64
+}
+
+func newMembershipCache() *membershipCache {
+	return &membershipCache{order: list.New(), entries: make(map[membershipKey]*list.Element)}
+}
+
+// get returns the cached membership, nothing is cached when size is 0
+func (c *membershipCache) get(userId, groupId string, size int) (in, ok bool) {
+	if size <= 0 {
+		return false, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[membershipKey{userId: userId, groupId: groupId}]
+	if !ok {
+		return false, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*membershipEntry).in, true
+}
+
+// getGeneration is called before reading the membership to be cached by set
+func (c *membershipCache) getGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// set caches the membership read since generation, unless the bindings changed meanwhile
+func (c *membershipCache) set(userId, groupId string, in bool, size int, generation uint64) {
+	if size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	key := membershipKey{userId: userId, groupId: groupId}
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*membershipEntry).in = in
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&membershipEntry{key: key, in: in})
+	for c.order.Len() > size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*membershipEntry).key)
+	}
+}
+
+// invalidate removes the entries of the users in the groups, nil userIds or groupIds matches all of them
+func (c *membershipCache) invalidate(userIds, groupIds []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for key, element := range c.entries {
+		if (userIds == nil || stringutil.Contains(userIds, key.userId)) &&
+			(groupIds == nil || stringutil.Contains(groupIds, key.groupId)) {
+			c.order.Remove(element)
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *membershipCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.order.Init()
+	c.entries = make(map[membershipKey]*list.Element)
+}
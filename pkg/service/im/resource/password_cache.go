@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"sync"
+	"time"
+)
+
+// passwordHashes caches the password hashes read by BatchComparePassword,
+// ModifyPassword invalidates the entry of the user
+var passwordHashes = newPasswordHashCache()
+
+type passwordHashEntry struct {
+	hash     string
+	expireAt time.Time
+}
+
+type passwordHashCache struct {
+	mu      sync.Mutex
+	entries map[string]passwordHashEntry
+	// the expired entries of the users not compared again are swept by set, at most once per ttl
+	sweptAt time.Time
+}
+
+func newPasswordHashCache() *passwordHashCache {
+	return &passwordHashCache{entries: make(map[string]passwordHashEntry)}
+}
+
+// get returns the hash of user cached less than ttl ago, nothing is cached when ttl is 0
+func (c *passwordHashCache) get(userId string, ttl time.Duration) (string, bool) {
+	if ttl <= 0 {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[userId]
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(entry.expireAt) {
+		delete(c.entries, userId)
+		return "", false
+	}
+	return entry.hash, true
+}
+
+func (c *passwordHashCache) set(userId, hash string, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if now.Sub(c.sweptAt) >= ttl {
+		for id, entry := range c.entries {
+			if now.After(entry.expireAt) {
+				delete(c.entries, id)
+			}
+		}
+		c.sweptAt = now
+	}
+	c.entries[userId] = passwordHashEntry{hash: hash, expireAt: now.Add(ttl)}
+}
+
+func (c *passwordHashCache) invalidate(userIds ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, userId := range userIds {
+		delete(c.entries, userId)
+	}
+}
+
+func (c *passwordHashCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]passwordHashEntry)
+}
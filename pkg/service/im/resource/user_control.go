@@ -18,8 +18,10 @@ package resource
 
 import (
 	"context"
+	"sort"
 	"time"
 
+	"github.com/jinzhu/gorm"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"openpitrix.io/logger"
@@ -30,16 +32,20 @@ import (
 	"cloudbases.io/im/pkg/models"
 	"cloudbases.io/im/pkg/pb"
 	"cloudbases.io/im/pkg/util/jsonutil"
+	"cloudbases.io/im/pkg/util/phoneutil"
 	"cloudbases.io/im/pkg/util/stringutil"
 )
 
 func CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
-	user := models.NewUser(req.Username, req.Email, req.PhoneNumber, req.Description, req.Password, req.Extra)
+	user, err := newUser(ctx, req)
+	if err != nil {
+		return nil, err
+	}
 
 	// create new record
-	if err := global.Global().Database.Create(user).Error; err != nil {
+	if err := global.Global().UserDatabase(user.UserId).Create(user).Error; err != nil {
 		logger.Errorf(ctx, "Insert user failed: %+v", err)
-		return nil, err
+		return nil, db.MapError(err)
 	}
 
 	return &pb.CreateUserResponse{
@@ -47,35 +53,69 @@ func CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserR
 	}, nil
 }
 
+// newUser validates req and returns the user to be created by it
+func newUser(ctx context.Context, req *pb.CreateUserRequest) (*models.User, error) {
+	var violations fieldViolations
+	if stringutil.SimplifyString(req.Username) == "" {
+		violations.Add("username", "empty username")
+	}
+	violations.checkEmail("email", stringutil.SimplifyString(req.Email))
+	phoneNumber := violations.checkPhoneNumber("phone_number", req.PhoneNumber)
+	violations.checkAvatarUrl("avatar_url", req.AvatarUrl)
+	// users without password can not login by password
+	if req.Password != "" {
+		violations.checkPassword(ctx, "password", req.Password)
+	}
+	if err := violations.Err(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := checkPhoneNumberUnique(ctx, phoneNumber, ""); err != nil {
+		return nil, err
+	}
+
+	user := models.NewUser(req.Username, req.Email, phoneNumber, req.Description, req.Password, req.Extra)
+	user.AvatarUrl = req.AvatarUrl
+	user.FirstName = stringutil.SimplifyString(req.FirstName)
+	user.LastName = stringutil.SimplifyString(req.LastName)
+	return user, nil
+}
+
 func DeleteUsers(ctx context.Context, req *pb.DeleteUsersRequest) (*pb.DeleteUsersResponse, error) {
 	userIds := req.UserId
-	if len(userIds) == 0 {
-		err := status.Errorf(codes.InvalidArgument, "empty user id")
-		logger.Errorf(ctx, "%+v", err)
+	var violations fieldViolations
+	violations.checkNotEmpty("user_id", userIds)
+	if err := violations.Err(ctx); err != nil {
 		return nil, err
 	}
 
 	tx := global.Global().Database.Begin()
 	{
-		tx.Delete(models.UserGroupBinding{}, constants.ColumnUserId+" in (?)", userIds)
-		if err := tx.Error; err != nil {
+		if _, err := deleteBindings(tx, constants.ColumnUserId+" in (?)", userIds); err != nil {
 			tx.Rollback()
 			logger.Errorf(ctx, "Delete user group binding failed: %+v", err)
 			return nil, err
 		}
 
-		now := time.Now()
+		now := models.NowUTC()
 		attributes := map[string]interface{}{
 			constants.ColumnStatusTime: now,
 			constants.ColumnUpdateTime: now,
 			constants.ColumnStatus:     constants.StatusDeleted,
 		}
-		if err := tx.Table(constants.TableUser).
-			Where(constants.ColumnUserId+" in (?)", userIds).
-			Updates(attributes).Error; err != nil {
-			tx.Rollback()
-			logger.Errorf(ctx, "Update user status failed: %+v", err)
-			return nil, err
+		// the shards are updated outside of the transaction, a failed deletion is retried as a whole
+		for database, shardUserIds := range global.Global().GroupUserIds(userIds) {
+			query := database.DB
+			if database == global.Global().Database {
+				query = tx
+			}
+			if err := query.Table(db.TableName(constants.TableUser)).
+				Where(constants.ColumnUserId+" in (?)", shardUserIds).
+				Updates(attributes).Error; err != nil {
+				tx.Rollback()
+				logger.Errorf(ctx, "Update user status failed: %+v", err)
+				return nil, err
+			}
 		}
 	}
 
@@ -83,6 +123,7 @@ func DeleteUsers(ctx context.Context, req *pb.DeleteUsersRequest) (*pb.DeleteUse
 		logger.Errorf(ctx, "Delete user failed: %+v", err)
 		return nil, err
 	}
+	memberships.invalidate(userIds, nil)
 
 	return &pb.DeleteUsersResponse{
 		UserId: userIds,
@@ -91,11 +132,26 @@ func DeleteUsers(ctx context.Context, req *pb.DeleteUsersRequest) (*pb.DeleteUse
 
 func ModifyUser(ctx context.Context, req *pb.ModifyUserRequest) (*pb.ModifyUserResponse, error) {
 	userId := req.UserId
-	_, err := GetUser(ctx, userId)
+	user, err := GetUser(ctx, userId)
 	if err != nil {
 		return nil, err
 	}
 
+	version := user.Version
+	if req.Version != nil {
+		version = req.Version.GetValue()
+	}
+
+	var violations fieldViolations
+	violations.checkEmail("email", stringutil.SimplifyString(req.Email))
+	phoneNumber := violations.checkPhoneNumber("phone_number", req.PhoneNumber)
+	if req.AvatarUrl != nil {
+		violations.checkAvatarUrl("avatar_url", req.AvatarUrl.GetValue())
+	}
+	if err := violations.Err(ctx); err != nil {
+		return nil, err
+	}
+
 	attributes := make(map[string]interface{})
 	if req.Username != "" {
 		attributes[constants.ColumnUsername] = req.Username
@@ -106,29 +162,51 @@ func ModifyUser(ctx context.Context, req *pb.ModifyUserRequest) (*pb.ModifyUserR
 	if req.Email != "" {
 		attributes[constants.ColumnEmail] = stringutil.SimplifyString(req.Email)
 	}
-	if req.PhoneNumber != "" {
-		attributes[constants.ColumnPhoneNumber] = stringutil.SimplifyString(req.PhoneNumber)
+	if phoneNumber != "" {
+		if err := checkPhoneNumberUnique(ctx, phoneNumber, userId); err != nil {
+			return nil, err
+		}
+		attributes[constants.ColumnPhoneNumber] = phoneNumber
 	}
 	if len(req.Extra) > 0 {
 		attributes[constants.ColumnExtra] = stringutil.NewString(jsonutil.ToString(req.Extra))
 	}
-	attributes[constants.ColumnUpdateTime] = time.Now()
+	if req.AvatarUrl != nil {
+		attributes[constants.ColumnAvatarUrl] = req.AvatarUrl.GetValue()
+	}
+	if firstName := stringutil.SimplifyString(req.FirstName); firstName != "" {
+		attributes[constants.ColumnFirstName] = firstName
+	}
+	if lastName := stringutil.SimplifyString(req.LastName); lastName != "" {
+		attributes[constants.ColumnLastName] = lastName
+	}
+	attributes[constants.ColumnUpdateTime] = models.NowUTC()
+	attributes[constants.ColumnVersion] = version + 1
 
-	if err := global.Global().Database.Table(constants.TableUser).
+	// optimistic lock, the user must not be modified by others after it is read
+	result := global.Global().UserDatabase(userId).Table(db.TableName(constants.TableUser)).
 		Where(constants.ColumnUserId+" = ?", userId).
-		Updates(attributes).Error; err != nil {
+		Where(constants.ColumnVersion+" = ?", version).
+		Updates(attributes)
+	if err := result.Error; err != nil {
 		logger.Errorf(ctx, "Update user [%s] failed: %+v", userId, err)
+		return nil, db.MapError(err)
+	}
+	if result.RowsAffected == 0 {
+		err := status.Errorf(codes.Aborted, "user [%s] has been modified, version [%d] is stale", userId, version)
+		logger.Errorf(ctx, "%+v", err)
 		return nil, err
 	}
 
 	return &pb.ModifyUserResponse{
-		UserId: userId,
-	}, err
+		UserId:  userId,
+		Version: version + 1,
+	}, nil
 }
 
 func GetUser(ctx context.Context, userId string) (*models.User, error) {
 	var user = &models.User{UserId: userId}
-	if err := global.Global().Database.Table(constants.TableUser).
+	if err := global.Global().UserDatabase(userId).Table(db.TableName(constants.TableUser)).
 		Take(user).Error; err != nil {
 		logger.Errorf(ctx, "Get user [%s] failed: %+v", userId, err)
 		return nil, err
@@ -137,6 +215,180 @@ func GetUser(ctx context.Context, userId string) (*models.User, error) {
 	return user, nil
 }
 
+// GetInactiveUsers returns the active users not logged in since, including the users never logged in
+func GetInactiveUsers(ctx context.Context, since time.Time) ([]*models.User, error) {
+	var users []*models.User
+	for _, database := range global.Global().UserDatabases() {
+		var shardUsers []*models.User
+		if err := database.Table(db.TableName(constants.TableUser)).
+			Where(constants.ColumnStatus+" = ?", constants.StatusActive).
+			Where("("+constants.ColumnLastLoginAt+" IS NULL OR "+constants.ColumnLastLoginAt+" < ?)", since.UTC()).
+			Order(constants.ColumnCreateTime).
+			Find(&shardUsers).Error; err != nil {
+			logger.Errorf(ctx, "Get users inactive since [%s] failed: %+v", since, err)
+			return nil, err
+		}
+		users = append(users, shardUsers...)
+	}
+	// the users of the shards are merged in the order of creation
+	sort.SliceStable(users, func(i, j int) bool {
+		return users[i].CreateTime.Before(users[j].CreateTime)
+	})
+
+	return users, nil
+}
+
+func GetUserByPhoneNumber(ctx context.Context, phoneNumber string) (*models.User, error) {
+	phoneNumber, err := normalizePhoneNumber(ctx, phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := takeUser(func(tx *gorm.DB) *gorm.DB {
+		return tx.Where(constants.ColumnPhoneNumber+" = ?", phoneNumber).
+			Where(constants.ColumnStatus+" = ?", constants.StatusActive)
+	})
+	if err != nil {
+		logger.Errorf(ctx, "Get user by phone number [%s] failed: %+v", phoneNumber, err)
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetUserByExternalId returns the active user linked to the subject externalId of the identity provider
+func GetUserByExternalId(ctx context.Context, provider, externalId string) (*models.User, error) {
+	user, err := takeUser(func(tx *gorm.DB) *gorm.DB {
+		return tx.Where(constants.ColumnExternalProvider+" = ?", provider).
+			Where(constants.ColumnExternalId+" = ?", externalId).
+			Where(constants.ColumnStatus+" = ?", constants.StatusActive)
+	})
+	if err != nil {
+		logger.Errorf(ctx, "Get user by external id [%s/%s] failed: %+v", provider, externalId, err)
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// takeUser returns the first user matched by where in the databases holding the users,
+// gorm.ErrRecordNotFound when none of them has one
+func takeUser(where func(tx *gorm.DB) *gorm.DB) (*models.User, error) {
+	for _, database := range global.Global().UserDatabases() {
+		var user = &models.User{}
+		err := where(database.Table(db.TableName(constants.TableUser))).Take(user).Error
+		if err == nil {
+			return user, nil
+		}
+		if !gorm.IsRecordNotFoundError(err) {
+			return nil, err
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// LinkExternalId links the user to the subject externalId of the identity provider,
+// replacing its previous link, a subject is linked to one user of a provider
+func LinkExternalId(ctx context.Context, userId, provider, externalId string) error {
+	var violations fieldViolations
+	violations.checkNotBlank("user_id", userId)
+	violations.checkNotBlank("external_provider", provider)
+	violations.checkNotBlank("external_id", externalId)
+	if err := violations.Err(ctx); err != nil {
+		return err
+	}
+
+	countLinked := func(tx *gorm.DB) error {
+		var count int
+		if err := tx.Table(db.TableName(constants.TableUser)).
+			Where(constants.ColumnExternalProvider+" = ?", provider).
+			Where(constants.ColumnExternalId+" = ?", externalId).
+			Where(constants.ColumnUserId+" != ?", userId).
+			Count(&count).Error; err != nil {
+			logger.Errorf(ctx, "Get user count by external id [%s/%s] failed: %+v", provider, externalId, err)
+			return err
+		}
+		if count > 0 {
+			err := status.Errorf(codes.AlreadyExists, "external id [%s] of [%s] is linked to another user", externalId, provider)
+			logger.Errorf(ctx, "%+v", err)
+			return err
+		}
+		return nil
+	}
+	// the other shards are checked before the transaction on the shard of the user
+	userDatabase := global.Global().UserDatabase(userId)
+	for _, database := range global.Global().UserDatabases() {
+		if database == userDatabase {
+			continue
+		}
+		if err := countLinked(database.DB); err != nil {
+			return err
+		}
+	}
+
+	return userDatabase.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := countLinked(tx); err != nil {
+			return err
+		}
+
+		result := tx.Table(db.TableName(constants.TableUser)).
+			Where(constants.ColumnUserId+" = ?", userId).
+			Updates(map[string]interface{}{
+				constants.ColumnExternalProvider: provider,
+				constants.ColumnExternalId:       externalId,
+				constants.ColumnUpdateTime:       models.NowUTC(),
+			})
+		if err := result.Error; err != nil {
+			logger.Errorf(ctx, "Link user [%s] to external id [%s/%s] failed: %+v", userId, provider, externalId, err)
+			return db.MapError(err)
+		}
+		if result.RowsAffected == 0 {
+			err := status.Errorf(codes.NotFound, "user [%s] not found", userId)
+			logger.Errorf(ctx, "%+v", err)
+			return err
+		}
+		return nil
+	})
+}
+
+// GetOrCreateFederatedUser returns the user linked to the subject externalId of the identity provider,
+// the user is created from profile on the first login. Concurrent first logins create one user,
+// the logins losing the race on the unique external id read the user created by the winner.
+// When the users are sharded, the unique external id only guards the users of a shard.
+func GetOrCreateFederatedUser(ctx context.Context, provider, externalId string, profile *pb.CreateUserRequest) (user *models.User, created bool, err error) {
+	var violations fieldViolations
+	violations.checkNotBlank("external_provider", provider)
+	violations.checkNotBlank("external_id", externalId)
+	if err := violations.Err(ctx); err != nil {
+		return nil, false, err
+	}
+
+	user, err = GetUserByExternalId(ctx, provider, externalId)
+	if err == nil {
+		return user, false, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, false, err
+	}
+
+	user, err = newUser(ctx, profile)
+	if err != nil {
+		return nil, false, err
+	}
+	user.ExternalProvider = &provider
+	user.ExternalId = &externalId
+	if createErr := global.Global().UserDatabase(user.UserId).Create(user).Error; createErr != nil {
+		// retry the lookup, the user may be created by a concurrent login
+		if user, err := GetUserByExternalId(ctx, provider, externalId); err == nil {
+			return user, false, nil
+		}
+		logger.Errorf(ctx, "Insert user of external id [%s/%s] failed: %+v", provider, externalId, createErr)
+		return nil, false, db.MapError(createErr)
+	}
+
+	return user, true, nil
+}
+
 func GetUserWithGroup(ctx context.Context, userId string) (*models.UserWithGroup, error) {
 	user, err := GetUser(ctx, userId)
 	if err != nil {
@@ -152,24 +404,53 @@ func GetUserWithGroup(ctx context.Context, userId string) (*models.UserWithGroup
 	}, nil
 }
 
-func ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+// GetUserProfile returns the user with its groups in one call, only the display columns
+// of the user are selected so the password hash is never loaded
+func GetUserProfile(ctx context.Context, userId string) (*pb.UserWithGroup, error) {
+	var user = &models.User{}
+	if err := global.Global().UserDatabase(userId).Table(db.TableName(constants.TableUser)).
+		Select(constants.DisplayColumns[constants.TableUser]).
+		Where(constants.ColumnUserId+" = ?", userId).
+		Take(user).Error; err != nil {
+		logger.Errorf(ctx, "Get profile of user [%s] failed: %+v", userId, err)
+		return nil, err
+	}
+	groups, err := GetGroupsByUserIds(ctx, []string{userId})
+	if err != nil {
+		return nil, err
+	}
+	userWithGroup := &models.UserWithGroup{
+		User:   user,
+		Groups: groups,
+	}
+	return userWithGroup.ToPB(), nil
+}
+
+// resolveListUsersRequest simplifies req and turns its group conditions into user ids,
+// false is returned when no user can match
+func resolveListUsersRequest(ctx context.Context, req *pb.ListUsersRequest) (bool, error) {
+	var violations fieldViolations
+	violations.checkSearchMode("search_mode", req.SearchMode)
+	violations.checkTimestamp("joined_group_since", req.JoinedGroupSince)
+	violations.checkTimestamp("joined_group_before", req.JoinedGroupBefore)
+	if err := violations.Err(ctx); err != nil {
+		return false, err
+	}
+
 	req.GroupId = stringutil.SimplifyStringList(req.GroupId)
 	req.UserId = stringutil.SimplifyStringList(req.UserId)
 	req.Username = stringutil.SimplifyStringList(req.Username)
 	req.Email = stringutil.SimplifyStringList(req.Email)
 	req.PhoneNumber = stringutil.SimplifyStringList(req.PhoneNumber)
 	req.Status = stringutil.SimplifyStringList(req.Status)
-
-	limit := db.GetLimitFromRequest(req)
-	offset := db.GetOffsetFromRequest(req)
-
-	var pbUsers []*pb.User
+	req.Tag = stringutil.SimplifyStringList(req.Tag)
+	req.InGroupIds = stringutil.SimplifyStringList(req.InGroupIds)
 
 	// get group
 	if len(req.RootGroupId) > 0 {
 		allGroupIds, err := getAllSubGroupIds(ctx, req.RootGroupId)
 		if err != nil {
-			return nil, err
+			return false, err
 		}
 		allGroupIds = append(allGroupIds, req.RootGroupId...)
 
@@ -185,10 +466,7 @@ func ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResp
 			req.GroupId = inGroupIds
 		}
 		if len(req.GroupId) == 0 {
-			return &pb.ListUsersResponse{
-				UserSet: pbUsers,
-				Total:   0,
-			}, nil
+			return false, nil
 		}
 	}
 
@@ -196,58 +474,251 @@ func ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResp
 	if len(req.GroupId) > 0 {
 		userIds, err := GetUserIdsByGroupIds(ctx, req.GroupId)
 		if err != nil {
-			return nil, err
+			return false, err
+		}
+		if !restrictUserIds(req, userIds) {
+			return false, nil
 		}
+	}
 
-		if len(req.UserId) == 0 {
-			req.UserId = userIds
-		} else {
-			var inUserIds []string
-			for _, userId := range req.UserId {
-				if stringutil.Contains(userIds, userId) {
-					inUserIds = append(inUserIds, userId)
-				}
+	if len(global.Global().Shards) > 0 {
+		return resolveShardedListUsersRequest(ctx, req)
+	}
+	return true, nil
+}
+
+// resolveShardedListUsersRequest resolves the filters of req by the tags and the bindings to user ids,
+// the tags and the bindings are in the main database, the shards only hold the users
+func resolveShardedListUsersRequest(ctx context.Context, req *pb.ListUsersRequest) (bool, error) {
+	// the words are matched against the names of the groups or the columns of the users, it is not a filter
+	if req.SearchGroupName && len(req.SearchWord) > 0 {
+		err := status.Errorf(codes.Unimplemented, "search_group_name is not supported when the users are sharded")
+		logger.Errorf(ctx, "%+v", err)
+		return false, err
+	}
+	database := global.Global().ReadDatabase(ctx)
+	var filters []*gorm.DB
+	if len(req.Tag) > 0 {
+		filters = append(filters, database.Table(db.TableName(constants.TableUserTag)).
+			Where(constants.ColumnTag+" in (?)", req.Tag))
+	}
+	if len(req.InGroupIds) > 0 {
+		filters = append(filters, database.Table(db.TableName(constants.TableUserGroupBinding)).
+			Where(constants.ColumnGroupId+" in (?)", req.InGroupIds).
+			Where(constants.ColumnStatus+" = ?", constants.BindingStatusAccepted))
+	}
+	if req.JoinedGroupSince != nil || req.JoinedGroupBefore != nil {
+		filter := database.Table(db.TableName(constants.TableUserGroupBinding)).
+			Where(constants.ColumnStatus+" = ?", constants.BindingStatusAccepted)
+		if req.JoinedGroupSince != nil {
+			filter = filter.Where(constants.ColumnCreateTime+" >= ?", timestampTime(req.JoinedGroupSince).UTC())
+		}
+		if req.JoinedGroupBefore != nil {
+			filter = filter.Where(constants.ColumnCreateTime+" < ?", timestampTime(req.JoinedGroupBefore).UTC())
+		}
+		filters = append(filters, filter)
+	}
+
+	for _, filter := range filters {
+		var userIds []string
+		if err := filter.Pluck(constants.ColumnUserId, &userIds).Error; err != nil {
+			logger.Errorf(ctx, "Get user ids of the filters failed: %+v", err)
+			return false, err
+		}
+		if !restrictUserIds(req, stringutil.Unique(userIds)) {
+			return false, nil
+		}
+	}
+	// the shards are queried by the user ids instead
+	req.Tag = nil
+	req.InGroupIds = nil
+	req.JoinedGroupSince = nil
+	req.JoinedGroupBefore = nil
+	return true, nil
+}
+
+// restrictUserIds keeps the user ids of req in userIds, all of userIds when req has none,
+// false is returned when no user is left
+func restrictUserIds(req *pb.ListUsersRequest, userIds []string) bool {
+	if len(req.UserId) == 0 {
+		req.UserId = userIds
+	} else {
+		var inUserIds []string
+		for _, userId := range req.UserId {
+			if stringutil.Contains(userIds, userId) {
+				inUserIds = append(inUserIds, userId)
 			}
-			req.UserId = inUserIds
 		}
-		if len(req.UserId) == 0 {
-			return &pb.ListUsersResponse{
-				UserSet: pbUsers,
-				Total:   0,
-			}, nil
+		req.UserId = inUserIds
+	}
+	return len(req.UserId) > 0
+}
+
+func getListUsersChain(database *db.Database, req *pb.ListUsersRequest) *db.Chain {
+	var createdAfter time.Time
+	if req.CreatedInDays > 0 {
+		createdAfter = models.NowUTC().AddDate(0, 0, -int(req.CreatedInDays))
+	}
+
+	chain := db.GetChain(database.Table(db.AliasTable(constants.TableUser))).
+		BuildFilterConditions(req, constants.TableUser).
+		BuildTimeRangeConditions(constants.ColumnCreateTime, createdAfter, time.Time{})
+	if len(req.Tag) > 0 {
+		chain.DB = chain.Where("EXISTS (SELECT 1 FROM "+db.AliasTable(constants.TableUserTag)+
+			" WHERE "+constants.TableUserTag+"."+constants.ColumnUserId+" = `"+constants.TableUser+"`."+constants.ColumnUserId+
+			" AND "+constants.TableUserTag+"."+constants.ColumnTag+" in (?))", req.Tag)
+	}
+	if len(req.InGroupIds) > 0 {
+		chain.DB = chain.Where("EXISTS (SELECT 1 FROM "+db.AliasTable(constants.TableUserGroupBinding)+
+			" WHERE "+constants.TableUserGroupBinding+"."+constants.ColumnUserId+" = `"+constants.TableUser+"`."+constants.ColumnUserId+
+			" AND "+constants.TableUserGroupBinding+"."+constants.ColumnGroupId+" in (?)"+
+			" AND "+constants.TableUserGroupBinding+"."+constants.ColumnStatus+" = ?)", req.InGroupIds, constants.BindingStatusAccepted)
+	}
+	if req.JoinedGroupSince != nil || req.JoinedGroupBefore != nil {
+		condition := "EXISTS (SELECT 1 FROM " + db.AliasTable(constants.TableUserGroupBinding) +
+			" WHERE " + constants.TableUserGroupBinding + "." + constants.ColumnUserId + " = `" + constants.TableUser + "`." + constants.ColumnUserId +
+			" AND " + constants.TableUserGroupBinding + "." + constants.ColumnStatus + " = ?"
+		args := []interface{}{constants.BindingStatusAccepted}
+		if req.JoinedGroupSince != nil {
+			condition += " AND " + constants.TableUserGroupBinding + "." + constants.ColumnCreateTime + " >= ?"
+			args = append(args, timestampTime(req.JoinedGroupSince).UTC())
+		}
+		if req.JoinedGroupBefore != nil {
+			condition += " AND " + constants.TableUserGroupBinding + "." + constants.ColumnCreateTime + " < ?"
+			args = append(args, timestampTime(req.JoinedGroupBefore).UTC())
 		}
+		chain.DB = chain.Where(condition+")", args...)
+	}
+	return chain
+}
+
+// addGroupCountColumn joins the number of groups the users are accepted in as group_count,
+// when the users are sorted by it, the count is computed in the same query as the page
+func addGroupCountColumn(chain *db.Chain, req *pb.ListUsersRequest) *db.Chain {
+	if db.GetSortKeyFromRequest(req) != constants.SortKeyGroupCount {
+		return chain
+	}
+	chain.DB = chain.Select("`"+constants.TableUser+"`.*").
+		Joins("LEFT JOIN (SELECT user_id AS counted_user_id, COUNT(*) AS group_count FROM `"+
+			db.TableName(constants.TableUserGroupBinding)+"` WHERE status = ? GROUP BY user_id) AS group_counts "+
+			"ON group_counts.counted_user_id = `"+constants.TableUser+"`.user_id", constants.BindingStatusAccepted)
+	return chain
+}
+
+func ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	limit := db.GetLimitFromRequest(req)
+	offset := db.GetOffsetFromRequest(req)
+
+	var pbUsers []*pb.User
+
+	matched, err := resolveListUsersRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	// the bindings are not in the databases of the sharded users
+	if db.GetSortKeyFromRequest(req) == constants.SortKeyGroupCount && len(global.Global().Shards) > 0 {
+		err := status.Errorf(codes.Unimplemented, "sort key [%s] is not supported when the users are sharded", constants.SortKeyGroupCount)
+		logger.Errorf(ctx, "%+v", err)
+		return nil, err
+	}
+	if !matched {
+		return &pb.ListUsersResponse{
+			UserSet: pbUsers,
+			Total:   0,
+			Limit:   limit,
+			Offset:  offset,
+		}, nil
 	}
 
 	var users []*models.User
 	var count int
 
-	if err := db.GetChain(global.Global().Database.Table(constants.TableUser)).
-		AddQueryOrderDir(req, constants.ColumnCreateTime).
-		BuildFilterConditions(req, constants.TableUser).
-		Offset(offset).
-		Limit(limit).
-		Find(&users).Error; err != nil {
-		logger.Errorf(ctx, "List users failed: %+v", err)
-		return nil, err
+	// the users sharded across databases are merged, the page of the merged users
+	// is in the first offset+limit users of every shard
+	databases := global.Global().UserDatabases()
+	shardOffset, shardLimit := offset, limit
+	if len(databases) > 1 {
+		shardOffset, shardLimit = 0, offset+limit
 	}
+	for _, database := range databases {
+		var shardUsers []*models.User
+		var shardCount int
+		if limit == 0 {
+			// count only
+		} else if err := addGroupCountColumn(getListUsersChain(database, req), req).
+			AddSearchRankOrder(req, constants.TableUser).
+			AddQueryOrderDir(req, constants.TableUser, constants.ColumnCreateTime).
+			Offset(shardOffset).
+			Limit(shardLimit).
+			Find(&shardUsers).Error; err != nil {
+			logger.Errorf(ctx, "List users failed: %+v", err)
+			return nil, err
+		}
 
-	if err := db.GetChain(global.Global().Database.Table(constants.TableUser)).
-		BuildFilterConditions(req, constants.TableUser).
-		Count(&count).Error; err != nil {
-		logger.Errorf(ctx, "List users count failed: %+v", err)
-		return nil, err
+		if err := getListUsersChain(database, req).
+			Count(&shardCount).Error; err != nil {
+			logger.Errorf(ctx, "List users count failed: %+v", err)
+			return nil, err
+		}
+		users = append(users, shardUsers...)
+		count += shardCount
+	}
+	if len(databases) > 1 {
+		db.SortRows(users, req, constants.TableUser, constants.ColumnCreateTime)
+		if uint32(len(users)) > offset+limit {
+			users = users[:offset+limit]
+		}
+		if uint32(len(users)) > offset {
+			users = users[offset:]
+		} else {
+			users = nil
+		}
 	}
 
+	var highlights []*pb.SearchHighlight
 	for _, user := range users {
 		pbUsers = append(pbUsers, user.ToPB())
+		if req.Highlight {
+			highlights = append(highlights, &pb.SearchHighlight{
+				Id:     user.UserId,
+				Column: db.MatchedColumns(req, constants.TableUser, user),
+			})
+		}
 	}
 
 	return &pb.ListUsersResponse{
-		UserSet: pbUsers,
-		Total:   uint32(count),
+		UserSet:      pbUsers,
+		Total:        uint32(count),
+		Limit:        limit,
+		Offset:       offset,
+		HighlightSet: highlights,
 	}, nil
 }
 
+// CountUsers returns the number of users matching req, offset and limit are ignored
+func CountUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.CountUsersResponse, error) {
+	matched, err := resolveListUsersRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !matched {
+		return &pb.CountUsersResponse{Total: 0}, nil
+	}
+
+	var count int
+	for _, database := range global.Global().UserDatabases() {
+		var shardCount int
+		if err := getListUsersChain(database, req).
+			Count(&shardCount).Error; err != nil {
+			logger.Errorf(ctx, "Count users failed: %+v", err)
+			return nil, err
+		}
+		count += shardCount
+	}
+
+	return &pb.CountUsersResponse{Total: uint32(count)}, nil
+}
+
 func ListUsersWithGroup(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersWithGroupResponse, error) {
 	response, err := ListUsers(ctx, req)
 	if err != nil {
@@ -275,5 +746,54 @@ func ListUsersWithGroup(ctx context.Context, req *pb.ListUsersRequest) (*pb.List
 	return &pb.ListUsersWithGroupResponse{
 		UserSet: userWithGroups,
 		Total:   response.Total,
+		Limit:   response.Limit,
+		Offset:  response.Offset,
 	}, nil
 }
+
+func normalizePhoneNumber(ctx context.Context, phoneNumber string) (string, error) {
+	normalized, err := normalizePhone(phoneNumber)
+	if err != nil {
+		err = status.Errorf(codes.InvalidArgument, "%v", err)
+		logger.Errorf(ctx, "%+v", err)
+		return "", err
+	}
+	return normalized, nil
+}
+
+func normalizePhone(phoneNumber string) (string, error) {
+	if stringutil.SimplifyString(phoneNumber) == "" {
+		return "", nil
+	}
+	return phoneutil.Normalize(phoneNumber, global.Global().Config.DefaultCountryCode)
+}
+
+// phone number can be used to login, so it must be unique among the users not deleted,
+// the concurrent creations passing the check are rejected by user_active_phone_number_idx of their database
+func checkPhoneNumberUnique(ctx context.Context, phoneNumber, excludeUserId string) error {
+	if phoneNumber == "" {
+		return nil
+	}
+
+	var count int
+	for _, database := range global.Global().UserDatabases() {
+		var shardCount int
+		tx := database.Table(db.TableName(constants.TableUser)).
+			Where(constants.ColumnPhoneNumber+" = ?", phoneNumber).
+			Where(constants.ColumnStatus+" != ?", constants.StatusDeleted)
+		if excludeUserId != "" {
+			tx = tx.Where(constants.ColumnUserId+" != ?", excludeUserId)
+		}
+		if err := tx.Count(&shardCount).Error; err != nil {
+			logger.Errorf(ctx, "Get user count by phone number [%s] failed: %+v", phoneNumber, err)
+			return err
+		}
+		count += shardCount
+	}
+	if count > 0 {
+		err := status.Errorf(codes.AlreadyExists, "phone number [%s] already exists", phoneNumber)
+		logger.Errorf(ctx, "%+v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,1311 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"cloudbases.io/im/pkg/config"
+	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/db"
+	"cloudbases.io/im/pkg/global"
+	"cloudbases.io/im/pkg/models"
+	"cloudbases.io/im/pkg/pb"
+	"cloudbases.io/im/pkg/util/jsonutil"
+)
+
+func createTestUser(t *testing.T, username, phoneNumber string) string {
+	response, err := CreateUser(context.Background(), &pb.CreateUserRequest{
+		Username:    username,
+		Email:       username + "@op.com",
+		PhoneNumber: phoneNumber,
+		Password:    "t0p-secret",
+	})
+	require.NoError(t, err)
+	return response.UserId
+}
+
+func TestCreateUserNormalizePhoneNumber(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	userId := createTestUser(t, "phone", "+86 100-0000-0000")
+	user, err := GetUser(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, "+8610000000000", user.PhoneNumber)
+
+	_, err = ModifyUser(ctx, &pb.ModifyUserRequest{
+		UserId:      userId,
+		PhoneNumber: "0086 11111111111",
+	})
+	require.NoError(t, err)
+	user, err = GetUser(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, "+8611111111111", user.PhoneNumber)
+}
+
+func TestCreateUserInvalidPhoneNumber(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	_, err := CreateUser(ctx, &pb.CreateUserRequest{
+		Username:    "phone",
+		Email:       "phone@op.com",
+		PhoneNumber: "not a number",
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	userId := createTestUser(t, "phone", "")
+	_, err = ModifyUser(ctx, &pb.ModifyUserRequest{
+		UserId:      userId,
+		PhoneNumber: "12345",
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestPhoneNumberUnique(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	userId := createTestUser(t, "phone1", "10000000000")
+
+	_, err := CreateUser(ctx, &pb.CreateUserRequest{
+		Username:    "phone2",
+		Email:       "phone2@op.com",
+		PhoneNumber: "+86 10000000000",
+	})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	otherUserId := createTestUser(t, "phone2", "11111111111")
+	_, err = ModifyUser(ctx, &pb.ModifyUserRequest{
+		UserId:      otherUserId,
+		PhoneNumber: "10000000000",
+	})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	// keep own phone number is allowed
+	_, err = ModifyUser(ctx, &pb.ModifyUserRequest{
+		UserId:      userId,
+		PhoneNumber: "10000000000",
+	})
+	require.NoError(t, err)
+
+	// phone number of deleted user can be reused
+	_, err = DeleteUsers(ctx, &pb.DeleteUsersRequest{UserId: []string{userId}})
+	require.NoError(t, err)
+	createTestUser(t, "phone3", "10000000000")
+}
+
+func TestPhoneNumberUniqueIndex(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	userId := createTestUser(t, "phone1", "+8610000000000")
+
+	// the concurrent creations pass checkPhoneNumberUnique together, the index rejects the latter
+	user := models.NewUser("phone2", "phone2@op.com", "+8610000000000", "", "t0p-secret", nil)
+	err := db.MapError(global.Global().UserDatabase(user.UserId).Create(user).Error)
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	// users without phone number are not indexed
+	createTestUser(t, "empty1", "")
+	createTestUser(t, "empty2", "")
+
+	_, err = DeleteUsers(ctx, &pb.DeleteUsersRequest{UserId: []string{userId}})
+	require.NoError(t, err)
+	user = models.NewUser("phone3", "phone3@op.com", "+8610000000000", "", "t0p-secret", nil)
+	require.NoError(t, global.Global().UserDatabase(user.UserId).Create(user).Error)
+}
+
+func TestGetUserByPhoneNumber(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	userId := createTestUser(t, "phone", "10000000000")
+
+	user, err := GetUserByPhoneNumber(ctx, "+86 100 0000 0000")
+	require.NoError(t, err)
+	require.Equal(t, userId, user.UserId)
+
+	response, err := ComparePassword(ctx, &pb.ComparePasswordRequest{
+		PhoneNumber: "10000000000",
+		Password:    "t0p-secret",
+	})
+	require.NoError(t, err)
+	require.True(t, response.Ok)
+
+	response, err = ComparePassword(ctx, &pb.ComparePasswordRequest{
+		PhoneNumber: "10000000000",
+		Password:    "wrong",
+	})
+	require.NoError(t, err)
+	require.False(t, response.Ok)
+
+	_, err = GetUserByPhoneNumber(ctx, "11111111111")
+	require.Error(t, err)
+}
+
+func TestListUsersSearchGroupName(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	userId := createTestUser(t, "alice", "")
+	createTestUser(t, "bob", "")
+	group1 := createTestGroup(t, "developer", "")
+	group2 := createTestGroup(t, "developer-lead", "")
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{userId},
+		GroupId: []string{group1, group2},
+	})
+	require.NoError(t, err)
+
+	// group name is not searched by default
+	response, err := ListUsers(ctx, &pb.ListUsersRequest{
+		SearchWord: []string{"developer"},
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 0, response.Total)
+
+	response, err = ListUsers(ctx, &pb.ListUsersRequest{
+		SearchWord:      []string{"developer"},
+		SearchGroupName: true,
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, response.Total)
+	require.Len(t, response.UserSet, 1)
+	require.Equal(t, userId, response.UserSet[0].UserId)
+
+	// user columns are still searched
+	response, err = ListUsers(ctx, &pb.ListUsersRequest{
+		SearchWord:      []string{"bob"},
+		SearchGroupName: true,
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, response.Total)
+
+	// the search words are arguments of the query, not sql
+	response, err = ListUsers(ctx, &pb.ListUsersRequest{
+		SearchWord:      []string{"x')", "OR", "('1'='1"},
+		SearchGroupName: true,
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 0, response.Total)
+}
+
+func TestListUsersSearchFullName(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	userIds := make(map[string]string)
+	for _, name := range [][2]string{{"Jane", "Doe"}, {"Jane", "Smith"}, {"John", "Doe"}} {
+		response, err := CreateUser(ctx, &pb.CreateUserRequest{
+			Username:  strings.ToLower(name[0] + "_" + name[1]),
+			Email:     strings.ToLower(name[0]+"."+name[1]) + "@op.com",
+			Password:  "passw0rd",
+			FirstName: name[0],
+			LastName:  name[1],
+		})
+		require.NoError(t, err)
+		userIds[name[0]+" "+name[1]] = response.UserId
+	}
+
+	var tests = []struct {
+		searchWord []string
+		searchMode string
+		expect     []string
+	}{
+		{searchWord: []string{"Jane Doe"}, expect: []string{"Jane Doe"}},
+		{searchWord: []string{"jane", "doe"}, expect: []string{"Jane Doe"}},
+		// the short word is matched with the others against the full name
+		{searchWord: []string{"Jane D"}, expect: []string{"Jane Doe"}},
+		{searchWord: []string{"Jane S"}, searchMode: constants.SearchModePrefix, expect: []string{"Jane Smith"}},
+		{searchWord: []string{"ne Do"}, searchMode: constants.SearchModePrefix, expect: nil},
+		{searchWord: []string{"Doe"}, expect: []string{"Jane Doe", "John Doe"}},
+	}
+	for _, v := range tests {
+		response, err := ListUsers(ctx, &pb.ListUsersRequest{SearchWord: v.searchWord, SearchMode: v.searchMode})
+		require.NoError(t, err)
+		var expectIds, userIdSet []string
+		for _, name := range v.expect {
+			expectIds = append(expectIds, userIds[name])
+		}
+		for _, user := range response.UserSet {
+			userIdSet = append(userIdSet, user.UserId)
+		}
+		require.ElementsMatch(t, expectIds, userIdSet, "%v", v.searchWord)
+	}
+
+	response, err := GetUser(ctx, userIds["Jane Doe"])
+	require.NoError(t, err)
+	require.Equal(t, "Jane", response.FirstName)
+	require.Equal(t, "Doe", response.LastName)
+
+	_, err = ModifyUser(ctx, &pb.ModifyUserRequest{UserId: userIds["Jane Doe"], LastName: "Roe"})
+	require.NoError(t, err)
+	listResponse, err := ListUsers(ctx, &pb.ListUsersRequest{SearchWord: []string{"Jane R"}})
+	require.NoError(t, err)
+	require.Len(t, listResponse.UserSet, 1)
+	require.Equal(t, userIds["Jane Doe"], listResponse.UserSet[0].UserId)
+
+	// the phrase is an argument of the full name condition, not sql
+	listResponse, err = ListUsers(ctx, &pb.ListUsersRequest{SearchWord: []string{"Jane' OR '1'='1"}})
+	require.NoError(t, err)
+	require.Empty(t, listResponse.UserSet)
+}
+
+func TestListUsersExtraSearchColumns(t *testing.T) {
+	cfg := config.Default()
+	cfg.DB.Type = "sqlite3"
+	cfg.DB.Database = filepath.Join(t.TempDir(), "im.db")
+	cfg.DB.AutoMigrate = true
+	cfg.DB.UserSearchColumns = "description, password, unknown"
+	global.SetGlobal(cfg)
+	t.Cleanup(func() {
+		global.Global().Close()
+	})
+	ctx := context.Background()
+
+	// the password and the unknown column are skipped
+	require.Equal(t, []string{constants.ColumnDescription}, db.ExtraSearchColumns[constants.TableUser])
+
+	response, err := CreateUser(ctx, &pb.CreateUserRequest{
+		Username:    "alice",
+		Email:       "alice@op.com",
+		Password:    "t0p-secret",
+		Description: "on-call engineer",
+	})
+	require.NoError(t, err)
+	createTestUser(t, "bob", "")
+
+	listResponse, err := ListUsers(ctx, &pb.ListUsersRequest{
+		SearchWord: []string{"engineer"},
+		Highlight:  true,
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, listResponse.Total)
+	require.Equal(t, response.UserId, listResponse.UserSet[0].UserId)
+	require.Equal(t, []string{constants.ColumnDescription}, listResponse.HighlightSet[0].Column)
+
+	// the built-in search columns are still searched
+	listResponse, err = ListUsers(ctx, &pb.ListUsersRequest{
+		SearchWord: []string{"bob"},
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, listResponse.Total)
+}
+
+func TestModifyUserVersion(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	userId := createTestUser(t, "version", "")
+	user, err := GetUser(ctx, userId)
+	require.NoError(t, err)
+	require.EqualValues(t, 0, user.Version)
+
+	response, err := ModifyUser(ctx, &pb.ModifyUserRequest{
+		UserId:      userId,
+		Description: "without version",
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, response.Version)
+
+	// two updates based on the same version, only one of them wins
+	results := make(chan error, 2)
+	for _, description := range []string{"first", "second"} {
+		go func(description string) {
+			_, err := ModifyUser(ctx, &pb.ModifyUserRequest{
+				UserId:      userId,
+				Description: description,
+				Version:     &wrappers.UInt32Value{Value: response.Version},
+			})
+			results <- err
+		}(description)
+	}
+	var codeList []codes.Code
+	for i := 0; i < 2; i++ {
+		codeList = append(codeList, status.Code(<-results))
+	}
+	require.ElementsMatch(t, []codes.Code{codes.OK, codes.Aborted}, codeList)
+
+	user, err = GetUser(ctx, userId)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, user.Version)
+	require.EqualValues(t, 2, user.ToPB().Version)
+
+	// stale version
+	_, err = ModifyUser(ctx, &pb.ModifyUserRequest{
+		UserId:      userId,
+		Description: "stale",
+		Version:     &wrappers.UInt32Value{Value: 1},
+	})
+	require.Equal(t, codes.Aborted, status.Code(err))
+}
+
+func TestListUsersCreatedInDays(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	for username, createTime := range map[string]time.Time{
+		"today":      now,
+		"inside":     now.AddDate(0, 0, -7).Add(time.Hour),
+		"outside":    now.AddDate(0, 0, -7).Add(-time.Hour),
+		"last_month": now.AddDate(0, -1, 0),
+	} {
+		userId := createTestUser(t, username, "")
+		require.NoError(t, global.Global().Database.Table(constants.TableUser).
+			Where(constants.ColumnUserId+" = ?", userId).
+			Update(constants.ColumnCreateTime, createTime).Error)
+	}
+
+	response, err := ListUsers(ctx, &pb.ListUsersRequest{
+		CreatedInDays: 7,
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, response.Total)
+	var usernames []string
+	for _, user := range response.UserSet {
+		usernames = append(usernames, user.Username)
+	}
+	require.ElementsMatch(t, []string{"today", "inside"}, usernames)
+
+	// no filter by default
+	response, err = ListUsers(ctx, &pb.ListUsersRequest{})
+	require.NoError(t, err)
+	require.EqualValues(t, 4, response.Total)
+}
+
+func TestListUsersSortByGroupCount(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	groupIds := []string{createTestGroup(t, "g1", ""), createTestGroup(t, "g2", ""), createTestGroup(t, "g3", "")}
+	user1 := createTestUser(t, "u1", "")
+	user2 := createTestUser(t, "u2", "")
+	user3 := createTestUser(t, "u3", "")
+	createTestUser(t, "u4", "")
+
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{user2}, GroupId: groupIds})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{user3}, GroupId: groupIds[:2]})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{user1}, GroupId: groupIds[:1]})
+	require.NoError(t, err)
+	// pending invitations are not counted
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{user1},
+		GroupId: groupIds[1:],
+		Status:  constants.BindingStatusPending,
+	})
+	require.NoError(t, err)
+
+	usernames := func(req *pb.ListUsersRequest) []string {
+		response, err := ListUsers(ctx, req)
+		require.NoError(t, err)
+		require.EqualValues(t, 4, response.Total)
+		var names []string
+		for _, user := range response.UserSet {
+			names = append(names, user.Username)
+		}
+		return names
+	}
+
+	require.Equal(t, []string{"u2", "u3"}, usernames(&pb.ListUsersRequest{SortKey: constants.SortKeyGroupCount, Limit: 2}))
+	require.Equal(t, []string{"u1", "u4"}, usernames(&pb.ListUsersRequest{SortKey: constants.SortKeyGroupCount, Offset: 2, Limit: 2}))
+	require.Equal(t, []string{"u4", "u1", "u3", "u2"}, usernames(&pb.ListUsersRequest{SortKey: constants.SortKeyGroupCount, Reverse: true}))
+}
+
+func TestCountUsers(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	groupId := createTestGroup(t, "count", "")
+	var userIds []string
+	for _, username := range []string{"count1", "count2", "count3", "other"} {
+		userIds = append(userIds, createTestUser(t, username, ""))
+	}
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  userIds[:2],
+		GroupId: []string{groupId},
+	})
+	require.NoError(t, err)
+	_, err = DeleteUsers(ctx, &pb.DeleteUsersRequest{UserId: userIds[2:3]})
+	require.NoError(t, err)
+
+	var tests = []*pb.ListUsersRequest{
+		{},
+		{SearchWord: []string{"count"}},
+		{Status: []string{constants.StatusActive}},
+		{GroupId: []string{groupId}},
+		{RootGroupId: []string{groupId}, UserId: userIds[1:]},
+		{GroupId: []string{"gid-unknown"}},
+	}
+	for _, req := range tests {
+		listReq := *req
+		listReq.Limit = 200
+		listResponse, err := ListUsers(ctx, &listReq)
+		require.NoError(t, err)
+
+		countResponse, err := CountUsers(ctx, req)
+		require.NoError(t, err)
+		require.EqualValues(t, len(listResponse.UserSet), countResponse.Total, "%+v", req)
+		require.Equal(t, listResponse.Total, countResponse.Total, "%+v", req)
+	}
+
+	response, err := CountUsers(ctx, &pb.ListUsersRequest{Status: []string{constants.StatusActive}})
+	require.NoError(t, err)
+	require.EqualValues(t, 3, response.Total)
+}
+
+func TestListUsersByStatuses(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	active := createTestUser(t, "active", "")
+	deleted := createTestUser(t, "deleted", "")
+	_, err := DeleteUsers(ctx, &pb.DeleteUsersRequest{UserId: []string{deleted}})
+	require.NoError(t, err)
+
+	var tests = []struct {
+		status []string
+		expect []string
+	}{
+		{status: []string{constants.StatusActive, constants.StatusDeleted}, expect: []string{active, deleted}},
+		{status: []string{constants.StatusActive}, expect: []string{active}},
+		// empty statuses are ignored
+		{status: []string{"", constants.StatusDeleted}, expect: []string{deleted}},
+		{status: []string{"unknown"}, expect: nil},
+	}
+	for _, v := range tests {
+		response, err := ListUsers(ctx, &pb.ListUsersRequest{Status: v.status})
+		require.NoError(t, err)
+		var userIds []string
+		for _, user := range response.UserSet {
+			userIds = append(userIds, user.UserId)
+		}
+		require.ElementsMatch(t, v.expect, userIds, "%v", v.status)
+		require.EqualValues(t, len(v.expect), response.Total, "%v", v.status)
+	}
+}
+
+func TestListUsersIsEmpty(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	withPhone := createTestUser(t, "with_phone", "10000000000")
+	withoutPhone := createTestUser(t, "without_phone", "")
+	deleted := createTestUser(t, "deleted", "")
+	_, err := DeleteUsers(ctx, &pb.DeleteUsersRequest{UserId: []string{deleted}})
+	require.NoError(t, err)
+	// phone number of legacy users may be NULL
+	legacy := createTestUser(t, "legacy", "")
+	require.NoError(t, global.Global().Database.Exec(
+		"UPDATE user SET phone_number = NULL WHERE user_id = ?", legacy).Error)
+
+	listUserIds := func(req *pb.ListUsersRequest) []string {
+		response, err := ListUsers(ctx, req)
+		require.NoError(t, err)
+		var userIds []string
+		for _, user := range response.UserSet {
+			userIds = append(userIds, user.UserId)
+		}
+		return userIds
+	}
+
+	require.ElementsMatch(t, []string{withoutPhone, deleted, legacy}, listUserIds(&pb.ListUsersRequest{
+		PhoneNumberIsEmpty: true,
+	}))
+	require.ElementsMatch(t, []string{withoutPhone, legacy}, listUserIds(&pb.ListUsersRequest{
+		PhoneNumberIsEmpty: true,
+		Status:             []string{constants.StatusActive},
+	}))
+	require.ElementsMatch(t, []string{withoutPhone}, listUserIds(&pb.ListUsersRequest{
+		PhoneNumberIsEmpty: true,
+		Email:              []string{"without_phone@op.com"},
+	}))
+	require.Empty(t, listUserIds(&pb.ListUsersRequest{
+		EmailIsEmpty: true,
+	}))
+	require.ElementsMatch(t, []string{withPhone, withoutPhone, deleted, legacy}, listUserIds(&pb.ListUsersRequest{}))
+}
+
+func TestListUsersLimitOffset(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	createTestUser(t, "limit", "")
+
+	response, err := ListUsers(ctx, &pb.ListUsersRequest{})
+	require.NoError(t, err)
+	require.Equal(t, db.DefaultLimit, response.Limit)
+	require.Equal(t, db.DefaultOffset, response.Offset)
+
+	response, err = ListUsers(ctx, &pb.ListUsersRequest{Limit: db.DefaultSelectLimit + 1, Offset: 10})
+	require.NoError(t, err)
+	require.EqualValues(t, db.DefaultSelectLimit, response.Limit)
+	require.EqualValues(t, 10, response.Offset)
+	require.Empty(t, response.UserSet)
+
+	// no user matches
+	response, err = ListUsers(ctx, &pb.ListUsersRequest{GroupId: []string{"gid-unknown"}, Limit: 1000})
+	require.NoError(t, err)
+	require.EqualValues(t, db.DefaultSelectLimit, response.Limit)
+
+	withGroupResponse, err := ListUsersWithGroup(ctx, &pb.ListUsersRequest{Limit: 1000, Offset: 1})
+	require.NoError(t, err)
+	require.EqualValues(t, db.DefaultSelectLimit, withGroupResponse.Limit)
+	require.EqualValues(t, 1, withGroupResponse.Offset)
+}
+
+func TestListUsersCountOnly(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	createTestUser(t, "count1", "")
+	createTestUser(t, "count2", "")
+
+	// unspecified limit
+	response, err := ListUsers(ctx, &pb.ListUsersRequest{})
+	require.NoError(t, err)
+	require.Equal(t, db.DefaultLimit, response.Limit)
+	require.EqualValues(t, 2, response.Total)
+	require.Len(t, response.UserSet, 2)
+
+	// explicit small limit
+	response, err = ListUsers(ctx, &pb.ListUsersRequest{Limit: 1})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, response.Limit)
+	require.EqualValues(t, 2, response.Total)
+	require.Len(t, response.UserSet, 1)
+
+	// count only wins over limit
+	response, err = ListUsers(ctx, &pb.ListUsersRequest{Limit: 1, CountOnly: true})
+	require.NoError(t, err)
+	require.EqualValues(t, 0, response.Limit)
+	require.EqualValues(t, 2, response.Total)
+	require.Empty(t, response.UserSet)
+}
+
+func TestListUsersRankSearch(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	jimbob := createTestUser(t, "jimbob", "")
+	bobby := createTestUser(t, "bobby", "")
+	bob := createTestUser(t, "bob", "")
+
+	response, err := ListUsers(ctx, &pb.ListUsersRequest{
+		SearchWord: []string{"bob"},
+		RankSearch: true,
+		Reverse:    true,
+	})
+	require.NoError(t, err)
+	var userIds []string
+	for _, user := range response.UserSet {
+		userIds = append(userIds, user.UserId)
+	}
+	require.Equal(t, []string{bob, bobby, jimbob}, userIds)
+}
+
+func TestListUsersSearchMode(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	createTestUser(t, "anna", "")
+	createTestUser(t, "hanna", "")
+	createTestUser(t, "annabel", "")
+
+	usernames := func(mode string) []string {
+		response, err := ListUsers(ctx, &pb.ListUsersRequest{
+			SearchWord: []string{"anna"},
+			SearchMode: mode,
+			SortKey:    constants.ColumnUsername,
+		})
+		require.NoError(t, err)
+		var names []string
+		for _, user := range response.UserSet {
+			names = append(names, user.Username)
+		}
+		return names
+	}
+	require.ElementsMatch(t, []string{"anna", "hanna", "annabel"}, usernames(""))
+	require.ElementsMatch(t, []string{"anna", "annabel"}, usernames(constants.SearchModePrefix))
+	require.ElementsMatch(t, []string{"anna", "hanna"}, usernames(constants.SearchModeSuffix))
+
+	_, err := ListUsers(ctx, &pb.ListUsersRequest{SearchWord: []string{"anna"}, SearchMode: "fuzzy"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = CountGroups(ctx, &pb.ListGroupsRequest{SearchMode: "fuzzy"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestUserAvatarUrl(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	response, err := CreateUser(ctx, &pb.CreateUserRequest{
+		Username:  "avatar",
+		Email:     "avatar@op.com",
+		AvatarUrl: "https://cdn.op.com/avatars/avatar.png",
+	})
+	require.NoError(t, err)
+	userId := response.UserId
+	avatarUrl := func() string {
+		user, err := GetUser(ctx, userId)
+		require.NoError(t, err)
+		return user.ToPB().AvatarUrl
+	}
+	require.Equal(t, "https://cdn.op.com/avatars/avatar.png", avatarUrl())
+
+	// object store key
+	_, err = ModifyUser(ctx, &pb.ModifyUserRequest{
+		UserId:    userId,
+		AvatarUrl: &wrappers.StringValue{Value: "avatars/u-1.png"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "avatars/u-1.png", avatarUrl())
+
+	// unchanged without avatar url
+	_, err = ModifyUser(ctx, &pb.ModifyUserRequest{UserId: userId, Description: "modified"})
+	require.NoError(t, err)
+	require.Equal(t, "avatars/u-1.png", avatarUrl())
+
+	// cleared by empty value
+	_, err = ModifyUser(ctx, &pb.ModifyUserRequest{
+		UserId:    userId,
+		AvatarUrl: &wrappers.StringValue{},
+	})
+	require.NoError(t, err)
+	require.Empty(t, avatarUrl())
+
+	for _, invalid := range []string{
+		"ftp://cdn.op.com/avatar.png",
+		"https:///avatar.png",
+		"/avatars/avatar.png",
+		"avatars/../secret",
+		"avatar with space.png",
+		strings.Repeat("a", 1001),
+	} {
+		_, err = ModifyUser(ctx, &pb.ModifyUserRequest{
+			UserId:    userId,
+			AvatarUrl: &wrappers.StringValue{Value: invalid},
+		})
+		require.Equal(t, []string{"avatar_url"}, violatedFields(t, err), invalid)
+	}
+	_, err = CreateUser(ctx, &pb.CreateUserRequest{
+		Username:  "invalid",
+		Email:     "invalid@op.com",
+		AvatarUrl: "javascript:alert(1)",
+	})
+	require.Equal(t, []string{"avatar_url"}, violatedFields(t, err))
+}
+
+func TestGetInactiveUsers(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	active := createTestUser(t, "active", "")
+	stale := createTestUser(t, "stale", "")
+	never := createTestUser(t, "never", "")
+	deleted := createTestUser(t, "deleted", "")
+
+	for _, userId := range []string{active, stale} {
+		response, err := ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: userId, Password: "t0p-secret"})
+		require.NoError(t, err)
+		require.True(t, response.Ok)
+	}
+	// a failed login is not tracked
+	_, err := ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: never, Password: "wrong"})
+	require.NoError(t, err)
+
+	user, err := GetUser(ctx, active)
+	require.NoError(t, err)
+	require.NotNil(t, user.LastLoginAt)
+	require.NotNil(t, user.ToPB().LastLoginAt)
+	user, err = GetUser(ctx, never)
+	require.NoError(t, err)
+	require.Nil(t, user.LastLoginAt)
+	require.Nil(t, user.ToPB().LastLoginAt)
+
+	require.NoError(t, global.Global().Database.Table(constants.TableUser).
+		Where(constants.ColumnUserId+" = ?", stale).
+		Update(constants.ColumnLastLoginAt, time.Now().AddDate(0, -3, 0)).Error)
+	_, err = DeleteUsers(ctx, &pb.DeleteUsersRequest{UserId: []string{deleted}})
+	require.NoError(t, err)
+
+	users, err := GetInactiveUsers(ctx, time.Now().AddDate(0, -1, 0))
+	require.NoError(t, err)
+	var userIds []string
+	for _, user := range users {
+		userIds = append(userIds, user.UserId)
+	}
+	require.ElementsMatch(t, []string{stale, never}, userIds)
+}
+
+func TestListUsersHighlight(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	byName, err := CreateUser(ctx, &pb.CreateUserRequest{Username: "marketing-bob", Email: "bob@op.com"})
+	require.NoError(t, err)
+	byEmail, err := CreateUser(ctx, &pb.CreateUserRequest{Username: "alice", Email: "alice@marketing.com"})
+	require.NoError(t, err)
+	byBoth, err := CreateUser(ctx, &pb.CreateUserRequest{Username: "Marketing", Email: "marketing@op.com"})
+	require.NoError(t, err)
+	createTestUser(t, "carol", "")
+
+	response, err := ListUsers(ctx, &pb.ListUsersRequest{SearchWord: []string{"marketing"}, Highlight: true})
+	require.NoError(t, err)
+	require.EqualValues(t, 3, response.Total)
+	require.Len(t, response.HighlightSet, 3)
+	matched := make(map[string][]string)
+	for i, highlight := range response.HighlightSet {
+		require.Equal(t, response.UserSet[i].UserId, highlight.Id)
+		matched[highlight.Id] = highlight.Column
+	}
+	require.Equal(t, map[string][]string{
+		byName.UserId:  {constants.ColumnUsername},
+		byEmail.UserId: {constants.ColumnEmail},
+		byBoth.UserId:  {constants.ColumnUsername, constants.ColumnEmail},
+	}, matched)
+
+	// prefix mode
+	response, err = ListUsers(ctx, &pb.ListUsersRequest{
+		SearchWord: []string{"marketing"},
+		SearchMode: constants.SearchModePrefix,
+		Highlight:  true,
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, response.Total)
+	for _, highlight := range response.HighlightSet {
+		if highlight.Id == byBoth.UserId {
+			require.Equal(t, []string{constants.ColumnUsername, constants.ColumnEmail}, highlight.Column)
+		} else {
+			require.Equal(t, byName.UserId, highlight.Id)
+			require.Equal(t, []string{constants.ColumnUsername}, highlight.Column)
+		}
+	}
+
+	// not requested
+	response, err = ListUsers(ctx, &pb.ListUsersRequest{SearchWord: []string{"marketing"}})
+	require.NoError(t, err)
+	require.Empty(t, response.HighlightSet)
+}
+
+func TestGetUserByExternalId(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, "alice", "")
+	bob := createTestUser(t, "bob", "")
+
+	_, err := GetUserByExternalId(ctx, "github", "1001")
+	require.True(t, gorm.IsRecordNotFoundError(err))
+
+	require.NoError(t, LinkExternalId(ctx, alice, "github", "1001"))
+	require.NoError(t, LinkExternalId(ctx, bob, "gitlab", "1001"))
+	user, err := GetUserByExternalId(ctx, "github", "1001")
+	require.NoError(t, err)
+	require.Equal(t, alice, user.UserId)
+	require.Equal(t, "github", user.ToPB().ExternalProvider)
+	require.Equal(t, "1001", user.ToPB().ExternalId)
+	user, err = GetUserByExternalId(ctx, "gitlab", "1001")
+	require.NoError(t, err)
+	require.Equal(t, bob, user.UserId)
+
+	// a subject of a provider is linked to one user
+	err = LinkExternalId(ctx, bob, "github", "1001")
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+	// the unique index refuses the collision written behind the check too
+	require.Error(t, global.Global().Database.Table(constants.TableUser).
+		Where(constants.ColumnUserId+" = ?", bob).
+		Updates(map[string]interface{}{constants.ColumnExternalProvider: "github", constants.ColumnExternalId: "1001"}).Error)
+
+	// relinking replaces the previous link
+	require.NoError(t, LinkExternalId(ctx, alice, "github", "1002"))
+	_, err = GetUserByExternalId(ctx, "github", "1001")
+	require.True(t, gorm.IsRecordNotFoundError(err))
+
+	err = LinkExternalId(ctx, "uid-missing", "github", "1003")
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.Equal(t, []string{"external_id"}, violatedFields(t, LinkExternalId(ctx, alice, "github", "")))
+}
+
+func TestGetOrCreateFederatedUser(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	profile := func(username string) *pb.CreateUserRequest {
+		return &pb.CreateUserRequest{Username: username, Email: username + "@op.com"}
+	}
+	user, created, err := GetOrCreateFederatedUser(ctx, "github", "1001", profile("alice"))
+	require.NoError(t, err)
+	require.True(t, created)
+	require.Equal(t, "alice", user.Username)
+
+	// the profile of a known subject is not used
+	again, created, err := GetOrCreateFederatedUser(ctx, "github", "1001", profile("bob"))
+	require.NoError(t, err)
+	require.False(t, created)
+	require.Equal(t, user.UserId, again.UserId)
+
+	_, _, err = GetOrCreateFederatedUser(ctx, "github", "1002", &pb.CreateUserRequest{Email: "invalid"})
+	require.ElementsMatch(t, []string{"username", "email"}, violatedFields(t, err))
+}
+
+func TestGetOrCreateFederatedUserConcurrently(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	// concurrent first logins of one subject, only one of them creates the user
+	type result struct {
+		user    *models.User
+		created bool
+		err     error
+	}
+	const logins = 8
+	results := make(chan result, logins)
+	for i := 0; i < logins; i++ {
+		go func(username string) {
+			user, created, err := GetOrCreateFederatedUser(ctx, "github", "2001",
+				&pb.CreateUserRequest{Username: username, Email: username + "@op.com"})
+			results <- result{user, created, err}
+		}(fmt.Sprintf("carol%d", i))
+	}
+	userIds := make(map[string]bool)
+	createdCount := 0
+	for i := 0; i < logins; i++ {
+		r := <-results
+		require.NoError(t, r.err)
+		userIds[r.user.UserId] = true
+		if r.created {
+			createdCount++
+		}
+	}
+	require.Equal(t, 1, createdCount)
+	require.Len(t, userIds, 1)
+
+	var count int
+	require.NoError(t, global.Global().Database.Table(constants.TableUser).
+		Where(constants.ColumnExternalId+" = ?", "2001").
+		Count(&count).Error)
+	require.Equal(t, 1, count)
+}
+
+func TestUserPasswordNotSerialized(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	userId := createTestUser(t, "secret", "13900000001")
+	groupId := createTestGroup(t, "group", "")
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{userId}, GroupId: []string{groupId}})
+	require.NoError(t, err)
+
+	user, err := GetUser(ctx, userId)
+	require.NoError(t, err)
+	hash := user.Password
+	require.NotEmpty(t, hash)
+
+	userWithGroup, err := GetUserWithGroup(ctx, userId)
+	require.NoError(t, err)
+	members, err := GetUsersByGroupIds(ctx, []string{groupId})
+	require.NoError(t, err)
+	listUsers, err := ListUsers(ctx, &pb.ListUsersRequest{UserId: []string{userId}})
+	require.NoError(t, err)
+	listUsersWithGroup, err := ListUsersWithGroup(ctx, &pb.ListUsersRequest{UserId: []string{userId}})
+	require.NoError(t, err)
+	compare, err := ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: userId, Password: "t0p-secret", WithUser: true})
+	require.NoError(t, err)
+
+	for _, v := range []interface{}{
+		user, user.ToPB(), userWithGroup, userWithGroup.ToPB(), members,
+		listUsers, listUsersWithGroup, compare,
+	} {
+		s := jsonutil.ToString(v)
+		require.Contains(t, s, userId)
+		require.NotContains(t, s, hash)
+	}
+}
+
+func TestGetUserProfile(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	userId := createTestUser(t, "profile", "13900000001")
+	groupIds := []string{createTestGroup(t, "g1", ""), createTestGroup(t, "g2", "")}
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{userId}, GroupId: groupIds})
+	require.NoError(t, err)
+	user, err := GetUser(ctx, userId)
+	require.NoError(t, err)
+
+	profile, err := GetUserProfile(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, userId, profile.User.UserId)
+	require.Equal(t, "profile", profile.User.Username)
+	require.Equal(t, "profile@op.com", profile.User.Email)
+	require.Len(t, profile.GroupSet, 2)
+	require.ElementsMatch(t, groupIds, []string{profile.GroupSet[0].GroupId, profile.GroupSet[1].GroupId})
+	require.NotContains(t, jsonutil.ToString(profile), user.Password)
+
+	_, err = GetUserProfile(ctx, "usr-missing")
+	require.True(t, gorm.IsRecordNotFoundError(err))
+}
+
+func TestShardedUsers(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.DB.Type = "sqlite3"
+	cfg.DB.Database = filepath.Join(dir, "im.db")
+	cfg.DB.ShardDatabases = filepath.Join(dir, "shard0.db") + "," + filepath.Join(dir, "shard1.db")
+	cfg.DB.AutoMigrate = true
+	global.SetGlobal(cfg)
+	t.Cleanup(func() {
+		global.Global().Close()
+	})
+	ctx := context.Background()
+	shards := global.Global().Shards
+	require.Len(t, shards, 2)
+
+	userCount := func(database *db.Database, userId string) int {
+		var count int
+		require.NoError(t, database.Table(db.TableName(constants.TableUser)).
+			Where(constants.ColumnUserId+" = ?", userId).Count(&count).Error)
+		return count
+	}
+
+	// the users are created in the shard of their id, until both shards hold two of them
+	var userIds, usernames []string
+	shardSizes := make([]int, 2)
+	for i := 0; shardSizes[0] < 2 || shardSizes[1] < 2; i++ {
+		require.True(t, i < 32)
+		username := fmt.Sprintf("user%02d", i)
+		userId := createTestUser(t, username, "")
+		index := db.ShardIndex(userId, 2)
+		require.Equal(t, 1, userCount(shards[index], userId))
+		require.Equal(t, 0, userCount(shards[1-index], userId))
+		require.Equal(t, 0, userCount(global.Global().Database, userId))
+		shardSizes[index]++
+		userIds = append(userIds, userId)
+		usernames = append(usernames, username)
+	}
+
+	// the operations on a user are routed to its shard
+	for _, userId := range userIds {
+		_, err := ModifyUser(ctx, &pb.ModifyUserRequest{UserId: userId, Description: "sharded"})
+		require.NoError(t, err)
+		user, err := GetUser(ctx, userId)
+		require.NoError(t, err)
+		require.Equal(t, "sharded", user.Description)
+		response, err := ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: userId, Password: "t0p-secret"})
+		require.NoError(t, err)
+		require.True(t, response.Ok)
+	}
+
+	// the lists fan out to the shards and merge their users
+	response, err := ListUsers(ctx, &pb.ListUsersRequest{
+		SortKey: constants.ColumnUsername,
+		Reverse: true,
+		Offset:  1,
+		Limit:   3,
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, len(userIds), response.Total)
+	var names []string
+	for _, user := range response.UserSet {
+		names = append(names, user.Username)
+	}
+	require.Equal(t, usernames[1:4], names)
+
+	response, err = ListUsers(ctx, &pb.ListUsersRequest{Limit: uint32(len(userIds))})
+	require.NoError(t, err)
+	var ids []string
+	for _, user := range response.UserSet {
+		ids = append(ids, user.UserId)
+	}
+	for i, j := 0, len(userIds)-1; i < j; i, j = i+1, j-1 {
+		userIds[i], userIds[j] = userIds[j], userIds[i]
+	}
+	require.Equal(t, userIds, ids)
+
+	count, err := CountUsers(ctx, &pb.ListUsersRequest{Status: []string{constants.StatusActive}})
+	require.NoError(t, err)
+	require.EqualValues(t, len(userIds), count.Total)
+}
+
+// createShardedTestUsers creates the users with the phone numbers 100000000xx until both shards hold two of them
+func createShardedTestUsers(t *testing.T) []string {
+	var userIds []string
+	shardSizes := make([]int, 2)
+	for i := 0; shardSizes[0] < 2 || shardSizes[1] < 2; i++ {
+		require.True(t, i < 32)
+		userId := createTestUser(t, fmt.Sprintf("user%02d", i), fmt.Sprintf("100000000%02d", i))
+		shardSizes[db.ShardIndex(userId, 2)]++
+		userIds = append(userIds, userId)
+	}
+	return userIds
+}
+
+func TestShardedUserLookups(t *testing.T) {
+	prepareShards(t)
+	ctx := context.Background()
+
+	userIds := createShardedTestUsers(t)
+	for i, userId := range userIds {
+		phoneNumber := fmt.Sprintf("100000000%02d", i)
+		user, err := GetUserByPhoneNumber(ctx, phoneNumber)
+		require.NoError(t, err)
+		require.Equal(t, userId, user.UserId)
+		// the phone numbers are unique across the shards
+		_, err = CreateUser(ctx, &pb.CreateUserRequest{Username: "dup", Email: "dup@op.com", PhoneNumber: phoneNumber})
+		require.Equal(t, codes.AlreadyExists, status.Code(err))
+	}
+
+	// the subject linked to a user of one shard is not linked to a user of the other
+	alice := userIds[0]
+	var other string
+	for _, userId := range userIds {
+		if db.ShardIndex(userId, 2) != db.ShardIndex(alice, 2) {
+			other = userId
+			break
+		}
+	}
+	require.NoError(t, LinkExternalId(ctx, alice, "github", "1001"))
+	user, err := GetUserByExternalId(ctx, "github", "1001")
+	require.NoError(t, err)
+	require.Equal(t, alice, user.UserId)
+	require.Equal(t, codes.AlreadyExists, status.Code(LinkExternalId(ctx, other, "github", "1001")))
+
+	profile := &pb.CreateUserRequest{Username: "federated", Email: "federated@op.com"}
+	federated, created, err := GetOrCreateFederatedUser(ctx, "github", "1002", profile)
+	require.NoError(t, err)
+	require.True(t, created)
+	user, created, err = GetOrCreateFederatedUser(ctx, "github", "1002", profile)
+	require.NoError(t, err)
+	require.False(t, created)
+	require.Equal(t, federated.UserId, user.UserId)
+	_, err = GetUser(ctx, federated.UserId)
+	require.NoError(t, err)
+
+	inactive, err := GetInactiveUsers(ctx, time.Now())
+	require.NoError(t, err)
+	var inactiveIds []string
+	for _, user := range inactive {
+		inactiveIds = append(inactiveIds, user.UserId)
+	}
+	require.ElementsMatch(t, append([]string{federated.UserId}, userIds...), inactiveIds)
+
+	require.NoError(t, ForcePasswordResetForUsers(ctx, userIds))
+	for _, userId := range userIds {
+		user, err := GetUser(ctx, userId)
+		require.NoError(t, err)
+		require.True(t, user.MustChangePassword)
+	}
+
+	_, err = DeleteUsers(ctx, &pb.DeleteUsersRequest{UserId: userIds})
+	require.NoError(t, err)
+	for _, userId := range userIds {
+		user, err := GetUser(ctx, userId)
+		require.NoError(t, err)
+		require.Equal(t, constants.StatusDeleted, user.Status)
+	}
+	_, err = GetUserByPhoneNumber(ctx, "10000000000")
+	require.True(t, gorm.IsRecordNotFoundError(err))
+}
+
+func TestShardedListUsersFilters(t *testing.T) {
+	prepareShards(t)
+	ctx := context.Background()
+
+	// the tags and the bindings are in the main database, each filter matches a user of both shards
+	userIds := createShardedTestUsers(t)
+	firstOfShard, lastOfShard := make([]string, 2), make([]string, 2)
+	for _, userId := range userIds {
+		index := db.ShardIndex(userId, 2)
+		if firstOfShard[index] == "" {
+			firstOfShard[index] = userId
+		}
+		lastOfShard[index] = userId
+	}
+	members, tagged := firstOfShard, lastOfShard
+	group := createTestGroup(t, "group", "")
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{UserId: members, GroupId: []string{group}})
+	require.NoError(t, err)
+	for _, userId := range tagged {
+		require.NoError(t, AddUserTags(ctx, userId, []string{"vip"}))
+	}
+
+	listUserIds := func(req *pb.ListUsersRequest) []string {
+		response, err := ListUsers(ctx, req)
+		require.NoError(t, err)
+		var ids []string
+		for _, user := range response.UserSet {
+			ids = append(ids, user.UserId)
+		}
+		return ids
+	}
+	require.ElementsMatch(t, members, listUserIds(&pb.ListUsersRequest{InGroupIds: []string{group}}))
+	require.ElementsMatch(t, tagged, listUserIds(&pb.ListUsersRequest{Tag: []string{"vip"}}))
+	since, err := ptypes.TimestampProto(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.ElementsMatch(t, members, listUserIds(&pb.ListUsersRequest{JoinedGroupSince: since}))
+	require.Equal(t, members[:1], listUserIds(&pb.ListUsersRequest{UserId: members[:1], InGroupIds: []string{group}}))
+	require.Empty(t, listUserIds(&pb.ListUsersRequest{Tag: []string{"unknown"}}))
+
+	count, err := CountUsers(ctx, &pb.ListUsersRequest{Tag: []string{"vip"}})
+	require.NoError(t, err)
+	require.EqualValues(t, len(tagged), count.Total)
+
+	// the names of the groups are not in the shards to be searched
+	_, err = ListUsers(ctx, &pb.ListUsersRequest{SearchWord: []string{"group"}, SearchGroupName: true})
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+	_, err = CountUsers(ctx, &pb.ListUsersRequest{SearchWord: []string{"group"}, SearchGroupName: true})
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestListUsersInGroupIds(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, "alice", "")
+	alina := createTestUser(t, "alina", "")
+	bob := createTestUser(t, "bob", "")
+	group1 := createTestGroup(t, "group1", "")
+	group2 := createTestGroup(t, "group2", "")
+	group3 := createTestGroup(t, "group3", "")
+
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{alice}, GroupId: []string{group1}})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{bob}, GroupId: []string{group2}})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{alina}, GroupId: []string{group3}})
+	require.NoError(t, err)
+	// pending invitations are not memberships
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{alina},
+		GroupId: []string{group1},
+		Status:  constants.BindingStatusPending,
+	})
+	require.NoError(t, err)
+
+	userIds := func(req *pb.ListUsersRequest) []string {
+		response, err := ListUsers(ctx, req)
+		require.NoError(t, err)
+		var ids []string
+		for _, user := range response.UserSet {
+			ids = append(ids, user.UserId)
+		}
+		return ids
+	}
+
+	require.ElementsMatch(t, []string{alice, bob}, userIds(&pb.ListUsersRequest{InGroupIds: []string{group1, group2}}))
+	require.Equal(t, []string{alice}, userIds(&pb.ListUsersRequest{
+		SearchWord: []string{"ali"},
+		InGroupIds: []string{group1, group2},
+	}))
+	require.ElementsMatch(t, []string{alice, alina}, userIds(&pb.ListUsersRequest{
+		SearchWord: []string{"ali"},
+		InGroupIds: []string{group1, group2, group3},
+	}))
+	require.Empty(t, userIds(&pb.ListUsersRequest{SearchWord: []string{"bob"}, InGroupIds: []string{group1}}))
+
+	// with the pagination, the total counts all the matched users
+	response, err := ListUsers(ctx, &pb.ListUsersRequest{
+		SearchWord: []string{"ali"},
+		InGroupIds: []string{group1, group3},
+		SortKey:    constants.ColumnUsername,
+		Limit:      1,
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, response.Total)
+	require.Len(t, response.UserSet, 1)
+	require.Equal(t, alina, response.UserSet[0].UserId)
+}
+
+func TestListUsersJoinedGroup(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	monthStart := time.Date(2019, 9, 1, 0, 0, 0, 0, time.UTC)
+	monthEnd := monthStart.AddDate(0, 1, 0)
+	group := createTestGroup(t, "group", "")
+	joinedAt := func(username string, createTime time.Time, bindingStatus string) string {
+		userId := createTestUser(t, username, "")
+		_, err := JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{userId}, GroupId: []string{group}, Status: bindingStatus})
+		require.NoError(t, err)
+		require.NoError(t, global.Global().Database.Table(db.TableName(constants.TableUserGroupBinding)).
+			Where(constants.ColumnUserId+" = ?", userId).
+			Update(constants.ColumnCreateTime, createTime).Error)
+		return userId
+	}
+	first := joinedAt("first", monthStart, "")
+	last := joinedAt("last", monthEnd.Add(-time.Second), "")
+	joinedAt("before", monthStart.Add(-time.Second), "")
+	after := joinedAt("after", monthEnd, "")
+	joinedAt("invited", monthStart.Add(time.Hour), constants.BindingStatusPending)
+	createTestUser(t, "alone", "")
+
+	protoTime := func(tm time.Time) *timestamp.Timestamp {
+		ts, err := ptypes.TimestampProto(tm)
+		require.NoError(t, err)
+		return ts
+	}
+	userIds := func(req *pb.ListUsersRequest) []string {
+		response, err := ListUsers(ctx, req)
+		require.NoError(t, err)
+		var ids []string
+		for _, user := range response.UserSet {
+			ids = append(ids, user.UserId)
+		}
+		return ids
+	}
+
+	// the start is included and the end is not
+	require.ElementsMatch(t, []string{first, last}, userIds(&pb.ListUsersRequest{
+		JoinedGroupSince:  protoTime(monthStart),
+		JoinedGroupBefore: protoTime(monthEnd),
+	}))
+	require.ElementsMatch(t, []string{first, last, after}, userIds(&pb.ListUsersRequest{
+		JoinedGroupSince: protoTime(monthStart),
+	}))
+	require.Empty(t, userIds(&pb.ListUsersRequest{
+		JoinedGroupSince:  protoTime(monthStart.Add(time.Second)),
+		JoinedGroupBefore: protoTime(monthEnd.Add(-time.Second)),
+	}))
+
+	count, err := CountUsers(ctx, &pb.ListUsersRequest{
+		JoinedGroupSince:  protoTime(monthStart),
+		JoinedGroupBefore: protoTime(monthEnd),
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, count.Total)
+
+	_, err = ListUsers(ctx, &pb.ListUsersRequest{JoinedGroupSince: &timestamp.Timestamp{Nanos: -1}})
+	require.Equal(t, []string{"joined_group_since"}, violatedFields(t, err))
+}
@@ -18,24 +18,62 @@ package resource
 
 import (
 	"context"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/jinzhu/gorm"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"openpitrix.io/logger"
 
 	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/db"
 	"cloudbases.io/im/pkg/global"
 	"cloudbases.io/im/pkg/models"
 	"cloudbases.io/im/pkg/pb"
+	"cloudbases.io/im/pkg/util/stringutil"
 )
 
 func GetUserGroupBindings(ctx context.Context, userIds, groupIds []string) ([]*models.UserGroupBinding, error) {
+	return GetUserGroupBindingsWithOptions(ctx, userIds, groupIds, BindingQueryOptions{})
+}
+
+// BindingQueryOptions negates the user or group conditions of GetUserGroupBindingsWithOptions,
+// or filters the bindings by their creator
+type BindingQueryOptions struct {
+	// bindings of the users not in userIds, all users if userIds is empty
+	NotInUsers bool
+	// bindings to the groups not in groupIds, all groups if groupIds is empty
+	NotInGroups bool
+	// order by user_id, group_id instead of group_id, user_id
+	OrderByUser bool
+	// only the bindings created by the user, e.g. the invitations of an admin
+	CreatedBy string
+}
+
+// GetUserGroupBindingsWithOptions is GetUserGroupBindings with NOT IN conditions,
+// e.g. the bindings of userIds to the groups outside an allowed set.
+// The bindings are ordered by group then user so that repeated fetches are stable.
+func GetUserGroupBindingsWithOptions(ctx context.Context, userIds, groupIds []string, opts BindingQueryOptions) ([]*models.UserGroupBinding, error) {
+	// "in ()" of no ids matches nothing, the query is not sent
+	if len(userIds) == 0 && !opts.NotInUsers || len(groupIds) == 0 && !opts.NotInGroups {
+		return nil, nil
+	}
+	query := global.Global().Database.Table(db.TableName(constants.TableUserGroupBinding))
+	query = whereInOrNotIn(query, constants.ColumnUserId, userIds, opts.NotInUsers)
+	query = whereInOrNotIn(query, constants.ColumnGroupId, groupIds, opts.NotInGroups)
+	if opts.CreatedBy != "" {
+		query = query.Where(constants.ColumnCreatedBy+" = ?", opts.CreatedBy)
+	}
+	if opts.OrderByUser {
+		query = query.Order(constants.ColumnUserId).Order(constants.ColumnGroupId)
+	} else {
+		query = query.Order(constants.ColumnGroupId).Order(constants.ColumnUserId)
+	}
+
 	var userGroupBindings []*models.UserGroupBinding
-	if err := global.Global().Database.Table(constants.TableUserGroupBinding).
-		Where(constants.ColumnGroupId+" in (?)", groupIds).
-		Where(constants.ColumnUserId+" in (?)", userIds).
-		Find(&userGroupBindings).
-		Error; err != nil {
+	if err := query.Find(&userGroupBindings).Error; err != nil {
 		logger.Errorf(ctx, "Get user group binding failed: %+v", err)
 		return nil, err
 	}
@@ -43,13 +81,182 @@ func GetUserGroupBindings(ctx context.Context, userIds, groupIds []string) ([]*m
 	return userGroupBindings, nil
 }
 
-func JoinGroup(ctx context.Context, req *pb.JoinGroupRequest) (*pb.JoinGroupResponse, error) {
-	if len(req.UserId) == 0 || len(req.GroupId) == 0 {
-		err := status.Errorf(codes.InvalidArgument, "empty user id or group id")
+func whereInOrNotIn(query *gorm.DB, column string, values []string, not bool) *gorm.DB {
+	if !not {
+		return query.Where(column+" in (?)", values)
+	}
+	// "not in ()" is rendered as "not in (NULL)" which matches nothing
+	if len(values) == 0 {
+		return query
+	}
+	return query.Where(column+" not in (?)", values)
+}
+
+// IterateBindings calls fn with the bindings of groupIds one by one without loading them all,
+// the iteration stops at the first error returned by fn
+func IterateBindings(ctx context.Context, groupIds []string, fn func(*models.UserGroupBinding) error) error {
+	if len(groupIds) == 0 {
+		return nil
+	}
+	rows, err := global.Global().Database.Table(db.TableName(constants.TableUserGroupBinding)).
+		Where(constants.ColumnGroupId+" in (?)", groupIds).
+		Order(constants.ColumnCreateTime).
+		Rows()
+	if err != nil {
+		logger.Errorf(ctx, "Iterate user group bindings failed: %+v", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userGroupBinding models.UserGroupBinding
+		if err := global.Global().Database.ScanRows(rows, &userGroupBinding); err != nil {
+			logger.Errorf(ctx, "Scan user group binding failed: %+v", err)
+			return err
+		}
+		if err := fn(&userGroupBinding); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logger.Errorf(ctx, "Iterate user group bindings failed: %+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// max users * groups accepted by GetBindingMatrix, the matrix is kept in memory
+const maxBindingMatrixSize = 100000
+
+// GetBindingMatrix returns matrix[userId][groupId] for every requested user and group,
+// true means the user is in the group
+func GetBindingMatrix(ctx context.Context, userIds, groupIds []string) (map[string]map[string]bool, error) {
+	userIds = stringutil.Unique(userIds)
+	groupIds = stringutil.Unique(groupIds)
+	if len(userIds)*len(groupIds) > maxBindingMatrixSize {
+		err := status.Errorf(codes.InvalidArgument, "binding matrix of %d users and %d groups is too large, max size is %d",
+			len(userIds), len(groupIds), maxBindingMatrixSize)
 		logger.Errorf(ctx, "%+v", err)
 		return nil, err
 	}
 
+	matrix := make(map[string]map[string]bool, len(userIds))
+	for _, userId := range userIds {
+		matrix[userId] = make(map[string]bool, len(groupIds))
+		for _, groupId := range groupIds {
+			matrix[userId][groupId] = false
+		}
+	}
+	if len(userIds) == 0 || len(groupIds) == 0 {
+		return matrix, nil
+	}
+
+	userGroupBindings, err := GetUserGroupBindings(ctx, userIds, groupIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, binding := range userGroupBindings {
+		if binding.Status == constants.BindingStatusAccepted {
+			matrix[binding.UserId][binding.GroupId] = true
+		}
+	}
+
+	return matrix, nil
+}
+
+// IsUserInGroup returns whether the user is an accepted member of the group,
+// the results are cached for the most recent MembershipCacheSize users and groups
+func IsUserInGroup(ctx context.Context, userId, groupId string) (bool, error) {
+	size := global.Global().Config.MembershipCacheSize
+	if in, ok := memberships.get(userId, groupId, size); ok {
+		return in, nil
+	}
+
+	generation := memberships.getGeneration()
+	var count int
+	if err := global.Global().Database.Table(db.TableName(constants.TableUserGroupBinding)).
+		Where(constants.ColumnUserId+" = ?", userId).
+		Where(constants.ColumnGroupId+" = ?", groupId).
+		Where(constants.ColumnStatus+" = ?", constants.BindingStatusAccepted).
+		Count(&count).Error; err != nil {
+		logger.Errorf(ctx, "Get binding of user [%s] and group [%s] failed: %+v", userId, groupId, err)
+		return false, err
+	}
+	memberships.set(userId, groupId, count > 0, size, generation)
+	return count > 0, nil
+}
+
+// UserGroupPair is a user and a group to check by FilterExistingBindings
+type UserGroupPair struct {
+	UserId  string
+	GroupId string
+}
+
+// FilterExistingBindings returns for every pair whether the user is in the group, in the order of pairs
+func FilterExistingBindings(ctx context.Context, pairs []UserGroupPair) ([]bool, error) {
+	exists := make([]bool, len(pairs))
+	if len(pairs) == 0 {
+		return exists, nil
+	}
+
+	var userIds, groupIds []string
+	for _, pair := range pairs {
+		userIds = append(userIds, pair.UserId)
+		groupIds = append(groupIds, pair.GroupId)
+	}
+	userGroupBindings, err := GetUserGroupBindings(ctx, stringutil.Unique(userIds), stringutil.Unique(groupIds))
+	if err != nil {
+		return nil, err
+	}
+
+	accepted := make(map[UserGroupPair]bool, len(userGroupBindings))
+	for _, binding := range userGroupBindings {
+		if binding.Status == constants.BindingStatusAccepted {
+			accepted[UserGroupPair{UserId: binding.UserId, GroupId: binding.GroupId}] = true
+		}
+	}
+	for i, pair := range pairs {
+		exists[i] = accepted[pair]
+	}
+	return exists, nil
+}
+
+// checkJoinGroupRequest returns the status and role of the bindings to create for req
+func checkJoinGroupRequest(ctx context.Context, req *pb.JoinGroupRequest) (bindingStatus, bindingRole string, err error) {
+	var violations fieldViolations
+	violations.checkNotEmpty("user_id", req.UserId)
+	violations.checkNotEmpty("group_id", req.GroupId)
+	if err := violations.Err(ctx); err != nil {
+		return "", "", err
+	}
+	bindingStatus = req.Status
+	if bindingStatus == "" {
+		bindingStatus = constants.BindingStatusAccepted
+	}
+	if !stringutil.Contains(constants.BindingStatuses, bindingStatus) {
+		err := status.Errorf(codes.InvalidArgument, "invalid binding status [%s]", bindingStatus)
+		logger.Errorf(ctx, "%+v", err)
+		return "", "", err
+	}
+	bindingRole = req.Role
+	if bindingRole == "" {
+		bindingRole = constants.BindingRoleMember
+	}
+	if !stringutil.Contains(constants.BindingRoles, bindingRole) {
+		err := status.Errorf(codes.InvalidArgument, "invalid binding role [%s]", bindingRole)
+		logger.Errorf(ctx, "%+v", err)
+		return "", "", err
+	}
+	return bindingStatus, bindingRole, nil
+}
+
+func JoinGroup(ctx context.Context, req *pb.JoinGroupRequest) (*pb.JoinGroupResponse, error) {
+	bindingStatus, bindingRole, err := checkJoinGroupRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
 	// check user in group
 	userGroupBindings, err := GetUserGroupBindings(ctx, req.UserId, req.GroupId)
 	if err != nil {
@@ -65,10 +272,14 @@ func JoinGroup(ctx context.Context, req *pb.JoinGroupRequest) (*pb.JoinGroupResp
 	{
 		for _, groupId := range req.GroupId {
 			for _, userId := range req.UserId {
-				if err := tx.Create(models.NewUserGroupBinding(userId, groupId)).Error; err != nil {
+				userGroupBinding := models.NewUserGroupBinding(userId, groupId)
+				userGroupBinding.Status = bindingStatus
+				userGroupBinding.Role = bindingRole
+				userGroupBinding.CreatedBy = stringutil.SimplifyString(req.CreatedBy)
+				if err := tx.Create(userGroupBinding).Error; err != nil {
 					tx.Rollback()
 					logger.Errorf(ctx, "Insert user group binding failed: %+v", err)
-					return nil, err
+					return nil, db.MapError(err)
 				}
 			}
 		}
@@ -77,6 +288,7 @@ func JoinGroup(ctx context.Context, req *pb.JoinGroupRequest) (*pb.JoinGroupResp
 		logger.Errorf(ctx, "Batch insert user group binding failed: %+v", err)
 		return nil, err
 	}
+	memberships.invalidate(req.UserId, req.GroupId)
 
 	return &pb.JoinGroupResponse{
 		GroupId: req.GroupId,
@@ -84,10 +296,91 @@ func JoinGroup(ctx context.Context, req *pb.JoinGroupRequest) (*pb.JoinGroupResp
 	}, nil
 }
 
+// BindingItem is the item of the binding of user to group in BatchResult, "user_id/group_id"
+func BindingItem(userId, groupId string) string {
+	return userId + "/" + groupId
+}
+
+// BatchJoinGroup is JoinGroup creating every binding of req on its own, the bindings of the unknown
+// users and groups and the existing bindings fail without stopping the others. The invalid status
+// or role of req fails the whole batch.
+func BatchJoinGroup(ctx context.Context, req *pb.JoinGroupRequest) (*BatchResult, error) {
+	bindingStatus, bindingRole, err := checkJoinGroupRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	userIds := stringutil.Unique(req.UserId)
+	groupIds := stringutil.Unique(req.GroupId)
+
+	var activeGroupIds []string
+	if err := global.Global().Database.Table(db.TableName(constants.TableGroup)).
+		Where(constants.ColumnGroupId+" in (?)", groupIds).
+		Where(constants.ColumnStatus+" = ?", constants.StatusActive).
+		Pluck(constants.ColumnGroupId, &activeGroupIds).Error; err != nil {
+		logger.Errorf(ctx, "Get groups failed: %+v", err)
+		return nil, err
+	}
+	var activeUserIds []string
+	for _, database := range global.Global().UserDatabases() {
+		var shardUserIds []string
+		if err := database.Table(db.TableName(constants.TableUser)).
+			Where(constants.ColumnUserId+" in (?)", userIds).
+			Where(constants.ColumnStatus+" = ?", constants.StatusActive).
+			Pluck(constants.ColumnUserId, &shardUserIds).Error; err != nil {
+			logger.Errorf(ctx, "Get users failed: %+v", err)
+			return nil, err
+		}
+		activeUserIds = append(activeUserIds, shardUserIds...)
+	}
+	userGroupBindings, err := GetUserGroupBindings(ctx, userIds, groupIds)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[UserGroupPair]bool, len(userGroupBindings))
+	for _, binding := range userGroupBindings {
+		existing[UserGroupPair{UserId: binding.UserId, GroupId: binding.GroupId}] = true
+	}
+
+	result := &BatchResult{}
+	for _, groupId := range groupIds {
+		for _, userId := range userIds {
+			item := BindingItem(userId, groupId)
+			switch {
+			case !stringutil.Contains(activeGroupIds, groupId):
+				result.fail(item, status.Errorf(codes.NotFound, "group [%s] not found", groupId))
+				continue
+			case !stringutil.Contains(activeUserIds, userId):
+				result.fail(item, status.Errorf(codes.NotFound, "user [%s] not found", userId))
+				continue
+			case existing[UserGroupPair{UserId: userId, GroupId: groupId}]:
+				result.fail(item, status.Errorf(codes.AlreadyExists, "user [%s] already in group [%s]", userId, groupId))
+				continue
+			}
+
+			userGroupBinding := models.NewUserGroupBinding(userId, groupId)
+			userGroupBinding.Status = bindingStatus
+			userGroupBinding.Role = bindingRole
+			userGroupBinding.CreatedBy = stringutil.SimplifyString(req.CreatedBy)
+			if err := global.Global().Database.Create(userGroupBinding).Error; err != nil {
+				logger.Errorf(ctx, "Insert user group binding [%s] failed: %+v", item, err)
+				result.fail(item, db.MapError(err))
+				continue
+			}
+			result.succeed(item)
+		}
+	}
+	if len(result.Succeeded) > 0 {
+		memberships.invalidate(userIds, groupIds)
+	}
+
+	return result, nil
+}
+
 func LeaveGroup(ctx context.Context, req *pb.LeaveGroupRequest) (*pb.LeaveGroupResponse, error) {
-	if len(req.UserId) == 0 || len(req.GroupId) == 0 {
-		err := status.Errorf(codes.InvalidArgument, "empty user id or group id")
-		logger.Errorf(ctx, "%+v", err)
+	var violations fieldViolations
+	violations.checkNotEmpty("user_id", req.UserId)
+	violations.checkNotEmpty("group_id", req.GroupId)
+	if err := violations.Err(ctx); err != nil {
 		return nil, err
 	}
 
@@ -102,13 +395,15 @@ func LeaveGroup(ctx context.Context, req *pb.LeaveGroupRequest) (*pb.LeaveGroupR
 		return nil, err
 	}
 
-	if err := global.Global().Database.
-		Where(constants.ColumnGroupId+" in (?)", req.GroupId).
-		Where(constants.ColumnUserId+" in (?)", req.UserId).
-		Delete(models.UserGroupBinding{}).Error; err != nil {
+	if err := global.Global().Database.WithTransaction(ctx, func(tx *gorm.DB) error {
+		_, err := deleteBindings(tx, constants.ColumnGroupId+" in (?) AND "+constants.ColumnUserId+" in (?)",
+			req.GroupId, req.UserId)
+		return err
+	}); err != nil {
 		logger.Errorf(ctx, "Delete user group binding failed: %+v", err)
 		return nil, err
 	}
+	memberships.invalidate(req.UserId, req.GroupId)
 
 	return &pb.LeaveGroupResponse{
 		GroupId: req.GroupId,
@@ -116,13 +411,295 @@ func LeaveGroup(ctx context.Context, req *pb.LeaveGroupRequest) (*pb.LeaveGroupR
 	}, nil
 }
 
-func GetGroupsByUserIds(ctx context.Context, userIds []string) ([]*models.Group, error) {
+// deleteBindings deletes the bindings matching the condition in tx, a tombstone of every binding
+// is left for GetBindingChangesSince
+func deleteBindings(tx *gorm.DB, condition string, args ...interface{}) (int64, error) {
+	if err := tx.Exec("INSERT INTO "+db.TableName(constants.TableUserGroupBindingDeletion)+
+		" ("+constants.ColumnId+", "+constants.ColumnUserId+", "+constants.ColumnGroupId+", "+constants.ColumnDeleteTime+")"+
+		" SELECT "+constants.ColumnId+", "+constants.ColumnUserId+", "+constants.ColumnGroupId+", ?"+
+		" FROM "+db.TableName(constants.TableUserGroupBinding)+" WHERE "+condition,
+		append([]interface{}{models.NowUTC()}, args...)...).Error; err != nil {
+		return 0, err
+	}
+	result := tx.Where(condition, args...).Delete(models.UserGroupBinding{})
+	return result.RowsAffected, result.Error
+}
+
+// BindingChanges are the bindings created and deleted since a time, for the incremental sync of caches.
+// The deletions are applied before the creations, a binding deleted then created again has a new id.
+type BindingChanges struct {
+	Created []*models.UserGroupBinding
+	Deleted []*models.UserGroupBindingDeletion
+}
+
+// GetBindingChangesSince returns the bindings created since and still existing, and the bindings deleted since,
+// ordered by time. The status changes of the bindings, e.g. accepted invitations, are not changes.
+func GetBindingChangesSince(ctx context.Context, since time.Time) (*BindingChanges, error) {
+	var changes BindingChanges
+	if err := db.GetChain(global.Global().Database.Table(db.TableName(constants.TableUserGroupBinding))).
+		BuildTimeRangeConditions(constants.ColumnCreateTime, since, time.Time{}).
+		Order(constants.ColumnCreateTime).
+		Order(constants.ColumnId).
+		Find(&changes.Created).Error; err != nil {
+		logger.Errorf(ctx, "Get bindings created since [%s] failed: %+v", since, err)
+		return nil, err
+	}
+	if err := db.GetChain(global.Global().Database.Table(db.TableName(constants.TableUserGroupBindingDeletion))).
+		BuildTimeRangeConditions(constants.ColumnDeleteTime, since, time.Time{}).
+		Order(constants.ColumnDeleteTime).
+		Order(constants.ColumnId).
+		Find(&changes.Deleted).Error; err != nil {
+		logger.Errorf(ctx, "Get bindings deleted since [%s] failed: %+v", since, err)
+		return nil, err
+	}
+	return &changes, nil
+}
+
+// SetUserGroups replaces the groups of user with groupIds, the missing groups are joined as accepted member
+// and the bindings to the other groups are removed, in one transaction
+func SetUserGroups(ctx context.Context, userId string, groupIds []string) (added, removed int, err error) {
+	if userId == "" {
+		err := status.Errorf(codes.InvalidArgument, "empty user id")
+		logger.Errorf(ctx, "%+v", err)
+		return 0, 0, err
+	}
+	return setBindings(ctx, constants.ColumnUserId, userId, constants.ColumnGroupId, groupIds,
+		func(groupId string) *models.UserGroupBinding {
+			return models.NewUserGroupBinding(userId, groupId)
+		})
+}
+
+// SetGroupMembers replaces the members of group with userIds, the missing users are joined as accepted member
+// and the bindings of the other users are removed, in one transaction
+func SetGroupMembers(ctx context.Context, groupId string, userIds []string) (added, removed int, err error) {
+	if groupId == "" {
+		err := status.Errorf(codes.InvalidArgument, "empty group id")
+		logger.Errorf(ctx, "%+v", err)
+		return 0, 0, err
+	}
+	return setBindings(ctx, constants.ColumnGroupId, groupId, constants.ColumnUserId, userIds,
+		func(userId string) *models.UserGroupBinding {
+			return models.NewUserGroupBinding(userId, groupId)
+		})
+}
+
+// setBindings reconciles the bindings having column = id to exactly the otherIds of otherColumn
+func setBindings(ctx context.Context, column, id, otherColumn string, otherIds []string,
+	newBinding func(otherId string) *models.UserGroupBinding) (added, removed int, err error) {
+	otherIds = stringutil.Unique(otherIds)
+
+	err = global.Global().Database.WithTransaction(ctx, func(tx *gorm.DB) error {
+		var userGroupBindings []*models.UserGroupBinding
+		if err := tx.Table(db.TableName(constants.TableUserGroupBinding)).
+			Where(column+" = ?", id).
+			Find(&userGroupBindings).Error; err != nil {
+			logger.Errorf(ctx, "Get user group binding failed: %+v", err)
+			return err
+		}
+
+		var extraIds []string
+		existing := make(map[string]bool, len(userGroupBindings))
+		for _, binding := range userGroupBindings {
+			otherId := binding.GroupId
+			if otherColumn == constants.ColumnUserId {
+				otherId = binding.UserId
+			}
+			existing[otherId] = true
+			if !stringutil.Contains(otherIds, otherId) {
+				extraIds = append(extraIds, otherId)
+			}
+		}
+
+		if len(extraIds) > 0 {
+			n, err := deleteBindings(tx, column+" = ? AND "+otherColumn+" in (?)", id, extraIds)
+			if err != nil {
+				logger.Errorf(ctx, "Delete user group binding failed: %+v", err)
+				return err
+			}
+			removed = int(n)
+		}
+
+		for _, otherId := range otherIds {
+			if existing[otherId] {
+				continue
+			}
+			if err := tx.Create(newBinding(otherId)).Error; err != nil {
+				logger.Errorf(ctx, "Insert user group binding failed: %+v", err)
+				return db.MapError(err)
+			}
+			added++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	if column == constants.ColumnUserId {
+		memberships.invalidate([]string{id}, nil)
+	} else {
+		memberships.invalidate(nil, []string{id})
+	}
+
+	return added, removed, nil
+}
+
+// AcceptInvitations turns the pending bindings of user to the groups into accepted in one transaction,
+// none of them is accepted if user is not invited to any of the groups
+func AcceptInvitations(ctx context.Context, userId string, groupIds []string) error {
+	var violations fieldViolations
+	violations.checkNotBlank("user_id", userId)
+	violations.checkNotEmpty("group_id", groupIds)
+	if err := violations.Err(ctx); err != nil {
+		return err
+	}
+	groupIds = stringutil.Unique(groupIds)
+
+	err := global.Global().Database.WithTransaction(ctx, func(tx *gorm.DB) error {
+		result := tx.Table(db.TableName(constants.TableUserGroupBinding)).
+			Where(constants.ColumnUserId+" = ?", userId).
+			Where(constants.ColumnGroupId+" in (?)", groupIds).
+			Where(constants.ColumnStatus+" = ?", constants.BindingStatusPending).
+			Update(constants.ColumnStatus, constants.BindingStatusAccepted)
+		if err := result.Error; err != nil {
+			logger.Errorf(ctx, "Accept user [%s] invitations failed: %+v", userId, err)
+			return err
+		}
+		if result.RowsAffected != int64(len(groupIds)) {
+			err := status.Errorf(codes.PermissionDenied, "user [%s] is not invited to some of the groups %v", userId, groupIds)
+			logger.Errorf(ctx, "%+v", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	memberships.invalidate([]string{userId}, groupIds)
+
+	return nil
+}
+
+// TransferGroupAdmin demotes fromUserId to a member and promotes toUserId to an admin of the group
+// in one transaction, fromUserId must be an admin and toUserId an accepted member of the group
+func TransferGroupAdmin(ctx context.Context, groupId, fromUserId, toUserId string) error {
+	var violations fieldViolations
+	violations.checkNotBlank("group_id", groupId)
+	violations.checkNotBlank("from_user_id", fromUserId)
+	violations.checkNotBlank("to_user_id", toUserId)
+	if fromUserId == toUserId {
+		violations.Add("to_user_id", "the admin is transferred to the same user")
+	}
+	if err := violations.Err(ctx); err != nil {
+		return err
+	}
+
+	return global.Global().Database.WithTransaction(ctx, func(tx *gorm.DB) error {
+		var userGroupBindings []*models.UserGroupBinding
+		if err := tx.Table(db.TableName(constants.TableUserGroupBinding)).
+			Where(constants.ColumnGroupId+" = ?", groupId).
+			Where(constants.ColumnUserId+" in (?)", []string{fromUserId, toUserId}).
+			Where(constants.ColumnStatus+" = ?", constants.BindingStatusAccepted).
+			Find(&userGroupBindings).Error; err != nil {
+			logger.Errorf(ctx, "Get bindings of group [%s] failed: %+v", groupId, err)
+			return err
+		}
+		roles := make(map[string]string, len(userGroupBindings))
+		for _, binding := range userGroupBindings {
+			roles[binding.UserId] = binding.Role
+		}
+		if roles[fromUserId] != constants.BindingRoleAdmin {
+			err := status.Errorf(codes.PermissionDenied, "user [%s] is not an admin of group [%s]", fromUserId, groupId)
+			logger.Errorf(ctx, "%+v", err)
+			return err
+		}
+		if _, ok := roles[toUserId]; !ok {
+			err := status.Errorf(codes.PermissionDenied, "user [%s] is not a member of group [%s]", toUserId, groupId)
+			logger.Errorf(ctx, "%+v", err)
+			return err
+		}
+
+		for userId, role := range map[string]string{
+			fromUserId: constants.BindingRoleMember,
+			toUserId:   constants.BindingRoleAdmin,
+		} {
+			if err := tx.Table(db.TableName(constants.TableUserGroupBinding)).
+				Where(constants.ColumnGroupId+" = ?", groupId).
+				Where(constants.ColumnUserId+" = ?", userId).
+				Update(constants.ColumnRole, role).Error; err != nil {
+				logger.Errorf(ctx, "Set role of user [%s] in group [%s] to [%s] failed: %+v", userId, groupId, role, err)
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MembershipOptions controls which bindings make a user member of a group
+type MembershipOptions struct {
+	// pending invitations are counted as members besides the accepted bindings
+	IncludePending bool
+	// users left out of the members of groups, e.g. the users already invited
+	ExcludeUserIds []string
+	// only the groups under the root group, including it, e.g. the groups of a tenant
+	RootGroupId string
+	// the deleted users are returned as members too, only the active users are by default
+	IncludeInactiveUsers bool
+	// the archived groups are returned among the groups of users too, e.g. for the admin views
+	IncludeArchivedGroups bool
+}
+
+func (o MembershipOptions) bindingStatuses() []string {
+	if o.IncludePending {
+		return []string{constants.BindingStatusAccepted, constants.BindingStatusPending}
+	}
+	return []string{constants.BindingStatusAccepted}
+}
+
+// userStatusCondition filters the users joined with their bindings by status
+func (o MembershipOptions) userStatusCondition(query *gorm.DB) *gorm.DB {
+	if o.IncludeInactiveUsers {
+		return query
+	}
+	return query.Where("`user`."+constants.ColumnStatus+" = ?", constants.StatusActive)
+}
+
+// GetGroupsByUserIds returns the groups of users except the archived ones, only displayColumns are selected if given
+func GetGroupsByUserIds(ctx context.Context, userIds []string, displayColumns ...string) ([]*models.Group, error) {
+	return GetGroupsByUserIdsWithOptions(ctx, userIds, MembershipOptions{}, displayColumns...)
+}
+
+func GetGroupsByUserIdsWithOptions(ctx context.Context, userIds []string, opts MembershipOptions, displayColumns ...string) ([]*models.Group, error) {
+	selectColumns := []string{"`group`.*"}
+	if displayColumns != nil {
+		columns := db.GetDisplayColumns(displayColumns, constants.DisplayColumns[constants.TableGroup])
+		if len(columns) == 0 {
+			err := status.Errorf(codes.InvalidArgument, "no valid display columns in %v", displayColumns)
+			logger.Errorf(ctx, "%+v", err)
+			return nil, err
+		}
+		selectColumns = nil
+		for _, column := range columns {
+			selectColumns = append(selectColumns, "`group`."+column)
+		}
+	}
+	if len(userIds) == 0 {
+		return nil, nil
+	}
+
+	chain := db.GetChain(global.Global().ReadDatabase(ctx).
+		Table(db.AliasTable(constants.TableGroup)).
+		Select(selectColumns).
+		Joins("JOIN "+db.AliasTable(constants.TableUserGroupBinding)+" on `user_group_binding`.user_id in (?) AND `user_group_binding`.group_id=`group`.group_id"+
+			" AND `user_group_binding`.status in (?)", userIds, opts.bindingStatuses()))
+	if opts.RootGroupId != "" {
+		chain = chain.BuildRootGroupIdConditions([]string{opts.RootGroupId})
+	}
+	if !opts.IncludeArchivedGroups {
+		chain.DB = chain.Where("`group`."+constants.ColumnArchived+" = ?", false)
+	}
+
 	var groups []*models.Group
-	if err := global.Global().Database.
-		Table(constants.TableGroup).
-		Select("`group`.*").
-		Joins("JOIN `user_group_binding` on `user_group_binding`.user_id in (?) AND `user_group_binding`.group_id=`group`.group_id", userIds).
-		Scan(&groups).Error; err != nil {
+	if err := chain.Scan(&groups).Error; err != nil {
 		logger.Errorf(ctx, "Get groups by user id failed: %+v", err)
 		return nil, err
 	}
@@ -130,34 +707,298 @@ func GetGroupsByUserIds(ctx context.Context, userIds []string) ([]*models.Group,
 	return groups, nil
 }
 
-func GetUsersByGroupIds(ctx context.Context, groupIds []string) ([]*models.User, error) {
-	var users []*models.User
+// GroupMembership is a group of UserId with the role and the join time of the binding,
+// a group of several of the queried users appears once per user
+type GroupMembership struct {
+	models.Group
+	UserId   string
+	Role     string
+	JoinTime time.Time
+}
+
+// GetGroupMembershipsByUserIds is GetGroupsByUserIdsWithOptions with the binding of each group,
+// read in one query and ordered by user then join time
+func GetGroupMembershipsByUserIds(ctx context.Context, userIds []string, opts MembershipOptions) ([]*GroupMembership, error) {
+	if len(userIds) == 0 {
+		return nil, nil
+	}
+
+	chain := db.GetChain(global.Global().ReadDatabase(ctx).
+		Table(db.AliasTable(constants.TableGroup)).
+		Select("`group`.*, `user_group_binding`.user_id, `user_group_binding`.role, `user_group_binding`.create_time AS join_time").
+		Joins("JOIN "+db.AliasTable(constants.TableUserGroupBinding)+" on `user_group_binding`.user_id in (?) AND `user_group_binding`.group_id=`group`.group_id"+
+			" AND `user_group_binding`.status in (?)", userIds, opts.bindingStatuses()))
+	if opts.RootGroupId != "" {
+		chain = chain.BuildRootGroupIdConditions([]string{opts.RootGroupId})
+	}
+	if !opts.IncludeArchivedGroups {
+		chain.DB = chain.Where("`group`."+constants.ColumnArchived+" = ?", false)
+	}
+
+	var memberships []*GroupMembership
+	if err := chain.Order("`user_group_binding`.user_id, `user_group_binding`.create_time, `user_group_binding`.id").
+		Scan(&memberships).Error; err != nil {
+		logger.Errorf(ctx, "Get group memberships by user id failed: %+v", err)
+		return nil, err
+	}
+
+	return memberships, nil
+}
+
+// GroupsForUserOptions selects the view of the groups of a user returned by GetGroupsForUser
+type GroupsForUserOptions struct {
+	// the groups user is invited to are returned besides the accepted ones
+	IncludePending bool
+	// the ancestors of the groups of user are returned too, the groups user is effectively in
+	IncludeAncestors bool
+}
+
+// GetGroupsForUser returns the groups of user in the view of opts, every group once, ordered by group path
+func GetGroupsForUser(ctx context.Context, userId string, opts GroupsForUserOptions) ([]*models.Group, error) {
+	memberGroups, err := GetGroupsByUserIdsWithOptions(ctx, []string{userId},
+		MembershipOptions{IncludePending: opts.IncludePending}, constants.ColumnGroupId, constants.ColumnGroupPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(memberGroups) == 0 {
+		return nil, nil
+	}
+
+	var groupIds []string
+	for _, group := range memberGroups {
+		if opts.IncludeAncestors {
+			// the group path holds the ids of the ancestors and the group itself
+			groupIds = append(groupIds, strings.Split(group.GroupPath, constants.GroupPathSep)...)
+		} else {
+			groupIds = append(groupIds, group.GroupId)
+		}
+	}
+
+	// the ancestors are filtered like the groups of the bindings
+	var groups []*models.Group
+	if err := global.Global().ReadDatabase(ctx).
+		Table(db.TableName(constants.TableGroup)).
+		Where(constants.ColumnGroupId+" in (?)", stringutil.Unique(groupIds)).
+		Where(constants.ColumnStatus+" = ?", constants.StatusActive).
+		Where(constants.ColumnArchived+" = ?", false).
+		Order(constants.ColumnGroupPath).
+		Find(&groups).Error; err != nil {
+		logger.Errorf(ctx, "Get groups of user [%s] failed: %+v", userId, err)
+		return nil, err
+	}
+	return groups, nil
+}
+
+// GetAdminGroupsByUserId returns the active groups user administrates, which are the groups
+// user accepted to be admin of and their descendant groups
+func GetAdminGroupsByUserId(ctx context.Context, userId string) ([]*models.Group, error) {
+	var adminGroupPaths []string
 	if err := global.Global().Database.
-		Table(constants.TableUser).
-		Select("`user`.*").
-		Joins("JOIN `user_group_binding` on `user_group_binding`.group_id in (?) AND `user_group_binding`.user_id=`user`.user_id", groupIds).
-		Scan(&users).Error; err != nil {
-		logger.Errorf(ctx, "Get users by group id failed: %+v", err)
+		Table(db.AliasTable(constants.TableGroup)).
+		Joins("JOIN "+db.AliasTable(constants.TableUserGroupBinding)+" on `user_group_binding`.group_id=`group`.group_id"+
+			" AND `user_group_binding`.user_id = ? AND `user_group_binding`.status = ? AND `user_group_binding`.role = ?",
+			userId, constants.BindingStatusAccepted, constants.BindingRoleAdmin).
+		Where("`group`."+constants.ColumnStatus+" = ?", constants.StatusActive).
+		Pluck("`group`."+constants.ColumnGroupPath, &adminGroupPaths).Error; err != nil {
+		logger.Errorf(ctx, "Get admin groups of user [%s] failed: %+v", userId, err)
 		return nil, err
 	}
+	if len(adminGroupPaths) == 0 {
+		return nil, nil
+	}
+
+	var conditions []string
+	var args []interface{}
+	for _, groupPath := range adminGroupPaths {
+		conditions = append(conditions, constants.ColumnGroupPath+" = ? OR "+constants.ColumnGroupPath+" LIKE ?")
+		args = append(args, groupPath, groupPath+constants.GroupPathSep+"%")
+	}
+	var groups []*models.Group
+	if err := global.Global().Database.
+		Table(db.TableName(constants.TableGroup)).
+		Where(constants.ColumnStatus+" = ?", constants.StatusActive).
+		Where(strings.Join(conditions, " OR "), args...).
+		Order(constants.ColumnGroupPath).
+		Find(&groups).Error; err != nil {
+		logger.Errorf(ctx, "Get admin groups of user [%s] failed: %+v", userId, err)
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// GetUsersByGroupIds returns the active members of groups except excludeUserIds
+func GetUsersByGroupIds(ctx context.Context, groupIds []string, excludeUserIds ...string) ([]*models.User, error) {
+	return GetUsersByGroupIdsWithOptions(ctx, groupIds, MembershipOptions{ExcludeUserIds: excludeUserIds})
+}
+
+func GetUsersByGroupIdsWithOptions(ctx context.Context, groupIds []string, opts MembershipOptions) ([]*models.User, error) {
+	members, err := GetGroupMembersByGroupIds(ctx, groupIds, opts)
+	if err != nil {
+		return nil, err
+	}
+	var users []*models.User
+	for _, member := range members {
+		user := member.User
+		users = append(users, &user)
+	}
 
 	return users, nil
 }
 
+// GroupMember is a user found in GroupId, a user in several of the queried groups appears once per group
+type GroupMember struct {
+	models.User
+	GroupId string
+}
+
+// GetGroupMembersByGroupIds is GetUsersByGroupIdsWithOptions with the group each user is found in.
+// The bindings are read from the main database and their users from the databases holding them,
+// the members are ordered by group and the creation of the users.
+func GetGroupMembersByGroupIds(ctx context.Context, groupIds []string, opts MembershipOptions) ([]*GroupMember, error) {
+	if len(groupIds) == 0 {
+		return nil, nil
+	}
+	var bindings []*models.UserGroupBinding
+	if err := whereInOrNotIn(global.Global().ReadDatabase(ctx).
+		Table(db.TableName(constants.TableUserGroupBinding)).
+		Select([]string{constants.ColumnUserId, constants.ColumnGroupId}).
+		Where(constants.ColumnGroupId+" in (?)", groupIds).
+		Where(constants.ColumnStatus+" in (?)", opts.bindingStatuses()),
+		constants.ColumnUserId, opts.ExcludeUserIds, true).
+		Find(&bindings).Error; err != nil {
+		logger.Errorf(ctx, "Get group members by group id failed: %+v", err)
+		return nil, err
+	}
+	var userIds []string
+	for _, binding := range bindings {
+		userIds = append(userIds, binding.UserId)
+	}
+
+	users := make(map[string]*models.User)
+	for database, shardUserIds := range global.Global().GroupUserIds(stringutil.Unique(userIds)) {
+		// the users in the main database are read from its replica like the bindings
+		if database == global.Global().Database {
+			database = global.Global().ReadDatabase(ctx)
+		}
+		var shardUsers []*models.User
+		if err := opts.userStatusCondition(database.Table(db.AliasTable(constants.TableUser)).
+			Where("`user`."+constants.ColumnUserId+" in (?)", shardUserIds)).
+			Find(&shardUsers).Error; err != nil {
+			logger.Errorf(ctx, "Get users of group members failed: %+v", err)
+			return nil, err
+		}
+		for _, user := range shardUsers {
+			users[user.UserId] = user
+		}
+	}
+
+	var members []*GroupMember
+	for _, binding := range bindings {
+		if user, ok := users[binding.UserId]; ok {
+			members = append(members, &GroupMember{User: *user, GroupId: binding.GroupId})
+		}
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		if members[i].GroupId != members[j].GroupId {
+			return members[i].GroupId < members[j].GroupId
+		}
+		return members[i].CreateTime.Before(members[j].CreateTime)
+	})
+
+	return members, nil
+}
+
+// GetMembershipSummary returns the member user ids of every group in groupIds, read in one query,
+// it is meant for a few groups as all the members are kept in memory
+func GetMembershipSummary(ctx context.Context, groupIds []string) (map[string][]string, error) {
+	groupIds = stringutil.Unique(groupIds)
+	summary := make(map[string][]string, len(groupIds))
+	for _, groupId := range groupIds {
+		summary[groupId] = []string{}
+	}
+	if len(groupIds) == 0 {
+		return summary, nil
+	}
+
+	var userGroupBindings []*models.UserGroupBinding
+	if err := global.Global().ReadDatabase(ctx).Table(db.TableName(constants.TableUserGroupBinding)).
+		Select([]string{constants.ColumnGroupId, constants.ColumnUserId}).
+		Where(constants.ColumnGroupId+" in (?)", groupIds).
+		Where(constants.ColumnStatus+" = ?", constants.BindingStatusAccepted).
+		Order(constants.ColumnCreateTime).
+		Find(&userGroupBindings).Error; err != nil {
+		logger.Errorf(ctx, "Get membership summary failed: %+v", err)
+		return nil, err
+	}
+	for _, binding := range userGroupBindings {
+		summary[binding.GroupId] = append(summary[binding.GroupId], binding.UserId)
+	}
+
+	return summary, nil
+}
+
+// GetBindingsForUsers returns the group ids of every user in userIds, read in one query,
+// it is the summary of GetMembershipSummary grouped by user for prefetching the permissions of users
+func GetBindingsForUsers(ctx context.Context, userIds []string) (map[string][]string, error) {
+	userIds = stringutil.Unique(userIds)
+	bindings := make(map[string][]string, len(userIds))
+	for _, userId := range userIds {
+		bindings[userId] = []string{}
+	}
+	if len(userIds) == 0 {
+		return bindings, nil
+	}
+
+	var userGroupBindings []*models.UserGroupBinding
+	if err := global.Global().ReadDatabase(ctx).Table(db.TableName(constants.TableUserGroupBinding)).
+		Select([]string{constants.ColumnUserId, constants.ColumnGroupId}).
+		Where(constants.ColumnUserId+" in (?)", userIds).
+		Where(constants.ColumnStatus+" = ?", constants.BindingStatusAccepted).
+		Order(constants.ColumnCreateTime).
+		Find(&userGroupBindings).Error; err != nil {
+		logger.Errorf(ctx, "Get bindings of users failed: %+v", err)
+		return nil, err
+	}
+	for _, binding := range userGroupBindings {
+		bindings[binding.UserId] = append(bindings[binding.UserId], binding.GroupId)
+	}
+
+	return bindings, nil
+}
+
 func GetUserIdsByGroupIds(ctx context.Context, groupIds []string) ([]string, error) {
-	rows, err := global.Global().Database.Table(constants.TableUserGroupBinding).
+	return GetUserIdsByGroupIdsWithOptions(ctx, groupIds, MembershipOptions{})
+}
+
+func GetUserIdsByGroupIdsWithOptions(ctx context.Context, groupIds []string, opts MembershipOptions) ([]string, error) {
+	if len(groupIds) == 0 {
+		return nil, nil
+	}
+	rows, err := whereInOrNotIn(global.Global().Database.Table(db.TableName(constants.TableUserGroupBinding)).
 		Select(constants.ColumnUserId).
 		Where(constants.ColumnGroupId+" in (?)", groupIds).
+		Where(constants.ColumnStatus+" in (?)", opts.bindingStatuses()),
+		constants.ColumnUserId, opts.ExcludeUserIds, true).
 		Rows()
 	if err != nil {
 		logger.Errorf(ctx, "Get user ids by group id failed: %+v", err)
 		return nil, err
 	}
+	defer rows.Close()
+
 	var userIds []string
 	for rows.Next() {
 		var userId string
-		rows.Scan(&userId)
+		if err := rows.Scan(&userId); err != nil {
+			logger.Errorf(ctx, "Scan user id failed: %+v", err)
+			return nil, err
+		}
 		userIds = append(userIds, userId)
 	}
+	if err := rows.Err(); err != nil {
+		logger.Errorf(ctx, "Get user ids by group id failed: %+v", err)
+		return nil, err
+	}
 	return userIds, nil
 }
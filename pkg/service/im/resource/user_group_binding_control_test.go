@@ -0,0 +1,1494 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"cloudbases.io/im/pkg/config"
+	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/db"
+	"cloudbases.io/im/pkg/global"
+	"cloudbases.io/im/pkg/models"
+	"cloudbases.io/im/pkg/pb"
+)
+
+func createTestGroup(t *testing.T, groupName, parentGroupId string) string {
+	response, err := CreateGroup(context.Background(), &pb.CreateGroupRequest{
+		GroupName:     groupName,
+		ParentGroupId: parentGroupId,
+	})
+	require.NoError(t, err)
+	return response.GroupId
+}
+
+func TestGetBindingMatrix(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	user1 := createTestUser(t, "user1", "")
+	user2 := createTestUser(t, "user2", "")
+	group1 := createTestGroup(t, "group1", "")
+	group2 := createTestGroup(t, "group2", "")
+
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{user1},
+		GroupId: []string{group1, group2},
+	})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{user2},
+		GroupId: []string{group2},
+	})
+	require.NoError(t, err)
+
+	matrix, err := GetBindingMatrix(ctx, []string{user1, user2, user2}, []string{group1, group2})
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]bool{
+		user1: {group1: true, group2: true},
+		user2: {group1: false, group2: true},
+	}, matrix)
+
+	matrix, err = GetBindingMatrix(ctx, []string{user2}, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]bool{user2: {}}, matrix)
+}
+
+func TestBatchJoinGroup(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	user1 := createTestUser(t, "user1", "")
+	user2 := createTestUser(t, "user2", "")
+	deleted := createTestUser(t, "deleted", "")
+	group1 := createTestGroup(t, "group1", "")
+	group2 := createTestGroup(t, "group2", "")
+	_, err := DeleteUsers(ctx, &pb.DeleteUsersRequest{UserId: []string{deleted}})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{user1}, GroupId: []string{group1}})
+	require.NoError(t, err)
+
+	result, err := BatchJoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{user1, user2, deleted},
+		GroupId: []string{group1, group2, "gid-unknown"},
+		Role:    constants.BindingRoleAdmin,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		BindingItem(user2, group1), BindingItem(user1, group2), BindingItem(user2, group2),
+	}, result.Succeeded)
+
+	failed := make(map[string]codes.Code)
+	for _, itemError := range result.Failed {
+		require.NotEmpty(t, itemError.Reason)
+		failed[itemError.Item] = itemError.Code
+	}
+	require.Equal(t, map[string]codes.Code{
+		BindingItem(user1, group1):          codes.AlreadyExists,
+		BindingItem(deleted, group1):        codes.NotFound,
+		BindingItem(deleted, group2):        codes.NotFound,
+		BindingItem(user1, "gid-unknown"):   codes.NotFound,
+		BindingItem(user2, "gid-unknown"):   codes.NotFound,
+		BindingItem(deleted, "gid-unknown"): codes.NotFound,
+	}, failed)
+
+	// the succeeded bindings are created, the existing binding is left as it is
+	bindings, err := GetUserGroupBindings(ctx, []string{user1, user2}, []string{group1, group2})
+	require.NoError(t, err)
+	require.Len(t, bindings, 4)
+	for _, binding := range bindings {
+		expectedRole := constants.BindingRoleAdmin
+		if binding.UserId == user1 && binding.GroupId == group1 {
+			expectedRole = constants.BindingRoleMember
+		}
+		require.Equal(t, expectedRole, binding.Role, BindingItem(binding.UserId, binding.GroupId))
+	}
+	in, err := IsUserInGroup(ctx, user2, group2)
+	require.NoError(t, err)
+	require.True(t, in)
+
+	// the invalid request fails as a whole
+	_, err = BatchJoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{user1}, GroupId: []string{group2}, Status: "unknown"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = BatchJoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{user1}})
+	require.Equal(t, []string{"group_id"}, violatedFields(t, err))
+}
+
+func TestFilterExistingBindings(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	user1 := createTestUser(t, "user1", "")
+	user2 := createTestUser(t, "user2", "")
+	group1 := createTestGroup(t, "group1", "")
+	group2 := createTestGroup(t, "group2", "")
+
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{user1},
+		GroupId: []string{group1, group2},
+	})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{user2},
+		GroupId: []string{group2},
+	})
+	require.NoError(t, err)
+
+	exists, err := FilterExistingBindings(ctx, []UserGroupPair{
+		{UserId: user1, GroupId: group1},
+		{UserId: user2, GroupId: group1},
+		{UserId: user2, GroupId: group2},
+		{UserId: "uid-missing", GroupId: group2},
+		{UserId: user1, GroupId: "gid-missing"},
+		{UserId: user1, GroupId: group1},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []bool{true, false, true, false, false, true}, exists)
+
+	exists, err = FilterExistingBindings(ctx, nil)
+	require.NoError(t, err)
+	require.Empty(t, exists)
+}
+
+func TestGetBindingMatrixTooLarge(t *testing.T) {
+	prepare(t)
+
+	var userIds, groupIds []string
+	for i := 0; i < 1000; i++ {
+		userIds = append(userIds, fmt.Sprintf("uid-%d", i))
+	}
+	for i := 0; i < maxBindingMatrixSize/1000+1; i++ {
+		groupIds = append(groupIds, fmt.Sprintf("gid-%d", i))
+	}
+	_, err := GetBindingMatrix(context.Background(), userIds, groupIds)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestIterateBindings(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	groupId := createTestGroup(t, "large", "")
+	otherGroupId := createTestGroup(t, "other", "")
+	var userIds []string
+	for i := 0; i < 500; i++ {
+		userIds = append(userIds, fmt.Sprintf("uid-%d", i))
+	}
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  userIds,
+		GroupId: []string{groupId},
+	})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  userIds[:10],
+		GroupId: []string{otherGroupId},
+	})
+	require.NoError(t, err)
+
+	seen := make(map[string]bool)
+	err = IterateBindings(ctx, []string{groupId}, func(binding *models.UserGroupBinding) error {
+		require.Equal(t, groupId, binding.GroupId)
+		seen[binding.UserId] = true
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, seen, len(userIds))
+
+	// stop on callback error
+	stop := errors.New("stop")
+	count := 0
+	err = IterateBindings(ctx, []string{groupId, otherGroupId}, func(binding *models.UserGroupBinding) error {
+		count++
+		if count == 3 {
+			return stop
+		}
+		return nil
+	})
+	require.Equal(t, stop, err)
+	require.Equal(t, 3, count)
+}
+
+func TestGetGroupsByUserIdsDisplayColumns(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	userId := createTestUser(t, "sidebar", "")
+	groupId := createTestGroup(t, "sidebar", "")
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{userId},
+		GroupId: []string{groupId},
+	})
+	require.NoError(t, err)
+
+	groups, err := GetGroupsByUserIds(ctx, []string{userId})
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	require.Equal(t, groupId, groups[0].GroupId)
+	require.Equal(t, groupId, groups[0].GroupPath)
+	require.Equal(t, constants.StatusActive, groups[0].Status)
+
+	groups, err = GetGroupsByUserIds(ctx, []string{userId}, constants.ColumnGroupId, constants.ColumnGroupName, "unknown")
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	require.Equal(t, groupId, groups[0].GroupId)
+	require.Equal(t, "sidebar", groups[0].GroupName)
+	require.Empty(t, groups[0].GroupPath)
+	require.Empty(t, groups[0].Status)
+	require.True(t, groups[0].CreateTime.IsZero())
+
+	_, err = GetGroupsByUserIds(ctx, []string{userId}, "unknown")
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestPendingMembership(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	member := createTestUser(t, "member", "")
+	invited := createTestUser(t, "invited", "")
+	groupId := createTestGroup(t, "invite", "")
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{member},
+		GroupId: []string{groupId},
+	})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{invited},
+		GroupId: []string{groupId},
+		Status:  constants.BindingStatusPending,
+	})
+	require.NoError(t, err)
+
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{invited},
+		GroupId: []string{groupId},
+		Status:  "unknown",
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	userIds := func(users []*models.User) []string {
+		var ids []string
+		for _, user := range users {
+			ids = append(ids, user.UserId)
+		}
+		return ids
+	}
+
+	// accepted only by default
+	users, err := GetUsersByGroupIds(ctx, []string{groupId})
+	require.NoError(t, err)
+	require.Equal(t, []string{member}, userIds(users))
+	ids, err := GetUserIdsByGroupIds(ctx, []string{groupId})
+	require.NoError(t, err)
+	require.Equal(t, []string{member}, ids)
+	groups, err := GetGroupsByUserIds(ctx, []string{invited})
+	require.NoError(t, err)
+	require.Empty(t, groups)
+	matrix, err := GetBindingMatrix(ctx, []string{member, invited}, []string{groupId})
+	require.NoError(t, err)
+	require.True(t, matrix[member][groupId])
+	require.False(t, matrix[invited][groupId])
+
+	// include pending
+	opts := MembershipOptions{IncludePending: true}
+	users, err = GetUsersByGroupIdsWithOptions(ctx, []string{groupId}, opts)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{member, invited}, userIds(users))
+	ids, err = GetUserIdsByGroupIdsWithOptions(ctx, []string{groupId}, opts)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{member, invited}, ids)
+	groups, err = GetGroupsByUserIdsWithOptions(ctx, []string{invited}, opts)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+
+	// invited user can not join again, but can accept the invitation
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{invited},
+		GroupId: []string{groupId},
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	require.Equal(t, codes.PermissionDenied, status.Code(AcceptInvitations(ctx, member, []string{groupId})))
+
+	// the invitation is not accepted along with a group the user is not invited to
+	otherGroupId := createTestGroup(t, "other", "")
+	require.Equal(t, codes.PermissionDenied, status.Code(AcceptInvitations(ctx, invited, []string{groupId, otherGroupId})))
+	matrix, err = GetBindingMatrix(ctx, []string{invited}, []string{groupId})
+	require.NoError(t, err)
+	require.False(t, matrix[invited][groupId])
+
+	require.NoError(t, AcceptInvitations(ctx, invited, []string{groupId}))
+
+	users, err = GetUsersByGroupIds(ctx, []string{groupId})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{member, invited}, userIds(users))
+}
+
+func TestDeleteGroupWithPendingMembership(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	invited := createTestUser(t, "invited", "")
+	groupId := createTestGroup(t, "invite", "")
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{invited},
+		GroupId: []string{groupId},
+		Status:  constants.BindingStatusPending,
+	})
+	require.NoError(t, err)
+
+	_, err = DeleteGroups(ctx, &pb.DeleteGroupsRequest{GroupId: []string{groupId}})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestDeleteGroupsCascade(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	user1 := createTestUser(t, "user1", "")
+	user2 := createTestUser(t, "user2", "")
+	root := createTestGroup(t, "root", "")
+	child := createTestGroup(t, "child", root)
+	other := createTestGroup(t, "other", "")
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{user1}, GroupId: []string{root, other}})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{user2}, GroupId: []string{child}, Status: constants.BindingStatusPending})
+	require.NoError(t, err)
+	groupStatus := func(groupId string) string {
+		group, err := GetGroup(ctx, groupId)
+		require.NoError(t, err)
+		return group.Status
+	}
+
+	// without cascade the groups with bindings are not deleted
+	_, err = DeleteGroups(ctx, &pb.DeleteGroupsRequest{GroupId: []string{other}})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	require.Contains(t, status.Convert(err).Message(), other)
+	require.Equal(t, constants.StatusActive, groupStatus(other))
+
+	res, err := DeleteGroups(ctx, &pb.DeleteGroupsRequest{GroupId: []string{other}, Cascade: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{other}, res.GroupId)
+	require.Equal(t, constants.StatusDeleted, groupStatus(other))
+	bindings, err := GetUserGroupBindings(ctx, nil, []string{other})
+	require.NoError(t, err)
+	require.Empty(t, bindings)
+
+	// the sub groups are deleted only with cascade descendants
+	_, err = DeleteGroups(ctx, &pb.DeleteGroupsRequest{GroupId: []string{root}, Cascade: true})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	require.Equal(t, constants.StatusActive, groupStatus(root))
+
+	res, err = DeleteGroups(ctx, &pb.DeleteGroupsRequest{GroupId: []string{root}, Cascade: true, CascadeDescendants: true})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{root, child}, res.GroupId)
+	require.Equal(t, constants.StatusDeleted, groupStatus(root))
+	require.Equal(t, constants.StatusDeleted, groupStatus(child))
+	bindings, err = GetUserGroupBindings(ctx, []string{user1, user2}, nil)
+	require.NoError(t, err)
+	require.Empty(t, bindings)
+}
+
+func TestGetUserGroupBindingsNotIn(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	user1 := createTestUser(t, "user1", "")
+	user2 := createTestUser(t, "user2", "")
+	allowed1 := createTestGroup(t, "allowed1", "")
+	allowed2 := createTestGroup(t, "allowed2", "")
+	other := createTestGroup(t, "other", "")
+
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{user1, user2},
+		GroupId: []string{allowed1},
+	})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{user1},
+		GroupId: []string{allowed2, other},
+	})
+	require.NoError(t, err)
+
+	bindingPairs := func(bindings []*models.UserGroupBinding) map[string]string {
+		pairs := make(map[string]string)
+		for _, binding := range bindings {
+			pairs[binding.UserId+"/"+binding.GroupId] = binding.Status
+		}
+		return pairs
+	}
+
+	// bindings to groups outside the allowed set
+	bindings, err := GetUserGroupBindingsWithOptions(ctx, []string{user1, user2}, []string{allowed1, allowed2},
+		BindingQueryOptions{NotInGroups: true})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{user1 + "/" + other: constants.BindingStatusAccepted}, bindingPairs(bindings))
+
+	// bindings of the other users to a group
+	bindings, err = GetUserGroupBindingsWithOptions(ctx, []string{user1}, []string{allowed1},
+		BindingQueryOptions{NotInUsers: true})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{user2 + "/" + allowed1: constants.BindingStatusAccepted}, bindingPairs(bindings))
+
+	// empty NOT IN set matches all
+	bindings, err = GetUserGroupBindingsWithOptions(ctx, nil, []string{allowed1},
+		BindingQueryOptions{NotInUsers: true})
+	require.NoError(t, err)
+	require.Len(t, bindings, 2)
+
+	// without options it is the same as GetUserGroupBindings
+	bindings, err = GetUserGroupBindingsWithOptions(ctx, []string{user1}, []string{allowed1, other}, BindingQueryOptions{})
+	require.NoError(t, err)
+	require.Len(t, bindings, 2)
+}
+
+func TestGetUserGroupBindingsCreatedBy(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	admin1 := createTestUser(t, "admin1", "")
+	admin2 := createTestUser(t, "admin2", "")
+	user1 := createTestUser(t, "user1", "")
+	user2 := createTestUser(t, "user2", "")
+	group1 := createTestGroup(t, "group1", "")
+	group2 := createTestGroup(t, "group2", "")
+
+	for _, req := range []*pb.JoinGroupRequest{
+		{UserId: []string{user1, user2}, GroupId: []string{group1}, CreatedBy: admin1},
+		{UserId: []string{user1}, GroupId: []string{group2}, CreatedBy: admin2, Status: constants.BindingStatusPending},
+		{UserId: []string{user2}, GroupId: []string{group2}},
+	} {
+		_, err := JoinGroup(ctx, req)
+		require.NoError(t, err)
+	}
+
+	// the bindings created by admin1 among all the bindings
+	bindings, err := GetUserGroupBindingsWithOptions(ctx, nil, nil,
+		BindingQueryOptions{NotInUsers: true, NotInGroups: true, CreatedBy: admin1})
+	require.NoError(t, err)
+	require.Len(t, bindings, 2)
+	for _, binding := range bindings {
+		require.Equal(t, group1, binding.GroupId)
+		require.Equal(t, admin1, binding.CreatedBy)
+	}
+
+	bindings, err = GetUserGroupBindingsWithOptions(ctx, []string{user1, user2}, []string{group2},
+		BindingQueryOptions{CreatedBy: admin2})
+	require.NoError(t, err)
+	require.Len(t, bindings, 1)
+	require.Equal(t, user1, bindings[0].UserId)
+
+	bindings, err = GetUserGroupBindingsWithOptions(ctx, []string{user1, user2}, []string{group1},
+		BindingQueryOptions{CreatedBy: admin2})
+	require.NoError(t, err)
+	require.Empty(t, bindings)
+
+	// the creator is unknown without created_by
+	bindings, err = GetUserGroupBindings(ctx, []string{user2}, []string{group2})
+	require.NoError(t, err)
+	require.Len(t, bindings, 1)
+	require.Empty(t, bindings[0].CreatedBy)
+}
+
+func TestGetUserGroupBindingsOrder(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	var userIds, groupIds []string
+	for _, name := range []string{"c", "a", "b"} {
+		userIds = append(userIds, createTestUser(t, "user-"+name, ""))
+		groupIds = append(groupIds, createTestGroup(t, "group-"+name, ""))
+	}
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  userIds,
+		GroupId: groupIds,
+	})
+	require.NoError(t, err)
+
+	bindingPairs := func(bindings []*models.UserGroupBinding) []string {
+		var pairs []string
+		for _, binding := range bindings {
+			pairs = append(pairs, binding.GroupId+"/"+binding.UserId)
+		}
+		return pairs
+	}
+
+	bindings, err := GetUserGroupBindings(ctx, userIds, groupIds)
+	require.NoError(t, err)
+	require.Len(t, bindings, 9)
+	pairs := bindingPairs(bindings)
+	require.True(t, sort.StringsAreSorted(pairs), "%v", pairs)
+
+	// repeated fetches are the same
+	for i := 0; i < 3; i++ {
+		bindings, err = GetUserGroupBindings(ctx, userIds, groupIds)
+		require.NoError(t, err)
+		require.Equal(t, pairs, bindingPairs(bindings))
+	}
+
+	bindings, err = GetUserGroupBindingsWithOptions(ctx, userIds, groupIds, BindingQueryOptions{OrderByUser: true})
+	require.NoError(t, err)
+	var userPairs []string
+	for _, binding := range bindings {
+		userPairs = append(userPairs, binding.UserId+"/"+binding.GroupId)
+	}
+	require.Len(t, userPairs, 9)
+	require.True(t, sort.StringsAreSorted(userPairs), "%v", userPairs)
+}
+
+func TestGetAdminGroupsByUserId(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	admin := createTestUser(t, "admin", "")
+	member := createTestUser(t, "member", "")
+	root := createTestGroup(t, "root", "")
+	sub := createTestGroup(t, "sub", root)
+	subSub := createTestGroup(t, "subsub", sub)
+	other := createTestGroup(t, "other", "")
+	invited := createTestGroup(t, "invited", "")
+
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{admin},
+		GroupId: []string{sub},
+		Role:    constants.BindingRoleAdmin,
+	})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{admin, member},
+		GroupId: []string{root, other},
+	})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{admin},
+		GroupId: []string{invited},
+		Status:  constants.BindingStatusPending,
+		Role:    constants.BindingRoleAdmin,
+	})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{member},
+		GroupId: []string{sub},
+		Role:    "owner",
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	groupIds := func(groups []*models.Group) []string {
+		var ids []string
+		for _, group := range groups {
+			ids = append(ids, group.GroupId)
+		}
+		return ids
+	}
+
+	// admin of sub manages its descendants, but neither its parent nor the pending group
+	groups, err := GetAdminGroupsByUserId(ctx, admin)
+	require.NoError(t, err)
+	require.Equal(t, []string{sub, subSub}, groupIds(groups))
+
+	groups, err = GetAdminGroupsByUserId(ctx, member)
+	require.NoError(t, err)
+	require.Empty(t, groups)
+
+	// members of groups are still members regardless of the role
+	users, err := GetUsersByGroupIds(ctx, []string{sub})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+}
+
+func TestTransferGroupAdmin(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	admin := createTestUser(t, "admin", "")
+	member := createTestUser(t, "member", "")
+	invited := createTestUser(t, "invited", "")
+	outsider := createTestUser(t, "outsider", "")
+	groupId := createTestGroup(t, "group", "")
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{admin},
+		GroupId: []string{groupId},
+		Role:    constants.BindingRoleAdmin,
+	})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{member}, GroupId: []string{groupId}})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{invited},
+		GroupId: []string{groupId},
+		Status:  constants.BindingStatusPending,
+	})
+	require.NoError(t, err)
+
+	roles := func() map[string]string {
+		bindings, err := GetUserGroupBindings(ctx, []string{admin, member, invited}, []string{groupId})
+		require.NoError(t, err)
+		roles := make(map[string]string)
+		for _, binding := range bindings {
+			roles[binding.UserId] = binding.Role
+		}
+		return roles
+	}
+	before := roles()
+
+	// the target must be an accepted member, nothing is changed otherwise
+	for _, toUserId := range []string{outsider, invited} {
+		err = TransferGroupAdmin(ctx, groupId, admin, toUserId)
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+		require.Equal(t, before, roles())
+	}
+	// the source must be an admin
+	err = TransferGroupAdmin(ctx, groupId, member, admin)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	require.Equal(t, codes.InvalidArgument, status.Code(TransferGroupAdmin(ctx, groupId, admin, admin)))
+
+	require.NoError(t, TransferGroupAdmin(ctx, groupId, admin, member))
+	require.Equal(t, map[string]string{
+		admin:   constants.BindingRoleMember,
+		member:  constants.BindingRoleAdmin,
+		invited: constants.BindingRoleMember,
+	}, roles())
+	groups, err := GetAdminGroupsByUserId(ctx, admin)
+	require.NoError(t, err)
+	require.Empty(t, groups)
+}
+
+func TestGetGroupMembersByGroupIds(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	user1 := createTestUser(t, "user1", "")
+	user2 := createTestUser(t, "user2", "")
+	user3 := createTestUser(t, "user3", "")
+	group1 := createTestGroup(t, "group1", "")
+	group2 := createTestGroup(t, "group2", "")
+	group3 := createTestGroup(t, "group3", "")
+
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{user1, user2},
+		GroupId: []string{group1},
+	})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{user1},
+		GroupId: []string{group2, group3},
+	})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{user3},
+		GroupId: []string{group2},
+		Status:  constants.BindingStatusPending,
+	})
+	require.NoError(t, err)
+
+	pairs := func(members []*GroupMember) map[string][]string {
+		groupUsers := make(map[string][]string)
+		for _, member := range members {
+			require.NotEmpty(t, member.Username)
+			groupUsers[member.GroupId] = append(groupUsers[member.GroupId], member.UserId)
+		}
+		return groupUsers
+	}
+
+	members, err := GetGroupMembersByGroupIds(ctx, []string{group1, group2}, MembershipOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{
+		group1: {user1, user2},
+		group2: {user1},
+	}, pairs(members))
+
+	members, err = GetGroupMembersByGroupIds(ctx, []string{group2}, MembershipOptions{IncludePending: true})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{
+		group2: {user1, user3},
+	}, pairs(members))
+}
+
+func TestGetGroupMembershipsByUserIds(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	user1 := createTestUser(t, "user1", "")
+	user2 := createTestUser(t, "user2", "")
+	group1 := createTestGroup(t, "group1", "")
+	group2 := createTestGroup(t, "group2", "")
+	group3 := createTestGroup(t, "group3", "")
+
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{user1},
+		GroupId: []string{group1},
+		Role:    constants.BindingRoleAdmin,
+	})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{user1, user2},
+		GroupId: []string{group2},
+	})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{user2},
+		GroupId: []string{group3},
+		Status:  constants.BindingStatusPending,
+	})
+	require.NoError(t, err)
+
+	bindings, err := GetUserGroupBindings(ctx, []string{user1, user2}, nil)
+	require.NoError(t, err)
+	createTimes := make(map[string]time.Time)
+	for _, binding := range bindings {
+		createTimes[BindingItem(binding.UserId, binding.GroupId)] = binding.CreateTime
+	}
+
+	memberships, err := GetGroupMembershipsByUserIds(ctx, []string{user1, user2}, MembershipOptions{})
+	require.NoError(t, err)
+	type membership struct {
+		userId, groupId, role string
+	}
+	var got []membership
+	for _, m := range memberships {
+		require.NotEmpty(t, m.GroupName)
+		require.False(t, m.JoinTime.IsZero())
+		require.True(t, createTimes[BindingItem(m.UserId, m.GroupId)].Equal(m.JoinTime))
+		got = append(got, membership{m.UserId, m.GroupId, m.Role})
+	}
+	expected := []membership{
+		{user1, group1, constants.BindingRoleAdmin},
+		{user1, group2, constants.BindingRoleMember},
+		{user2, group2, constants.BindingRoleMember},
+	}
+	require.Equal(t, expected, got)
+
+	memberships, err = GetGroupMembershipsByUserIds(ctx, []string{user2}, MembershipOptions{IncludePending: true})
+	require.NoError(t, err)
+	require.Len(t, memberships, 2)
+	require.Equal(t, group3, memberships[1].GroupId)
+}
+
+func TestSetUserGroups(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	userId := createTestUser(t, "alice", "")
+	other := createTestUser(t, "bob", "")
+	group1 := createTestGroup(t, "group1", "")
+	group2 := createTestGroup(t, "group2", "")
+	group3 := createTestGroup(t, "group3", "")
+	group4 := createTestGroup(t, "group4", "")
+
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{userId, other},
+		GroupId: []string{group1, group2},
+	})
+	require.NoError(t, err)
+
+	groupsOf := func(userId string) []string {
+		bindings, err := GetUserGroupBindingsWithOptions(ctx, []string{userId}, nil, BindingQueryOptions{NotInGroups: true})
+		require.NoError(t, err)
+		var groupIds []string
+		for _, binding := range bindings {
+			groupIds = append(groupIds, binding.GroupId)
+		}
+		sort.Strings(groupIds)
+		return groupIds
+	}
+
+	// group2 is kept, group1 removed, group3 and group4 added
+	added, removed, err := SetUserGroups(ctx, userId, []string{group2, group3, group4, group3})
+	require.NoError(t, err)
+	require.Equal(t, 2, added)
+	require.Equal(t, 1, removed)
+	require.Equal(t, []string{group2, group3, group4}, groupsOf(userId))
+
+	// the other user is untouched
+	require.Equal(t, []string{group1, group2}, groupsOf(other))
+
+	// same set again changes nothing
+	added, removed, err = SetUserGroups(ctx, userId, []string{group4, group3, group2})
+	require.NoError(t, err)
+	require.Equal(t, 0, added)
+	require.Equal(t, 0, removed)
+
+	// empty set leaves all groups
+	added, removed, err = SetUserGroups(ctx, userId, nil)
+	require.NoError(t, err)
+	require.Equal(t, 0, added)
+	require.Equal(t, 3, removed)
+	require.Empty(t, groupsOf(userId))
+
+	_, _, err = SetUserGroups(ctx, "", []string{group1})
+	require.Error(t, err)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestSetGroupMembers(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	user1 := createTestUser(t, "user1", "")
+	user2 := createTestUser(t, "user2", "")
+	user3 := createTestUser(t, "user3", "")
+	user4 := createTestUser(t, "user4", "")
+	groupId := createTestGroup(t, "group", "")
+	otherGroup := createTestGroup(t, "other", "")
+
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{user1, user2},
+		GroupId: []string{groupId, otherGroup},
+	})
+	require.NoError(t, err)
+
+	membersOf := func(groupId string) []string {
+		bindings, err := GetUserGroupBindingsWithOptions(ctx, nil, []string{groupId}, BindingQueryOptions{NotInUsers: true})
+		require.NoError(t, err)
+		var userIds []string
+		for _, binding := range bindings {
+			userIds = append(userIds, binding.UserId)
+		}
+		sort.Strings(userIds)
+		return userIds
+	}
+
+	// overlap: user2 is kept
+	added, removed, err := SetGroupMembers(ctx, groupId, []string{user2, user3})
+	require.NoError(t, err)
+	require.Equal(t, 1, added)
+	require.Equal(t, 1, removed)
+	require.Equal(t, []string{user2, user3}, membersOf(groupId))
+
+	// full replacement
+	added, removed, err = SetGroupMembers(ctx, groupId, []string{user1, user4})
+	require.NoError(t, err)
+	require.Equal(t, 2, added)
+	require.Equal(t, 2, removed)
+	require.Equal(t, []string{user1, user4}, membersOf(groupId))
+
+	// emptying the group
+	added, removed, err = SetGroupMembers(ctx, groupId, []string{})
+	require.NoError(t, err)
+	require.Equal(t, 0, added)
+	require.Equal(t, 2, removed)
+	require.Empty(t, membersOf(groupId))
+
+	// the other group is untouched
+	require.Equal(t, []string{user1, user2}, membersOf(otherGroup))
+
+	_, _, err = SetGroupMembers(ctx, "", []string{user1})
+	require.Error(t, err)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGetUsersByGroupIdsExclude(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	user1 := createTestUser(t, "user1", "")
+	user2 := createTestUser(t, "user2", "")
+	user3 := createTestUser(t, "user3", "")
+	groupId := createTestGroup(t, "group", "")
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{user1, user2, user3},
+		GroupId: []string{groupId},
+	})
+	require.NoError(t, err)
+
+	userIds := func(users []*models.User) []string {
+		var ids []string
+		for _, user := range users {
+			ids = append(ids, user.UserId)
+		}
+		return ids
+	}
+
+	users, err := GetUsersByGroupIds(ctx, []string{groupId}, user1, user3, "uid-unknown")
+	require.NoError(t, err)
+	require.Equal(t, []string{user2}, userIds(users))
+
+	// empty exclude behaves as before
+	users, err = GetUsersByGroupIds(ctx, []string{groupId})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{user1, user2, user3}, userIds(users))
+	users, err = GetUsersByGroupIds(ctx, []string{groupId}, []string{}...)
+	require.NoError(t, err)
+	require.Len(t, users, 3)
+
+	opts := MembershipOptions{ExcludeUserIds: []string{user2}}
+	ids, err := GetUserIdsByGroupIdsWithOptions(ctx, []string{groupId}, opts)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{user1, user3}, ids)
+	members, err := GetGroupMembersByGroupIds(ctx, []string{groupId}, opts)
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+}
+
+func TestGetMembershipSummary(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	user1 := createTestUser(t, "user1", "")
+	user2 := createTestUser(t, "user2", "")
+	user3 := createTestUser(t, "user3", "")
+	group1 := createTestGroup(t, "group1", "")
+	group2 := createTestGroup(t, "group2", "")
+	empty := createTestGroup(t, "empty", "")
+	other := createTestGroup(t, "other", "")
+
+	for _, req := range []*pb.JoinGroupRequest{
+		{UserId: []string{user1, user2}, GroupId: []string{group1}},
+		{UserId: []string{user2}, GroupId: []string{group2}},
+		{UserId: []string{user3}, GroupId: []string{group2}, Status: constants.BindingStatusPending},
+		{UserId: []string{user3}, GroupId: []string{other}},
+	} {
+		_, err := JoinGroup(ctx, req)
+		require.NoError(t, err)
+	}
+
+	summary, err := GetMembershipSummary(ctx, []string{group1, group2, empty, group1})
+	require.NoError(t, err)
+	require.Len(t, summary, 3)
+	require.ElementsMatch(t, []string{user1, user2}, summary[group1])
+	// pending invitations are not members
+	require.Equal(t, []string{user2}, summary[group2])
+	require.Empty(t, summary[empty])
+	require.Contains(t, summary, empty)
+
+	summary, err = GetMembershipSummary(ctx, nil)
+	require.NoError(t, err)
+	require.Empty(t, summary)
+}
+
+func TestGetBindingsForUsers(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	user1 := createTestUser(t, "user1", "")
+	user2 := createTestUser(t, "user2", "")
+	user3 := createTestUser(t, "user3", "")
+	lonely := createTestUser(t, "lonely", "")
+	other := createTestUser(t, "other", "")
+	group1 := createTestGroup(t, "group1", "")
+	group2 := createTestGroup(t, "group2", "")
+	group3 := createTestGroup(t, "group3", "")
+
+	for _, req := range []*pb.JoinGroupRequest{
+		{UserId: []string{user1}, GroupId: []string{group1, group2, group3}},
+		{UserId: []string{user2}, GroupId: []string{group2}},
+		{UserId: []string{user3}, GroupId: []string{group1}, Status: constants.BindingStatusPending},
+		{UserId: []string{other}, GroupId: []string{group1}},
+	} {
+		_, err := JoinGroup(ctx, req)
+		require.NoError(t, err)
+	}
+
+	bindings, err := GetBindingsForUsers(ctx, []string{user1, user2, user3, lonely, user1})
+	require.NoError(t, err)
+	require.Len(t, bindings, 4)
+	require.ElementsMatch(t, []string{group1, group2, group3}, bindings[user1])
+	require.Equal(t, []string{group2}, bindings[user2])
+	// pending invitations are not bindings of the user
+	require.Empty(t, bindings[user3])
+	require.Contains(t, bindings, lonely)
+	require.Empty(t, bindings[lonely])
+
+	bindings, err = GetBindingsForUsers(ctx, nil)
+	require.NoError(t, err)
+	require.Empty(t, bindings)
+}
+
+func TestGetGroupsByUserIdsConsistentRead(t *testing.T) {
+	cfg := config.Default()
+	cfg.DB.Type = "sqlite3"
+	cfg.DB.Database = filepath.Join(t.TempDir(), "im.db")
+	cfg.DB.AutoMigrate = true
+	// the replica never receives the writes, as if it lagged behind the primary
+	cfg.DB.ReplicaHost = filepath.Join(t.TempDir(), "replica.db")
+	global.SetGlobal(cfg)
+	t.Cleanup(func() {
+		global.Global().Close()
+	})
+	require.NoError(t, global.Global().Replica.Migrate())
+	ctx := context.Background()
+
+	userId := createTestUser(t, "user1", "")
+	groupId := createTestGroup(t, "group1", "")
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{userId}, GroupId: []string{groupId}})
+	require.NoError(t, err)
+
+	groups, err := GetGroupsByUserIds(ctx, []string{userId})
+	require.NoError(t, err)
+	require.Empty(t, groups)
+	users, err := GetUsersByGroupIds(ctx, []string{groupId})
+	require.NoError(t, err)
+	require.Empty(t, users)
+
+	ctx = db.WithConsistentRead(ctx)
+	groups, err = GetGroupsByUserIds(ctx, []string{userId})
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	require.Equal(t, groupId, groups[0].GroupId)
+	users, err = GetUsersByGroupIds(ctx, []string{groupId})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, userId, users[0].UserId)
+}
+
+func TestGetGroupsByUserIdsArchived(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	userId := createTestUser(t, "user1", "")
+	active := createTestGroup(t, "active", "")
+	archived := createTestGroup(t, "archived", "")
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{userId}, GroupId: []string{active, archived}})
+	require.NoError(t, err)
+	require.NoError(t, ArchiveGroups(ctx, []string{archived}, true))
+
+	groups, err := GetGroupsByUserIds(ctx, []string{userId})
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	require.Equal(t, active, groups[0].GroupId)
+	require.False(t, groups[0].Archived)
+
+	// the admin views see the archived groups too
+	groups, err = GetGroupsByUserIdsWithOptions(ctx, []string{userId}, MembershipOptions{IncludeArchivedGroups: true})
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	for _, group := range groups {
+		require.Equal(t, group.GroupId == archived, group.Archived, group.GroupId)
+	}
+
+	// the archived group is still there for its members
+	in, err := IsUserInGroup(ctx, userId, archived)
+	require.NoError(t, err)
+	require.True(t, in)
+
+	require.NoError(t, ArchiveGroups(ctx, []string{archived}, false))
+	groups, err = GetGroupsByUserIds(ctx, []string{userId})
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+
+	require.Equal(t, codes.InvalidArgument, status.Code(ArchiveGroups(ctx, nil, true)))
+}
+
+func TestGetGroupsByUserIdsInRootGroup(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	userId := createTestUser(t, "tenant-user", "")
+	tenant := createTestGroup(t, "tenant", "")
+	team := createTestGroup(t, "team", tenant)
+	squad := createTestGroup(t, "squad", team)
+	otherTenant := createTestGroup(t, "other-tenant", "")
+	otherTeam := createTestGroup(t, "other-team", otherTenant)
+
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{userId},
+		GroupId: []string{tenant, squad, otherTenant, otherTeam},
+	})
+	require.NoError(t, err)
+
+	groupIds := func(opts MembershipOptions) []string {
+		groups, err := GetGroupsByUserIdsWithOptions(ctx, []string{userId}, opts)
+		require.NoError(t, err)
+		var ids []string
+		for _, group := range groups {
+			ids = append(ids, group.GroupId)
+		}
+		return ids
+	}
+
+	require.ElementsMatch(t, []string{tenant, squad, otherTenant, otherTeam}, groupIds(MembershipOptions{}))
+	require.ElementsMatch(t, []string{tenant, squad}, groupIds(MembershipOptions{RootGroupId: tenant}))
+	require.ElementsMatch(t, []string{squad}, groupIds(MembershipOptions{RootGroupId: team}))
+	require.ElementsMatch(t, []string{otherTenant, otherTeam}, groupIds(MembershipOptions{RootGroupId: otherTenant}))
+	require.Empty(t, groupIds(MembershipOptions{RootGroupId: "gid-unknown"}))
+
+	// root groups of ListGroups are matched by prefix too
+	response, err := ListGroups(ctx, &pb.ListGroupsRequest{RootGroupId: []string{team}})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, response.Total)
+}
+
+func TestGetGroupsForUser(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	userId := createTestUser(t, "member", "")
+	tenant := createTestGroup(t, "tenant", "")
+	team := createTestGroup(t, "team", tenant)
+	squad := createTestGroup(t, "squad", team)
+	otherTenant := createTestGroup(t, "other-tenant", "")
+	otherTeam := createTestGroup(t, "other-team", otherTenant)
+
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{userId},
+		GroupId: []string{squad, tenant},
+	})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{
+		UserId:  []string{userId},
+		GroupId: []string{otherTeam},
+		Status:  constants.BindingStatusPending,
+	})
+	require.NoError(t, err)
+
+	groupIds := func(opts GroupsForUserOptions) []string {
+		groups, err := GetGroupsForUser(ctx, userId, opts)
+		require.NoError(t, err)
+		var ids []string
+		for _, group := range groups {
+			ids = append(ids, group.GroupId)
+		}
+		return ids
+	}
+
+	// ordered by group path, the ancestor shared by two groups is returned once
+	require.Equal(t, []string{tenant, squad}, groupIds(GroupsForUserOptions{}))
+	require.Equal(t, []string{tenant, team, squad}, groupIds(GroupsForUserOptions{IncludeAncestors: true}))
+	require.ElementsMatch(t, []string{tenant, squad, otherTeam},
+		groupIds(GroupsForUserOptions{IncludePending: true}))
+	require.ElementsMatch(t, []string{tenant, team, squad, otherTenant, otherTeam},
+		groupIds(GroupsForUserOptions{IncludePending: true, IncludeAncestors: true}))
+
+	// the archived and deleted ancestors are left out
+	require.NoError(t, ArchiveGroups(ctx, []string{team}, true))
+	require.Equal(t, []string{tenant, squad}, groupIds(GroupsForUserOptions{IncludeAncestors: true}))
+	require.NoError(t, global.Global().Database.Table(db.TableName(constants.TableGroup)).
+		Where(constants.ColumnGroupId+" = ?", otherTenant).
+		Update(constants.ColumnStatus, constants.StatusDeleted).Error)
+	require.ElementsMatch(t, []string{tenant, squad, otherTeam},
+		groupIds(GroupsForUserOptions{IncludePending: true, IncludeAncestors: true}))
+
+	groups, err := GetGroupsForUser(ctx, "uid-unknown", GroupsForUserOptions{IncludePending: true, IncludeAncestors: true})
+	require.NoError(t, err)
+	require.Empty(t, groups)
+}
+
+func TestBindingLoadersEmptyIds(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	userId := createTestUser(t, "member", "")
+	groupId := createTestGroup(t, "group", "")
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{userId}, GroupId: []string{groupId}})
+	require.NoError(t, err)
+
+	for _, ids := range [][]string{nil, {}} {
+		bindings, err := GetUserGroupBindings(ctx, ids, []string{groupId})
+		require.NoError(t, err)
+		require.Empty(t, bindings)
+		bindings, err = GetUserGroupBindings(ctx, []string{userId}, ids)
+		require.NoError(t, err)
+		require.Empty(t, bindings)
+		// no ids of a negated condition mean all of them
+		bindings, err = GetUserGroupBindingsWithOptions(ctx, ids, []string{groupId}, BindingQueryOptions{NotInUsers: true})
+		require.NoError(t, err)
+		require.Len(t, bindings, 1)
+
+		require.NoError(t, IterateBindings(ctx, ids, func(*models.UserGroupBinding) error {
+			return fmt.Errorf("unexpected binding")
+		}))
+
+		groups, err := GetGroupsByUserIds(ctx, ids)
+		require.NoError(t, err)
+		require.Empty(t, groups)
+		users, err := GetUsersByGroupIds(ctx, ids)
+		require.NoError(t, err)
+		require.Empty(t, users)
+		members, err := GetGroupMembersByGroupIds(ctx, ids, MembershipOptions{})
+		require.NoError(t, err)
+		require.Empty(t, members)
+		userIds, err := GetUserIdsByGroupIds(ctx, ids)
+		require.NoError(t, err)
+		require.Empty(t, userIds)
+		summary, err := GetMembershipSummary(ctx, ids)
+		require.NoError(t, err)
+		require.Empty(t, summary)
+		matrix, err := GetBindingMatrix(ctx, []string{userId}, ids)
+		require.NoError(t, err)
+		require.Equal(t, map[string]map[string]bool{userId: {}}, matrix)
+	}
+}
+
+func TestGetBindingChangesSince(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	user1 := createTestUser(t, "user1", "")
+	user2 := createTestUser(t, "user2", "")
+	user3 := createTestUser(t, "user3", "")
+	group1 := createTestGroup(t, "group1", "")
+	group2 := createTestGroup(t, "group2", "")
+	bindingId := func(userId, groupId string) string {
+		bindings, err := GetUserGroupBindings(ctx, []string{userId}, []string{groupId})
+		require.NoError(t, err)
+		require.Len(t, bindings, 1)
+		return bindings[0].Id
+	}
+
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{user1, user2}, GroupId: []string{group1}})
+	require.NoError(t, err)
+	binding1 := bindingId(user1, group1)
+	binding2 := bindingId(user2, group1)
+
+	since := time.Now()
+	changes, err := GetBindingChangesSince(ctx, since)
+	require.NoError(t, err)
+	require.Empty(t, changes.Created)
+	require.Empty(t, changes.Deleted)
+
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{user3}, GroupId: []string{group1, group2}})
+	require.NoError(t, err)
+	binding3 := bindingId(user3, group1)
+	binding4 := bindingId(user3, group2)
+	_, err = LeaveGroup(ctx, &pb.LeaveGroupRequest{UserId: []string{user1}, GroupId: []string{group1}})
+	require.NoError(t, err)
+	// created and deleted after since, only its deletion is seen
+	_, err = LeaveGroup(ctx, &pb.LeaveGroupRequest{UserId: []string{user3}, GroupId: []string{group2}})
+	require.NoError(t, err)
+	_, err = DeleteUsers(ctx, &pb.DeleteUsersRequest{UserId: []string{user2}})
+	require.NoError(t, err)
+
+	deletedIds := func(changes *BindingChanges) []string {
+		var ids []string
+		for _, deletion := range changes.Deleted {
+			ids = append(ids, deletion.Id)
+		}
+		return ids
+	}
+	changes, err = GetBindingChangesSince(ctx, since)
+	require.NoError(t, err)
+	require.Len(t, changes.Created, 1)
+	require.Equal(t, binding3, changes.Created[0].Id)
+	require.Equal(t, []string{binding1, binding4, binding2}, deletedIds(changes))
+	require.Equal(t, user1, changes.Deleted[0].UserId)
+	require.Equal(t, group1, changes.Deleted[0].GroupId)
+
+	// the bindings removed with their group leave tombstones too
+	since = time.Now()
+	_, err = DeleteGroups(ctx, &pb.DeleteGroupsRequest{GroupId: []string{group1}, Cascade: true})
+	require.NoError(t, err)
+	changes, err = GetBindingChangesSince(ctx, since)
+	require.NoError(t, err)
+	require.Empty(t, changes.Created)
+	require.Equal(t, []string{binding3}, deletedIds(changes))
+}
+
+func TestTablePrefix(t *testing.T) {
+	cfg := config.Default()
+	cfg.DB.Type = "sqlite3"
+	cfg.DB.Database = filepath.Join(t.TempDir(), "im.db")
+	cfg.DB.AutoMigrate = true
+	cfg.DB.TablePrefix = "im_"
+	global.SetGlobal(cfg)
+	t.Cleanup(func() {
+		global.Global().Database.Close()
+		db.TablePrefix = ""
+	})
+	ctx := context.Background()
+
+	userId := createTestUser(t, "user1", "")
+	groupId := createTestGroup(t, "group1", "")
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{userId}, GroupId: []string{groupId}})
+	require.NoError(t, err)
+	err = AddUserTags(ctx, userId, []string{"tag1"})
+	require.NoError(t, err)
+
+	database := global.Global().Database
+	for _, table := range []string{constants.TableUser, constants.TableGroup, constants.TableUserGroupBinding, constants.TableUserTag} {
+		var count int
+		require.NoError(t, database.Table("im_"+table).Count(&count).Error)
+		require.Equal(t, 1, count, table)
+		require.False(t, database.HasTable(table), table)
+	}
+
+	users, err := GetUsersByGroupIds(ctx, []string{groupId})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	groups, err := GetGroupsByUserIds(ctx, []string{userId})
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	members, err := GetGroupMembersByGroupIds(ctx, []string{groupId}, MembershipOptions{})
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+
+	res, err := ListUsers(ctx, &pb.ListUsersRequest{SearchWord: []string{"group1"}, SearchGroupName: true, Tag: []string{"tag1"}})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, res.Total)
+	require.Equal(t, userId, res.UserSet[0].UserId)
+}
+
+func TestIsUserInGroupCache(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+	t.Cleanup(memberships.reset)
+
+	user1 := createTestUser(t, "user1", "")
+	user2 := createTestUser(t, "user2", "")
+	group1 := createTestGroup(t, "group1", "")
+	group2 := createTestGroup(t, "group2", "")
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{user1, user2}, GroupId: []string{group1, group2}})
+	require.NoError(t, err)
+	deleteBinding := func(userId, groupId string) {
+		require.NoError(t, global.Global().Database.
+			Where(constants.ColumnUserId+" = ?", userId).
+			Where(constants.ColumnGroupId+" = ?", groupId).
+			Delete(models.UserGroupBinding{}).Error)
+	}
+
+	// without cache the bindings are read every time
+	in, err := IsUserInGroup(ctx, user1, group1)
+	require.NoError(t, err)
+	require.True(t, in)
+	deleteBinding(user1, group1)
+	in, err = IsUserInGroup(ctx, user1, group1)
+	require.NoError(t, err)
+	require.False(t, in)
+
+	global.Global().Config.MembershipCacheSize = 2
+	for _, groupId := range []string{group1, group2} {
+		in, err = IsUserInGroup(ctx, user2, groupId)
+		require.NoError(t, err)
+		require.True(t, in)
+	}
+
+	// the cached hits do not read the bindings deleted behind the cache
+	deleteBinding(user2, group1)
+	in, err = IsUserInGroup(ctx, user2, group1)
+	require.NoError(t, err)
+	require.True(t, in)
+
+	// leaving busts the entry of the user and group only
+	_, err = LeaveGroup(ctx, &pb.LeaveGroupRequest{UserId: []string{user2}, GroupId: []string{group2}})
+	require.NoError(t, err)
+	in, err = IsUserInGroup(ctx, user2, group2)
+	require.NoError(t, err)
+	require.False(t, in)
+	in, err = IsUserInGroup(ctx, user2, group1)
+	require.NoError(t, err)
+	require.True(t, in)
+
+	// joining busts the entry cached as not in group
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{user2}, GroupId: []string{group2}})
+	require.NoError(t, err)
+	in, err = IsUserInGroup(ctx, user2, group2)
+	require.NoError(t, err)
+	require.True(t, in)
+}
+
+func TestMembershipCacheEviction(t *testing.T) {
+	cache := newMembershipCache()
+	cache.set("uid-1", "gid-1", true, 2, cache.getGeneration())
+	cache.set("uid-2", "gid-1", true, 2, cache.getGeneration())
+	_, ok := cache.get("uid-1", "gid-1", 2)
+	require.True(t, ok)
+
+	// uid-2 is the least recently used
+	cache.set("uid-3", "gid-1", false, 2, cache.getGeneration())
+	_, ok = cache.get("uid-2", "gid-1", 2)
+	require.False(t, ok)
+	in, ok := cache.get("uid-3", "gid-1", 2)
+	require.True(t, ok)
+	require.False(t, in)
+
+	// a result read before an invalidation is not cached
+	generation := cache.getGeneration()
+	cache.invalidate(nil, []string{"gid-2"})
+	cache.set("uid-4", "gid-1", true, 2, generation)
+	_, ok = cache.get("uid-4", "gid-1", 2)
+	require.False(t, ok)
+	_, ok = cache.get("uid-0", "gid-1", 0)
+	require.False(t, ok)
+}
+
+func TestGetUsersByGroupIdsActiveOnly(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	active := createTestUser(t, "active", "")
+	deleted := createTestUser(t, "deleted", "")
+	groupId := createTestGroup(t, "group", "")
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{active, deleted}, GroupId: []string{groupId}})
+	require.NoError(t, err)
+	// the binding is kept, unlike DeleteUsers
+	require.NoError(t, global.Global().Database.Table(constants.TableUser).
+		Where(constants.ColumnUserId+" = ?", deleted).
+		Update(constants.ColumnStatus, constants.StatusDeleted).Error)
+	userIds := func(users []*models.User) []string {
+		var ids []string
+		for _, user := range users {
+			ids = append(ids, user.UserId)
+		}
+		return ids
+	}
+
+	users, err := GetUsersByGroupIds(ctx, []string{groupId})
+	require.NoError(t, err)
+	require.Equal(t, []string{active}, userIds(users))
+	members, err := GetGroupMembersByGroupIds(ctx, []string{groupId}, MembershipOptions{})
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	require.Equal(t, active, members[0].UserId)
+
+	users, err = GetUsersByGroupIdsWithOptions(ctx, []string{groupId}, MembershipOptions{IncludeInactiveUsers: true})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{active, deleted}, userIds(users))
+}
+
+func TestShardedGroupMembers(t *testing.T) {
+	prepareShards(t)
+	ctx := context.Background()
+
+	userIds := createShardedTestUsers(t)
+	group1 := createTestGroup(t, "group1", "")
+	group2 := createTestGroup(t, "group2", "")
+	_, err := JoinGroup(ctx, &pb.JoinGroupRequest{UserId: userIds, GroupId: []string{group1}})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{UserId: userIds[:1], GroupId: []string{group2}})
+	require.NoError(t, err)
+
+	// the bindings are in the main database and the users in their shards
+	users, err := GetUsersByGroupIds(ctx, []string{group1}, userIds[0])
+	require.NoError(t, err)
+	var ids []string
+	for _, user := range users {
+		ids = append(ids, user.UserId)
+	}
+	require.ElementsMatch(t, userIds[1:], ids)
+
+	members, err := GetGroupMembersByGroupIds(ctx, []string{group1, group2}, MembershipOptions{})
+	require.NoError(t, err)
+	require.Len(t, members, len(userIds)+1)
+	for _, member := range members {
+		require.NotEmpty(t, member.Username)
+	}
+	var group2Members []string
+	for _, member := range members {
+		if member.GroupId == group2 {
+			group2Members = append(group2Members, member.UserId)
+		}
+	}
+	require.Equal(t, userIds[:1], group2Members)
+}
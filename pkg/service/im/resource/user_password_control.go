@@ -18,37 +18,380 @@ package resource
 
 import (
 	"context"
-	"crypto/md5"
 	"time"
 
+	"github.com/golang/protobuf/ptypes"
+	"github.com/jinzhu/gorm"
 	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"openpitrix.io/logger"
 
 	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/db"
+	"cloudbases.io/im/pkg/event"
 	"cloudbases.io/im/pkg/global"
 	"cloudbases.io/im/pkg/models"
 	"cloudbases.io/im/pkg/pb"
+	"cloudbases.io/im/pkg/util/idutil"
+	"cloudbases.io/im/pkg/util/passwordutil"
+	"cloudbases.io/im/pkg/util/stringutil"
 )
 
+// CorrelationIdMetadata is the incoming metadata the logs of ComparePassword are correlated by,
+// the request id sent by the im client by default
+var CorrelationIdMetadata = "x-request-id"
+
+// correlationId returns the correlation id sent with the request of ctx, a new one if there is none
+func correlationId(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, value := range md.Get(CorrelationIdMetadata) {
+			if value != "" {
+				return value
+			}
+		}
+	}
+	return idutil.GetUuid(constants.PrefixCorrelationId)
+}
+
+// ComparePassword does not match a missing user, only the failures of the database
+// and the corrupt password hashes are returned as errors.
+// The outcome is logged with the correlation id of the request, never with the password.
 func ComparePassword(ctx context.Context, req *pb.ComparePasswordRequest) (*pb.ComparePasswordResponse, error) {
+	cid := correlationId(ctx)
 	var user = &models.User{UserId: req.UserId}
-	if err := global.Global().Database.Table(constants.TableUser).
+	if req.UserId == "" && req.PhoneNumber != "" {
+		var err error
+		user, err = GetUserByPhoneNumber(ctx, req.PhoneNumber)
+		if err != nil {
+			logger.Errorf(ctx, "Compare password [%s] failed, get user by phone number failed: %+v", cid, err)
+			event.Publish(ctx, event.NewAuditRecord(ctx, event.TypeComparePassword, "", event.OutcomeFailure))
+			if gorm.IsRecordNotFoundError(err) {
+				return &pb.ComparePasswordResponse{Ok: false}, nil
+			}
+			if _, ok := status.FromError(err); ok {
+				return nil, err
+			}
+			return nil, status.Errorf(codes.Internal, "get user by phone number failed: %v", err)
+		}
+	} else if err := global.Global().UserDatabase(req.UserId).Table(db.TableName(constants.TableUser)).
 		Take(user).Error; err != nil {
-		logger.Errorf(ctx, "Get user [%s] failed: %+v", req.UserId, err)
-		return nil, err
+		logger.Errorf(ctx, "Compare password [%s] failed, get user [%s] failed: %+v", cid, req.UserId, err)
+		event.Publish(ctx, event.NewAuditRecord(ctx, event.TypeComparePassword, req.UserId, event.OutcomeFailure))
+		if gorm.IsRecordNotFoundError(err) {
+			return &pb.ComparePasswordResponse{Ok: false}, nil
+		}
+		return nil, status.Errorf(codes.Internal, "get user [%s] failed: %v", req.UserId, err)
 	}
 
-	err := bcrypt.CompareHashAndPassword(
-		[]byte(user.Password), []byte(req.GetPassword()),
-	)
+	ok, locked, err := compareUserPassword(ctx, user, user.Password, req.GetPassword())
 	if err != nil {
-		logger.Errorf(ctx, "Compare password failed, md5(password): %x", md5.Sum([]byte(req.Password)))
+		logger.Errorf(ctx, "Compare password [%s] failed: %+v", cid, err)
+		event.Publish(ctx, event.NewAuditRecord(ctx, event.TypeComparePassword, user.UserId, event.OutcomeFailure))
+		return nil, err
+	}
+	if locked {
+		logger.Errorf(ctx, "Compare password [%s] failed, user [%s] is locked until %s", cid, user.UserId, user.LockedUntil)
+		event.Publish(ctx, event.NewAuditRecord(ctx, event.TypeComparePassword, user.UserId, event.OutcomeFailure))
+		lockedUntil, _ := ptypes.TimestampProto(*user.LockedUntil)
+		return &pb.ComparePasswordResponse{Ok: false, Locked: true, LockedUntil: lockedUntil}, nil
+	}
+	if !ok {
+		logger.Errorf(ctx, "Compare password [%s] failed, wrong password of user [%s]", cid, user.UserId)
+		event.Publish(ctx, event.NewAuditRecord(ctx, event.TypeComparePassword, user.UserId, event.OutcomeFailure))
 		return &pb.ComparePasswordResponse{Ok: false}, nil
 	}
 
-	return &pb.ComparePasswordResponse{Ok: true}, nil
+	logger.Infof(ctx, "Compare password [%s] of user [%s] succeeded", cid, user.UserId)
+	event.Publish(ctx, event.NewAuditRecord(ctx, event.TypeComparePassword, user.UserId, event.OutcomeSuccess))
+	res := &pb.ComparePasswordResponse{
+		Ok:                 true,
+		MustChangePassword: user.MustChangePassword || isPasswordExpired(user, global.Global().Config.Password.MaxAgeDays),
+	}
+	// the user read for the compare is returned, pb.User has no password
+	if req.WithUser {
+		res.User = user.ToPB()
+	}
+	return res, nil
+}
+
+// compareUserPassword compares password with hash of user unless the user is locked by too many failed
+// compares, the outcome is recorded by recordLoginAttempt. A failure to record it does not fail the compare.
+func compareUserPassword(ctx context.Context, user *models.User, hash, password string) (ok, locked bool, err error) {
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		return false, true, nil
+	}
+	ok, err = compareHashAndPassword(ctx, user.UserId, hash, password)
+	if err != nil {
+		return false, false, err
+	}
+	if err := recordLoginAttempt(ctx, user, ok); err != nil {
+		logger.Errorf(ctx, "Record login attempt of user [%s] failed: %+v", user.UserId, err)
+	}
+	return ok, false, nil
+}
+
+// compareHashAndPassword compares password with the stored hash of user, a user without password
+// never matches. A hash that bcrypt cannot parse is a data integrity problem, e.g. a truncated hash,
+// which is returned as codes.Internal instead of a wrong password.
+func compareHashAndPassword(ctx context.Context, userId, hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err == nil {
+		return true, nil
+	}
+	if err == bcrypt.ErrMismatchedHashAndPassword || hash == "" {
+		return false, nil
+	}
+	err = status.Errorf(codes.Internal, "password hash of user [%s] is corrupt: %v", userId, err)
+	logger.Criticalf(ctx, "Data integrity problem: %+v", err)
+	return false, err
+}
+
+// recordLoginAttempt writes the side effects of a compare of the password of user in one transaction,
+// a success resets the failed login count and sets the last login, a failure increments the count
+// and locks the user when the count reaches Password.MaxFailedLogins
+func recordLoginAttempt(ctx context.Context, user *models.User, ok bool) error {
+	now := models.NowUTC()
+	attributes := map[string]interface{}{
+		constants.ColumnFailedLoginCount: gorm.Expr(constants.ColumnFailedLoginCount + " + 1"),
+	}
+	if ok {
+		attributes = map[string]interface{}{
+			constants.ColumnFailedLoginCount: 0,
+			constants.ColumnLockedUntil:      nil,
+			constants.ColumnLastLoginAt:      now,
+		}
+	}
+	passwordConfig := global.Global().Config.Password
+	if err := global.Global().UserDatabase(user.UserId).WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Table(db.TableName(constants.TableUser)).
+			Where(constants.ColumnUserId+" = ?", user.UserId).
+			UpdateColumns(attributes).Error; err != nil {
+			return err
+		}
+		if ok || passwordConfig.MaxFailedLogins <= 0 {
+			return nil
+		}
+		// the count incremented by this transaction, not the one read before the compare
+		var counted models.User
+		if err := tx.Table(db.TableName(constants.TableUser)).
+			Select(constants.ColumnFailedLoginCount).
+			Where(constants.ColumnUserId+" = ?", user.UserId).
+			Take(&counted).Error; err != nil {
+			return err
+		}
+		if counted.FailedLoginCount < uint32(passwordConfig.MaxFailedLogins) {
+			return nil
+		}
+		return tx.Table(db.TableName(constants.TableUser)).
+			Where(constants.ColumnUserId+" = ?", user.UserId).
+			UpdateColumn(constants.ColumnLockedUntil, now.Add(time.Duration(passwordConfig.LockoutSeconds)*time.Second)).Error
+	}); err != nil {
+		return err
+	}
+	if ok {
+		user.FailedLoginCount = 0
+		user.LockedUntil = nil
+		user.LastLoginAt = &now
+	}
+	return nil
+}
+
+// UnlockUser lets the user locked after too many failed compares of the password login again
+// before the end of the lockout, the failed login count is reset
+func UnlockUser(ctx context.Context, userId string) error {
+	var violations fieldViolations
+	violations.checkNotBlank("user_id", userId)
+	if err := violations.Err(ctx); err != nil {
+		return err
+	}
+
+	result := global.Global().UserDatabase(userId).Table(db.TableName(constants.TableUser)).
+		Where(constants.ColumnUserId+" = ?", userId).
+		UpdateColumns(map[string]interface{}{
+			constants.ColumnFailedLoginCount: 0,
+			constants.ColumnLockedUntil:      nil,
+		})
+	if err := result.Error; err != nil {
+		logger.Errorf(ctx, "Unlock user [%s] failed: %+v", userId, err)
+		return err
+	}
+	// mysql does not count the rows left unchanged, e.g. a user not locked
+	if result.RowsAffected == 0 {
+		if _, err := GetUser(ctx, userId); err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				return status.Errorf(codes.NotFound, "user [%s] not found", userId)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// BatchComparePassword compares the passwords keyed by user id, the users are read in one query
+// per database holding them and the unknown users do not match. Like ComparePassword the locked users
+// do not match and every compare is recorded as a login attempt. The hashes are cached for
+// Password.HashCacheSeconds, only the lock of the cached users is read again, so a password modified
+// on another node is compared when the entry of the user expires.
+// A corrupt hash of any user fails the whole batch with codes.Internal.
+func BatchComparePassword(ctx context.Context, passwords map[string]string) (map[string]bool, error) {
+	ttl := time.Duration(global.Global().Config.Password.HashCacheSeconds) * time.Second
+
+	hashes := make(map[string]string, len(passwords))
+	var cachedUserIds, missingUserIds []string
+	for userId := range passwords {
+		if hash, ok := passwordHashes.get(userId, ttl); ok {
+			hashes[userId] = hash
+			cachedUserIds = append(cachedUserIds, userId)
+		} else {
+			missingUserIds = append(missingUserIds, userId)
+		}
+	}
+
+	users := make(map[string]*models.User, len(passwords))
+	readUsers := func(userIds, columns []string) error {
+		for database, shardUserIds := range global.Global().GroupUserIds(userIds) {
+			var shardUsers []*models.User
+			if err := database.Table(db.TableName(constants.TableUser)).
+				Select(columns).
+				Where(constants.ColumnUserId+" in (?)", shardUserIds).
+				Find(&shardUsers).Error; err != nil {
+				logger.Errorf(ctx, "Get users failed: %+v", err)
+				return err
+			}
+			for _, user := range shardUsers {
+				users[user.UserId] = user
+			}
+		}
+		return nil
+	}
+	lockColumns := []string{constants.ColumnUserId, constants.ColumnLockedUntil, constants.ColumnFailedLoginCount}
+	if err := readUsers(cachedUserIds, lockColumns); err != nil {
+		return nil, err
+	}
+	if err := readUsers(missingUserIds, append(lockColumns, constants.ColumnPassword)); err != nil {
+		return nil, err
+	}
+	for _, userId := range missingUserIds {
+		if user, ok := users[userId]; ok {
+			hashes[userId] = user.Password
+			passwordHashes.set(userId, user.Password, ttl)
+		}
+	}
+
+	results := make(map[string]bool, len(passwords))
+	for userId, password := range passwords {
+		results[userId] = false
+		if user, ok := users[userId]; ok {
+			matched, _, err := compareUserPassword(ctx, user, hashes[userId], password)
+			if err != nil {
+				return nil, err
+			}
+			results[userId] = matched
+		}
+		outcome := event.OutcomeSuccess
+		if !results[userId] {
+			outcome = event.OutcomeFailure
+		}
+		event.Publish(ctx, event.NewAuditRecord(ctx, event.TypeComparePassword, userId, outcome))
+	}
+	return results, nil
+}
+
+func isPasswordExpired(user *models.User, maxAgeDays int) bool {
+	if maxAgeDays <= 0 || user.PasswordUpdatedAt == nil {
+		return false
+	}
+	return time.Now().After(user.PasswordUpdatedAt.AddDate(0, 0, maxAgeDays))
+}
+
+// ForcePasswordResetForUsers flags the users to change their password at the next login in one update per database,
+// their passwords are kept and the flag is cleared when the password is modified
+func ForcePasswordResetForUsers(ctx context.Context, userIds []string) error {
+	userIds = stringutil.SimplifyStringList(userIds)
+	var violations fieldViolations
+	violations.checkNotEmpty("user_id", userIds)
+	if err := violations.Err(ctx); err != nil {
+		return err
+	}
+
+	attributes := map[string]interface{}{
+		constants.ColumnMustChangePassword: true,
+		constants.ColumnUpdateTime:         models.NowUTC(),
+	}
+	for database, shardUserIds := range global.Global().GroupUserIds(userIds) {
+		if err := database.Table(db.TableName(constants.TableUser)).
+			Where(constants.ColumnUserId+" in (?)", shardUserIds).
+			Updates(attributes).Error; err != nil {
+			logger.Errorf(ctx, "Force password reset of users %v failed: %+v", shardUserIds, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// ChangeOwnPassword modifies the password of user only if oldPassword is correct,
+// unlike ModifyPassword which is used by administrators to reset passwords
+func ChangeOwnPassword(ctx context.Context, userId, oldPassword, newPassword string) (*pb.ModifyPasswordResponse, error) {
+	if userId == "" {
+		err := status.Errorf(codes.InvalidArgument, "empty user id")
+		logger.Errorf(ctx, "%+v", err)
+		return nil, err
+	}
+	res, err := ComparePassword(ctx, &pb.ComparePasswordRequest{
+		UserId:   userId,
+		Password: oldPassword,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the old password of a locked user is not compared, it is not incorrect
+	if res.Locked {
+		err := status.Errorf(codes.FailedPrecondition, "user [%s] is locked until %s", userId, ptypes.TimestampString(res.LockedUntil))
+		logger.Errorf(ctx, "%+v", err)
+		return nil, err
+	}
+	if !res.Ok {
+		err := status.Errorf(codes.PermissionDenied, "old password of user [%s] is incorrect", userId)
+		logger.Errorf(ctx, "%+v", err)
+		return nil, err
+	}
+
+	return ModifyPassword(ctx, &pb.ModifyPasswordRequest{
+		UserId:   userId,
+		Password: newPassword,
+	})
+}
+
+// ValidatePassword checks password against the password policy in config
+func ValidatePassword(ctx context.Context, password string) (bool, []string) {
+	cfg := global.Global().Config.Password
+	policy := passwordutil.Policy{
+		MinLength:     cfg.MinLength,
+		RequireLetter: cfg.RequireLetter,
+		RequireDigit:  cfg.RequireDigit,
+		RequireSymbol: cfg.RequireSymbol,
+	}
+	if cfg.BlockCommon {
+		policy.Blocklist = passwordutil.DefaultBlocklist()
+		if cfg.BlocklistFile != "" {
+			blocklist, err := passwordutil.LoadBlocklist(cfg.BlocklistFile)
+			if err != nil {
+				// fall back to the embedded list rather than accepting any password
+				logger.Errorf(ctx, "Load password blocklist [%s] failed: %+v", cfg.BlocklistFile, err)
+			} else {
+				policy.Blocklist = blocklist
+			}
+		}
+	}
+	violations := policy.Validate(password)
+	return len(violations) == 0, violations
+}
+
+// PasswordStrength estimates the strength of password from 0 (very weak) to 4 (very strong)
+func PasswordStrength(password string) uint32 {
+	return uint32(passwordutil.Strength(password))
 }
 
 func ModifyPassword(ctx context.Context, req *pb.ModifyPasswordRequest) (*pb.ModifyPasswordResponse, error) {
@@ -57,18 +400,30 @@ func ModifyPassword(ctx context.Context, req *pb.ModifyPasswordRequest) (*pb.Mod
 		logger.Errorf(ctx, "%+v", err)
 		return nil, err
 	}
+	var violations fieldViolations
+	violations.checkPassword(ctx, "password", req.Password)
+	if err := violations.Err(ctx); err != nil {
+		return nil, err
+	}
 
+	now := models.NowUTC()
 	attributes := map[string]interface{}{
-		constants.ColumnPassword:   models.GetBcryptPassword(req.Password),
-		constants.ColumnUpdateTime: time.Now(),
+		constants.ColumnPassword:           models.GetBcryptPassword(req.Password),
+		constants.ColumnUpdateTime:         now,
+		constants.ColumnPasswordUpdatedAt:  now,
+		constants.ColumnMustChangePassword: false,
 	}
 
-	if err := global.Global().Database.Table(constants.TableUser).
+	if err := global.Global().UserDatabase(req.UserId).Table(db.TableName(constants.TableUser)).
 		Where(constants.ColumnUserId+" = ?", req.UserId).
 		Updates(attributes).Error; err != nil {
 		logger.Errorf(ctx, "Modify user [%s] password failed: %+v", req.UserId, err)
 		return nil, err
 	}
+	passwordHashes.invalidate(req.UserId)
 
-	return &pb.ModifyPasswordResponse{UserId: req.UserId}, nil
+	return &pb.ModifyPasswordResponse{
+		UserId:   req.UserId,
+		Strength: PasswordStrength(req.Password),
+	}, nil
 }
@@ -0,0 +1,678 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"openpitrix.io/logger"
+
+	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/db"
+	"cloudbases.io/im/pkg/event"
+	"cloudbases.io/im/pkg/global"
+	"cloudbases.io/im/pkg/models"
+	"cloudbases.io/im/pkg/pb"
+	"cloudbases.io/im/pkg/util/jsonutil"
+	"cloudbases.io/im/pkg/util/passwordutil"
+)
+
+type recordPublisher struct {
+	records []*event.AuditRecord
+}
+
+func (p *recordPublisher) Publish(ctx context.Context, record *event.AuditRecord) {
+	p.records = append(p.records, record)
+}
+
+func setupRecordPublisher(t *testing.T) *recordPublisher {
+	p := new(recordPublisher)
+	event.SetPublisher(p)
+	t.Cleanup(func() {
+		event.SetPublisher(nil)
+	})
+	return p
+}
+
+func TestComparePasswordAudit(t *testing.T) {
+	prepare(t)
+	publisher := setupRecordPublisher(t)
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 52000},
+	})
+
+	userId := createTestUser(t, "audit", "")
+
+	var tests = []struct {
+		password string
+		outcome  string
+	}{
+		{password: "t0p-secret", outcome: event.OutcomeSuccess},
+		{password: "wrong", outcome: event.OutcomeFailure},
+	}
+	for _, v := range tests {
+		publisher.records = nil
+		_, err := ComparePassword(ctx, &pb.ComparePasswordRequest{
+			UserId:   userId,
+			Password: v.password,
+		})
+		require.NoError(t, err)
+		require.Len(t, publisher.records, 1)
+
+		record := publisher.records[0]
+		require.Equal(t, event.TypeComparePassword, record.Type)
+		require.Equal(t, userId, record.UserId)
+		require.Equal(t, v.outcome, record.Outcome)
+		require.Equal(t, "10.0.0.1", record.ClientIp)
+		require.False(t, record.Time.IsZero())
+		require.False(t, strings.Contains(jsonutil.ToString(record), v.password))
+	}
+
+	// unknown user
+	publisher.records = nil
+	response, err := ComparePassword(ctx, &pb.ComparePasswordRequest{
+		UserId:   "uid-unknown",
+		Password: "t0p-secret",
+	})
+	require.NoError(t, err)
+	require.False(t, response.Ok)
+	require.Len(t, publisher.records, 1)
+	require.Equal(t, event.OutcomeFailure, publisher.records[0].Outcome)
+}
+
+func TestComparePasswordCorrelationId(t *testing.T) {
+	prepare(t)
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	t.Cleanup(func() {
+		logger.SetOutput(os.Stdout)
+	})
+
+	userId := createTestUser(t, "correlated", "")
+	password := "wr0ng-secret"
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(CorrelationIdMetadata, "req-42"))
+	response, err := ComparePassword(ctx, &pb.ComparePasswordRequest{
+		UserId:   userId,
+		Password: password,
+	})
+	require.NoError(t, err)
+	require.False(t, response.Ok)
+	require.Contains(t, buf.String(), "Compare password [req-42] failed")
+	require.NotContains(t, buf.String(), password)
+	require.NotContains(t, buf.String(), fmt.Sprintf("%x", md5.Sum([]byte(password))))
+
+	// an id is generated for the requests without one
+	buf.Reset()
+	_, err = ComparePassword(context.Background(), &pb.ComparePasswordRequest{
+		UserId:   userId,
+		Password: password,
+	})
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), "Compare password ["+constants.PrefixCorrelationId)
+	require.NotContains(t, buf.String(), password)
+}
+
+func TestComparePasswordWithUser(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	userId := createTestUser(t, "login", "10000000000")
+	var stored models.User
+	require.NoError(t, global.Global().Database.Table(db.TableName(constants.TableUser)).
+		Where(constants.ColumnUserId+" = ?", userId).Take(&stored).Error)
+
+	response, err := ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: userId, Password: "t0p-secret"})
+	require.NoError(t, err)
+	require.True(t, response.Ok)
+	require.Nil(t, response.User)
+
+	response, err = ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: userId, Password: "wrong", WithUser: true})
+	require.NoError(t, err)
+	require.False(t, response.Ok)
+	require.Nil(t, response.User)
+
+	for _, req := range []*pb.ComparePasswordRequest{
+		{UserId: userId, Password: "t0p-secret", WithUser: true},
+		{PhoneNumber: "10000000000", Password: "t0p-secret", WithUser: true},
+	} {
+		response, err = ComparePassword(ctx, req)
+		require.NoError(t, err)
+		require.True(t, response.Ok)
+		require.Equal(t, userId, response.User.UserId)
+		require.Equal(t, "login", response.User.Username)
+		require.Equal(t, stored.PhoneNumber, response.User.PhoneNumber)
+		require.NotNil(t, response.User.LastLoginAt)
+		// neither the password nor its hash are returned
+		s := jsonutil.ToString(response)
+		require.False(t, strings.Contains(s, stored.Password))
+		require.False(t, strings.Contains(s, "t0p-secret"))
+	}
+}
+
+func TestForcePasswordResetForUsers(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, "alice", "")
+	bob := createTestUser(t, "bob", "")
+	carol := createTestUser(t, "carol", "")
+	mustChangePassword := func(userId, password string) bool {
+		response, err := ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: userId, Password: password})
+		require.NoError(t, err)
+		require.True(t, response.Ok)
+		return response.MustChangePassword
+	}
+
+	err := ForcePasswordResetForUsers(ctx, nil)
+	require.Equal(t, []string{"user_id"}, violatedFields(t, err))
+
+	require.NoError(t, ForcePasswordResetForUsers(ctx, []string{alice, bob}))
+	// the passwords are kept
+	require.True(t, mustChangePassword(alice, "t0p-secret"))
+	require.True(t, mustChangePassword(bob, "t0p-secret"))
+	require.False(t, mustChangePassword(carol, "t0p-secret"))
+
+	// a successful change clears the flag
+	_, err = ChangeOwnPassword(ctx, alice, "wrong", "n3w-secret")
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	require.True(t, mustChangePassword(alice, "t0p-secret"))
+	_, err = ChangeOwnPassword(ctx, alice, "t0p-secret", "n3w-secret")
+	require.NoError(t, err)
+	require.False(t, mustChangePassword(alice, "n3w-secret"))
+
+	_, err = ModifyPassword(ctx, &pb.ModifyPasswordRequest{UserId: bob, Password: "n3w-secret"})
+	require.NoError(t, err)
+	require.False(t, mustChangePassword(bob, "n3w-secret"))
+}
+
+func TestComparePasswordFailedLoginCount(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	userId := createTestUser(t, "counted", "")
+	failedLoginCount := func() uint32 {
+		user, err := GetUser(ctx, userId)
+		require.NoError(t, err)
+		return user.FailedLoginCount
+	}
+
+	// the concurrent failures are all counted
+	const attempts = 8
+	results := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		go func() {
+			response, err := ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: userId, Password: "wrong"})
+			if err == nil && response.Ok {
+				err = fmt.Errorf("wrong password matched")
+			}
+			results <- err
+		}()
+	}
+	for i := 0; i < attempts; i++ {
+		require.NoError(t, <-results)
+	}
+	require.EqualValues(t, attempts, failedLoginCount())
+
+	response, err := ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: userId, Password: "t0p-secret"})
+	require.NoError(t, err)
+	require.True(t, response.Ok)
+	user, err := GetUser(ctx, userId)
+	require.NoError(t, err)
+	require.EqualValues(t, 0, user.FailedLoginCount)
+	require.NotNil(t, user.LastLoginAt)
+
+	_, err = ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: userId, Password: "wrong"})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, failedLoginCount())
+}
+
+func TestComparePasswordLockout(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+	global.Global().Config.Password.MaxFailedLogins = 3
+	global.Global().Config.Password.LockoutSeconds = 3600
+
+	userId := createTestUser(t, "locked", "")
+	compare := func(password string) *pb.ComparePasswordResponse {
+		response, err := ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: userId, Password: password})
+		require.NoError(t, err)
+		return response
+	}
+
+	for i := 0; i < 3; i++ {
+		response := compare("wrong")
+		require.False(t, response.Ok)
+		require.False(t, response.Locked)
+	}
+	// the right password does not match while the user is locked
+	response := compare("t0p-secret")
+	require.False(t, response.Ok)
+	require.True(t, response.Locked)
+	lockedUntil, err := ptypes.Timestamp(response.LockedUntil)
+	require.NoError(t, err)
+	require.True(t, lockedUntil.After(time.Now().Add(59*time.Minute)))
+	// the locked user is told apart from a wrong old password
+	_, err = ChangeOwnPassword(ctx, userId, "t0p-secret", "n3w-secret")
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.Contains(t, status.Convert(err).Message(), ptypes.TimestampString(response.LockedUntil))
+
+	require.NoError(t, UnlockUser(ctx, userId))
+	user, err := GetUser(ctx, userId)
+	require.NoError(t, err)
+	require.Nil(t, user.LockedUntil)
+	require.EqualValues(t, 0, user.FailedLoginCount)
+	require.True(t, compare("t0p-secret").Ok)
+
+	// the lock ends with the window
+	for i := 0; i < 3; i++ {
+		compare("wrong")
+	}
+	require.True(t, compare("t0p-secret").Locked)
+	require.NoError(t, global.Global().Database.Table(db.TableName(constants.TableUser)).
+		Where(constants.ColumnUserId+" = ?", userId).
+		UpdateColumn(constants.ColumnLockedUntil, time.Now().Add(-time.Second)).Error)
+	require.True(t, compare("t0p-secret").Ok)
+
+	// unlocking a user not locked is fine, an unknown one is not found
+	require.NoError(t, UnlockUser(ctx, userId))
+	require.Equal(t, codes.NotFound, status.Code(UnlockUser(ctx, "uid-unknown")))
+	require.Equal(t, []string{"user_id"}, violatedFields(t, UnlockUser(ctx, "")))
+}
+
+func TestComparePasswordNotFound(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	createTestUser(t, "phone", "10000000000")
+
+	response, err := ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: "uid-unknown", Password: "t0p-secret"})
+	require.NoError(t, err)
+	require.False(t, response.Ok)
+	response, err = ComparePassword(ctx, &pb.ComparePasswordRequest{PhoneNumber: "10000000001", Password: "t0p-secret"})
+	require.NoError(t, err)
+	require.False(t, response.Ok)
+
+	// a genuine failure of the database is an error
+	require.NoError(t, global.Global().Database.Exec("DROP TABLE `"+constants.TableUser+"`").Error)
+	_, err = ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: "uid-unknown", Password: "t0p-secret"})
+	require.Equal(t, codes.Internal, status.Code(err))
+	_, err = ComparePassword(ctx, &pb.ComparePasswordRequest{PhoneNumber: "10000000000", Password: "t0p-secret"})
+	require.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestComparePasswordCorruptHash(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+	t.Cleanup(passwordHashes.reset)
+
+	userId := createTestUser(t, "corrupt", "")
+	user, err := GetUser(ctx, userId)
+	require.NoError(t, err)
+	// a hash truncated by a migration
+	require.NoError(t, global.Global().Database.Table(constants.TableUser).
+		Where(constants.ColumnUserId+" = ?", userId).
+		Update(constants.ColumnPassword, user.Password[:20]).Error)
+
+	_, err = ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: userId, Password: "t0p-secret"})
+	require.Equal(t, codes.Internal, status.Code(err))
+	_, err = BatchComparePassword(ctx, map[string]string{userId: "t0p-secret"})
+	require.Equal(t, codes.Internal, status.Code(err))
+
+	// the corrupt hash is not a failed login of the user
+	user, err = GetUser(ctx, userId)
+	require.NoError(t, err)
+	require.Zero(t, user.FailedLoginCount)
+
+	// a user without password is not corrupt, it never matches
+	require.NoError(t, global.Global().Database.Table(constants.TableUser).
+		Where(constants.ColumnUserId+" = ?", userId).
+		Update(constants.ColumnPassword, "").Error)
+	response, err := ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: userId, Password: "t0p-secret"})
+	require.NoError(t, err)
+	require.False(t, response.Ok)
+}
+
+func TestComparePasswordExpired(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	userId := createTestUser(t, "expiry", "")
+	comparePassword := func() *pb.ComparePasswordResponse {
+		response, err := ComparePassword(ctx, &pb.ComparePasswordRequest{
+			UserId:   userId,
+			Password: "t0p-secret",
+		})
+		require.NoError(t, err)
+		require.True(t, response.Ok)
+		return response
+	}
+
+	// never expire by default
+	require.NoError(t, global.Global().Database.Table(constants.TableUser).
+		Where(constants.ColumnUserId+" = ?", userId).
+		Update(constants.ColumnPasswordUpdatedAt, time.Now().AddDate(0, 0, -100)).Error)
+	require.False(t, comparePassword().MustChangePassword)
+
+	global.Global().Config.Password.MaxAgeDays = 90
+	require.True(t, comparePassword().MustChangePassword)
+
+	// fresh password after modify
+	_, err := ModifyPassword(ctx, &pb.ModifyPasswordRequest{
+		UserId:   userId,
+		Password: "t0p-secret",
+	})
+	require.NoError(t, err)
+	require.False(t, comparePassword().MustChangePassword)
+
+	// wrong password never reports it
+	response, err := ComparePassword(ctx, &pb.ComparePasswordRequest{
+		UserId:   userId,
+		Password: "wrong",
+	})
+	require.NoError(t, err)
+	require.False(t, response.Ok)
+	require.False(t, response.MustChangePassword)
+}
+
+func TestValidatePassword(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	ok, violations := ValidatePassword(ctx, "t0p-secret")
+	require.True(t, ok)
+	require.Empty(t, violations)
+
+	ok, violations = ValidatePassword(ctx, "short")
+	require.False(t, ok)
+	require.Len(t, violations, 2)
+
+	global.Global().Config.Password.RequireSymbol = true
+	ok, violations = ValidatePassword(ctx, "newpassw0rd")
+	require.False(t, ok)
+	require.Len(t, violations, 1)
+}
+
+func TestPasswordPolicyEnforced(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	_, err := CreateUser(ctx, &pb.CreateUserRequest{
+		Username: "policy",
+		Email:    "policy@op.com",
+		Password: "password",
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	userId := createTestUser(t, "policy", "")
+	_, err = ModifyPassword(ctx, &pb.ModifyPasswordRequest{
+		UserId:   userId,
+		Password: "12345678",
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = ModifyPassword(ctx, &pb.ModifyPasswordRequest{
+		UserId:   userId,
+		Password: "newpassw0rd",
+	})
+	require.NoError(t, err)
+}
+
+func TestModifyPasswordStrength(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+	userId := createTestUser(t, "strength", "")
+
+	res, err := ModifyPassword(ctx, &pb.ModifyPasswordRequest{
+		UserId:   userId,
+		Password: "kdjw1pq3",
+	})
+	require.NoError(t, err)
+	weak := res.Strength
+
+	res, err = ModifyPassword(ctx, &pb.ModifyPasswordRequest{
+		UserId:   userId,
+		Password: "Tr0ub4dor&3xz!Q",
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint32(passwordutil.StrengthVeryStrong), res.Strength)
+	require.True(t, weak < res.Strength)
+}
+
+func TestPasswordBlocklist(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+	userId := createTestUser(t, "blocklist", "")
+
+	for _, password := range []string{"password1", "Passw0rd1", "P@ssw0rd"} {
+		_, err := ModifyPassword(ctx, &pb.ModifyPasswordRequest{
+			UserId:   userId,
+			Password: password,
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err), password)
+		require.Contains(t, err.Error(), "too common")
+	}
+
+	_, err := CreateUser(ctx, &pb.CreateUserRequest{
+		Username: "blocklist2",
+		Email:    "blocklist2@op.com",
+		Password: "Qwerty123",
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// configured blocklist replaces the embedded one
+	file := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, ioutil.WriteFile(file, []byte("kubesphere1\n"), 0644))
+	global.Global().Config.Password.BlocklistFile = file
+	ok, _ := ValidatePassword(ctx, "KubeSph3re1")
+	require.False(t, ok)
+	ok, _ = ValidatePassword(ctx, "passw0rd")
+	require.True(t, ok)
+
+	global.Global().Config.Password.BlockCommon = false
+	ok, _ = ValidatePassword(ctx, "KubeSph3re1")
+	require.True(t, ok)
+}
+
+func TestChangeOwnPassword(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+	userId := createTestUser(t, "own", "")
+
+	_, err := ChangeOwnPassword(ctx, userId, "wrong", "newpassw0rd")
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// the new password must still satisfy the policy
+	_, err = ChangeOwnPassword(ctx, userId, "t0p-secret", "short")
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = ChangeOwnPassword(ctx, "", "t0p-secret", "newpassw0rd")
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	res, err := ChangeOwnPassword(ctx, userId, "t0p-secret", "newpassw0rd")
+	require.NoError(t, err)
+	require.Equal(t, userId, res.UserId)
+
+	compare, err := ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: userId, Password: "newpassw0rd"})
+	require.NoError(t, err)
+	require.True(t, compare.Ok)
+	compare, err = ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: userId, Password: "t0p-secret"})
+	require.NoError(t, err)
+	require.False(t, compare.Ok)
+}
+
+func TestBatchComparePassword(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+	t.Cleanup(passwordHashes.reset)
+
+	alice := createTestUser(t, "alice", "")
+	bob := createTestUser(t, "bob", "")
+
+	// without cache the rows are read every time
+	results, err := BatchComparePassword(ctx, map[string]string{
+		alice:         "t0p-secret",
+		bob:           "wrong-secret",
+		"uid-unknown": "t0p-secret",
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{alice: true, bob: false, "uid-unknown": false}, results)
+	setPasswordHash := func(userId, password string) {
+		require.NoError(t, global.Global().Database.Table(constants.TableUser).
+			Where(constants.ColumnUserId+" = ?", userId).
+			Update(constants.ColumnPassword, models.GetBcryptPassword(password)).Error)
+	}
+	setPasswordHash(bob, "b0b-secret")
+	results, err = BatchComparePassword(ctx, map[string]string{bob: "b0b-secret"})
+	require.NoError(t, err)
+	require.True(t, results[bob])
+
+	global.Global().Config.Password.HashCacheSeconds = 60
+	results, err = BatchComparePassword(ctx, map[string]string{alice: "t0p-secret", bob: "b0b-secret"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{alice: true, bob: true}, results)
+
+	// the second batch hits the cache, only the lock of the users is read
+	var queries []string
+	global.Global().Database.Callback().Query().After("gorm:query").Register("test:capture_sql", func(scope *gorm.Scope) {
+		queries = append(queries, scope.SQL)
+	})
+	results, err = BatchComparePassword(ctx, map[string]string{alice: "t0p-secret", bob: "b0b-secret"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{alice: true, bob: true}, results)
+	require.Len(t, queries, 1)
+	require.Contains(t, queries[0], constants.ColumnLockedUntil)
+	require.NotContains(t, queries[0], constants.ColumnPassword)
+
+	// a password change busts the entry of the user, its password is read again
+	_, err = ModifyPassword(ctx, &pb.ModifyPasswordRequest{UserId: alice, Password: "n3w-secret"})
+	require.NoError(t, err)
+	queries = nil
+	results, err = BatchComparePassword(ctx, map[string]string{alice: "n3w-secret"})
+	require.NoError(t, err)
+	require.True(t, results[alice])
+	require.Len(t, queries, 1)
+	require.Contains(t, queries[0], constants.ColumnPassword)
+	results, err = BatchComparePassword(ctx, map[string]string{alice: "t0p-secret"})
+	require.NoError(t, err)
+	require.False(t, results[alice])
+}
+
+func TestPasswordHashCacheSweep(t *testing.T) {
+	cache := newPasswordHashCache()
+	cache.set("usr-1", "hash", time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	// the expired entry of a user not read again is swept by the next set
+	cache.set("usr-2", "hash", time.Millisecond)
+	require.Len(t, cache.entries, 1)
+	require.Contains(t, cache.entries, "usr-2")
+}
+
+func TestBatchComparePasswordLockout(t *testing.T) {
+	prepareShards(t)
+	ctx := context.Background()
+	t.Cleanup(passwordHashes.reset)
+	global.Global().Config.Password.MaxFailedLogins = 3
+	global.Global().Config.Password.LockoutSeconds = 3600
+	global.Global().Config.Password.HashCacheSeconds = 60
+
+	var userIds []string
+	for _, username := range []string{"alice", "bob", "carol", "dave"} {
+		userIds = append(userIds, createTestUser(t, username, ""))
+	}
+	alice := userIds[0]
+	passwords := make(map[string]string)
+	for _, userId := range userIds {
+		passwords[userId] = "t0p-secret"
+	}
+	passwords[alice] = "wrong"
+	for i := 0; i < 3; i++ {
+		results, err := BatchComparePassword(ctx, passwords)
+		require.NoError(t, err)
+		for _, userId := range userIds {
+			require.Equal(t, userId != alice, results[userId], userId)
+		}
+	}
+
+	// the failed compares of the batches lock the user like ComparePassword
+	results, err := BatchComparePassword(ctx, map[string]string{alice: "t0p-secret"})
+	require.NoError(t, err)
+	require.False(t, results[alice])
+	response, err := ComparePassword(ctx, &pb.ComparePasswordRequest{UserId: alice, Password: "t0p-secret"})
+	require.NoError(t, err)
+	require.True(t, response.Locked)
+
+	for _, userId := range userIds[1:] {
+		user, err := GetUser(ctx, userId)
+		require.NoError(t, err)
+		require.Zero(t, user.FailedLoginCount)
+		require.NotNil(t, user.LastLoginAt)
+	}
+
+	require.NoError(t, UnlockUser(ctx, alice))
+	results, err = BatchComparePassword(ctx, map[string]string{alice: "t0p-secret"})
+	require.NoError(t, err)
+	require.True(t, results[alice])
+}
+
+func TestTimestampsUTC(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+	local := time.Local
+	time.Local = time.FixedZone("UTC+8", 8*60*60)
+	t.Cleanup(func() {
+		time.Local = local
+	})
+	requireUTC := func(v time.Time) {
+		_, offset := v.Zone()
+		require.Zero(t, offset, v.String())
+	}
+
+	userId := createTestUser(t, "alice", "")
+	groupId := createTestGroup(t, "group", "")
+	_, err := ModifyPassword(ctx, &pb.ModifyPasswordRequest{UserId: userId, Password: "n3w-secret"})
+	require.NoError(t, err)
+	_, err = JoinGroup(ctx, &pb.JoinGroupRequest{UserId: []string{userId}, GroupId: []string{groupId}})
+	require.NoError(t, err)
+
+	user, err := GetUser(ctx, userId)
+	require.NoError(t, err)
+	requireUTC(user.CreateTime)
+	requireUTC(user.UpdateTime)
+	requireUTC(*user.PasswordUpdatedAt)
+	bindings, err := GetUserGroupBindings(ctx, []string{userId}, []string{groupId})
+	require.NoError(t, err)
+	require.Len(t, bindings, 1)
+	requireUTC(bindings[0].CreateTime)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"context"
+
+	"openpitrix.io/logger"
+
+	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/db"
+	"cloudbases.io/im/pkg/global"
+	"cloudbases.io/im/pkg/models"
+	"cloudbases.io/im/pkg/util/stringutil"
+)
+
+func GetUserTags(ctx context.Context, userId string) ([]string, error) {
+	var tags []string
+	if err := global.Global().Database.Table(db.TableName(constants.TableUserTag)).
+		Where(constants.ColumnUserId+" = ?", userId).
+		Order(constants.ColumnTag).
+		Pluck(constants.ColumnTag, &tags).Error; err != nil {
+		logger.Errorf(ctx, "Get user [%s] tags failed: %+v", userId, err)
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// AddUserTags adds the tags to user, the tags user already has are skipped
+func AddUserTags(ctx context.Context, userId string, tags []string) error {
+	tags = stringutil.Unique(stringutil.SimplifyStringList(tags))
+	var violations fieldViolations
+	violations.checkNotBlank("user_id", userId)
+	violations.checkNotEmpty("tag", tags)
+	if err := violations.Err(ctx); err != nil {
+		return err
+	}
+	if _, err := GetUser(ctx, userId); err != nil {
+		return err
+	}
+
+	existTags, err := GetUserTags(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	tx := global.Global().Database.Begin()
+	{
+		for _, tag := range tags {
+			if stringutil.Contains(existTags, tag) {
+				continue
+			}
+			if err := tx.Create(models.NewUserTag(userId, tag)).Error; err != nil {
+				tx.Rollback()
+				logger.Errorf(ctx, "Insert user [%s] tag [%s] failed: %+v", userId, tag, err)
+				return db.MapError(err)
+			}
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		logger.Errorf(ctx, "Batch insert user [%s] tags failed: %+v", userId, err)
+		return err
+	}
+
+	return nil
+}
+
+func RemoveUserTags(ctx context.Context, userId string, tags []string) error {
+	tags = stringutil.SimplifyStringList(tags)
+	var violations fieldViolations
+	violations.checkNotBlank("user_id", userId)
+	violations.checkNotEmpty("tag", tags)
+	if err := violations.Err(ctx); err != nil {
+		return err
+	}
+
+	if err := global.Global().Database.
+		Where(constants.ColumnUserId+" = ?", userId).
+		Where(constants.ColumnTag+" in (?)", tags).
+		Delete(models.UserTag{}).Error; err != nil {
+		logger.Errorf(ctx, "Delete user [%s] tags failed: %+v", userId, err)
+		return err
+	}
+
+	return nil
+}
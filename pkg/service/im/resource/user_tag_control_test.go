@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"cloudbases.io/im/pkg/pb"
+)
+
+func TestUserTags(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	userId := createTestUser(t, "tag", "")
+	require.NoError(t, AddUserTags(ctx, userId, []string{"vip", " beta ", "vip"}))
+	require.NoError(t, AddUserTags(ctx, userId, []string{"beta", "staff"}))
+
+	tags, err := GetUserTags(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, []string{"beta", "staff", "vip"}, tags)
+
+	require.NoError(t, RemoveUserTags(ctx, userId, []string{"staff", "unknown"}))
+	tags, err = GetUserTags(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, []string{"beta", "vip"}, tags)
+
+	require.Equal(t, codes.InvalidArgument, status.Code(AddUserTags(ctx, userId, []string{" "})))
+	require.Error(t, AddUserTags(ctx, "uid-unknown", []string{"vip"}))
+}
+
+func TestListUsersByTag(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	vip := createTestUser(t, "vip", "")
+	vipBeta := createTestUser(t, "vip_beta", "")
+	beta := createTestUser(t, "beta", "")
+	createTestUser(t, "none", "")
+	require.NoError(t, AddUserTags(ctx, vip, []string{"vip"}))
+	require.NoError(t, AddUserTags(ctx, vipBeta, []string{"vip", "beta"}))
+	require.NoError(t, AddUserTags(ctx, beta, []string{"beta"}))
+
+	var tests = []struct {
+		tag    []string
+		expect []string
+	}{
+		{tag: []string{"vip"}, expect: []string{vip, vipBeta}},
+		{tag: []string{"vip", "beta"}, expect: []string{vip, vipBeta, beta}},
+		{tag: []string{"staff"}, expect: nil},
+	}
+	for _, v := range tests {
+		response, err := ListUsers(ctx, &pb.ListUsersRequest{Tag: v.tag})
+		require.NoError(t, err)
+		var userIds []string
+		for _, user := range response.UserSet {
+			userIds = append(userIds, user.UserId)
+		}
+		require.ElementsMatch(t, v.expect, userIds, "%v", v.tag)
+		require.EqualValues(t, len(v.expect), response.Total, "%v", v.tag)
+	}
+
+	// coexists with the other filters
+	response, err := ListUsers(ctx, &pb.ListUsersRequest{
+		Tag:        []string{"vip"},
+		SearchWord: []string{"beta"},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.UserSet, 1)
+	require.Equal(t, vipBeta, response.UserSet[0].UserId)
+}
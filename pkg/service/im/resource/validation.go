@@ -0,0 +1,176 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"openpitrix.io/logger"
+
+	"cloudbases.io/im/pkg/constants"
+	"cloudbases.io/im/pkg/util/stringutil"
+)
+
+var reEmail = regexp.MustCompile(`^[^@\s]+@[^@\s]+$`)
+
+// object store keys of the characters safe in any store, e.g. "avatars/u-1.png"
+var reObjectKey = regexp.MustCompile(`^[A-Za-z0-9!_.*'()-]+(/[A-Za-z0-9!_.*'()-]+)*$`)
+
+const maxAvatarUrlLength = 1000
+
+// the length of the group_name column
+const maxGroupNameLength = 50
+
+// fieldViolations collects the invalid fields of a request, so that all of them are reported at once
+type fieldViolations []*errdetails.BadRequest_FieldViolation
+
+func (p *fieldViolations) Add(field, description string) {
+	*p = append(*p, &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: description,
+	})
+}
+
+// Err returns an InvalidArgument error with the violations in a BadRequest detail, nil if there is none
+func (p fieldViolations) Err(ctx context.Context) error {
+	if len(p) == 0 {
+		return nil
+	}
+	var descriptions []string
+	for _, violation := range p {
+		descriptions = append(descriptions, violation.Field+": "+violation.Description)
+	}
+	st := status.Newf(codes.InvalidArgument, "invalid fields: %s", strings.Join(descriptions, "; "))
+	if detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: p}); err == nil {
+		st = detailed
+	}
+	err := st.Err()
+	logger.Errorf(ctx, "%+v", err)
+	return err
+}
+
+// checkNotEmpty records field as violated if it has no values
+func (p *fieldViolations) checkNotEmpty(field string, values []string) {
+	if len(values) == 0 {
+		p.Add(field, "empty "+field)
+	}
+}
+
+// checkNotBlank records field as violated if value is empty
+func (p *fieldViolations) checkNotBlank(field, value string) {
+	if value == "" {
+		p.Add(field, "empty "+field)
+	}
+}
+
+// checkGroupName rejects the empty and overlong names, and the names with the separator of the group paths
+func (p *fieldViolations) checkGroupName(field, groupName string) {
+	switch {
+	case groupName == "":
+		p.Add(field, "empty group name")
+	case utf8.RuneCountInString(groupName) > maxGroupNameLength:
+		p.Add(field, fmt.Sprintf("group name is longer than %d", maxGroupNameLength))
+	case strings.Contains(groupName, constants.GroupPathSep):
+		p.Add(field, "group name ["+groupName+"] contains the reserved character ["+constants.GroupPathSep+"]")
+	}
+}
+
+func (p *fieldViolations) checkEmail(field, email string) {
+	if email != "" && !reEmail.MatchString(email) {
+		p.Add(field, "invalid email ["+email+"]")
+	}
+}
+
+// checkPhoneNumber returns the normalized phoneNumber, or records the violation
+func (p *fieldViolations) checkPhoneNumber(field, phoneNumber string) string {
+	normalized, err := normalizePhone(phoneNumber)
+	if err != nil {
+		p.Add(field, err.Error())
+	}
+	return normalized
+}
+
+func (p *fieldViolations) checkPassword(ctx context.Context, field, password string) {
+	if ok, violations := ValidatePassword(ctx, password); !ok {
+		for _, violation := range violations {
+			p.Add(field, violation)
+		}
+	}
+}
+
+func (p *fieldViolations) checkSearchMode(field, searchMode string) {
+	if searchMode != "" && !stringutil.Contains(constants.SearchModes, searchMode) {
+		p.Add(field, "invalid search mode ["+searchMode+"]")
+	}
+}
+
+// checkTimestamp accepts a null ts, which means no bound
+func (p *fieldViolations) checkTimestamp(field string, ts *timestamp.Timestamp) {
+	if ts == nil {
+		return
+	}
+	if _, err := ptypes.Timestamp(ts); err != nil {
+		p.Add(field, "invalid timestamp: "+err.Error())
+	}
+}
+
+// timestampTime returns the time of ts checked by checkTimestamp, zero time for a null ts
+func timestampTime(ts *timestamp.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	t, _ := ptypes.Timestamp(ts)
+	return t
+}
+
+func (p *fieldViolations) checkDistinctColumn(field, tableName, column string) {
+	if !stringutil.Contains(constants.DistinctColumns[tableName], column) {
+		p.Add(field, "invalid distinct column ["+column+"] of table ["+tableName+"]")
+	}
+}
+
+// checkAvatarUrl accepts an absolute http(s) url or an object store key, empty clears the avatar
+func (p *fieldViolations) checkAvatarUrl(field, avatarUrl string) {
+	if avatarUrl == "" {
+		return
+	}
+	if len(avatarUrl) > maxAvatarUrlLength {
+		p.Add(field, fmt.Sprintf("avatar url is longer than %d", maxAvatarUrlLength))
+		return
+	}
+	if strings.Contains(avatarUrl, "://") {
+		u, err := url.Parse(avatarUrl)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			p.Add(field, "invalid avatar url ["+avatarUrl+"]")
+		}
+		return
+	}
+	if !reObjectKey.MatchString(avatarUrl) || strings.Contains(avatarUrl, "..") {
+		p.Add(field, "invalid avatar object key ["+avatarUrl+"]")
+	}
+}
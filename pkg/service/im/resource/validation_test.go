@@ -0,0 +1,155 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"cloudbases.io/im/pkg/pb"
+)
+
+// violatedFields returns the fields in the BadRequest detail of err
+func violatedFields(t *testing.T, err error) []string {
+	st := status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	var fields []string
+	for _, detail := range st.Details() {
+		badRequest, ok := detail.(*errdetails.BadRequest)
+		require.True(t, ok, "%T", detail)
+		for _, violation := range badRequest.FieldViolations {
+			require.NotEmpty(t, violation.Description)
+			fields = append(fields, violation.Field)
+		}
+	}
+	return fields
+}
+
+func TestCreateUserFieldViolations(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	_, err := CreateUser(ctx, &pb.CreateUserRequest{
+		Username:    " ",
+		Email:       "not an email",
+		PhoneNumber: "not a number",
+		Password:    "short",
+	})
+	// too short and without digit
+	require.Equal(t, []string{"username", "email", "phone_number", "password", "password"}, violatedFields(t, err))
+
+	_, err = CreateUser(ctx, &pb.CreateUserRequest{
+		Username: "valid",
+		Email:    "valid@op.com",
+		Password: "password",
+	})
+	require.Equal(t, []string{"password", "password"}, violatedFields(t, err))
+}
+
+func TestModifyUserFieldViolations(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+	userId := createTestUser(t, "valid", "")
+
+	_, err := ModifyUser(ctx, &pb.ModifyUserRequest{
+		UserId:      userId,
+		Email:       "@",
+		PhoneNumber: "12345",
+	})
+	require.Equal(t, []string{"email", "phone_number"}, violatedFields(t, err))
+
+	_, err = ModifyUser(ctx, &pb.ModifyUserRequest{
+		UserId: userId,
+		Email:  "modified@op.com",
+	})
+	require.NoError(t, err)
+}
+
+func TestModifyPasswordFieldViolations(t *testing.T) {
+	prepare(t)
+	userId := createTestUser(t, "valid", "")
+
+	_, err := ModifyPassword(context.Background(), &pb.ModifyPasswordRequest{
+		UserId:   userId,
+		Password: "passw0rd",
+	})
+	require.Equal(t, []string{"password"}, violatedFields(t, err))
+}
+
+func TestEmptyFieldViolations(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+
+	var tests = []struct {
+		userId  []string
+		groupId []string
+		expect  []string
+	}{
+		{userId: nil, groupId: []string{"gid-1"}, expect: []string{"user_id"}},
+		{userId: []string{"uid-1"}, groupId: nil, expect: []string{"group_id"}},
+		{userId: nil, groupId: nil, expect: []string{"user_id", "group_id"}},
+	}
+	for _, v := range tests {
+		_, err := JoinGroup(ctx, &pb.JoinGroupRequest{UserId: v.userId, GroupId: v.groupId})
+		require.Equal(t, v.expect, violatedFields(t, err))
+		_, err = LeaveGroup(ctx, &pb.LeaveGroupRequest{UserId: v.userId, GroupId: v.groupId})
+		require.Equal(t, v.expect, violatedFields(t, err))
+	}
+
+	require.Equal(t, []string{"user_id"}, violatedFields(t, AcceptInvitations(ctx, "", []string{"gid-1"})))
+	require.Equal(t, []string{"group_id"}, violatedFields(t, AcceptInvitations(ctx, "uid-1", nil)))
+
+	_, err := DeleteUsers(ctx, &pb.DeleteUsersRequest{})
+	require.Equal(t, []string{"user_id"}, violatedFields(t, err))
+	_, err = DeleteGroups(ctx, &pb.DeleteGroupsRequest{})
+	require.Equal(t, []string{"group_id"}, violatedFields(t, err))
+	_, err = BatchCreateGroups(ctx, "", nil)
+	require.Equal(t, []string{"names"}, violatedFields(t, err))
+
+	require.Equal(t, []string{"user_id", "tag"}, violatedFields(t, AddUserTags(ctx, "", []string{" "})))
+	require.Equal(t, []string{"tag"}, violatedFields(t, RemoveUserTags(ctx, "uid-1", nil)))
+}
+
+func TestGroupNameFieldViolations(t *testing.T) {
+	prepare(t)
+	ctx := context.Background()
+	groupId := createTestGroup(t, "valid", "")
+
+	for _, groupName := range []string{" ", strings.Repeat("g", maxGroupNameLength+1), "team.a"} {
+		_, err := CreateGroup(ctx, &pb.CreateGroupRequest{GroupName: groupName})
+		require.Equal(t, []string{"group_name"}, violatedFields(t, err), groupName)
+		_, err = ModifyGroup(ctx, &pb.ModifyGroupRequest{GroupId: groupId, GroupName: groupName})
+		require.Equal(t, []string{"group_name"}, violatedFields(t, err), groupName)
+	}
+	_, err := BatchCreateGroups(ctx, "", []string{"valid-a", "team.b"})
+	require.Equal(t, []string{"names[1]"}, violatedFields(t, err))
+
+	// the longest name is accepted, counted in characters
+	_, err = CreateGroup(ctx, &pb.CreateGroupRequest{GroupName: strings.Repeat("群", maxGroupNameLength)})
+	require.NoError(t, err)
+	_, err = ModifyGroup(ctx, &pb.ModifyGroupRequest{GroupId: groupId, GroupName: "renamed"})
+	require.NoError(t, err)
+	group, err := GetGroup(ctx, groupId)
+	require.NoError(t, err)
+	require.Equal(t, "renamed", group.GroupName)
+}
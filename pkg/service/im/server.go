@@ -17,7 +17,11 @@ limitations under the License.
 package im
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/google/gops/agent"
 	"google.golang.org/grpc"
@@ -28,13 +32,21 @@ import (
 	"cloudbases.io/im/pkg/global"
 	"cloudbases.io/im/pkg/manager"
 	"cloudbases.io/im/pkg/pb"
+	"cloudbases.io/im/pkg/service/im/resource"
 )
 
 type Server struct {
 }
 
 func Serve(cfg *config.Config) {
-	global.SetGlobal(cfg)
+	global.Init(cfg)
+	resource.SetupAuditLog(cfg.Audit)
+	// the server stops on the shutdown signals, then the background loops stop too
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	if cfg.CleanupIntervalSeconds > 0 {
+		resource.StartCleanup(ctx, time.Duration(cfg.CleanupIntervalSeconds)*time.Second)
+	}
 	s := new(Server)
 	if err := agent.Listen(agent.Options{
 		ShutdownCleanup: true,
@@ -48,12 +60,16 @@ func Serve(cfg *config.Config) {
 			os.Exit(1)
 		}
 		manager.NewGrpcServer(cfg.Host, cfg.Port).
+			WithHandlerTimeout(time.Duration(cfg.HandlerTimeoutSeconds) * time.Second).
+			WithContext(ctx).
 			Serve(func(server *grpc.Server) {
 				pb.RegisterIdentityManagerServer(server, s)
 				grpc.Creds(creds)
 			})
 	} else {
 		manager.NewGrpcServer(cfg.Host, cfg.Port).
+			WithHandlerTimeout(time.Duration(cfg.HandlerTimeoutSeconds) * time.Second).
+			WithContext(ctx).
 			Serve(func(server *grpc.Server) {
 				pb.RegisterIdentityManagerServer(server, s)
 			})
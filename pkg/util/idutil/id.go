@@ -95,6 +95,30 @@ func GetUuid36(prefix string) string {
 	return prefix + stringutil.Reverse(i)
 }
 
+// digits and letters in ASCII order, ids encoded by it sort the same as the numbers
+const AlphabetSortable62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+const (
+	sortableIdWidth        = 11 // base62 width of a uint64
+	sortableMachineIdWidth = 3  // base62 width of a uint16
+)
+
+// fixed width base62, padded with '0'
+func encodeSortable(n uint64, width int) string {
+	output := make([]byte, width)
+	for pos := width - 1; pos >= 0; pos-- {
+		output[pos] = AlphabetSortable62[n%62]
+		n /= 62
+	}
+	return string(output)
+}
+
+// GetSortableId returns an id ordered by creation time, so it can be used by keyset pagination.
+// format likes: uid-0OvWp3Vrz3E000
+func GetSortableId(prefix string) string {
+	return prefix + encodeSortable(GetIntId(), sortableIdWidth) + encodeSortable(uint64(upperMachineID), sortableMachineIdWidth)
+}
+
 func randString(letters string, n int) string {
 	output := make([]byte, n)
 
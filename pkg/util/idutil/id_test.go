@@ -18,7 +18,10 @@ package idutil
 
 import (
 	"fmt"
+	"math"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -50,3 +53,49 @@ func TestRandString(t *testing.T) {
 	assert.Equal(t, 255, len(str))
 	t.Log(str)
 }
+
+func TestEncodeSortable(t *testing.T) {
+	assert.Equal(t, "00000000000", encodeSortable(0, sortableIdWidth))
+	assert.Equal(t, "0000000000z", encodeSortable(61, sortableIdWidth))
+	assert.Equal(t, "00000000010", encodeSortable(62, sortableIdWidth))
+	assert.Equal(t, "LygHa16AHYF", encodeSortable(math.MaxUint64, sortableIdWidth))
+	assert.Equal(t, "H31", encodeSortable(math.MaxUint16, sortableMachineIdWidth))
+
+	numbers := []uint64{0, 1, 61, 62, 3843, 3844, 1 << 40, math.MaxUint64}
+	for i := 1; i < len(numbers); i++ {
+		assert.True(t, encodeSortable(numbers[i-1], sortableIdWidth) < encodeSortable(numbers[i], sortableIdWidth))
+	}
+}
+
+func TestGetSortableId(t *testing.T) {
+	id := GetSortableId("uid-")
+	t.Log(id)
+	assert.True(t, strings.HasPrefix(id, "uid-"))
+	assert.Equal(t, len("uid-")+sortableIdWidth+sortableMachineIdWidth, len(id))
+
+	var ids []string
+	for i := 0; i < 1000; i++ {
+		ids = append(ids, GetSortableId("gid-"))
+	}
+	assert.True(t, sort.StringsAreSorted(ids))
+}
+
+func TestGetSortableIdConcurrently(t *testing.T) {
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	ids := make(map[string]bool)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				id := GetSortableId("bid-")
+				mutex.Lock()
+				ids[id] = true
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 8000, len(ids))
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package passwordutil
+
+import (
+	_ "embed"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+//go:embed blocklist.txt
+var defaultBlocklistText string
+
+var defaultBlocklist = NewBlocklist(strings.Split(defaultBlocklistText, "\n"))
+
+// leetReplacer undoes the common letter substitutions, "l" is folded into "i" as "1" may stand for both
+var leetReplacer = strings.NewReplacer(
+	"0", "o",
+	"1", "i",
+	"!", "i",
+	"l", "i",
+	"|", "i",
+	"3", "e",
+	"4", "a",
+	"@", "a",
+	"5", "s",
+	"$", "s",
+	"7", "t",
+	"+", "t",
+)
+
+func normalize(password string) string {
+	return leetReplacer.Replace(strings.ToLower(strings.TrimSpace(password)))
+}
+
+// Blocklist matches passwords case-insensitively and after leet normalization, e.g. "P@ssw0rd" matches "password"
+type Blocklist map[string]bool
+
+func NewBlocklist(passwords []string) Blocklist {
+	blocklist := make(Blocklist)
+	for _, password := range passwords {
+		if password = normalize(password); password != "" {
+			blocklist[password] = true
+		}
+	}
+	return blocklist
+}
+
+// DefaultBlocklist is the embedded list of common passwords
+func DefaultBlocklist() Blocklist {
+	return defaultBlocklist
+}
+
+func (p Blocklist) Contains(password string) bool {
+	return p[normalize(password)]
+}
+
+var blocklistCache sync.Map
+
+// LoadBlocklist reads a blocklist of one password per line from file, the result is cached by file
+func LoadBlocklist(file string) (Blocklist, error) {
+	if blocklist, ok := blocklistCache.Load(file); ok {
+		return blocklist.(Blocklist), nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	blocklist := NewBlocklist(strings.Split(string(data), "\n"))
+	blocklistCache.Store(file, blocklist)
+	return blocklist, nil
+}
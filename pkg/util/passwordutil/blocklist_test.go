@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package passwordutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "cloudbases.io/im/pkg/util/assert"
+)
+
+func TestBlocklistExact(t *testing.T) {
+	blocklist := DefaultBlocklist()
+	Assert(t, blocklist.Contains("password"))
+	Assert(t, blocklist.Contains("PASSWORD"))
+	Assert(t, blocklist.Contains("Qwerty123"))
+	Assert(t, !blocklist.Contains("t0p-secret"))
+}
+
+func TestBlocklistLeet(t *testing.T) {
+	blocklist := DefaultBlocklist()
+	for _, password := range []string{"passw0rd", "P@ssw0rd", "pa$$w0rd", "p4ssword", "Passw0rd1", "1loveyou", "!lov3you", "tru5tn0l"} {
+		Assertf(t, blocklist.Contains(password), "%q should be blocked", password)
+	}
+	Assert(t, !blocklist.Contains("passw0rdx"))
+}
+
+func TestLoadBlocklist(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "blocklist.txt")
+	err := ioutil.WriteFile(file, []byte("Kubesphere\n\n  openpitrix  \n"), 0644)
+	Assert(t, err == nil)
+
+	blocklist, err := LoadBlocklist(file)
+	Assertf(t, err == nil, "%+v", err)
+	Assert(t, len(blocklist) == 2)
+	Assert(t, blocklist.Contains("kub3sph3r3"))
+	Assert(t, blocklist.Contains("OpenPitrix"))
+	Assert(t, !blocklist.Contains("password"))
+
+	_, err = LoadBlocklist(filepath.Join(os.TempDir(), "not-exist", "blocklist.txt"))
+	Assert(t, err != nil)
+}
+
+func TestPolicyBlocklist(t *testing.T) {
+	p := Policy{Blocklist: DefaultBlocklist()}
+	Assert(t, len(p.Validate("P@ssw0rd")) == 1)
+	Assert(t, len(p.Validate("t0p-secret")) == 0)
+	Assert(t, len(Policy{}.Validate("P@ssw0rd")) == 0)
+}
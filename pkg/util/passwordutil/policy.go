@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package passwordutil
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// bcrypt only uses the first 72 bytes of password
+const MaxLength = 72
+
+type Policy struct {
+	MinLength     int
+	RequireLetter bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// passwords in the blocklist are rejected, nil means no blocklist
+	Blocklist Blocklist
+}
+
+// Validate returns the rules of policy password violates, empty means password is acceptable
+func (p Policy) Validate(password string) []string {
+	var violations []string
+	if len([]rune(password)) < p.MinLength {
+		violations = append(violations, fmt.Sprintf("password must contain at least %d characters", p.MinLength))
+	}
+	if len(password) > MaxLength {
+		violations = append(violations, fmt.Sprintf("password must not be longer than %d bytes", MaxLength))
+	}
+
+	var hasLetter, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireLetter && !hasLetter {
+		violations = append(violations, "password must contain a letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, "password must contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, "password must contain a symbol")
+	}
+	if p.Blocklist.Contains(password) {
+		violations = append(violations, "password is too common")
+	}
+	return violations
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package passwordutil
+
+import (
+	"strings"
+	"testing"
+
+	. "cloudbases.io/im/pkg/util/assert"
+)
+
+func TestPolicyMinLength(t *testing.T) {
+	p := Policy{MinLength: 8}
+	Assert(t, len(p.Validate("1234567")) == 1)
+	Assert(t, len(p.Validate("12345678")) == 0)
+	// characters rather than bytes
+	Assert(t, len(p.Validate("密码密码密码密码")) == 0)
+	Assert(t, len(p.Validate("密码密码")) == 1)
+}
+
+func TestPolicyMaxLength(t *testing.T) {
+	p := Policy{}
+	Assert(t, len(p.Validate(strings.Repeat("a", MaxLength))) == 0)
+	Assert(t, len(p.Validate(strings.Repeat("a", MaxLength+1))) == 1)
+}
+
+func TestPolicyRequireLetter(t *testing.T) {
+	p := Policy{RequireLetter: true}
+	Assert(t, len(p.Validate("12345678")) == 1)
+	Assert(t, len(p.Validate("1234567a")) == 0)
+}
+
+func TestPolicyRequireDigit(t *testing.T) {
+	p := Policy{RequireDigit: true}
+	Assert(t, len(p.Validate("password")) == 1)
+	Assert(t, len(p.Validate("passw0rd")) == 0)
+}
+
+func TestPolicyRequireSymbol(t *testing.T) {
+	p := Policy{RequireSymbol: true}
+	Assert(t, len(p.Validate("passw0rd")) == 1)
+	Assert(t, len(p.Validate("passw0rd!")) == 0)
+	Assert(t, len(p.Validate("passw0rd$")) == 0)
+}
+
+func TestPolicyViolations(t *testing.T) {
+	p := Policy{MinLength: 8, RequireLetter: true, RequireDigit: true, RequireSymbol: true}
+	violations := p.Validate("")
+	Assertf(t, len(violations) == 4, "violations = %q", violations)
+	violations = p.Validate("passw0rd!")
+	Assertf(t, len(violations) == 0, "violations = %q", violations)
+}
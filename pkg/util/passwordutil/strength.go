@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package passwordutil
+
+import (
+	"math"
+	"unicode"
+)
+
+const (
+	StrengthVeryWeak = iota
+	StrengthWeak
+	StrengthFair
+	StrengthStrong
+	StrengthVeryStrong
+)
+
+// IsCommon reports whether password is in the default blocklist
+func IsCommon(password string) bool {
+	return defaultBlocklist.Contains(password)
+}
+
+// Strength estimates the strength of password from StrengthVeryWeak to StrengthVeryStrong,
+// by the entropy of its length and character classes, common passwords are always very weak.
+func Strength(password string) int {
+	if password == "" || IsCommon(password) {
+		return StrengthVeryWeak
+	}
+
+	var lower, upper, digit, symbol, other bool
+	unique := make(map[rune]bool)
+	for _, r := range password {
+		unique[r] = true
+		switch {
+		case unicode.IsLower(r) && r < unicode.MaxASCII:
+			lower = true
+		case unicode.IsUpper(r) && r < unicode.MaxASCII:
+			upper = true
+		case unicode.IsDigit(r) && r < unicode.MaxASCII:
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+	charset := 0
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.present {
+			charset += class.size
+		}
+	}
+
+	// repeated characters add little, e.g. "aaaaaaaa"
+	length := len([]rune(password))
+	if length > 2*len(unique) {
+		length = 2 * len(unique)
+	}
+	entropy := float64(length) * math.Log2(float64(charset))
+
+	switch {
+	case entropy < 25:
+		return StrengthVeryWeak
+	case entropy < 35:
+		return StrengthWeak
+	case entropy < 50:
+		return StrengthFair
+	case entropy < 70:
+		return StrengthStrong
+	default:
+		return StrengthVeryStrong
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package passwordutil
+
+import (
+	"testing"
+
+	. "cloudbases.io/im/pkg/util/assert"
+)
+
+func TestIsCommon(t *testing.T) {
+	Assert(t, IsCommon("password"))
+	Assert(t, IsCommon("PassWord"))
+	Assert(t, IsCommon("qwerty123"))
+	Assert(t, !IsCommon("correct horse battery staple"))
+}
+
+func TestStrength(t *testing.T) {
+	var tests = []struct {
+		password string
+		strength int
+	}{
+		{"", StrengthVeryWeak},
+		{"password", StrengthVeryWeak},
+		{"Password", StrengthVeryWeak},
+		{"aaaaaaaaaaaaaaaa", StrengthVeryWeak},
+		{"abc", StrengthVeryWeak},
+		{"kdjwpq", StrengthWeak},
+		{"kdjw1pq3", StrengthFair},
+		{"Kdjw1pq3!x", StrengthStrong},
+		{"Tr0ub4dor&3xz!Q", StrengthVeryStrong},
+		{"correct horse battery staple", StrengthVeryStrong},
+	}
+	for _, tt := range tests {
+		strength := Strength(tt.password)
+		Assertf(t, strength == tt.strength, "Strength(%q) = %d, expected %d", tt.password, strength, tt.strength)
+	}
+}
+
+func TestStrengthWeakVsStrong(t *testing.T) {
+	Assert(t, Strength("kdjw1pq3") < Strength("Kdjw1pq3!x"))
+	Assert(t, Strength("qwerty") < Strength("qwertz"))
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phoneutil
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// E.164: a leading '+', a country code that does not start with 0,
+// 8 to 15 digits in total
+var reE164 = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// separators users commonly type into a phone number
+var phoneSeparators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "", "\t", "")
+
+// Normalize converts phone into E.164 format, numbers without international
+// prefix are treated as national numbers of defaultCountryCode.
+// "+86 100-0000-0000" => "+8610000000000"
+// "0086 10000000000" => "+8610000000000"
+func Normalize(phone, defaultCountryCode string) (string, error) {
+	s := phoneSeparators.Replace(strings.TrimSpace(phone))
+	switch {
+	case strings.HasPrefix(s, "+"):
+	case strings.HasPrefix(s, "00"):
+		s = "+" + strings.TrimPrefix(s, "00")
+	case defaultCountryCode != "":
+		s = "+" + strings.TrimPrefix(defaultCountryCode, "+") + s
+	}
+	if !reE164.MatchString(s) {
+		return "", fmt.Errorf("invalid phone number [%s]", phone)
+	}
+	return s, nil
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phoneutil
+
+import (
+	"testing"
+
+	. "cloudbases.io/im/pkg/util/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	var tests = []struct{ s, expect string }{
+		{s: "+8610000000000", expect: "+8610000000000"},
+		{s: "+86 100-0000-0000", expect: "+8610000000000"},
+		{s: "0086 10000000000", expect: "+8610000000000"},
+		{s: "10000000000", expect: "+8610000000000"},
+		{s: "+1 (415) 555.2671", expect: "+14155552671"},
+	}
+	for _, v := range tests {
+		got, err := Normalize(v.s, "86")
+		Assertf(t, err == nil, "normalize %q failed: %v", v.s, err)
+		Assertf(t, got == v.expect, "expect = %q, got = %q", v.expect, got)
+	}
+}
+
+func TestNormalizeInvalid(t *testing.T) {
+	var tests = []string{
+		"",
+		"abc",
+		"+0123456789",
+		"+1234567890123456",
+		"+86 1000a000000",
+		"12345",
+	}
+	for _, s := range tests {
+		_, err := Normalize(s, "86")
+		Assertf(t, err != nil, "expect %q to be invalid", s)
+	}
+}
+
+func TestNormalizeWithoutDefaultCountryCode(t *testing.T) {
+	_, err := Normalize("10000000000", "")
+	Assert(t, err != nil)
+
+	got, err := Normalize("+8610000000000", "")
+	Assert(t, err == nil)
+	Assert(t, got == "+8610000000000")
+}
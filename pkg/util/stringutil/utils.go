@@ -19,7 +19,10 @@ package stringutil
 import (
 	"regexp"
 	"strings"
+	"unicode"
 	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 func NewString(v string) *string {
@@ -43,6 +46,17 @@ func SimplifyString(s string) string {
 	return reMoreSpace.ReplaceAllString(strings.TrimSpace(s), " ")
 }
 
+// RemoveAccents strips the combining marks after NFD decomposition, e.g. "José" becomes "Jose"
+func RemoveAccents(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return norm.NFC.String(b.String())
+}
+
 func Contains(ss []string, s string) bool {
 	for _, v := range ss {
 		if v == s {
@@ -52,6 +66,19 @@ func Contains(ss []string, s string) bool {
 	return false
 }
 
+// Unique removes duplicated strings, the order of first occurrence is kept
+func Unique(ss []string) []string {
+	seen := make(map[string]bool, len(ss))
+	var b []string
+	for _, s := range ss {
+		if !seen[s] {
+			seen[s] = true
+			b = append(b, s)
+		}
+	}
+	return b
+}
+
 func Reverse(s string) string {
 	size := len(s)
 	buf := make([]byte, size)
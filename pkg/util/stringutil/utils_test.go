@@ -43,3 +43,26 @@ func TestSimplifyString(t *testing.T) {
 		Assertf(t, got == v.expect, "expect = %q, got = %q", v.expect, got)
 	}
 }
+
+func TestUnique(t *testing.T) {
+	s := Unique([]string{"a", "b", "a", "c", "b"})
+
+	Assert(t, len(s) == 3)
+	Assert(t, s[0] == "a")
+	Assert(t, s[1] == "b")
+	Assert(t, s[2] == "c")
+}
+
+func TestRemoveAccents(t *testing.T) {
+	var tests = []struct{ s, expect string }{
+		{s: "José", expect: "Jose"},
+		{s: "José", expect: "Jose"},
+		{s: "Ångström", expect: "Angstrom"},
+		{s: "crème brûlée", expect: "creme brulee"},
+		{s: "张三", expect: "张三"},
+	}
+	for _, v := range tests {
+		got := RemoveAccents(v.s)
+		Assertf(t, got == v.expect, "expect = %q, got = %q", v.expect, got)
+	}
+}
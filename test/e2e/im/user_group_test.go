@@ -42,13 +42,13 @@ func TestUserGroup(t *testing.T) {
 	user := &pb.User{
 		Username:    "test",
 		Email:       "test@op.com",
-		PhoneNumber: "10000000000",
+		PhoneNumber: "+8610000000000",
 		Description: "for test",
 		Extra: map[string]string{
 			"age": "20",
 		},
 	}
-	password := "passw0rd"
+	password := "t0p-secret"
 
 	ctx := context.Background()
 
@@ -43,13 +43,13 @@ func TestUser(t *testing.T) {
 	user := &pb.User{
 		Username:    "test",
 		Email:       "test@op.com",
-		PhoneNumber: "10000000000",
+		PhoneNumber: "+8610000000000",
 		Description: "for test",
 		Extra: map[string]string{
 			"age": "20",
 		},
 	}
-	password := "passw0rd"
+	password := "t0p-secret"
 
 	ctx := context.Background()
 
@@ -81,6 +81,14 @@ func TestUser(t *testing.T) {
 	require.NoError(t, err)
 	require.EqualValues(t, comparePasswordResponse.Ok, true)
 
+	// compare password, use phone number
+	comparePasswordResponse, err = imClient.ComparePassword(ctx, &pb.ComparePasswordRequest{
+		PhoneNumber: user.PhoneNumber,
+		Password:    password,
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, comparePasswordResponse.Ok, true)
+
 	// get user
 	getUserResponse, err := imClient.GetUser(ctx, &pb.GetUserRequest{
 		UserId: user.UserId,
@@ -109,7 +117,7 @@ func TestUser(t *testing.T) {
 	// modify user
 	user.Username = "new test"
 	user.Email = "new_test@op.com"
-	user.PhoneNumber = "11111111111"
+	user.PhoneNumber = "+8611111111111"
 	user.Description = "for new test"
 	user.Extra = map[string]string{
 		"age": "21",